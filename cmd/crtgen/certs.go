@@ -0,0 +1,180 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"net/url"
+	"os"
+	"time"
+)
+
+const (
+	keyECDSA = "ecdsa"
+	keyRSA   = "rsa"
+)
+
+type (
+	// request describes a certificate to issue.
+	request struct {
+		keyType  string
+		validity time.Duration
+		cn       string
+		dns      []string
+		ips      []string
+		uris     []string
+		isCA     bool
+		server   bool
+		parent   *authority // nil for a self-signed CA
+	}
+
+	authority struct {
+		cert *x509.Certificate
+		key  crypto.Signer
+	}
+)
+
+// issue creates a certificate described by req and writes it
+// and its private key in PEM format to crtPath and keyPath.
+func issue(req request, crtPath, keyPath string) error {
+	key, err := generateKey(req.keyType)
+	if err != nil {
+		return err
+	}
+
+	tmpl, err := template(req)
+	if err != nil {
+		return err
+	}
+
+	parent, signer := tmpl, key
+	if req.parent != nil {
+		parent, signer = req.parent.cert, req.parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, key.Public(), signer)
+	if err != nil {
+		return fmt.Errorf("failed to create certificate: %w", err)
+	}
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return fmt.Errorf("failed to encode key: %w", err)
+	}
+
+	if err := writePEM(keyPath, "PRIVATE KEY", keyDER, 0o600); err != nil {
+		return err
+	}
+
+	return writePEM(crtPath, "CERTIFICATE", der, 0o644)
+}
+
+func template(req request) (*x509.Certificate, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: req.cn},
+		NotBefore:    now.Add(-time.Minute),
+		NotAfter:     now.Add(req.validity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		DNSNames:     req.dns,
+	}
+
+	for _, v := range req.ips {
+		ip := net.ParseIP(v)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid IP address %q", v)
+		}
+
+		tmpl.IPAddresses = append(tmpl.IPAddresses, ip)
+	}
+
+	for _, v := range req.uris {
+		u, err := url.Parse(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid URI %q: %w", v, err)
+		}
+
+		tmpl.URIs = append(tmpl.URIs, u)
+	}
+
+	switch {
+	case req.isCA:
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.MaxPathLenZero = true
+		tmpl.KeyUsage |= x509.KeyUsageCertSign | x509.KeyUsageCRLSign
+	case req.server:
+		tmpl.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+	default:
+		tmpl.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+	}
+
+	if req.keyType == keyRSA {
+		tmpl.KeyUsage |= x509.KeyUsageKeyEncipherment
+	}
+
+	return tmpl, nil
+}
+
+func generateKey(keyType string) (crypto.Signer, error) {
+	switch keyType {
+	case keyECDSA:
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case keyRSA:
+		return rsa.GenerateKey(rand.Reader, 3072)
+	default:
+		return nil, fmt.Errorf("unsupported key algorithm %q, want %v or %v", keyType, keyECDSA, keyRSA)
+	}
+}
+
+func loadCA(crtPath, keyPath string) (*authority, error) {
+	pair, err := tls.LoadX509KeyPair(crtPath, keyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return nil, err
+	}
+
+	if !cert.IsCA {
+		return nil, fmt.Errorf("%v is not a CA certificate", crtPath)
+	}
+
+	key, ok := pair.PrivateKey.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported CA key type %T", pair.PrivateKey)
+	}
+
+	return &authority{cert: cert, key: key}, nil
+}
+
+func writePEM(path, blockType string, der []byte, perm os.FileMode) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := pem.Encode(f, &pem.Block{Type: blockType, Bytes: der}); err != nil {
+		return err
+	}
+
+	return f.Close()
+}
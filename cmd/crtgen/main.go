@@ -1,81 +1,165 @@
+// Command crtgen is a small local CA tool that issues certificates
+// for the shortener servers and their mTLS clients.
+//
+// Usage:
+//
+//	crtgen ca [flags]      create a CA certificate, ca.crt and ca.key
+//	crtgen server [flags]  issue a server certificate signed by the CA,
+//	                       example.crt and example.key by default
+//	crtgen client [flags]  issue a client certificate signed by the CA,
+//	                       <cn>.crt and <cn>.key by default
+//
+// Run crtgen <command> -h to list flags of a command.
 package main
 
 import (
-	"crypto/rand"
-	"crypto/rsa"
-	"crypto/x509"
-	"crypto/x509/pkix"
-	"encoding/pem"
+	"flag"
+	"fmt"
 	"log"
-	"math/big"
-	"net"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
+
+	"github.com/usa4ev/urlshortner/internal/config"
+)
+
+const (
+	// defaultPath is the folder the servers load TLS files from by default.
+	defaultPath = config.DefaultSSLPath
+	// defaultServerName is the name of certificate files the servers load.
+	defaultServerName = "example"
+	day               = 24 * time.Hour
 )
 
-const defaultPath = "./ssl"
+const usage = `usage: crtgen <command> [flags]
+
+commands:
+  ca      create a CA certificate
+  server  issue a server certificate signed by the CA
+  client  issue a client certificate signed by the CA`
 
 func main() {
-	var path string
+	log.SetFlags(0)
 
-	if len(os.Args) > 1 {
-		path = os.Args[1]
+	if len(os.Args) < 2 {
+		log.Fatal(usage)
 	}
 
-	if path == "" {
-		path = defaultPath
+	if err := run(os.Args[1], os.Args[2:]); err != nil {
+		log.Fatal(err)
 	}
+}
+
+func run(cmd string, args []string) error {
+	fs := flag.NewFlagSet("crtgen "+cmd, flag.ContinueOnError)
+
+	dir := fs.String("dir", defaultPath, "directory to write certificate and key files to")
+	keyType := fs.String("key", keyECDSA, "key algorithm: ecdsa or rsa")
+	days := fs.Int("days", 365, "certificate validity in days")
+	cn := fs.String("cn", "", "subject common name")
+	name := fs.String("name", "", "base name of certificate and key files")
+	caCert := fs.String("ca-cert", "", "CA certificate file (default <dir>/ca.crt)")
+	caKey := fs.String("ca-key", "", "CA key file (default <dir>/ca.key)")
 
-	cert := &x509.Certificate{
-		SerialNumber: big.NewInt(1658),
-		Subject: pkix.Name{
-			Organization: []string{"Yandex.Praktikum"},
-			Country:      []string{"RU"},
-		},
-		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
-		NotBefore:    time.Now(),
-		NotAfter:     time.Now().AddDate(10, 0, 0),
-		SubjectKeyId: []byte{1, 2, 3, 4, 6},
-		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
-		KeyUsage:     x509.KeyUsageDigitalSignature,
+	var dns, ips, uris string
+
+	switch cmd {
+	case "ca":
+		*days = 3650
+	case "server":
+		fs.StringVar(&dns, "dns", "localhost", "comma-separated DNS names")
+		fs.StringVar(&ips, "ip", "127.0.0.1,::1", "comma-separated IP addresses")
+	case "client":
+		fs.StringVar(&dns, "dns", "", "comma-separated DNS names")
+		fs.StringVar(&uris, "uri", "", "comma-separated URIs, e.g. spiffe://example.com/service")
+	default:
+		return fmt.Errorf("unknown command %q\n%v", cmd, usage)
 	}
 
-	privateKey, err := rsa.GenerateKey(rand.Reader, 4096)
-	if err != nil {
-		log.Fatal(err)
+	if err := fs.Parse(args); err != nil {
+		return err
 	}
 
-	certBytes, err := x509.CreateCertificate(rand.Reader, cert, cert, &privateKey.PublicKey, privateKey)
-	if err != nil {
-		log.Fatal(err)
+	req := request{
+		keyType:  *keyType,
+		validity: time.Duration(*days) * day,
+		cn:       *cn,
+		dns:      splitList(dns),
+		ips:      splitList(ips),
+		uris:     splitList(uris),
 	}
 
-	// encode certificate and write to a file
-	crtFile, err := os.OpenFile(filepath.Join(path, ".crt"), os.O_CREATE|os.O_WRONLY, 0o644)
-	if err != nil {
-		log.Fatal(err)
+	if *caCert == "" {
+		*caCert = filepath.Join(*dir, "ca.crt")
 	}
 
-	err = pem.Encode(crtFile, &pem.Block{
-		Type:  "CERTIFICATE",
-		Bytes: certBytes,
-	})
-	if err != nil {
-		log.Fatal(err)
+	if *caKey == "" {
+		*caKey = filepath.Join(*dir, "ca.key")
 	}
 
-	// encode key and write to a file
-	keyFile, err := os.OpenFile(filepath.Join(path, ".key"), os.O_CREATE|os.O_RDWR, 0o644)
-	if err != nil {
-		log.Fatal(err)
+	switch cmd {
+	case "ca":
+		req.isCA = true
+		if req.cn == "" {
+			req.cn = "urlshortner local CA"
+		}
+
+		if *name == "" {
+			*name = "ca"
+		}
+	case "server":
+		req.server = true
+		if req.cn == "" && len(req.dns) > 0 {
+			req.cn = req.dns[0]
+		}
+
+		if *name == "" {
+			*name = defaultServerName
+		}
+	case "client":
+		if req.cn == "" {
+			return fmt.Errorf("client certificate requires -cn flag")
+		}
+
+		if *name == "" {
+			*name = req.cn
+		}
 	}
 
-	err = pem.Encode(keyFile, &pem.Block{
-		Type:  "RSA PRIVATE KEY",
-		Bytes: x509.MarshalPKCS1PrivateKey(privateKey),
-	})
-	if err != nil {
-		log.Fatal(err)
+	if !req.isCA {
+		ca, err := loadCA(*caCert, *caKey)
+		if err != nil {
+			return fmt.Errorf("failed to load CA: %w", err)
+		}
+
+		req.parent = ca
+	}
+
+	if err := os.MkdirAll(*dir, 0o755); err != nil {
+		return err
+	}
+
+	crtPath := filepath.Join(*dir, *name+".crt")
+	keyPath := filepath.Join(*dir, *name+".key")
+
+	if err := issue(req, crtPath, keyPath); err != nil {
+		return err
 	}
+
+	log.Printf("wrote %v and %v", crtPath, keyPath)
+
+	return nil
+}
+
+func splitList(s string) []string {
+	res := make([]string, 0)
+
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			res = append(res, v)
+		}
+	}
+
+	return res
 }
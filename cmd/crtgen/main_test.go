@@ -0,0 +1,61 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_run(t *testing.T) {
+	for _, keyType := range []string{keyECDSA, keyRSA} {
+		t.Run(keyType, func(t *testing.T) {
+			dir := t.TempDir()
+
+			require.NoError(t, run("ca", []string{"-dir", dir, "-key", keyType}))
+			require.NoError(t, run("server", []string{"-dir", dir, "-key", keyType, "-dns", "localhost,short.example", "-ip", "127.0.0.1"}))
+			require.NoError(t, run("client", []string{"-dir", dir, "-key", keyType, "-cn", "stats-exporter", "-days", "30"}))
+
+			caPEM, err := os.ReadFile(filepath.Join(dir, "ca.crt"))
+			require.NoError(t, err)
+
+			roots := x509.NewCertPool()
+			require.True(t, roots.AppendCertsFromPEM(caPEM))
+
+			server := loadCert(t, dir, defaultServerName)
+			_, err = server.Verify(x509.VerifyOptions{DNSName: "short.example", Roots: roots})
+			assert.NoError(t, err)
+			assert.Len(t, server.IPAddresses, 1)
+
+			client := loadCert(t, dir, "stats-exporter")
+			_, err = client.Verify(x509.VerifyOptions{Roots: roots, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}})
+			assert.NoError(t, err)
+			assert.Equal(t, "stats-exporter", client.Subject.CommonName)
+		})
+	}
+
+	t.Run("errors", func(t *testing.T) {
+		dir := t.TempDir()
+
+		assert.Error(t, run("unknown", nil))
+		assert.Error(t, run("server", []string{"-dir", dir}), "CA is required")
+
+		require.NoError(t, run("ca", []string{"-dir", dir}))
+		assert.Error(t, run("client", []string{"-dir", dir}), "common name is required")
+		assert.Error(t, run("ca", []string{"-dir", dir, "-key", "dsa"}))
+	})
+}
+
+func loadCert(t *testing.T, dir, name string) *x509.Certificate {
+	pair, err := tls.LoadX509KeyPair(filepath.Join(dir, name+".crt"), filepath.Join(dir, name+".key"))
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	require.NoError(t, err)
+
+	return cert
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"io"
+	"log"
+	"os"
+
+	"github.com/usa4ev/urlshortner/internal/bulk"
+	"github.com/usa4ev/urlshortner/internal/config"
+	"github.com/usa4ev/urlshortner/internal/server/auth"
+	"github.com/usa4ev/urlshortner/internal/shortener"
+	"github.com/usa4ev/urlshortner/internal/storage"
+	"github.com/usa4ev/urlshortner/internal/storage/model"
+)
+
+// newUser is the user runImport imports links for if -user is not set.
+// The session token lets the operator pass the links to the user.
+type newUser struct {
+	UserID       string `json:"user_id"`
+	SessionToken string `json:"session_token"`
+}
+
+// runImport imports links from a file into the storage the server is
+// configured with: shortener import [-user id] [-format f] <file> [server flags].
+// Links of a new user are imported unless -user is set, its ID and session
+// token are written to w as the first NDJSON line then, see newUser.
+// Records that are not created are written to w as NDJSON bulk.Result lines.
+func runImport(args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("shortener import", flag.ContinueOnError)
+	userID := fs.String("user", "", "ID of the user to import links for, a new user by default")
+	formatName := fs.String("format", "", "csv or ndjson, by file extension by default")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() == 0 {
+		return errors.New("usage: shortener import [-user id] [-format csv|ndjson] <file> [server flags]")
+	}
+
+	path := fs.Arg(0)
+
+	format, err := bulkFormat(*formatName, path)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	strg, s, err := openShortener(fs.Args()[1:])
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	enc := json.NewEncoder(w)
+
+	if *userID == "" {
+		var token string
+
+		*userID, token, err = auth.OpenSession(strg)
+		if err != nil {
+			return err
+		}
+
+		// the token is only given to the operator, it must not get to logs
+		if err := enc.Encode(newUser{UserID: *userID, SessionToken: token}); err != nil {
+			return err
+		}
+
+		log.Printf("importing links for new user %v", *userID)
+	}
+	store := func(links []model.Link) ([]error, error) {
+		return s.StoreURLs(*userID, links, false)
+	}
+
+	summary, err := bulk.Import(bulk.NewReader(f, format), store, func(res bulk.Result) error {
+		return enc.Encode(res)
+	})
+
+	// links imported before an error are kept
+	if flushErr := strg.Flush(); flushErr != nil && err == nil {
+		err = flushErr
+	}
+
+	log.Printf("created %v, conflicts %v, invalid %v, over quota %v",
+		summary.Created, summary.Conflicts, summary.Invalid, summary.QuotaExceeded)
+
+	return err
+}
+
+// runExport writes links to a file or, if the file is -, to w:
+// shortener export [-user id] [-format f] <file> [server flags].
+// All links are exported unless -user is set.
+func runExport(args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("shortener export", flag.ContinueOnError)
+	userID := fs.String("user", "", "ID of the user to export links of, all links by default")
+	formatName := fs.String("format", "", "csv or ndjson, by file extension by default")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() == 0 {
+		return errors.New("usage: shortener export [-user id] [-format csv|ndjson] <file|-> [server flags]")
+	}
+
+	path := fs.Arg(0)
+
+	name := path
+	if path == "-" && *formatName == "" {
+		name = "stdout.ndjson"
+	}
+
+	format, err := bulkFormat(*formatName, name)
+	if err != nil {
+		return err
+	}
+
+	_, s, err := openShortener(fs.Args()[1:])
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	if path != "-" {
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		w = f
+	}
+
+	bw := bulk.NewWriter(w, format)
+
+	if *userID == "" {
+		err = s.RangeLinks(bw.Write)
+	} else {
+		var links []model.Link
+
+		links, err = s.LoadLinksByUser(*userID)
+		for i := 0; err == nil && i < len(links); i++ {
+			err = bw.Write(links[i])
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return bw.Flush()
+}
+
+// bulkFormat returns the format named name or, if it is empty,
+// the format of the file at path.
+func bulkFormat(name, path string) (bulk.Format, error) {
+	if name != "" {
+		return bulk.ParseFormat(name)
+	}
+
+	return bulk.FormatByName(path)
+}
+
+// openShortener opens the storage and the shortener
+// configured with the server flags in args.
+func openShortener(args []string) (*storage.Storage, *shortener.MyShortener, error) {
+	cfg, err := config.New(config.WithOsArgs(args))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	strg, err := storage.New(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s, err := shortener.NewShortener(cfg, strg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return strg, s, nil
+}
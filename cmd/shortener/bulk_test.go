@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/usa4ev/urlshortner/internal/bulk"
+)
+
+func Test_runImportExport(t *testing.T) {
+	dir := t.TempDir()
+	storagePath := filepath.Join(dir, "storage.csv")
+
+	in := filepath.Join(dir, "links.csv")
+	require.NoError(t, os.WriteFile(in, []byte("old1,http://ya.ru/old\nold1,http://go.dev\nhttp://vk.com\n"), 0o644))
+
+	var out bytes.Buffer
+	require.NoError(t, runImport([]string{"-user", "importer", in, "-f", storagePath}, &out))
+
+	var res bulk.Result
+	require.NoError(t, json.NewDecoder(&out).Decode(&res))
+	assert.Equal(t, bulk.Result{Line: 2, ID: "old1", Status: bulk.StatusConflict, Error: res.Error}, res)
+
+	t.Run("export user links", func(t *testing.T) {
+		var out bytes.Buffer
+		require.NoError(t, runExport([]string{"-user", "importer", "-format", "csv", "-", "-f", storagePath}, &out))
+
+		assert.Contains(t, out.String(), "old1,http://localhost:8080/old1,http://ya.ru/old,importer,false,false\n")
+		assert.Equal(t, 3, strings.Count(out.String(), "\n"), "header and 2 links")
+	})
+
+	t.Run("export all links to file", func(t *testing.T) {
+		path := filepath.Join(dir, "all.ndjson")
+		require.NoError(t, runExport([]string{path, "-f", storagePath}, &bytes.Buffer{}))
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, 2, strings.Count(string(data), "\n"))
+	})
+
+	t.Run("import for new user", func(t *testing.T) {
+		var logs bytes.Buffer
+
+		defer log.SetOutput(log.Writer())
+		log.SetOutput(&logs)
+
+		in := filepath.Join(dir, "new.ndjson")
+		require.NoError(t, os.WriteFile(in, []byte(`{"original_url": "http://ya.ru/new"}`+"\n"), 0o644))
+
+		var out bytes.Buffer
+		require.NoError(t, runImport([]string{in, "-f", storagePath}, &out))
+
+		var user newUser
+		require.NoError(t, json.NewDecoder(&out).Decode(&user))
+		require.NotEmpty(t, user.UserID)
+		require.NotEmpty(t, user.SessionToken)
+
+		assert.Contains(t, logs.String(), user.UserID)
+		assert.NotContains(t, logs.String(), user.SessionToken, "session token must not be logged")
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		assert.Error(t, runImport(nil, &bytes.Buffer{}))
+		assert.Error(t, runImport([]string{filepath.Join(dir, "links.xml")}, &bytes.Buffer{}))
+		assert.Error(t, runExport([]string{"-format", "xml", "-"}, &bytes.Buffer{}))
+	})
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/usa4ev/urlshortner/internal/config"
+)
+
+// runConfig runs config subcommands with args parsed as the server flags:
+// check validates the configuration, print shows effective settings
+// along with their sources and validates them too.
+func runConfig(cmd string, args []string, w io.Writer) error {
+	opts := config.WithOsArgs(args)
+
+	switch cmd {
+	case "check":
+		if _, err := config.New(opts); err != nil {
+			return err
+		}
+
+		fmt.Fprintln(w, "config is valid")
+
+		return nil
+	case "print":
+		settings, err := config.Describe(opts)
+		if err != nil {
+			return err
+		}
+
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		for _, s := range settings {
+			value, source := s.Value, s.Source
+			if s.Secret && value != "" {
+				value = "***"
+			}
+
+			if source == "" {
+				source = "unset"
+			}
+
+			fmt.Fprintf(tw, "%v\t%v\t%v\n", s.Name, value, source)
+		}
+
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+
+		_, err = config.New(opts)
+
+		return err
+	default:
+		return fmt.Errorf("unknown config command %q, want check or print", cmd)
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_runConfig(t *testing.T) {
+	t.Run("check", func(t *testing.T) {
+		var out bytes.Buffer
+		require.NoError(t, runConfig("check", []string{"-a", "localhost:9090"}, &out))
+		assert.Contains(t, out.String(), "config is valid")
+
+		err := runConfig("check", []string{"-b", "localhost:9090", "-t", "10.0.0.0/33"}, &out)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "BASE_URL")
+		assert.Contains(t, err.Error(), "TRUSTED_SUBNET")
+	})
+
+	t.Run("print", func(t *testing.T) {
+		var out bytes.Buffer
+		require.NoError(t, runConfig("print", []string{"-a", "localhost:9090", "-admin-token", "secret"}, &out))
+
+		assert.Regexp(t, `SERVER_ADDRESS\s+localhost:9090\s+flag`, out.String())
+		assert.Regexp(t, `BASE_URL\s+http://localhost:8080\s+default`, out.String())
+		assert.Regexp(t, `ADMIN_TOKEN\s+\*\*\*\s+flag`, out.String())
+		assert.NotContains(t, out.String(), "secret")
+	})
+
+	t.Run("unknown command", func(t *testing.T) {
+		assert.Error(t, runConfig("show", nil, &bytes.Buffer{}))
+	})
+}
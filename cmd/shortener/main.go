@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"os"
@@ -11,6 +12,7 @@ import (
 	"syscall"
 
 	"github.com/usa4ev/urlshortner/internal/config"
+	"github.com/usa4ev/urlshortner/internal/logging"
 	"github.com/usa4ev/urlshortner/internal/server"
 	"github.com/usa4ev/urlshortner/internal/shortener"
 	"github.com/usa4ev/urlshortner/internal/storage"
@@ -26,20 +28,64 @@ func printMetaInfo() {
 	fmt.Printf("Build commit: %v\n", buildCommit)
 }
 
+// commands run instead of the server when the first argument names one.
+var commands = map[string]func(args []string, w io.Writer) error{
+	"config": func(args []string, w io.Writer) error {
+		var cmd string
+		if len(args) > 0 {
+			cmd, args = args[0], args[1:]
+		}
+
+		return runConfig(cmd, args, w)
+	},
+	"import": runImport,
+	"export": runExport,
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		if run, ok := commands[os.Args[1]]; ok {
+			if err := run(os.Args[2:], os.Stdout); err != nil {
+				log.Fatal(err)
+			}
+
+			return
+		}
+	}
+
 	printMetaInfo()
 
-	os.Environ()
 	// The HTTP Server
-	cfg := config.New()
+	cfg, err := config.New()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// the level is valid, config.New validates it
+	level, _ := logging.ParseLevel(cfg.LogLevel())
+	logging.SetLevel(level)
+
 	strg, err := storage.New(cfg)
 	if err != nil {
 		panic(err.Error())
 	}
 
-	myShortener := shortener.NewShortener(cfg, strg)
+	myShortener, err := shortener.NewShortener(cfg, strg)
+	if err != nil {
+		panic(err.Error())
+	}
+
+	defer myShortener.Close()
+
+	srv, err := server.New(cfg, myShortener, strg)
+	if err != nil {
+		panic(err.Error())
+	}
 
-	srv := server.New(cfg, myShortener, strg)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go strg.RunPurge(ctx, cfg.DeletedRetention())
 
 	// Listen for syscall signals for process to interrupt/quit
 	sig := make(chan os.Signal, 1)
@@ -47,19 +93,26 @@ func main() {
 
 	go func() {
 		call := <-sig
+		// SIGHUP reloads configuration and TLS certificates,
+		// other signals stop the server
+		for applied := cfg; call == syscall.SIGHUP; call = <-sig {
+			applied = reload(applied, srv)
+		}
+
+		cancel()
 
 		// Trigger graceful shutdown
 		if err := strg.Flush(); err != nil {
 			// failed to flush storage
-			log.Printf("HTTP server Shutdown (storage flush): %v", err)
+			logging.Errorf("HTTP server Shutdown (storage flush): %v", err)
 		}
 
 		if err := srv.Shutdown(context.Background()); err != nil {
 			// failed to close listener
-			log.Printf("HTTP server Shutdown: %v", err)
+			logging.Errorf("HTTP server Shutdown: %v", err)
 		}
 
-		log.Printf("graceful shutdown, got call: %v\n", call.String())
+		logging.Infof("graceful shutdown, got call: %v", call.String())
 	}()
 
 	srv.Run()
@@ -68,3 +121,42 @@ func main() {
 		panic(err.Error())
 	}
 }
+
+// reload rereads configuration and applies settings that can change
+// without a restart, including the log level. Changes are found by comparing
+// with applied, the configuration of the last reload or the start,
+// and reload returns the configuration to compare with next time.
+// Changes of the other settings are only logged.
+func reload(applied *config.Config, srv server.Server) *config.Config {
+	next, err := config.New()
+	if err != nil {
+		logging.Errorf("failed to reload config: %v", err)
+
+		return applied
+	}
+
+	for _, name := range applied.RestartRequired(next) {
+		logging.Warnf("%v has changed, restart the server to apply it", name)
+	}
+
+	for _, name := range applied.Changed(next) {
+		logging.Debugf("%v has changed", name)
+	}
+
+	if err := srv.ApplyConfig(next); err != nil {
+		logging.Errorf("failed to apply config: %v", err)
+	} else {
+		level, _ := logging.ParseLevel(next.LogLevel())
+		logging.SetLevel(level)
+
+		logging.Infof("config reloaded")
+
+		applied = next
+	}
+
+	if err := srv.ReloadCertificates(); err != nil {
+		logging.Errorf("failed to reload TLS certificates: %v", err)
+	}
+
+	return applied
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	ps "github.com/usa4ev/urlshortner/internal/server/grpcserver/protoshortener"
+)
+
+type (
+	dialOptions struct {
+		addr       string
+		useTLS     bool
+		caPath     string
+		certPath   string
+		keyPath    string
+		serverName string
+	}
+
+	// client calls the shortener service within the session saved in the profile.
+	client struct {
+		addr string
+		conn *grpc.ClientConn
+		cl   ps.ShortenerClient
+		prof *profile
+	}
+)
+
+func dial(o dialOptions, prof *profile) (*client, error) {
+	creds := insecure.NewCredentials()
+
+	if o.useTLS {
+		tlsCfg, err := o.tlsConfig()
+		if err != nil {
+			return nil, err
+		}
+
+		creds = credentials.NewTLS(tlsCfg)
+	}
+
+	conn, err := grpc.Dial(o.addr, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, err
+	}
+
+	return &client{
+		addr: o.addr,
+		conn: conn,
+		cl:   ps.NewShortenerClient(conn),
+		prof: prof,
+	}, nil
+}
+
+// tlsConfig returns client TLS configuration,
+// with a client certificate if both its files are set.
+func (o dialOptions) tlsConfig() (*tls.Config, error) {
+	tlsCfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: o.serverName,
+	}
+
+	if o.caPath != "" {
+		pem, err := os.ReadFile(o.caPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %v", o.caPath)
+		}
+
+		tlsCfg.RootCAs = pool
+	}
+
+	if (o.certPath == "") != (o.keyPath == "") {
+		return nil, errors.New("both -cert and -key are required for mutual TLS")
+	}
+
+	if o.certPath != "" {
+		cert, err := tls.LoadX509KeyPair(o.certPath, o.keyPath)
+		if err != nil {
+			return nil, err
+		}
+
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsCfg, nil
+}
+
+func (c *client) close() error {
+	return c.conn.Close()
+}
+
+// outgoing returns ctx with the session token of the profile.
+func (c *client) outgoing(ctx context.Context) context.Context {
+	if token := c.prof.Tokens[c.addr]; token != "" {
+		return metadata.AppendToOutgoingContext(ctx, "authorization", token)
+	}
+
+	return ctx
+}
+
+// session saves the token of a new session passed in the response header
+// and forgets the saved one the server does not accept.
+func (c *client) session(header metadata.MD, err error) error {
+	if tokens := header.Get("authorization"); len(tokens) > 0 {
+		if saveErr := c.prof.setToken(c.addr, tokens[0]); saveErr != nil {
+			return fmt.Errorf("failed to save session token: %w", saveErr)
+		}
+	}
+
+	if status.Code(err) == codes.Unauthenticated && c.prof.Tokens[c.addr] != "" {
+		if saveErr := c.prof.setToken(c.addr, ""); saveErr != nil {
+			return fmt.Errorf("failed to remove session token: %w", saveErr)
+		}
+
+		return fmt.Errorf("%w; the saved session is removed, run the command again to open a new one", err)
+	}
+
+	return err
+}
+
+func (c *client) shorten(ctx context.Context, urls []string, w io.Writer) error {
+	if len(urls) == 0 {
+		return errors.New("shorten requires at least one url")
+	}
+
+	for _, url := range urls {
+		var header metadata.MD
+
+		res, err := c.cl.Shorten(c.outgoing(ctx), &ps.ShortenRequest{Url: url}, grpc.Header(&header))
+		if err := c.session(header, err); err != nil {
+			return fmt.Errorf("%v: %w", url, err)
+		}
+
+		fmt.Fprintln(w, res.Id)
+	}
+
+	return nil
+}
+
+// batch shortens records read from r: either a url or an id,url pair.
+// Records without id get their line number. It writes id,short url pairs to w.
+func (c *client) batch(ctx context.Context, r io.Reader, w io.Writer) error {
+	in := &ps.ShortenBatchRequest{}
+
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return err
+		}
+
+		line, _ := cr.FieldPos(0)
+
+		switch len(record) {
+		case 1:
+			in.Data = append(in.Data, &ps.URLwId{Id: strconv.Itoa(line), Url: record[0]})
+		case 2:
+			in.Data = append(in.Data, &ps.URLwId{Id: record[0], Url: record[1]})
+		default:
+			return fmt.Errorf("line %v: want url or id,url, got %v fields", line, len(record))
+		}
+	}
+
+	if len(in.Data) == 0 {
+		return errors.New("batch is empty")
+	}
+
+	var header metadata.MD
+
+	res, err := c.cl.ShortenBatch(c.outgoing(ctx), in, grpc.Header(&header))
+	if err := c.session(header, err); err != nil {
+		return err
+	}
+
+	cw := csv.NewWriter(w)
+	for _, v := range res.Data {
+		if err := cw.Write([]string{v.Id, v.Url}); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+
+	return cw.Error()
+}
+
+func (c *client) resolve(ctx context.Context, ids []string, w io.Writer) error {
+	if len(ids) == 0 {
+		return errors.New("resolve requires at least one id")
+	}
+
+	for _, id := range ids {
+		var header metadata.MD
+
+		res, err := c.cl.GetLong(c.outgoing(ctx), &ps.GetLongRequest{Id: id}, grpc.Header(&header))
+		if err := c.session(header, err); err != nil {
+			return fmt.Errorf("%v: %w", id, err)
+		}
+
+		fmt.Fprintln(w, res.Url)
+	}
+
+	return nil
+}
+
+func (c *client) list(ctx context.Context, w io.Writer) error {
+	var header metadata.MD
+
+	res, err := c.cl.GetLongByUser(c.outgoing(ctx), &ps.Dummy{}, grpc.Header(&header))
+	if status.Code(err) == codes.NotFound {
+		// the session has no links
+		return c.session(header, nil)
+	}
+
+	if err := c.session(header, err); err != nil {
+		return err
+	}
+
+	for _, url := range res.Urls {
+		fmt.Fprintln(w, url)
+	}
+
+	return nil
+}
+
+func (c *client) delete(ctx context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return errors.New("delete requires at least one id")
+	}
+
+	var header metadata.MD
+
+	_, err := c.cl.DeleteBatch(c.outgoing(ctx), &ps.DeleteBatchRequest{Ids: ids}, grpc.Header(&header))
+
+	return c.session(header, err)
+}
+
+func (c *client) stats(ctx context.Context, w io.Writer) error {
+	res, err := c.cl.Stats(c.outgoing(ctx), &ps.Dummy{})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "urls: %v\nusers: %v\n", res.Urls, res.Users)
+
+	return nil
+}
@@ -0,0 +1,131 @@
+// Command shortenerctl manages short links through the gRPC API of the shortener.
+//
+// Usage:
+//
+//	shortenerctl [flags] shorten <url>...     shorten URLs, prints their IDs
+//	shortenerctl [flags] batch [file]         shorten URLs read from a file or stdin,
+//	                                          one url or id,url CSV record per line
+//	shortenerctl [flags] resolve <id>...      print original URLs
+//	shortenerctl [flags] list                 print short URLs of the session
+//	shortenerctl [flags] delete <id>...       delete links of the session
+//	shortenerctl [flags] stats                print the number of URLs and users
+//
+// The session token the server returns on the first call is saved
+// in the profile file and passed with next calls to the same address.
+// Run shortenerctl -h to list flags.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const usage = `usage: shortenerctl [flags] <command> [args]
+
+commands:
+  shorten <url>...  shorten URLs, prints their IDs
+  batch [file]      shorten URLs read from a file or stdin, one url or id,url record per line
+  resolve <id>...   print original URLs
+  list              print short URLs of the session
+  delete <id>...    delete links of the session
+  stats             print the number of URLs and users
+
+flags:`
+
+func main() {
+	log.SetFlags(0)
+
+	if err := run(os.Args[1:], os.Stdin, os.Stdout); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run(args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := flag.NewFlagSet("shortenerctl", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), usage)
+		fs.PrintDefaults()
+	}
+
+	var opts dialOptions
+
+	fs.StringVar(&opts.addr, "addr", "localhost:8080", "address of the gRPC server")
+	fs.BoolVar(&opts.useTLS, "tls", false, "connect with TLS")
+	fs.StringVar(&opts.caPath, "ca", "", "CA bundle to verify the server certificate, system roots by default")
+	fs.StringVar(&opts.certPath, "cert", "", "client certificate for mutual TLS")
+	fs.StringVar(&opts.keyPath, "key", "", "client private key for mutual TLS")
+	fs.StringVar(&opts.serverName, "server-name", "", "server name to verify the certificate against, host of -addr by default")
+	profilePath := fs.String("profile", defaultProfilePath(), "file to keep session tokens in")
+	timeout := fs.Duration("timeout", 10*time.Second, "timeout of the command")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+
+		return fmt.Errorf("command is not set")
+	}
+
+	cmd, cmdArgs := fs.Arg(0), fs.Args()[1:]
+
+	prof, err := loadProfile(*profilePath)
+	if err != nil {
+		return err
+	}
+
+	c, err := dial(opts, prof)
+	if err != nil {
+		return err
+	}
+	defer c.close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	switch cmd {
+	case "shorten":
+		return c.shorten(ctx, cmdArgs, stdout)
+	case "batch":
+		in := stdin
+		if len(cmdArgs) > 0 {
+			f, err := os.Open(cmdArgs[0])
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+
+			in = f
+		}
+
+		return c.batch(ctx, in, stdout)
+	case "resolve":
+		return c.resolve(ctx, cmdArgs, stdout)
+	case "list":
+		return c.list(ctx, stdout)
+	case "delete":
+		return c.delete(ctx, cmdArgs)
+	case "stats":
+		return c.stats(ctx, stdout)
+	default:
+		return fmt.Errorf("unknown command %q\n%v", cmd, usage)
+	}
+}
+
+// defaultProfilePath returns the profile file in the user config directory
+// or in the working directory if there is none.
+func defaultProfilePath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "shortenerctl.json"
+	}
+
+	return filepath.Join(dir, "shortenerctl", "profile.json")
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	ps "github.com/usa4ev/urlshortner/internal/server/grpcserver/protoshortener"
+)
+
+// fakeServer opens a session on the first call and keeps URLs by session token.
+type fakeServer struct {
+	ps.UnimplementedShortenerServer
+
+	urls map[string][]string
+}
+
+func (s *fakeServer) token(ctx context.Context) (string, error) {
+	if v := metadata.ValueFromIncomingContext(ctx, "authorization"); len(v) > 0 {
+		if _, ok := s.urls[v[0]]; !ok {
+			return "", status.Error(codes.Unauthenticated, "session is not found or invalidated")
+		}
+
+		return v[0], nil
+	}
+
+	token := "token" + string(rune('0'+len(s.urls)))
+	s.urls[token] = nil
+
+	return token, grpc.SetHeader(ctx, metadata.Pairs("authorization", token))
+}
+
+func (s *fakeServer) Shorten(ctx context.Context, in *ps.ShortenRequest) (*ps.ShortenResponse, error) {
+	token, err := s.token(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.urls[token] = append(s.urls[token], in.Url)
+
+	return &ps.ShortenResponse{Id: "id-" + in.Url}, nil
+}
+
+func (s *fakeServer) ShortenBatch(ctx context.Context, in *ps.ShortenBatchRequest) (*ps.ShortenBatchResponse, error) {
+	token, err := s.token(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &ps.ShortenBatchResponse{}
+	for _, v := range in.Data {
+		s.urls[token] = append(s.urls[token], v.Url)
+		res.Data = append(res.Data, &ps.URLwId{Id: v.Id, Url: "http://short/id-" + v.Url})
+	}
+
+	return res, nil
+}
+
+func (s *fakeServer) GetLong(ctx context.Context, in *ps.GetLongRequest) (*ps.GetLongResponse, error) {
+	return &ps.GetLongResponse{Url: strings.TrimPrefix(in.Id, "id-")}, nil
+}
+
+func (s *fakeServer) GetLongByUser(ctx context.Context, in *ps.Dummy) (*ps.GetLongByUserResponse, error) {
+	token, err := s.token(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(s.urls[token]) == 0 {
+		return nil, status.Error(codes.NotFound, "no URLs found")
+	}
+
+	return &ps.GetLongByUserResponse{Urls: s.urls[token]}, nil
+}
+
+func (s *fakeServer) DeleteBatch(ctx context.Context, in *ps.DeleteBatchRequest) (*ps.DeleteBatchResponse, error) {
+	token, err := s.token(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.urls[token] = nil
+
+	return &ps.DeleteBatchResponse{}, nil
+}
+
+func (s *fakeServer) Stats(ctx context.Context, in *ps.Dummy) (*ps.StatsResponse, error) {
+	return &ps.StatsResponse{Urls: 3, Users: 1}, nil
+}
+
+func Test_run(t *testing.T) {
+	listen, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	srv := &fakeServer{urls: make(map[string][]string)}
+	gs := grpc.NewServer()
+	ps.RegisterShortenerServer(gs, srv)
+
+	go gs.Serve(listen)
+	defer gs.Stop()
+
+	profilePath := filepath.Join(t.TempDir(), "ctl", "profile.json")
+
+	ctl := func(stdin string, args ...string) (string, error) {
+		out := &bytes.Buffer{}
+		args = append([]string{"-addr", listen.Addr().String(), "-profile", profilePath}, args...)
+		err := run(args, strings.NewReader(stdin), out)
+
+		return out.String(), err
+	}
+
+	t.Run("shorten saves session", func(t *testing.T) {
+		out, err := ctl("", "shorten", "http://ya.ru")
+		require.NoError(t, err)
+		assert.Equal(t, "id-http://ya.ru\n", out)
+
+		prof, err := loadProfile(profilePath)
+		require.NoError(t, err)
+		assert.Equal(t, "token0", prof.Tokens[listen.Addr().String()])
+	})
+
+	t.Run("batch", func(t *testing.T) {
+		out, err := ctl("http://vk.com\nfirst,http://go.dev\n", "batch")
+		require.NoError(t, err)
+		assert.Equal(t, "1,http://short/id-http://vk.com\nfirst,http://short/id-http://go.dev\n", out)
+	})
+
+	t.Run("list uses saved session", func(t *testing.T) {
+		out, err := ctl("", "list")
+		require.NoError(t, err)
+		assert.Equal(t, "http://ya.ru\nhttp://vk.com\nhttp://go.dev\n", out)
+	})
+
+	t.Run("resolve", func(t *testing.T) {
+		out, err := ctl("", "resolve", "id-http://ya.ru")
+		require.NoError(t, err)
+		assert.Equal(t, "http://ya.ru\n", out)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		_, err := ctl("", "delete", "id-http://ya.ru")
+		require.NoError(t, err)
+
+		out, err := ctl("", "list")
+		require.NoError(t, err)
+		assert.Empty(t, out)
+	})
+
+	t.Run("stats", func(t *testing.T) {
+		out, err := ctl("", "stats")
+		require.NoError(t, err)
+		assert.Equal(t, "urls: 3\nusers: 1\n", out)
+	})
+
+	t.Run("invalid session is removed", func(t *testing.T) {
+		srv.urls = make(map[string][]string)
+
+		_, err := ctl("", "list")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "saved session is removed")
+
+		prof, err := loadProfile(profilePath)
+		require.NoError(t, err)
+		assert.Empty(t, prof.Tokens)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := ctl("")
+		assert.Error(t, err)
+
+		_, err = ctl("", "unknown")
+		assert.Error(t, err)
+
+		_, err = ctl("", "shorten")
+		assert.Error(t, err)
+
+		_, err = ctl("a,b,c\n", "batch")
+		assert.Error(t, err)
+
+		_, err = ctl("", "-tls", "-cert", "client.crt", "stats")
+		assert.Error(t, err, "key is required with cert")
+	})
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// profile keeps session tokens by server address.
+type profile struct {
+	Tokens map[string]string `json:"tokens"`
+
+	path string
+}
+
+// loadProfile reads the profile at path. Missing file is an empty profile.
+func loadProfile(path string) (*profile, error) {
+	p := &profile{Tokens: make(map[string]string), path: path}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return p, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, p); err != nil {
+		return nil, err
+	}
+
+	if p.Tokens == nil {
+		p.Tokens = make(map[string]string)
+	}
+
+	return p, nil
+}
+
+// setToken saves token of the session opened at addr.
+// Empty token removes the session.
+func (p *profile) setToken(addr, token string) error {
+	if token == "" {
+		delete(p.Tokens, addr)
+	} else {
+		p.Tokens[addr] = token
+	}
+
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(p.path), 0o700); err != nil {
+		return err
+	}
+
+	// the token gives access to the session's links
+	return os.WriteFile(p.path, data, 0o600)
+}
@@ -0,0 +1,301 @@
+// Package bulk reads and writes links in CSV and NDJSON formats
+// to import and export them in bulk.
+package bulk
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/usa4ev/urlshortner/internal/storage/model"
+)
+
+// Format is a format of link records.
+type Format string
+
+const (
+	// CSV records are id,short_url,original_url,user_id,deleted,disabled
+	// with a header. Records of files without a header are url or id,url.
+	CSV Format = "csv"
+	// NDJSON records are model.Link JSON objects, one per line.
+	NDJSON Format = "ndjson"
+)
+
+const (
+	ctCSV    = "text/csv"
+	ctNDJSON = "application/x-ndjson"
+)
+
+// csvHeader names columns of exported CSV records.
+var csvHeader = []string{"id", "short_url", "original_url", "user_id", "deleted", "disabled"}
+
+// ParseFormat returns the format named s: csv or ndjson.
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(s)); f {
+	case CSV, NDJSON:
+		return f, nil
+	default:
+		return "", fmt.Errorf("unsupported format %q, want csv or ndjson", s)
+	}
+}
+
+// FormatByContentType returns the format of a text/csv
+// or application/x-ndjson content type.
+func FormatByContentType(ct string) (Format, error) {
+	mediaType, _, err := mime.ParseMediaType(ct)
+	if err != nil {
+		return "", fmt.Errorf("unsupported content type %q", ct)
+	}
+
+	switch mediaType {
+	case ctCSV:
+		return CSV, nil
+	case ctNDJSON:
+		return NDJSON, nil
+	default:
+		return "", fmt.Errorf("unsupported content type %q, want %v or %v", ct, ctCSV, ctNDJSON)
+	}
+}
+
+// FormatByName returns the format of a .csv, .ndjson or .jsonl file.
+func FormatByName(name string) (Format, error) {
+	switch ext := strings.ToLower(filepath.Ext(name)); ext {
+	case ".csv":
+		return CSV, nil
+	case ".ndjson", ".jsonl":
+		return NDJSON, nil
+	default:
+		return "", fmt.Errorf("unsupported file extension %q, want .csv, .ndjson or .jsonl", ext)
+	}
+}
+
+// ContentType returns the MIME type of f.
+func (f Format) ContentType() string {
+	if f == CSV {
+		return ctCSV
+	}
+
+	return ctNDJSON
+}
+
+type (
+	// Record is a link read from line Line. Err is set
+	// if the line cannot be parsed, the link is empty then.
+	Record struct {
+		Line int
+		Link model.Link
+		Err  error
+	}
+
+	// Reader reads link records.
+	Reader struct {
+		format Format
+		csv    *csv.Reader
+		cols   map[string]int // CSV columns by name, nil if there is no header
+		lines  *bufio.Reader
+		line   int
+	}
+
+	// ndjsonRecord accepts url as a short name of original_url.
+	ndjsonRecord struct {
+		ID          string `json:"id"`
+		OriginalURL string `json:"original_url"`
+		URL         string `json:"url"`
+	}
+)
+
+// NewReader returns a reader of records in format f.
+func NewReader(r io.Reader, f Format) *Reader {
+	reader := &Reader{format: f}
+
+	if f == CSV {
+		reader.csv = csv.NewReader(r)
+		reader.csv.FieldsPerRecord = -1
+		reader.csv.TrimLeadingSpace = true
+	} else {
+		reader.lines = bufio.NewReader(r)
+	}
+
+	return reader
+}
+
+// Read returns the next record or io.EOF if there are no more.
+// Records that fail to parse are returned with Err set.
+func (r *Reader) Read() (Record, error) {
+	if r.format == CSV {
+		return r.readCSV()
+	}
+
+	return r.readNDJSON()
+}
+
+func (r *Reader) readCSV() (Record, error) {
+	fields, err := r.csv.Read()
+
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return Record{Line: parseErr.Line, Err: parseErr.Err}, nil
+	}
+
+	if err != nil {
+		return Record{}, err
+	}
+
+	line, _ := r.csv.FieldPos(0)
+
+	if r.line == 0 {
+		r.line = line
+		if cols := headerColumns(fields); cols != nil {
+			r.cols = cols
+
+			return r.readCSV()
+		}
+	}
+
+	rec := Record{Line: line}
+
+	switch {
+	case r.cols != nil:
+		rec.Link.ID = column(fields, r.cols, "id")
+		rec.Link.OriginalURL = column(fields, r.cols, "original_url")
+	case len(fields) == 1:
+		rec.Link.OriginalURL = fields[0]
+	case len(fields) == 2:
+		rec.Link.ID, rec.Link.OriginalURL = fields[0], fields[1]
+	default:
+		rec.Err = fmt.Errorf("want url or id,url, got %v fields", len(fields))
+	}
+
+	return rec, nil
+}
+
+// headerColumns returns indexes of header fields by name
+// or nil if fields are not a header.
+func headerColumns(fields []string) map[string]int {
+	cols := make(map[string]int)
+	for i, name := range fields {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "url" {
+			name = "original_url"
+		}
+
+		cols[name] = i
+	}
+
+	if _, ok := cols["original_url"]; !ok {
+		return nil
+	}
+
+	return cols
+}
+
+func column(fields []string, cols map[string]int, name string) string {
+	i, ok := cols[name]
+	if !ok || i >= len(fields) {
+		return ""
+	}
+
+	return fields[i]
+}
+
+func (r *Reader) readNDJSON() (Record, error) {
+	for {
+		data, err := r.lines.ReadBytes('\n')
+		if len(data) == 0 && err != nil {
+			return Record{}, err
+		}
+
+		if err != nil && !errors.Is(err, io.EOF) {
+			return Record{}, err
+		}
+
+		r.line++
+
+		data = bytes.TrimSpace(data)
+		if len(data) == 0 {
+			continue
+		}
+
+		rec := Record{Line: r.line}
+
+		var v ndjsonRecord
+		if err := json.Unmarshal(data, &v); err != nil {
+			rec.Err = err
+
+			return rec, nil
+		}
+
+		rec.Link.ID, rec.Link.OriginalURL = v.ID, v.OriginalURL
+		if rec.Link.OriginalURL == "" {
+			rec.Link.OriginalURL = v.URL
+		}
+
+		return rec, nil
+	}
+}
+
+// Writer writes link records.
+type Writer struct {
+	csv    *csv.Writer
+	header bool
+	enc    *json.Encoder
+}
+
+// NewWriter returns a writer of records in format f.
+func NewWriter(w io.Writer, f Format) *Writer {
+	if f == CSV {
+		return &Writer{csv: csv.NewWriter(w)}
+	}
+
+	return &Writer{enc: json.NewEncoder(w)}
+}
+
+// Write writes link. CSV header is written before the first link.
+func (w *Writer) Write(link model.Link) error {
+	if w.csv == nil {
+		return w.enc.Encode(link)
+	}
+
+	if !w.header {
+		w.header = true
+		if err := w.csv.Write(csvHeader); err != nil {
+			return err
+		}
+	}
+
+	return w.csv.Write([]string{
+		link.ID,
+		link.ShortURL,
+		link.OriginalURL,
+		link.UserID,
+		strconv.FormatBool(link.Deleted),
+		strconv.FormatBool(link.Disabled),
+	})
+}
+
+// Flush writes buffered records. CSV header is written
+// if there were no links.
+func (w *Writer) Flush() error {
+	if w.csv == nil {
+		return nil
+	}
+
+	if !w.header {
+		w.header = true
+		if err := w.csv.Write(csvHeader); err != nil {
+			return err
+		}
+	}
+
+	w.csv.Flush()
+
+	return w.csv.Error()
+}
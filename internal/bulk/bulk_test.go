@@ -0,0 +1,189 @@
+package bulk
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/usa4ev/urlshortner/internal/shortener"
+	"github.com/usa4ev/urlshortner/internal/storage/model"
+	"github.com/usa4ev/urlshortner/internal/storage/storageerrors"
+)
+
+func readAll(t *testing.T, r *Reader) []Record {
+	records := make([]Record, 0)
+
+	for {
+		rec, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			return records
+		}
+
+		require.NoError(t, err)
+		records = append(records, rec)
+	}
+}
+
+func TestReader(t *testing.T) {
+	tests := []struct {
+		name   string
+		format Format
+		input  string
+		want   []model.Link
+		errs   []int // lines of records with Err
+	}{
+		{
+			name:   "csv with header",
+			format: CSV,
+			input:  "short_url,original_url,id\nhttp://localhost/a,http://ya.ru,a\n,http://go.dev,\n",
+			want:   []model.Link{{ID: "a", OriginalURL: "http://ya.ru"}, {OriginalURL: "http://go.dev"}},
+		},
+		{
+			name:   "csv without header",
+			format: CSV,
+			input:  "http://ya.ru\nb,http://go.dev\na,b,c\n",
+			want:   []model.Link{{OriginalURL: "http://ya.ru"}, {ID: "b", OriginalURL: "http://go.dev"}, {}},
+			errs:   []int{3},
+		},
+		{
+			name:   "ndjson",
+			format: NDJSON,
+			input:  "{\"id\":\"a\",\"original_url\":\"http://ya.ru\",\"deleted\":true}\n\n{\"url\":\"http://go.dev\"}\n{broken\n{\"id\":\"c\",\"url\":\"http://vk.com\"}",
+			want:   []model.Link{{ID: "a", OriginalURL: "http://ya.ru"}, {OriginalURL: "http://go.dev"}, {}, {ID: "c", OriginalURL: "http://vk.com"}},
+			errs:   []int{4},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records := readAll(t, NewReader(strings.NewReader(tt.input), tt.format))
+
+			links := make([]model.Link, 0, len(records))
+			errs := make([]int, 0)
+
+			for _, rec := range records {
+				links = append(links, rec.Link)
+				if rec.Err != nil {
+					errs = append(errs, rec.Line)
+				}
+			}
+
+			assert.Equal(t, tt.want, links)
+			if len(tt.errs) > 0 {
+				assert.Equal(t, tt.errs, errs)
+			} else {
+				assert.Empty(t, errs)
+			}
+		})
+	}
+}
+
+func TestWriter(t *testing.T) {
+	links := []model.Link{
+		{ID: "a", ShortURL: "http://localhost/a", OriginalURL: "http://ya.ru", UserID: "user", Deleted: true},
+		{ID: "b", ShortURL: "http://localhost/b", OriginalURL: "http://go.dev", UserID: "user"},
+	}
+
+	for _, f := range []Format{CSV, NDJSON} {
+		t.Run(string(f), func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			w := NewWriter(buf, f)
+
+			for _, link := range links {
+				require.NoError(t, w.Write(link))
+			}
+
+			require.NoError(t, w.Flush())
+
+			records := readAll(t, NewReader(buf, f))
+			require.Len(t, records, len(links))
+
+			for i, rec := range records {
+				assert.NoError(t, rec.Err)
+				assert.Equal(t, links[i].ID, rec.Link.ID)
+				assert.Equal(t, links[i].OriginalURL, rec.Link.OriginalURL)
+			}
+		})
+	}
+
+	t.Run("empty csv", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		require.NoError(t, NewWriter(buf, CSV).Flush())
+		assert.Equal(t, "id,short_url,original_url,user_id,deleted,disabled\n", buf.String())
+	})
+}
+
+func TestImport(t *testing.T) {
+	input := &strings.Builder{}
+	for i := 0; i < BatchSize+2; i++ {
+		fmt.Fprintf(input, "id%v,http://example.com/%v\n", i, i)
+	}
+
+	input.WriteString("a,b,c\n")
+
+	calls := 0
+	store := func(links []model.Link) ([]error, error) {
+		calls++
+		errs := make([]error, len(links))
+
+		for i, link := range links {
+			switch link.ID {
+			case "id1":
+				errs[i] = storageerrors.ErrConflict
+			case "id2":
+				errs[i] = shortener.ErrQuotaExceeded
+			case "id3":
+				errs[i] = shortener.ErrInvalidURL
+			}
+		}
+
+		return errs, nil
+	}
+
+	reported := make([]Result, 0)
+	summary, err := Import(NewReader(strings.NewReader(input.String()), CSV), store, func(res Result) error {
+		reported = append(reported, res)
+
+		return nil
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, Summary{Created: BatchSize - 1, Conflicts: 1, Invalid: 2, QuotaExceeded: 1}, summary)
+	assert.Equal(t, []Result{
+		{Line: 2, ID: "id1", Status: StatusConflict, Error: storageerrors.ErrConflict.Error()},
+		{Line: 3, ID: "id2", Status: StatusQuotaExceeded, Error: shortener.ErrQuotaExceeded.Error()},
+		{Line: 4, ID: "id3", Status: StatusInvalid, Error: shortener.ErrInvalidURL.Error()},
+		{Line: BatchSize + 3, Status: StatusInvalid, Error: "want url or id,url, got 3 fields"},
+	}, reported)
+
+	t.Run("store error", func(t *testing.T) {
+		storeErr := errors.New("storage is down")
+		_, err := Import(NewReader(strings.NewReader("http://ya.ru\n"), CSV), func(links []model.Link) ([]error, error) {
+			return nil, storeErr
+		}, func(res Result) error { return nil })
+		assert.ErrorIs(t, err, storeErr)
+	})
+}
+
+func TestFormat(t *testing.T) {
+	f, err := FormatByContentType("text/csv; charset=utf-8")
+	require.NoError(t, err)
+	assert.Equal(t, CSV, f)
+
+	f, err = FormatByName("links.jsonl")
+	require.NoError(t, err)
+	assert.Equal(t, NDJSON, f)
+
+	_, err = FormatByContentType("application/json")
+	assert.Error(t, err)
+
+	_, err = ParseFormat("xml")
+	assert.Error(t, err)
+}
@@ -0,0 +1,146 @@
+package bulk
+
+import (
+	"errors"
+	"io"
+
+	"github.com/usa4ev/urlshortner/internal/shortener"
+	"github.com/usa4ev/urlshortner/internal/storage/model"
+	"github.com/usa4ev/urlshortner/internal/storage/storageerrors"
+)
+
+// BatchSize is the number of records stored at once.
+const BatchSize = 1000
+
+// Statuses of imported records.
+const (
+	StatusCreated       = "created"
+	StatusConflict      = "conflict"
+	StatusInvalid       = "invalid"
+	StatusQuotaExceeded = "quota_exceeded"
+)
+
+type (
+	// StoreFunc stores links and returns an error for every link
+	// that is not stored, see shortener.Shortener.StoreURLs.
+	StoreFunc func(links []model.Link) ([]error, error)
+
+	// Result is the outcome of importing the record read from line Line.
+	Result struct {
+		Line   int    `json:"line"`
+		ID     string `json:"id,omitempty"`
+		Status string `json:"status"`
+		Error  string `json:"error,omitempty"`
+	}
+
+	// Summary counts imported records by status.
+	Summary struct {
+		Created       int `json:"created"`
+		Conflicts     int `json:"conflicts"`
+		Invalid       int `json:"invalid"`
+		QuotaExceeded int `json:"quota_exceeded"`
+	}
+)
+
+// Import reads records from r and stores them with store by BatchSize.
+// It calls report for every record that is not created. Records stored
+// before an error of r, store or report stay stored.
+func Import(r *Reader, store StoreFunc, report func(res Result) error) (Summary, error) {
+	var summary Summary
+
+	records := make([]Record, 0, BatchSize)
+
+	flush := func() error {
+		links := make([]model.Link, len(records))
+		for i, rec := range records {
+			links[i] = rec.Link
+		}
+
+		errs, err := store(links)
+		if err != nil {
+			return err
+		}
+
+		for i, rec := range records {
+			res := Result{Line: rec.Line, ID: links[i].ID, Status: Status(errs[i])}
+			if errs[i] != nil {
+				res.Error = errs[i].Error()
+			}
+
+			if err := summary.add(res, report); err != nil {
+				return err
+			}
+		}
+
+		records = records[:0]
+
+		return nil
+	}
+
+	for {
+		rec, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return summary, err
+		}
+
+		if rec.Err != nil {
+			res := Result{Line: rec.Line, Status: StatusInvalid, Error: rec.Err.Error()}
+			if err := summary.add(res, report); err != nil {
+				return summary, err
+			}
+
+			continue
+		}
+
+		records = append(records, rec)
+		if len(records) == BatchSize {
+			if err := flush(); err != nil {
+				return summary, err
+			}
+		}
+	}
+
+	if len(records) > 0 {
+		if err := flush(); err != nil {
+			return summary, err
+		}
+	}
+
+	return summary, nil
+}
+
+// add counts res and reports it unless it is created.
+func (s *Summary) add(res Result, report func(res Result) error) error {
+	switch res.Status {
+	case StatusCreated:
+		s.Created++
+
+		return nil
+	case StatusConflict:
+		s.Conflicts++
+	case StatusQuotaExceeded:
+		s.QuotaExceeded++
+	default:
+		s.Invalid++
+	}
+
+	return report(res)
+}
+
+// Status returns the status of a link stored with err.
+func Status(err error) string {
+	switch {
+	case err == nil:
+		return StatusCreated
+	case errors.Is(err, storageerrors.ErrConflict):
+		return StatusConflict
+	case errors.Is(err, shortener.ErrQuotaExceeded):
+		return StatusQuotaExceeded
+	default:
+		return StatusInvalid
+	}
+}
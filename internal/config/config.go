@@ -1,13 +1,15 @@
 package config
 
 import (
-	"encoding/json"
 	"flag"
-	"log"
-	"os"
-	"strconv"
+	"fmt"
+	"path/filepath"
+	"time"
 )
 
+// DefaultSSLPath is the folder with TLS files used unless SSL_PATH is set.
+const DefaultSSLPath = "./etc/ssl"
+
 const (
 	priorityFile = iota
 	priorityEnvVars
@@ -22,27 +24,78 @@ type Config struct {
 	dbDSN         string
 	sslPath       string
 	trustedSubnet string
+	trustedProxy  string
 	useTLS        bool
 	useGRPC       bool
-	grpcModeSet   bool
+	validateReqs  bool
+	rateLimit     float64
+	rateBurst     int
+	userQuota     int
+	blocklistPath string
+	retention     time.Duration
+	logLevel      string
+	adminToken    string
+	clientCAPath  string
+	identities    string
+	tlsCertPath   string
+	tlsKeyPath    string
+	tlsMinVersion string
+	tlsCiphers    string
+}
+
+// New returns configuration merged from the config file, env vars
+// and command line flags, in order of increasing priority.
+// It fails if any source cannot be parsed or the result is invalid.
+func New(opts ...configOption) (*Config, error) {
+	c, err := load(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
 }
 
-func New(opts ...configOption) *Config {
-	configOptions := setConfigOptions(opts)
+// load returns configuration merged from all sources without validating it.
+func load(opts []configOption) (*Config, error) {
+	configs, err := readSources(setConfigOptions(opts))
+	if err != nil {
+		return nil, err
+	}
+
+	return fillCfg(configs), nil
+}
+
+// readSources returns configurations read from every source indexed by priority.
+// Sources that are not used are nil.
+func readSources(o *configOptions) ([]*pConfig, error) {
+	var err error
 
 	configs := make([]*pConfig, topPriority)
 
-	configs[priorityEnvVars] = fromEnv(configOptions.envVars)
+	configs[priorityEnvVars], err = fromEnv(o.envVars)
+	if err != nil {
+		return nil, err
+	}
 
-	if !configOptions.ignoreOsArgs {
-		configs[priorityOsArgs] = fromArgs(configOptions.osArgs, &configOptions.filePath)
+	if !o.ignoreOsArgs {
+		configs[priorityOsArgs], err = fromArgs(o.osArgs, &o.filePath)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	if !configOptions.ignoreCfgFile && configOptions.filePath != "" {
-		configs[priorityFile] = fromFile(configOptions.filePath)
+	if !o.ignoreCfgFile && o.filePath != "" {
+		configs[priorityFile], err = fromFile(o.filePath)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	return fillCfg(configs)
+	return configs, nil
 }
 
 // fillCfg fills Config from passed collection of pConfig
@@ -55,28 +108,11 @@ func fillCfg(configs []*pConfig) *Config {
 			continue
 		}
 
-		if pCfg.srvAddr != "" {
-			cfg.srvAddr = pCfg.srvAddr
-		}
-		if pCfg.baseURL != "" {
-			cfg.baseURL = pCfg.baseURL
-		}
-		if pCfg.storagePath != "" {
-			cfg.storagePath = pCfg.storagePath
-		}
-		if pCfg.dbDSN != "" {
-			cfg.dbDSN = pCfg.dbDSN
-		}
-		if pCfg.sslPath != "" {
-			cfg.sslPath = pCfg.sslPath
-		}
-		if pCfg.trustedSubnet != "" {
-			cfg.trustedSubnet = pCfg.trustedSubnet
-		}
-		if pCfg.tlsModeSet {
-			cfg.useTLS = pCfg.useTLS
+		for _, o := range options {
+			if pCfg.isSet[o.env] {
+				o.copy(&cfg, &pCfg.Config)
+			}
 		}
-
 	}
 
 	return cfg.setDefaults()
@@ -106,14 +142,137 @@ func (c Config) UseTLS() bool {
 	return c.useTLS
 }
 
+// TrustedSubnet returns comma-separated CIDRs of clients
+// allowed to call internal methods.
 func (c Config) TrustedSubnet() string {
 	return c.trustedSubnet
 }
 
+// TrustedProxies returns comma-separated CIDRs of proxies
+// whose X-Forwarded-For and X-Real-IP headers are used to find client IP.
+func (c Config) TrustedProxies() string {
+	return c.trustedProxy
+}
+
 func (c Config) GRPC() bool {
 	return c.useGRPC
 }
 
+// RateLimit returns the number of requests per second
+// allowed for a single client IP or user. Zero disables limiting.
+func (c Config) RateLimit() float64 {
+	return c.rateLimit
+}
+
+// RateBurst returns the number of requests a client
+// may send at once before RateLimit applies.
+func (c Config) RateBurst() int {
+	return c.rateBurst
+}
+
+// UserQuota returns the maximum number of links a user may store.
+// Zero means no quota.
+func (c Config) UserQuota() int {
+	return c.userQuota
+}
+
+// BlocklistPath returns path to a file with blocked destinations.
+func (c Config) BlocklistPath() string {
+	return c.blocklistPath
+}
+
+// DeletedRetention returns how long deleted URLs are kept
+// before they are purged from the storage. Zero means forever.
+func (c Config) DeletedRetention() time.Duration {
+	return c.retention
+}
+
+// LogLevel returns the minimum level of logged messages,
+// see logging.ParseLevel. Empty value means info.
+func (c Config) LogLevel() string {
+	return c.logLevel
+}
+
+// ValidateRequests reports whether the HTTP server validates
+// requests against its OpenAPI specification.
+func (c Config) ValidateRequests() bool {
+	return c.validateReqs
+}
+
+// AdminToken returns the bearer token that grants access to the admin API.
+// The admin API is disabled if the token is empty.
+func (c Config) AdminToken() string {
+	return c.adminToken
+}
+
+// ClientCAPath returns path to a PEM bundle of CAs used to verify
+// client certificates of the gRPC server. Client certificates
+// are not requested if the path is empty.
+func (c Config) ClientCAPath() string {
+	return c.clientCAPath
+}
+
+// ServiceIdentities returns comma-separated name=role pairs
+// mapping client certificate names to service identities.
+func (c Config) ServiceIdentities() string {
+	return c.identities
+}
+
+// TLSCertPath returns path to the server certificate,
+// example.crt in SslPath by default.
+func (c Config) TLSCertPath() string {
+	if c.tlsCertPath == "" {
+		return filepath.Join(c.sslPath, "example.crt")
+	}
+
+	return c.tlsCertPath
+}
+
+// TLSKeyPath returns path to the server private key,
+// example.key in SslPath by default.
+func (c Config) TLSKeyPath() string {
+	if c.tlsKeyPath == "" {
+		return filepath.Join(c.sslPath, "example.key")
+	}
+
+	return c.tlsKeyPath
+}
+
+// TLSMinVersion returns the minimum TLS version, "1.2" or "1.3".
+// Empty value means 1.2.
+func (c Config) TLSMinVersion() string {
+	return c.tlsMinVersion
+}
+
+// TLSCipherSuites returns comma-separated names of TLS 1.2 cipher suites.
+// Empty value means Go defaults.
+func (c Config) TLSCipherSuites() string {
+	return c.tlsCiphers
+}
+
+// Changed returns env names of settings that differ in next.
+func (c Config) Changed(next *Config) []string {
+	return c.changed(next, func(o option) bool { return true })
+}
+
+// RestartRequired returns env names of settings that differ in next
+// but only take effect when the service starts.
+func (c Config) RestartRequired(next *Config) []string {
+	return c.changed(next, func(o option) bool { return !o.live })
+}
+
+func (c Config) changed(next *Config, filter func(o option) bool) []string {
+	changed := make([]string, 0)
+
+	for _, o := range options {
+		if filter(o) && o.value(&c) != o.value(next) {
+			changed = append(changed, o.env)
+		}
+	}
+
+	return changed
+}
+
 func (c *Config) setDefaults() *Config {
 	if c.srvAddr == "" {
 		c.srvAddr = "localhost:8080"
@@ -122,163 +281,66 @@ func (c *Config) setDefaults() *Config {
 		c.baseURL = "http://localhost:8080"
 	}
 	if c.sslPath == "" {
-		c.sslPath = "./etc/ssl"
+		c.sslPath = DefaultSSLPath
 	}
 
 	return c
 }
 
-// pConfig is a temporary Config with service fields
+// pConfig is a temporary Config read from a single source.
 type pConfig struct {
 	Config
-	tlsModeSet bool // marks if useTLS param is set
+	isSet map[string]bool // env names of options set by the source
 }
 
-func newpConfig() pConfig {
-	return pConfig{
-		Config:     Config{},
-		tlsModeSet: false,
+func newpConfig() *pConfig {
+	return &pConfig{
+		isSet: make(map[string]bool),
 	}
 }
 
-func fromEnv(envVars map[string]string) *pConfig {
-	pc := newpConfig()
-
-	if v := envVars["BASE_URL"]; v != "" {
-		pc.baseURL = v
-	}
-	if v := envVars["SERVER_ADDRESS"]; v != "" {
-		pc.srvAddr = v
-	}
-	if v := envVars["FILE_STORAGE_PATH"]; v != "" {
-		pc.storagePath = v
-	}
-	if v := envVars["DATABASE_DSN"]; v != "" {
-		pc.dbDSN = v
-	}
-	if v := envVars["TRUSTED_SUBNET"]; v != "" {
-		pc.trustedSubnet = v
-	}
-	if v := envVars["SSL_PATH"]; v != "" {
-		pc.sslPath = v
-	}
-	if v := envVars["ENABLE_HTTPS"]; v != "" {
-		pc.setTLSMode(v)
-	}
-	if v := envVars["USE_GRPC"]; v != "" {
-		pc.setGrpcMode(v)
+// set parses v as the value of o.
+func (pc *pConfig) set(o option, v string) error {
+	if err := o.set(&pc.Config, v); err != nil {
+		return err
 	}
 
-	return &pc
-}
+	pc.isSet[o.env] = true
 
-func fromArgs(osArgs []string, filePath *string) *pConfig {
-	pc := newpConfig()
-	fs := flag.NewFlagSet("myFS", flag.ContinueOnError)
-	if !fs.Parsed() {
-		var useTLS, useGRPC string
-
-		fs.StringVar(&pc.baseURL, "b", "", "base for short URLs")
-		fs.StringVar(&pc.srvAddr, "a", "", "the shortener service address")
-		fs.StringVar(&pc.storagePath, "f", "", "path to a storage file")
-		fs.StringVar(&pc.dbDSN, "d", "", "db connection path")
-		fs.StringVar(&pc.trustedSubnet, "t", "", "trusted subnet subnet to accept calls without authentication")
-		fs.StringVar(&pc.sslPath, "p", "", "path to folder with .key and .srt files")
-		fs.StringVar(filePath, "c", *filePath, "path to JSON config file")
-		fs.StringVar(filePath, "config", *filePath, "path to JSON config file")
-		fs.StringVar(&useTLS, "s", useTLS, "the server will use HTTPS if set to true")
-		fs.StringVar(&useGRPC, "r", useGRPC, "the server will start as gRPC-server")
-
-		fs.Parse(osArgs)
-
-		pc.setTLSMode(useTLS)
-		pc.setGrpcMode(useGRPC)
-	}
-
-	return &pc
+	return nil
 }
 
-func fromFile(filePath string) *pConfig {
+func fromEnv(envVars map[string]string) (*pConfig, error) {
 	pc := newpConfig()
-	fileData, err := parseFile(filePath)
 
-	if err != nil {
-		log.Printf("failed to parse config file %v: %v", filePath, err)
-		return &pc
-	}
-
-	pc.baseURL = fileData.BaseUrl
-	pc.dbDSN = fileData.DatabaseDsn
-	pc.srvAddr = fileData.ServerAddress
-	pc.storagePath = fileData.FileStoragePath
-	pc.useTLS = fileData.EnableHttps
-	pc.sslPath = fileData.SslPath
-	pc.trustedSubnet = fileData.TrustedSubnet
-	pc.tlsModeSet = true
-	pc.useGRPC = fileData.UseGrpc
-	pc.grpcModeSet = true
-
-	return &pc
-}
-
-type fileStruct struct {
-	ServerAddress   string `json:"server_address"`
-	BaseUrl         string `json:"base_url"`
-	FileStoragePath string `json:"file_storage_path"`
-	DatabaseDsn     string `json:"database_dsn"`
-	TrustedSubnet   string `json:"trusted_subnet"`
-	EnableHttps     bool   `json:"enable_https"`
-	SslPath         string `json:"ssl_path"`
-	UseGrpc         bool   `json:"use_grpc"`
-}
-
-func parseFile(p string) (*fileStruct, error) {
-	f, err := os.OpenFile(p, os.O_RDONLY, 0o777)
-	if err != nil {
-		return nil, err
-	}
-
-	data := fileStruct{}
+	for _, o := range options {
+		v := envVars[o.env]
+		if v == "" {
+			continue
+		}
 
-	dec := json.NewDecoder(f)
-	err = dec.Decode(&data)
-	if err != nil {
-		return nil, err
+		if err := pc.set(o, v); err != nil {
+			return nil, fmt.Errorf("%v env var: %w", o.env, err)
+		}
 	}
 
-	return &data, nil
+	return pc, nil
 }
 
-func (pc *pConfig) setTLSMode(v string) {
-	if v == "" {
-		return
-	}
+func fromArgs(osArgs []string, filePath *string) (*pConfig, error) {
+	pc := newpConfig()
+	fs := flag.NewFlagSet("myFS", flag.ContinueOnError)
 
-	use, err := strconv.ParseBool(v)
-	if err != nil {
-		log.Printf("failed to parse bool from ENABLE_HTTPS env var: %v", v)
+	fs.StringVar(filePath, "c", *filePath, "path to config file: .json, .yaml, .yml or .toml")
+	fs.StringVar(filePath, "config", *filePath, "path to config file: .json, .yaml, .yml or .toml")
 
-		return
-	} else {
-		pc.useTLS = use
-		pc.tlsModeSet = true
-		return
+	for _, o := range options {
+		fs.Var(flagValue{pc: pc, opt: o}, o.flag, o.usage)
 	}
-}
 
-func (pc *pConfig) setGrpcMode(v string) {
-	if v == "" {
-		return
+	if err := fs.Parse(osArgs); err != nil {
+		return nil, err
 	}
 
-	use, err := strconv.ParseBool(v)
-	if err != nil {
-		log.Printf("failed to parse bool of grpc mode flag: %v", v)
-
-		return
-	} else {
-		pc.useGRPC = use
-		pc.grpcModeSet = true
-		return
-	}
+	return pc, nil
 }
@@ -1,8 +1,13 @@
 package config
 
 import (
+	"errors"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestNewConfig(t *testing.T) {
@@ -13,17 +18,39 @@ func TestNewConfig(t *testing.T) {
 		"-f", "/storageTest.csv",
 		"-p", "./ssl",
 		"-t", "0.0.0.0",
+		"-trusted-proxies", "127.0.0.1",
 		"-s", "false",
+		"-rate-limit", "10",
+		"-rate-burst", "20",
+		"-user-quota", "1000",
+		"-blocklist", "./blocklist.txt",
+		"-deleted-retention", "720h",
+		"-admin-token", "secret",
+		"-client-ca", "./ca.crt",
+		"-service-identities", "ops=admin",
+		"-tls-cert", "./ssl/server.crt",
+		"-tls-min-version", "1.3",
 		"-d", "user=ubuntu password=test101825 host=localhost port=5432 dbname=testdb"}
 
 	envVars := map[string]string{
-		"BASE_URL":          "http://localhost:5555",
-		"SERVER_ADDRESS":    "localhost:5555",
-		"FILE_STORAGE_PATH": "/storageTest.csv",
-		"SSL_PATH":          "./ssl",
-		"TRUSTED_SUBNET":    "0.0.0.0",
-		"ENABLE_HTTPS":      "false",
-		"DATABASE_DSN":      "user=ubuntu password=test101825 host=localhost port=5432 dbname=testdb",
+		"BASE_URL":           "http://localhost:5555",
+		"SERVER_ADDRESS":     "localhost:5555",
+		"FILE_STORAGE_PATH":  "/storageTest.csv",
+		"SSL_PATH":           "./ssl",
+		"TRUSTED_SUBNET":     "0.0.0.0",
+		"TRUSTED_PROXIES":    "127.0.0.1",
+		"ENABLE_HTTPS":       "false",
+		"DATABASE_DSN":       "user=ubuntu password=test101825 host=localhost port=5432 dbname=testdb",
+		"RATE_LIMIT":         "10",
+		"RATE_BURST":         "20",
+		"USER_QUOTA":         "1000",
+		"BLOCKLIST_PATH":     "./blocklist.txt",
+		"DELETED_RETENTION":  "720h",
+		"ADMIN_TOKEN":        "secret",
+		"CLIENT_CA_PATH":     "./ca.crt",
+		"SERVICE_IDENTITIES": "ops=admin",
+		"TLS_CERT_PATH":      "./ssl/server.crt",
+		"TLS_MIN_VERSION":    "1.3",
 	}
 
 	filePath := "./testdata/1.json"
@@ -35,77 +62,141 @@ func TestNewConfig(t *testing.T) {
 	}{
 		{
 			name: "flags only",
-			opts: []configOption{WithEnvVars(map[string]string{}), withOsArgs(osArgs)},
+			opts: []configOption{WithEnvVars(map[string]string{}), WithOsArgs(osArgs)},
 			want: Config{
 				baseURL:       "http://localhost:5555",
 				srvAddr:       "localhost:5555",
 				storagePath:   "/storageTest.csv",
 				sslPath:       "./ssl",
 				trustedSubnet: "0.0.0.0",
+				trustedProxy:  "127.0.0.1",
 				dbDSN:         "user=ubuntu password=test101825 host=localhost port=5432 dbname=testdb",
+				rateLimit:     10,
+				rateBurst:     20,
+				userQuota:     1000,
+				blocklistPath: "./blocklist.txt",
+				retention:     720 * time.Hour,
+				adminToken:    "secret",
+				clientCAPath:  "./ca.crt",
+				identities:    "ops=admin",
+				tlsCertPath:   "./ssl/server.crt",
+				tlsMinVersion: "1.3",
 			},
 		},
 		{
 			name: "envs only",
-			opts: []configOption{IgnoreOsArgs(), withOsArgs([]string{}), WithEnvVars(envVars)},
+			opts: []configOption{IgnoreOsArgs(), WithOsArgs([]string{}), WithEnvVars(envVars)},
 			want: Config{
 				baseURL:       "http://localhost:5555",
 				srvAddr:       "localhost:5555",
 				storagePath:   "/storageTest.csv",
 				sslPath:       "./ssl",
 				trustedSubnet: "0.0.0.0",
+				trustedProxy:  "127.0.0.1",
 				dbDSN:         "user=ubuntu password=test101825 host=localhost port=5432 dbname=testdb",
+				rateLimit:     10,
+				rateBurst:     20,
+				userQuota:     1000,
+				blocklistPath: "./blocklist.txt",
+				retention:     720 * time.Hour,
+				adminToken:    "secret",
+				clientCAPath:  "./ca.crt",
+				identities:    "ops=admin",
+				tlsCertPath:   "./ssl/server.crt",
+				tlsMinVersion: "1.3",
 			},
 		},
 		{
 			name: "file only",
-			opts: []configOption{WithFile(filePath)},
+			opts: []configOption{IgnoreOsArgs(), WithEnvVars(map[string]string{}), WithFile(filePath)},
 			want: Config{
+				useGRPC:       true,
 				baseURL:       "111",
 				srvAddr:       "111",
 				storagePath:   "111",
 				dbDSN:         "111",
 				sslPath:       "111",
 				trustedSubnet: "111",
+				trustedProxy:  "111",
 				useTLS:        true,
+				rateLimit:     1.5,
+				rateBurst:     3,
+				userQuota:     100,
+				blocklistPath: "111",
+				retention:     111 * time.Hour,
+				adminToken:    "111",
+				clientCAPath:  "111",
+				identities:    "111=admin",
+				tlsCertPath:   "111.crt",
+				tlsKeyPath:    "111.key",
+				tlsMinVersion: "1.3",
+				tlsCiphers:    "111",
 			},
 		},
 		{
 			name: "flags over file",
-			opts: []configOption{WithEnvVars(map[string]string{}), withOsArgs(osArgs), WithFile(filePath)},
+			opts: []configOption{WithEnvVars(map[string]string{}), WithOsArgs(osArgs), WithFile(filePath)},
 			want: Config{
+				useGRPC:       true,
 				baseURL:       "http://localhost:5555",
 				srvAddr:       "localhost:5555",
 				storagePath:   "/storageTest.csv",
 				sslPath:       "./ssl",
 				trustedSubnet: "0.0.0.0",
+				trustedProxy:  "127.0.0.1",
 				dbDSN:         "user=ubuntu password=test101825 host=localhost port=5432 dbname=testdb",
+				rateLimit:     10,
+				rateBurst:     20,
+				userQuota:     1000,
+				blocklistPath: "./blocklist.txt",
+				retention:     720 * time.Hour,
+				adminToken:    "secret",
+				clientCAPath:  "./ca.crt",
+				identities:    "ops=admin",
+				tlsCertPath:   "./ssl/server.crt",
+				tlsMinVersion: "1.3",
+				tlsKeyPath:    "111.key",
+				tlsCiphers:    "111",
 				useTLS:        false,
 			},
 		},
 		{
 			name: "envs over file",
-			opts: []configOption{IgnoreOsArgs(), WithFile(filePath), withOsArgs([]string{}), WithEnvVars(envVars)},
+			opts: []configOption{IgnoreOsArgs(), WithFile(filePath), WithOsArgs([]string{}), WithEnvVars(envVars)},
 			want: Config{
+				useGRPC:       true,
 				baseURL:       "http://localhost:5555",
 				srvAddr:       "localhost:5555",
 				storagePath:   "/storageTest.csv",
 				sslPath:       "./ssl",
 				trustedSubnet: "0.0.0.0",
+				trustedProxy:  "127.0.0.1",
 				dbDSN:         "user=ubuntu password=test101825 host=localhost port=5432 dbname=testdb",
+				rateLimit:     10,
+				rateBurst:     20,
+				userQuota:     1000,
+				blocklistPath: "./blocklist.txt",
+				retention:     720 * time.Hour,
+				adminToken:    "secret",
+				clientCAPath:  "./ca.crt",
+				identities:    "ops=admin",
+				tlsCertPath:   "./ssl/server.crt",
+				tlsMinVersion: "1.3",
+				tlsKeyPath:    "111.key",
+				tlsCiphers:    "111",
 				useTLS:        false,
 			},
 		},
 		{
 			name: "flags over vars",
-			opts: []configOption{withOsArgs(osArgs),
+			opts: []configOption{WithOsArgs(osArgs),
 				WithEnvVars(map[string]string{
 					"BASE_URL":          "111",
 					"SERVER_ADDRESS":    "111",
 					"FILE_STORAGE_PATH": "111",
 					"SSL_PATH":          "111",
 					"DATABASE_DSN":      "111",
-					"ENABLE_HTTPS":      "111",
+					"ENABLE_HTTPS":      "true",
 				})},
 			want: Config{
 				baseURL:       "http://localhost:5555",
@@ -113,22 +204,289 @@ func TestNewConfig(t *testing.T) {
 				storagePath:   "/storageTest.csv",
 				sslPath:       "./ssl",
 				trustedSubnet: "0.0.0.0",
+				trustedProxy:  "127.0.0.1",
 				dbDSN:         "user=ubuntu password=test101825 host=localhost port=5432 dbname=testdb",
+				rateLimit:     10,
+				rateBurst:     20,
+				userQuota:     1000,
+				blocklistPath: "./blocklist.txt",
+				retention:     720 * time.Hour,
+				adminToken:    "secret",
+				clientCAPath:  "./ca.crt",
+				identities:    "ops=admin",
+				tlsCertPath:   "./ssl/server.crt",
+				tlsMinVersion: "1.3",
 				useTLS:        false,
 			},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := New(tt.opts...); !reflect.DeepEqual(*got, tt.want) {
+			// testdata holds placeholders that do not pass validation
+			got, err := load(tt.opts)
+			if err != nil {
+				t.Fatalf("load() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(*got, tt.want) {
 				t.Errorf("New().UseTLS() = %v, want %v", got.UseTLS(), tt.want.useTLS)
+				t.Errorf("New().GRPC() = %v, want %v", got.GRPC(), tt.want.useGRPC)
 				t.Errorf("New().TrustedSubnet() = %v, want %v", got.TrustedSubnet(), tt.want.trustedSubnet)
+				t.Errorf("New().TrustedProxies() = %v, want %v", got.TrustedProxies(), tt.want.trustedProxy)
 				t.Errorf("New().SslPath() = %v, want %v", got.SslPath(), tt.want.sslPath)
 				t.Errorf("New().BaseURL() = %v, want %v", got.BaseURL(), tt.want.baseURL)
 				t.Errorf("New().DBDSN() = %v, want %v", got.DBDSN(), tt.want.dbDSN)
 				t.Errorf("New().SrvAddr() = %v, want %v", got.SrvAddr(), tt.want.srvAddr)
 				t.Errorf("New().StoragePath() = %v, want %v", got.StoragePath(), tt.want.storagePath)
+				t.Errorf("New().RateLimit() = %v, want %v", got.RateLimit(), tt.want.rateLimit)
+				t.Errorf("New().RateBurst() = %v, want %v", got.RateBurst(), tt.want.rateBurst)
+				t.Errorf("New().UserQuota() = %v, want %v", got.UserQuota(), tt.want.userQuota)
+				t.Errorf("New().BlocklistPath() = %v, want %v", got.BlocklistPath(), tt.want.blocklistPath)
+				t.Errorf("New().DeletedRetention() = %v, want %v", got.DeletedRetention(), tt.want.retention)
+				t.Errorf("New().AdminToken() = %v, want %v", got.AdminToken(), tt.want.adminToken)
+				t.Errorf("New().ClientCAPath() = %v, want %v", got.ClientCAPath(), tt.want.clientCAPath)
+				t.Errorf("New().ServiceIdentities() = %v, want %v", got.ServiceIdentities(), tt.want.identities)
+				t.Errorf("New().TLSCertPath() = %v, want %v", got.TLSCertPath(), tt.want.TLSCertPath())
+				t.Errorf("New().TLSKeyPath() = %v, want %v", got.TLSKeyPath(), tt.want.TLSKeyPath())
+				t.Errorf("New().TLSMinVersion() = %v, want %v", got.TLSMinVersion(), tt.want.tlsMinVersion)
+				t.Errorf("New().TLSCipherSuites() = %v, want %v", got.TLSCipherSuites(), tt.want.tlsCiphers)
+			}
+		})
+	}
+}
+
+func TestConfig_TLSPaths(t *testing.T) {
+	c := Config{sslPath: "./ssl"}
+	if got := c.TLSCertPath(); got != "ssl/example.crt" {
+		t.Errorf("TLSCertPath() = %v, want ssl/example.crt", got)
+	}
+	if got := c.TLSKeyPath(); got != "ssl/example.key" {
+		t.Errorf("TLSKeyPath() = %v, want ssl/example.key", got)
+	}
+
+	c.tlsKeyPath = "/etc/shortener/server.key"
+	if got := c.TLSKeyPath(); got != c.tlsKeyPath {
+		t.Errorf("TLSKeyPath() = %v, want %v", got, c.tlsKeyPath)
+	}
+}
+
+func TestNew(t *testing.T) {
+	dir := t.TempDir()
+
+	invalid := []configOption{IgnoreOsArgs(), WithEnvVars(map[string]string{
+		"BASE_URL":       "localhost:8080",
+		"SERVER_ADDRESS": "localhost",
+		"TRUSTED_SUBNET": "192.168.0.0/33",
+		"USER_QUOTA":     "-1",
+		"LOG_LEVEL":      "verbose",
+		"BLOCKLIST_PATH": filepath.Join(dir, "missing.txt"),
+		"ENABLE_HTTPS":   "true",
+		"SSL_PATH":       dir,
+	})}
+
+	tests := []struct {
+		name    string
+		opts    []configOption
+		wantErr string
+	}{
+		{
+			name: "defaults",
+			opts: []configOption{IgnoreOsArgs(), WithEnvVars(map[string]string{})},
+		},
+		{
+			name:    "missing file",
+			opts:    []configOption{IgnoreOsArgs(), WithEnvVars(map[string]string{}), WithFile("./testdata/missing.json")},
+			wantErr: "failed to parse config file",
+		},
+		{
+			name:    "unknown file key",
+			opts:    []configOption{IgnoreOsArgs(), WithEnvVars(map[string]string{}), WithFile(writeFile(t, dir, "unknown.json", `{"base_ulr": "http://localhost"}`))},
+			wantErr: "base_ulr",
+		},
+		{
+			name:    "unknown nested key",
+			opts:    []configOption{IgnoreOsArgs(), WithEnvVars(map[string]string{}), WithFile(writeFile(t, dir, "unknown.yaml", "server:\n  adress: localhost:8080\n"))},
+			wantErr: "server.adress",
+		},
+		{
+			name:    "key and flat alias",
+			opts:    []configOption{IgnoreOsArgs(), WithEnvVars(map[string]string{}), WithFile(writeFile(t, dir, "both.toml", "base_url = \"http://a\"\n[server]\nbase_url = \"http://b\"\n"))},
+			wantErr: "both server.base_url and base_url",
+		},
+		{
+			name:    "unsupported format",
+			opts:    []configOption{IgnoreOsArgs(), WithEnvVars(map[string]string{}), WithFile(writeFile(t, dir, "config.ini", "base_url=http://a"))},
+			wantErr: ".ini",
+		},
+		{
+			name:    "invalid file value",
+			opts:    []configOption{IgnoreOsArgs(), WithEnvVars(map[string]string{}), WithFile(writeFile(t, dir, "invalid.yaml", "rate_limit:\n  burst: many\n"))},
+			wantErr: "rate_limit.burst",
+		},
+		{
+			name:    "invalid bool",
+			opts:    []configOption{IgnoreOsArgs(), WithEnvVars(map[string]string{"ENABLE_HTTPS": "yes please"})},
+			wantErr: "ENABLE_HTTPS",
+		},
+		{
+			name:    "invalid number",
+			opts:    []configOption{IgnoreOsArgs(), WithEnvVars(map[string]string{"RATE_LIMIT": "fast"})},
+			wantErr: "RATE_LIMIT",
+		},
+		{
+			name:    "unknown flag",
+			opts:    []configOption{WithEnvVars(map[string]string{}), WithOsArgs([]string{"-unknown"})},
+			wantErr: "-unknown",
+		},
+		{
+			name:    "invalid values",
+			opts:    invalid,
+			wantErr: "BASE_URL",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.opts...)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("New() error = %v", err)
+				}
+
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("New() error = %v, want error containing %q", err, tt.wantErr)
 			}
 		})
 	}
+
+	t.Run("all invalid settings are reported", func(t *testing.T) {
+		_, err := New(invalid...)
+
+		var verr ValidationError
+		if !errors.As(err, &verr) {
+			t.Fatalf("New() error = %v, want ValidationError", err)
+		}
+
+		for _, name := range []string{"BASE_URL", "SERVER_ADDRESS", "TRUSTED_SUBNET", "USER_QUOTA", "LOG_LEVEL", "BLOCKLIST_PATH", "TLS_CERT_PATH", "TLS_KEY_PATH"} {
+			if !strings.Contains(err.Error(), name) {
+				t.Errorf("New() error = %v, want %v reported", err, name)
+			}
+		}
+	})
+
+	t.Run("grpc mode", func(t *testing.T) {
+		c, err := New(IgnoreOsArgs(), WithEnvVars(map[string]string{"USE_GRPC": "true"}))
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+
+		if !c.GRPC() {
+			t.Errorf("New().GRPC() = false, want true")
+		}
+	})
+}
+
+func TestDescribe(t *testing.T) {
+	settings, err := Describe(
+		WithFile("./testdata/1.json"),
+		WithEnvVars(map[string]string{"BASE_URL": "http://localhost:5555"}),
+		WithOsArgs([]string{"-a", "localhost:5555"}))
+	if err != nil {
+		t.Fatalf("Describe() error = %v", err)
+	}
+
+	got := make(map[string]Setting)
+	for _, s := range settings {
+		got[s.Name] = s
+	}
+
+	want := map[string]Setting{
+		"SERVER_ADDRESS": {Name: "SERVER_ADDRESS", Value: "localhost:5555", Source: SourceFlag},
+		"BASE_URL":       {Name: "BASE_URL", Value: "http://localhost:5555", Source: SourceEnv},
+		"TRUSTED_SUBNET": {Name: "TRUSTED_SUBNET", Value: "111", Source: SourceFile},
+		"ADMIN_TOKEN":    {Name: "ADMIN_TOKEN", Value: "111", Source: SourceFile, Secret: true},
+	}
+
+	for name, w := range want {
+		if got[name] != w {
+			t.Errorf("Describe()[%v] = %+v, want %+v", name, got[name], w)
+		}
+	}
+
+	settings, err = Describe(IgnoreOsArgs(), WithEnvVars(map[string]string{}))
+	if err != nil {
+		t.Fatalf("Describe() error = %v", err)
+	}
+
+	for _, s := range settings {
+		switch s.Name {
+		case "SERVER_ADDRESS", "BASE_URL", "SSL_PATH", "TLS_CERT_PATH", "TLS_KEY_PATH":
+			if s.Source != SourceDefault {
+				t.Errorf("Describe()[%v].Source = %q, want %q", s.Name, s.Source, SourceDefault)
+			}
+		default:
+			if s.Source != "" {
+				t.Errorf("Describe()[%v].Source = %q, want unset", s.Name, s.Source)
+			}
+		}
+	}
+}
+
+func TestNew_fileFormats(t *testing.T) {
+	want, err := load([]configOption{IgnoreOsArgs(), WithEnvVars(map[string]string{}), WithFile("./testdata/1.json")})
+	if err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+
+	for _, path := range []string{"./testdata/1.yaml", "./testdata/1.toml"} {
+		t.Run(filepath.Ext(path), func(t *testing.T) {
+			got, err := load([]configOption{IgnoreOsArgs(), WithEnvVars(map[string]string{}), WithFile(path)})
+			if err != nil {
+				t.Fatalf("load() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("load() = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestConfig_RestartRequired(t *testing.T) {
+	c := Config{srvAddr: "localhost:8080", baseURL: "http://localhost:8080", sslPath: "./ssl", rateLimit: 10}
+
+	next := c
+	next.baseURL = "https://short.example"
+	next.rateLimit = 20
+	next.trustedSubnet = "10.0.0.0/8"
+	next.logLevel = "debug"
+
+	if got := c.RestartRequired(&next); len(got) != 0 {
+		t.Errorf("RestartRequired() = %v, want none", got)
+	}
+
+	changed := []string{"BASE_URL", "TRUSTED_SUBNET", "RATE_LIMIT", "LOG_LEVEL"}
+	if got := c.Changed(&next); !reflect.DeepEqual(got, changed) {
+		t.Errorf("Changed() = %v, want %v", got, changed)
+	}
+
+	next.srvAddr = "localhost:9090"
+	next.sslPath = "/etc/ssl"
+
+	want := []string{"SERVER_ADDRESS", "SSL_PATH", "TLS_CERT_PATH", "TLS_KEY_PATH"}
+	if got := c.RestartRequired(&next); !reflect.DeepEqual(got, want) {
+		t.Errorf("RestartRequired() = %v, want %v", got, want)
+	}
 }
@@ -0,0 +1,62 @@
+package config
+
+// Sources of setting values reported by Describe.
+const (
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceEnv     = "env"
+	SourceFlag    = "flag"
+)
+
+// sourceNames are indexed by source priority.
+var sourceNames = []string{
+	priorityFile:    SourceFile,
+	priorityEnvVars: SourceEnv,
+	priorityOsArgs:  SourceFlag,
+}
+
+// Setting is an effective configuration value and the source it came from.
+// Source is empty if the setting is not set.
+type Setting struct {
+	Name   string // env var name
+	Value  string
+	Source string
+	Secret bool // Value must not be shown
+}
+
+// Describe returns every effective setting along with the source it came from,
+// the highest priority source that sets it. The configuration is not validated.
+func Describe(opts ...configOption) ([]Setting, error) {
+	configs, err := readSources(setConfigOptions(opts))
+	if err != nil {
+		return nil, err
+	}
+
+	c := fillCfg(configs)
+	settings := make([]Setting, 0, len(options))
+
+	for _, o := range options {
+		s := Setting{Name: o.env, Value: o.value(c), Secret: o.secret}
+
+		for priority := len(configs) - 1; priority >= 0; priority-- {
+			if configs[priority] != nil && configs[priority].isSet[o.env] {
+				s.Source = sourceNames[priority]
+
+				break
+			}
+		}
+
+		if s.Source == "" && s.Value != "" && s.Value != zeroValue(o) {
+			s.Source = SourceDefault
+		}
+
+		settings = append(settings, s)
+	}
+
+	return settings, nil
+}
+
+// zeroValue returns the value of o in an empty Config.
+func zeroValue(o option) string {
+	return o.value(&Config{})
+}
@@ -0,0 +1,158 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// fromFile reads the config file at filePath. The format is picked by
+// the file extension: .json, .yaml, .yml or .toml.
+func fromFile(filePath string) (*pConfig, error) {
+	values, err := parseFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file %v: %w", filePath, err)
+	}
+
+	pc := newpConfig()
+
+	for _, o := range options {
+		v, ok := values[o.key]
+		if alias, aliasOK := values[o.alias]; aliasOK {
+			if ok {
+				return nil, fmt.Errorf("config file %v: both %v and %v are set", filePath, o.key, o.alias)
+			}
+
+			v, ok = alias, true
+		}
+
+		delete(values, o.key)
+		delete(values, o.alias)
+
+		if !ok {
+			continue
+		}
+
+		if err := pc.set(o, v); err != nil {
+			return nil, fmt.Errorf("config file %v: %v: %w", filePath, o.key, err)
+		}
+	}
+
+	if len(values) > 0 {
+		keys := make([]string, 0, len(values))
+		for k := range values {
+			keys = append(keys, k)
+		}
+
+		sort.Strings(keys)
+
+		return nil, fmt.Errorf("config file %v: unknown keys: %v", filePath, strings.Join(keys, ", "))
+	}
+
+	return pc, nil
+}
+
+// parseFile decodes the file at filePath and returns its values
+// by dot-separated keys.
+func parseFile(filePath string) (map[string]string, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	tree := make(map[string]interface{})
+
+	switch ext := strings.ToLower(filepath.Ext(filePath)); ext {
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		err = dec.Decode(&tree)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &tree)
+	case ".toml":
+		err = toml.Unmarshal(data, &tree)
+	default:
+		return nil, fmt.Errorf("unsupported format %q", ext)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string)
+	if err := flatten("", tree, values); err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
+
+// flatten puts scalar values of tree to values by dot-separated keys.
+// Lists are joined with commas. Null values are skipped.
+func flatten(prefix string, tree map[string]interface{}, values map[string]string) error {
+	for k, v := range tree {
+		key := prefix + k
+
+		if sub, ok := v.(map[string]interface{}); ok {
+			if err := flatten(key+".", sub, values); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if v == nil {
+			continue
+		}
+
+		s, err := scalar(v)
+		if err != nil {
+			return fmt.Errorf("%v: %w", key, err)
+		}
+
+		values[key] = s
+	}
+
+	return nil
+}
+
+// scalar formats a decoded value or list of values as a string.
+func scalar(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case json.Number:
+		return v.String(), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64), nil
+	case []interface{}:
+		items := make([]string, 0, len(v))
+
+		for _, item := range v {
+			s, err := scalar(item)
+			if err != nil {
+				return "", err
+			}
+
+			items = append(items, s)
+		}
+
+		return strings.Join(items, ","), nil
+	default:
+		return "", fmt.Errorf("unsupported value %v of type %T", v, v)
+	}
+}
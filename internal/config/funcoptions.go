@@ -15,17 +15,17 @@ type (
 )
 
 func setConfigOptions(opts []configOption) *configOptions {
+	envVars := map[string]string{
+		"CONFIG": os.Getenv("CONFIG"),
+	}
+
+	for _, o := range options {
+		envVars[o.env] = os.Getenv(o.env)
+	}
+
 	configOptions := &configOptions{
-		osArgs: os.Args[1:],
-		envVars: map[string]string{
-			"BASE_URL":          os.Getenv("BASE_URL"),
-			"SERVER_ADDRESS":    os.Getenv("SERVER_ADDRESS"),
-			"FILE_STORAGE_PATH": os.Getenv("FILE_STORAGE_PATH"),
-			"DATABASE_DSN":      os.Getenv("DATABASE_DSN"),
-			"ENABLE_HTTPS":      os.Getenv("ENABLE_HTTPS"),
-			"SSL_PATH":          os.Getenv("SSL_PATH"),
-			"CONFIG":            os.Getenv("CONFIG"),
-		},
+		osArgs:  os.Args[1:],
+		envVars: envVars,
 	}
 
 	for _, o := range opts {
@@ -38,7 +38,7 @@ func setConfigOptions(opts []configOption) *configOptions {
 	return configOptions
 }
 
-func withOsArgs(osArgs []string) configOption {
+func WithOsArgs(osArgs []string) configOption {
 	return func(o *configOptions) {
 		o.osArgs = osArgs
 	}
@@ -0,0 +1,206 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+type (
+	// option describes a setting once for every source it can be read from,
+	// so env vars, flags and file keys cannot drift apart.
+	option struct {
+		env    string // env var name, also identifies the option
+		flag   string // command line flag name
+		key    string // dot-separated key of the config file
+		alias  string // flat key of the JSON config file kept for compatibility
+		usage  string
+		live   bool // the setting is applied on SIGHUP without a restart
+		secret bool // the value must not be shown
+		field  func(c *Config) interface{}
+		// effective returns the value in use when it differs from the field, optional
+		effective func(c *Config) string
+	}
+
+	// flagValue sets an option of pConfig from a command line flag.
+	flagValue struct {
+		pc  *pConfig
+		opt option
+	}
+)
+
+// options lists every setting. Values of fields are parsed according
+// to their type: string, bool, int, float64 or time.Duration.
+var options = []option{
+	{
+		env: "SERVER_ADDRESS", flag: "a", key: "server.address", alias: "server_address",
+		usage: "the shortener service address",
+		field: func(c *Config) interface{} { return &c.srvAddr },
+	},
+	{
+		env: "BASE_URL", flag: "b", key: "server.base_url", alias: "base_url",
+		usage: "base for short URLs", live: true,
+		field: func(c *Config) interface{} { return &c.baseURL },
+	},
+	{
+		env: "USE_GRPC", flag: "r", key: "server.grpc", alias: "use_grpc",
+		usage: "the server will start as gRPC-server",
+		field: func(c *Config) interface{} { return &c.useGRPC },
+	},
+	{
+		env: "TRUSTED_SUBNET", flag: "t", key: "server.trusted_subnet", alias: "trusted_subnet",
+		usage: "comma-separated trusted subnets to accept internal calls from", live: true,
+		field: func(c *Config) interface{} { return &c.trustedSubnet },
+	},
+	{
+		env: "TRUSTED_PROXIES", flag: "trusted-proxies", key: "server.trusted_proxies", alias: "trusted_proxies",
+		usage: "comma-separated subnets of proxies trusted to set X-Forwarded-For and X-Real-IP", live: true,
+		field: func(c *Config) interface{} { return &c.trustedProxy },
+	},
+	{
+		env: "VALIDATE_REQUESTS", flag: "validate-requests", key: "server.validate_requests", alias: "validate_requests",
+		usage: "the HTTP server will reject requests that do not match the OpenAPI specification",
+		field: func(c *Config) interface{} { return &c.validateReqs },
+	},
+	{
+		env: "FILE_STORAGE_PATH", flag: "f", key: "storage.file_path", alias: "file_storage_path",
+		usage: "path to a storage file",
+		field: func(c *Config) interface{} { return &c.storagePath },
+	},
+	{
+		env: "DATABASE_DSN", flag: "d", key: "storage.database_dsn", alias: "database_dsn",
+		usage: "db connection path", secret: true,
+		field: func(c *Config) interface{} { return &c.dbDSN },
+	},
+	{
+		env: "DELETED_RETENTION", flag: "deleted-retention", key: "storage.deleted_retention", alias: "deleted_retention",
+		usage: "how long deleted URLs are kept before they are purged, e.g. 720h",
+		field: func(c *Config) interface{} { return &c.retention },
+	},
+	{
+		env: "ENABLE_HTTPS", flag: "s", key: "tls.enabled", alias: "enable_https",
+		usage: "the server will use HTTPS if set to true",
+		field: func(c *Config) interface{} { return &c.useTLS },
+	},
+	{
+		env: "SSL_PATH", flag: "p", key: "tls.ssl_path", alias: "ssl_path",
+		usage: "path to folder with .key and .srt files",
+		field: func(c *Config) interface{} { return &c.sslPath },
+	},
+	{
+		env: "TLS_CERT_PATH", flag: "tls-cert", key: "tls.cert_path", alias: "tls_cert_path",
+		usage:     "path to the server certificate, example.crt in ssl path by default",
+		field:     func(c *Config) interface{} { return &c.tlsCertPath },
+		effective: func(c *Config) string { return c.TLSCertPath() },
+	},
+	{
+		env: "TLS_KEY_PATH", flag: "tls-key", key: "tls.key_path", alias: "tls_key_path",
+		usage:     "path to the server private key, example.key in ssl path by default",
+		field:     func(c *Config) interface{} { return &c.tlsKeyPath },
+		effective: func(c *Config) string { return c.TLSKeyPath() },
+	},
+	{
+		env: "TLS_MIN_VERSION", flag: "tls-min-version", key: "tls.min_version", alias: "tls_min_version",
+		usage: "minimum TLS version: 1.2 or 1.3",
+		field: func(c *Config) interface{} { return &c.tlsMinVersion },
+	},
+	{
+		env: "TLS_CIPHER_SUITES", flag: "tls-ciphers", key: "tls.cipher_suites", alias: "tls_cipher_suites",
+		usage: "comma-separated TLS 1.2 cipher suites",
+		field: func(c *Config) interface{} { return &c.tlsCiphers },
+	},
+	{
+		env: "CLIENT_CA_PATH", flag: "client-ca", key: "tls.client_ca_path", alias: "client_ca_path",
+		usage: "path to CA bundle to verify gRPC client certificates",
+		field: func(c *Config) interface{} { return &c.clientCAPath },
+	},
+	{
+		env: "RATE_LIMIT", flag: "rate-limit", key: "rate_limit.rate", alias: "rate_limit",
+		usage: "requests per second allowed per client IP and per user", live: true,
+		field: func(c *Config) interface{} { return &c.rateLimit },
+	},
+	{
+		env: "RATE_BURST", flag: "rate-burst", key: "rate_limit.burst", alias: "rate_burst",
+		usage: "requests a client may send at once before rate limit applies", live: true,
+		field: func(c *Config) interface{} { return &c.rateBurst },
+	},
+	{
+		env: "USER_QUOTA", flag: "user-quota", key: "shortener.user_quota", alias: "user_quota",
+		usage: "maximum number of links a user may store", live: true,
+		field: func(c *Config) interface{} { return &c.userQuota },
+	},
+	{
+		env: "BLOCKLIST_PATH", flag: "blocklist", key: "shortener.blocklist_path", alias: "blocklist_path",
+		usage: "path to a file with blocked destination domains and regexps", live: true,
+		field: func(c *Config) interface{} { return &c.blocklistPath },
+	},
+	{
+		env: "LOG_LEVEL", flag: "log-level", key: "log.level", alias: "log_level",
+		usage: "minimum level of logged messages: debug, info, warn or error", live: true,
+		field: func(c *Config) interface{} { return &c.logLevel },
+	},
+	{
+		env: "ADMIN_TOKEN", flag: "admin-token", key: "auth.admin_token", alias: "admin_token",
+		usage: "bearer token granting access to the admin API", secret: true,
+		field: func(c *Config) interface{} { return &c.adminToken },
+	},
+	{
+		env: "SERVICE_IDENTITIES", flag: "service-identities", key: "auth.service_identities", alias: "service_identities",
+		usage: "comma-separated name=role pairs mapping client certificates to service identities",
+		field: func(c *Config) interface{} { return &c.identities },
+	},
+}
+
+// set parses v into the field of o in c.
+func (o option) set(c *Config, v string) error {
+	var err error
+
+	switch p := o.field(c).(type) {
+	case *string:
+		*p = v
+	case *bool:
+		*p, err = strconv.ParseBool(v)
+	case *int:
+		*p, err = strconv.Atoi(v)
+	case *float64:
+		*p, err = strconv.ParseFloat(v, 64)
+	case *time.Duration:
+		*p, err = time.ParseDuration(v)
+	default:
+		panic(fmt.Sprintf("config: unsupported type %T of %v", p, o.env))
+	}
+
+	return err
+}
+
+// value returns the effective value of o in c formatted as a string.
+func (o option) value(c *Config) string {
+	if o.effective != nil {
+		return o.effective(c)
+	}
+
+	switch p := o.field(c).(type) {
+	case *float64:
+		return strconv.FormatFloat(*p, 'g', -1, 64)
+	default:
+		return fmt.Sprint(reflect.ValueOf(p).Elem().Interface())
+	}
+}
+
+// copy copies the field of o from src to dst.
+func (o option) copy(dst, src *Config) {
+	reflect.ValueOf(o.field(dst)).Elem().Set(reflect.ValueOf(o.field(src)).Elem())
+}
+
+func (v flagValue) String() string {
+	if v.pc == nil {
+		return ""
+	}
+
+	return v.opt.value(&v.pc.Config)
+}
+
+func (v flagValue) Set(s string) error {
+	return v.pc.set(v.opt, s)
+}
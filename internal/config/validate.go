@@ -0,0 +1,149 @@
+package config
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx"
+
+	"github.com/usa4ev/urlshortner/internal/logging"
+	"github.com/usa4ev/urlshortner/internal/server/identity"
+	"github.com/usa4ev/urlshortner/internal/server/tlsconfig"
+	"github.com/usa4ev/urlshortner/internal/server/trustednet"
+)
+
+// ValidationError lists all invalid settings of a configuration.
+type ValidationError []error
+
+func (e ValidationError) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+
+	return "invalid config: " + strings.Join(msgs, "; ")
+}
+
+// Validate checks every setting of c and returns ValidationError
+// listing all invalid ones. Files c refers to have to be readable.
+func (c Config) Validate() error {
+	errs := make(ValidationError, 0)
+
+	check := func(name string, err error) {
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%v: %w", name, err))
+		}
+	}
+
+	check("BASE_URL", validateURL(c.baseURL))
+	check("SERVER_ADDRESS", validateAddr(c.srvAddr))
+
+	if c.dbDSN != "" {
+		_, err := pgx.ParseConnectionString(c.dbDSN)
+		check("DATABASE_DSN", err)
+	}
+
+	_, err := trustednet.New(c.trustedSubnet, "")
+	check("TRUSTED_SUBNET", err)
+
+	_, err = trustednet.New("", c.trustedProxy)
+	check("TRUSTED_PROXIES", err)
+
+	check("RATE_LIMIT", notNegative(c.rateLimit))
+	check("RATE_BURST", notNegative(float64(c.rateBurst)))
+	check("USER_QUOTA", notNegative(float64(c.userQuota)))
+	check("DELETED_RETENTION", notNegative(c.retention.Seconds()))
+	check("BLOCKLIST_PATH", readable(c.blocklistPath))
+
+	_, err = logging.ParseLevel(c.logLevel)
+	check("LOG_LEVEL", err)
+
+	_, err = identity.Parse(c.identities)
+	check("SERVICE_IDENTITIES", err)
+
+	_, err = tlsconfig.ParseVersion(c.tlsMinVersion)
+	check("TLS_MIN_VERSION", err)
+
+	_, err = tlsconfig.ParseCipherSuites(c.tlsCiphers)
+	check("TLS_CIPHER_SUITES", err)
+
+	if c.useTLS {
+		certErr, keyErr := readable(c.TLSCertPath()), readable(c.TLSKeyPath())
+		check("TLS_CERT_PATH", certErr)
+		check("TLS_KEY_PATH", keyErr)
+
+		if certErr == nil && keyErr == nil {
+			_, err = tls.LoadX509KeyPair(c.TLSCertPath(), c.TLSKeyPath())
+			check("TLS_CERT_PATH", err)
+		}
+
+		check("CLIENT_CA_PATH", readable(c.clientCAPath))
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// validateURL checks that s is an absolute http or https URL.
+func validateURL(s string) error {
+	u, err := url.Parse(s)
+	if err != nil {
+		return err
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%q is not an http or https URL", s)
+	}
+
+	if u.Host == "" {
+		return fmt.Errorf("%q has no host", s)
+	}
+
+	return nil
+}
+
+// validateAddr checks that s is a host:port address to listen on.
+func validateAddr(s string) error {
+	_, port, err := net.SplitHostPort(s)
+	if err != nil {
+		return err
+	}
+
+	if n, err := strconv.ParseUint(port, 10, 16); err != nil || n == 0 {
+		return fmt.Errorf("invalid port %q", port)
+	}
+
+	return nil
+}
+
+func notNegative(v float64) error {
+	if v < 0 {
+		return errors.New("must not be negative")
+	}
+
+	return nil
+}
+
+// readable checks that the file at path can be read.
+// Empty path is not checked.
+func readable(path string) error {
+	if path == "" {
+		return nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+
+	return f.Close()
+}
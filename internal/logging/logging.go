@@ -0,0 +1,85 @@
+// Package logging writes leveled messages with the standard logger.
+// Messages below the level set with SetLevel are dropped,
+// the level can be changed while the service runs.
+package logging
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync/atomic"
+)
+
+// Level is the severity of a message.
+type Level int32
+
+// Levels in increasing severity.
+const (
+	Debug Level = iota
+	Info
+	Warn
+	Error
+)
+
+var levelNames = []string{"debug", "info", "warn", "error"}
+
+// level holds the minimum Level of written messages.
+var level = int32(Info)
+
+// ParseLevel returns the Level named s: debug, info, warn or error.
+// Empty s means info.
+func ParseLevel(s string) (Level, error) {
+	if s == "" {
+		return Info, nil
+	}
+
+	for i, name := range levelNames {
+		if strings.EqualFold(s, name) {
+			return Level(i), nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown log level %q, expected one of %v", s, strings.Join(levelNames, ", "))
+}
+
+func (l Level) String() string {
+	if l < 0 || int(l) >= len(levelNames) {
+		return fmt.Sprintf("Level(%d)", l)
+	}
+
+	return levelNames[l]
+}
+
+// SetLevel makes messages below l dropped.
+func SetLevel(l Level) {
+	atomic.StoreInt32(&level, int32(l))
+}
+
+// Debugf writes a message useful to find out what the service does.
+func Debugf(format string, v ...interface{}) {
+	output(Debug, format, v...)
+}
+
+// Infof writes a message about a regular event.
+func Infof(format string, v ...interface{}) {
+	output(Info, format, v...)
+}
+
+// Warnf writes a message about an event that needs attention.
+func Warnf(format string, v ...interface{}) {
+	output(Warn, format, v...)
+}
+
+// Errorf writes a message about a failure.
+func Errorf(format string, v ...interface{}) {
+	output(Error, format, v...)
+}
+
+func output(l Level, format string, v ...interface{}) {
+	if int32(l) < atomic.LoadInt32(&level) {
+		return
+	}
+
+	// calldepth 3 reports the caller of Debugf, Infof and others with Lshortfile
+	_ = log.Output(3, strings.ToUpper(l.String())+": "+fmt.Sprintf(format, v...))
+}
@@ -0,0 +1,55 @@
+package logging
+
+import (
+	"bytes"
+	"log"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Level
+		wantErr bool
+	}{
+		{"", Info, false},
+		{"debug", Debug, false},
+		{"WARN", Warn, false},
+		{"error", Error, false},
+		{"verbose", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseLevel(tt.in)
+		if tt.wantErr {
+			assert.Error(t, err, "level %q", tt.in)
+
+			continue
+		}
+
+		require.NoError(t, err, "level %q", tt.in)
+		assert.Equal(t, tt.want, got, "level %q", tt.in)
+	}
+}
+
+func TestSetLevel(t *testing.T) {
+	var buf bytes.Buffer
+
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(&buf)
+
+	defer SetLevel(Info)
+
+	SetLevel(Warn)
+	Infof("dropped")
+	Warnf("written %v", 1)
+	assert.NotContains(t, buf.String(), "dropped")
+	assert.Contains(t, buf.String(), "WARN: written 1")
+
+	SetLevel(Debug)
+	Debugf("debug message")
+	assert.Contains(t, buf.String(), "DEBUG: debug message")
+}
@@ -0,0 +1,202 @@
+// Package qrcode renders QR codes as PNG or SVG images
+// with the pure Go encoder rsc.io/qr.
+package qrcode
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+
+	"rsc.io/qr"
+)
+
+// Format is an image format of QR codes.
+type Format string
+
+const (
+	PNG Format = "png"
+	SVG Format = "svg"
+)
+
+// Level is an error correction level, from the least
+// to the most tolerant of damage: L, M, Q, H.
+type Level string
+
+const (
+	L Level = "L" // recovers 7% of the code
+	M Level = "M" // recovers 15% of the code
+	Q Level = "Q" // recovers 25% of the code
+	H Level = "H" // recovers 30% of the code
+)
+
+// Limits of Options.
+const (
+	DefaultSize   = 256
+	MaxSize       = 4096
+	DefaultMargin = 4 // the quiet zone the QR specification requires
+	MaxMargin     = 32
+)
+
+// ErrInvalidOptions is returned for options out of their limits.
+var ErrInvalidOptions = errors.New("invalid QR code options")
+
+// Options define the image of a QR code.
+type Options struct {
+	Format Format
+	// Size is the side of the image in pixels, a PNG image
+	// is padded to it if modules do not fit in evenly.
+	Size int
+	// Margin is the width of the quiet zone around the code in modules.
+	Margin int
+	Level  Level
+}
+
+var levels = map[Level]qr.Level{L: qr.L, M: qr.M, Q: qr.Q, H: qr.H}
+
+// DefaultOptions returns options of a 256 pixels PNG image
+// with the standard margin and medium error correction.
+func DefaultOptions() Options {
+	return Options{Format: PNG, Size: DefaultSize, Margin: DefaultMargin, Level: M}
+}
+
+// ParseFormat returns the format named s: png or svg.
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(s)); f {
+	case PNG, SVG:
+		return f, nil
+	default:
+		return "", fmt.Errorf("%w: unsupported format %q, want png or svg", ErrInvalidOptions, s)
+	}
+}
+
+// ParseLevel returns the error correction level named s: L, M, Q or H.
+func ParseLevel(s string) (Level, error) {
+	l := Level(strings.ToUpper(s))
+	if _, ok := levels[l]; !ok {
+		return "", fmt.Errorf("%w: unsupported error correction level %q, want L, M, Q or H", ErrInvalidOptions, s)
+	}
+
+	return l, nil
+}
+
+// ContentType returns the MIME type of f.
+func (f Format) ContentType() string {
+	if f == SVG {
+		return "image/svg+xml"
+	}
+
+	return "image/png"
+}
+
+// Validate returns ErrInvalidOptions if o is out of limits.
+// Format and Level must be one of the constants, see ParseFormat and ParseLevel.
+func (o Options) Validate() error {
+	if o.Format != PNG && o.Format != SVG {
+		return fmt.Errorf("%w: unsupported format %q, want png or svg", ErrInvalidOptions, o.Format)
+	}
+
+	if _, ok := levels[o.Level]; !ok {
+		return fmt.Errorf("%w: unsupported error correction level %q, want L, M, Q or H", ErrInvalidOptions, o.Level)
+	}
+
+	if o.Size < 1 || o.Size > MaxSize {
+		return fmt.Errorf("%w: size %v is out of range 1..%v", ErrInvalidOptions, o.Size, MaxSize)
+	}
+
+	if o.Margin < 0 || o.Margin > MaxMargin {
+		return fmt.Errorf("%w: margin %v is out of range 0..%v", ErrInvalidOptions, o.Margin, MaxMargin)
+	}
+
+	return nil
+}
+
+// Encode returns the image of the QR code of text.
+func Encode(text string, o Options) ([]byte, error) {
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+
+	code, err := qr.Encode(text, levels[o.Level])
+	if err != nil {
+		return nil, err
+	}
+
+	if o.Format == SVG {
+		return encodeSVG(code, o), nil
+	}
+
+	return encodePNG(code, o)
+}
+
+// encodePNG draws every module as a square of the same number of pixels.
+func encodePNG(code *qr.Code, o Options) ([]byte, error) {
+	modules := code.Size + 2*o.Margin
+
+	scale := o.Size / modules
+	if scale < 1 {
+		return nil, fmt.Errorf("%w: size %v is less than %v modules of the code", ErrInvalidOptions, o.Size, modules)
+	}
+
+	offset := (o.Size-modules*scale)/2 + o.Margin*scale
+
+	img := image.NewPaletted(image.Rect(0, 0, o.Size, o.Size), color.Palette{color.White, color.Black})
+
+	for y := 0; y < code.Size; y++ {
+		for x := 0; x < code.Size; x++ {
+			if !code.Black(x, y) {
+				continue
+			}
+
+			for py := 0; py < scale; py++ {
+				row := img.Pix[img.PixOffset(offset+x*scale, offset+y*scale+py):]
+				for px := 0; px < scale; px++ {
+					row[px] = 1
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// encodeSVG draws every row of the code as a path of runs of dark modules,
+// the view box is in modules so the image scales without loss.
+func encodeSVG(code *qr.Code, o Options) []byte {
+	modules := code.Size + 2*o.Margin
+
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%[1]v" height="%[1]v" viewBox="0 0 %[2]v %[2]v" shape-rendering="crispEdges">`, o.Size, modules)
+	fmt.Fprintf(&buf, `<rect width="%[1]v" height="%[1]v" fill="#fff"/><path fill="#000" d="`, modules)
+
+	for y := 0; y < code.Size; y++ {
+		for x := 0; x < code.Size; {
+			if !code.Black(x, y) {
+				x++
+
+				continue
+			}
+
+			run := 1
+			for code.Black(x+run, y) {
+				run++
+			}
+
+			fmt.Fprintf(&buf, "M%v %vh%vv1h-%vz", x+o.Margin, y+o.Margin, run, run)
+			x += run
+		}
+	}
+
+	buf.WriteString(`"/></svg>`)
+
+	return buf.Bytes()
+}
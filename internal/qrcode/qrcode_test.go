@@ -0,0 +1,98 @@
+package qrcode
+
+import (
+	"bytes"
+	"encoding/xml"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const text = "http://localhost:8080/aHR0cDovL3lhLnJ1"
+
+func TestEncodePNG(t *testing.T) {
+	o := DefaultOptions()
+
+	data, err := Encode(text, o)
+	require.NoError(t, err)
+
+	img, err := png.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, o.Size, img.Bounds().Dx())
+	assert.Equal(t, o.Size, img.Bounds().Dy())
+
+	isBlack := func(x, y int) bool {
+		return color.GrayModel.Convert(img.At(x, y)).(color.Gray).Y == 0
+	}
+
+	// the quiet zone is white, the top left finder pattern starts after it
+	assert.False(t, isBlack(0, 0))
+	assert.False(t, isBlack(o.Size/2, 0))
+
+	found := false
+	for i := 0; i < o.Size/2 && !found; i++ {
+		found = isBlack(i, i)
+	}
+
+	assert.True(t, found, "no dark modules on the diagonal")
+}
+
+func TestEncodeSVG(t *testing.T) {
+	o := DefaultOptions()
+	o.Format = SVG
+	o.Size = 100
+	o.Margin = 0
+
+	data, err := Encode(text, o)
+	require.NoError(t, err)
+
+	var svg struct {
+		XMLName xml.Name `xml:"svg"`
+		Width   string   `xml:"width,attr"`
+		Path    struct {
+			D string `xml:"d,attr"`
+		} `xml:"path"`
+	}
+	require.NoError(t, xml.Unmarshal(data, &svg))
+	assert.Equal(t, "100", svg.Width)
+	// the finder pattern is at the corner without margin
+	assert.Contains(t, svg.Path.D, "M0 0h7v1h-7z")
+}
+
+func TestOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		edit func(o *Options)
+	}{
+		{"format", func(o *Options) { o.Format = "gif" }},
+		{"level", func(o *Options) { o.Level = "X" }},
+		{"zero size", func(o *Options) { o.Size = 0 }},
+		{"huge size", func(o *Options) { o.Size = MaxSize + 1 }},
+		{"negative margin", func(o *Options) { o.Margin = -1 }},
+		{"huge margin", func(o *Options) { o.Margin = MaxMargin + 1 }},
+		{"modules do not fit", func(o *Options) { o.Size = 20 }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := DefaultOptions()
+			tt.edit(&o)
+
+			_, err := Encode(text, o)
+			assert.ErrorIs(t, err, ErrInvalidOptions)
+		})
+	}
+
+	f, err := ParseFormat("SVG")
+	require.NoError(t, err)
+	assert.Equal(t, SVG, f)
+
+	l, err := ParseLevel("q")
+	require.NoError(t, err)
+	assert.Equal(t, Q, l)
+
+	_, err = ParseLevel("")
+	assert.ErrorIs(t, err, ErrInvalidOptions)
+}
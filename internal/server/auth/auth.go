@@ -4,9 +4,11 @@ import (
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
+	"crypto/subtle"
 	"encoding/hex"
 	"fmt"
 	"log"
+	"strings"
 
 	"github.com/google/uuid"
 )
@@ -52,6 +54,28 @@ func LoadUser(token string, s SessionStoreLoader) (string, error) {
 	return userId, nil
 }
 
+// BearerToken returns the token from an Authorization header value
+// of the "Bearer" scheme or an empty string.
+func BearerToken(header string) string {
+	const prefix = "Bearer "
+
+	if len(header) < len(prefix) || !strings.EqualFold(header[:len(prefix)], prefix) {
+		return ""
+	}
+
+	return strings.TrimSpace(header[len(prefix):])
+}
+
+// IsAdmin reports whether token matches adminToken.
+// No token is accepted if adminToken is empty.
+func IsAdmin(adminToken, token string) bool {
+	if adminToken == "" || token == "" {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(adminToken), []byte(token)) == 1
+}
+
 func generateRandom(size int) (string, error) {
 	b := make([]byte, size)
 	_, err := rand.Read(b)
@@ -0,0 +1,125 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	ps "github.com/usa4ev/urlshortner/internal/server/grpcserver/protoshortener"
+	"github.com/usa4ev/urlshortner/internal/storage/model"
+	"github.com/usa4ev/urlshortner/internal/storage/storageerrors"
+)
+
+// adminServer implements ShortenerAdmin service.
+// Calls are authorized by authInterceptor.
+type adminServer struct {
+	ps.UnimplementedShortenerAdminServer
+
+	srv *Server
+}
+
+func (a *adminServer) GetLink(ctx context.Context, in *ps.AdminGetLinkRequest) (*ps.AdminGetLinkResponse, error) {
+	var (
+		link model.Link
+		err  error
+	)
+
+	res := ps.AdminGetLinkResponse{}
+
+	switch {
+	case in.Id != "":
+		link, err = a.srv.shortener.LoadLink(in.Id)
+	case in.Url != "":
+		link, err = a.srv.shortener.FindLinkByURL(in.Url)
+	default:
+		res.Error = "either id or url must be set"
+		return &res, status.Error(codes.InvalidArgument, res.Error)
+	}
+
+	if err != nil {
+		res.Error = err.Error()
+		return &res, status.Error(adminErrCode(err), err.Error())
+	}
+
+	res.Link = linkToProto(link)
+
+	return &res, nil
+}
+
+func (a *adminServer) SetDisabled(ctx context.Context, in *ps.AdminSetDisabledRequest) (*ps.AdminSetDisabledResponse, error) {
+	res := ps.AdminSetDisabledResponse{}
+
+	if err := a.srv.shortener.SetDisabled(in.Id, in.Disabled); err != nil {
+		res.Error = err.Error()
+		return &res, status.Error(adminErrCode(err), err.Error())
+	}
+
+	return &res, nil
+}
+
+func (a *adminServer) GetUserLinks(ctx context.Context, in *ps.AdminUserRequest) (*ps.AdminGetUserLinksResponse, error) {
+	res := ps.AdminGetUserLinksResponse{}
+
+	links, err := a.srv.shortener.LoadLinksByUser(in.UserId)
+	if err != nil {
+		res.Error = err.Error()
+		return &res, status.Errorf(codes.Internal, "failed to load URLs by user: %v", err.Error())
+	}
+
+	res.Links = make([]*ps.Link, len(links))
+	for i, v := range links {
+		res.Links[i] = linkToProto(v)
+	}
+
+	return &res, nil
+}
+
+func (a *adminServer) DeleteUser(ctx context.Context, in *ps.AdminUserRequest) (*ps.AdminDeleteResponse, error) {
+	res := ps.AdminDeleteResponse{}
+
+	n, err := a.srv.shortener.DeleteUser(in.UserId)
+	if err != nil {
+		res.Error = err.Error()
+		return &res, status.Error(codes.Internal, err.Error())
+	}
+
+	res.Deleted = int32(n)
+
+	return &res, nil
+}
+
+func (a *adminServer) DeleteSessions(ctx context.Context, in *ps.AdminUserRequest) (*ps.AdminDeleteResponse, error) {
+	res := ps.AdminDeleteResponse{}
+
+	n, err := a.srv.shortener.DeleteSessions(in.UserId)
+	if err != nil {
+		res.Error = err.Error()
+		return &res, status.Error(codes.Internal, err.Error())
+	}
+
+	res.Deleted = int32(n)
+
+	return &res, nil
+}
+
+// adminErrCode returns gRPC status code for an error returned by admin methods.
+func adminErrCode(err error) codes.Code {
+	if errors.Is(err, storageerrors.ErrNotFound) {
+		return codes.NotFound
+	}
+
+	return codes.Internal
+}
+
+func linkToProto(link model.Link) *ps.Link {
+	return &ps.Link{
+		Id:          link.ID,
+		ShortUrl:    link.ShortURL,
+		OriginalUrl: link.OriginalURL,
+		UserId:      link.UserID,
+		Deleted:     link.Deleted,
+		Disabled:    link.Disabled,
+	}
+}
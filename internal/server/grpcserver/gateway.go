@@ -0,0 +1,70 @@
+package grpcserver
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"golang.org/x/sync/singleflight"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	psv2 "github.com/usa4ev/urlshortner/internal/server/grpcserver/protoshortener/v2"
+	"github.com/usa4ev/urlshortner/internal/shortener"
+)
+
+// NewGateway returns the REST/JSON gateway of grpcserver.v2.Shortener service
+// that calls the service in-process, see HTTP annotations of shortener.proto.
+// The gateway does not authorize requests: the caller does it and
+// userID returns the user of an authorized request.
+func NewGateway(c config, s shortener.Shortener, userID func(r *http.Request) string) (http.Handler, error) {
+	srv := &Server{
+		cfg:       c,
+		shortener: s,
+		sfgr:      new(singleflight.Group),
+	}
+
+	mux := runtime.NewServeMux(
+		// field names are snake_case as of the rest of HTTP API
+		runtime.WithMarshalerOption(runtime.MIMEWildcard, &runtime.JSONPb{
+			MarshalOptions:   protojson.MarshalOptions{UseProtoNames: true},
+			UnmarshalOptions: protojson.UnmarshalOptions{DiscardUnknown: true},
+		}),
+		runtime.WithMetadata(func(ctx context.Context, r *http.Request) metadata.MD {
+			return metadata.Pairs("user_id", userID(r))
+		}),
+		// clients must not pass user_id or any other metadata in headers
+		runtime.WithIncomingHeaderMatcher(func(string) (string, bool) {
+			return "", false
+		}),
+		runtime.WithErrorHandler(gatewayError),
+	)
+
+	if err := psv2.RegisterShortenerHandlerServer(context.Background(), mux, &serverV2{srv: srv}); err != nil {
+		return nil, err
+	}
+
+	return mux, nil
+}
+
+// gatewayError writes err as runtime.DefaultHTTPErrorHandler does, except
+// that an exceeded user quota is 403 Forbidden like in the rest of HTTP API
+// rather than 429 Too Many Requests, since the quota does not reset.
+func gatewayError(ctx context.Context, mux *runtime.ServeMux, m runtime.Marshaler, w http.ResponseWriter, r *http.Request, err error) {
+	if st, ok := status.FromError(err); ok && st.Code() == codes.ResourceExhausted {
+		for _, d := range st.Details() {
+			if _, ok := d.(*errdetails.QuotaFailure); ok {
+				p := st.Proto()
+				p.Code = int32(codes.PermissionDenied)
+				err = status.ErrorProto(p)
+
+				break
+			}
+		}
+	}
+
+	runtime.DefaultHTTPErrorHandler(ctx, mux, m, w, r, err)
+}
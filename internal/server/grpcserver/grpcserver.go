@@ -4,10 +4,17 @@ package grpcserver
 import (
 	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"fmt"
+	"io"
+	"log"
+	"math"
 	"net"
-	"path/filepath"
+	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"golang.org/x/sync/singleflight"
 	"google.golang.org/grpc"
@@ -18,19 +25,45 @@ import (
 	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/status"
 
+	conf "github.com/usa4ev/urlshortner/internal/config"
 	"github.com/usa4ev/urlshortner/internal/server/auth"
 	ps "github.com/usa4ev/urlshortner/internal/server/grpcserver/protoshortener"
+	psv2 "github.com/usa4ev/urlshortner/internal/server/grpcserver/protoshortener/v2"
+	"github.com/usa4ev/urlshortner/internal/server/identity"
+	"github.com/usa4ev/urlshortner/internal/server/ratelimit"
+	"github.com/usa4ev/urlshortner/internal/server/tlsconfig"
+	"github.com/usa4ev/urlshortner/internal/server/trustednet"
 	"github.com/usa4ev/urlshortner/internal/shortener"
+	"github.com/usa4ev/urlshortner/internal/shortener/policy"
 	"github.com/usa4ev/urlshortner/internal/storage/database"
+	"github.com/usa4ev/urlshortner/internal/storage/model"
 	"github.com/usa4ev/urlshortner/internal/storage/storageerrors"
 )
 
 type config interface {
 	UseTLS() bool
-	SslPath() string
+	TLSCertPath() string
+	TLSKeyPath() string
+	TLSMinVersion() string
+	TLSCipherSuites() string
 	DBDSN() string
 	SrvAddr() string
 	TrustedSubnet() string
+	TrustedProxies() string
+	RateLimit() float64
+	RateBurst() int
+	AdminToken() string
+	ClientCAPath() string
+	ServiceIdentities() string
+}
+
+// streamBatchSize limits the number of items ShortenStream stores at once.
+const streamBatchSize = 100
+
+// trustedMethods are only available to clients from trusted subnets.
+var trustedMethods = map[string]bool{
+	"/grpcserver.Shortener/Stats":       true,
+	"/grpcserver.v2.Shortener/GetStats": true,
 }
 
 type Server struct {
@@ -40,6 +73,12 @@ type Server struct {
 	sessionMgr auth.SessionStoreLoader
 	cfg        config
 	sfgr       *singleflight.Group
+	limiter    *ratelimit.Limiter
+	trusted    *trustednet.Network
+	identities *identity.Map
+	tlsCfg     *tls.Config
+	certs      *tlsconfig.Loader
+	tlsErr     error
 	gs         *grpc.Server
 }
 
@@ -50,6 +89,29 @@ func New(c config, s shortener.Shortener, sm auth.SessionStoreLoader) *Server {
 	srv.shortener = s
 	srv.sessionMgr = sm
 	srv.sfgr = new(singleflight.Group)
+	srv.limiter = ratelimit.New(c.RateLimit(), c.RateBurst())
+
+	trusted, err := trustednet.New(c.TrustedSubnet(), c.TrustedProxies())
+	if err != nil {
+		// empty network trusts nobody
+		log.Printf("internal methods are disabled: %v", err)
+		trusted = &trustednet.Network{}
+	}
+
+	srv.trusted = trusted
+
+	identities, err := identity.Parse(c.ServiceIdentities())
+	if err != nil {
+		// nil map has no identities
+		log.Printf("service identities are disabled: %v", err)
+	}
+
+	srv.identities = identities
+
+	if c.UseTLS() {
+		// the error is returned by Run
+		srv.tlsCfg, srv.certs, srv.tlsErr = tlsconfig.New(c)
+	}
 
 	return &srv
 }
@@ -60,12 +122,10 @@ func (srv *Server) listenAndServe() error {
 		return err
 	}
 
-	interceptor := grpc.UnaryServerInterceptor(srv.authInterceptor)
-
 	gs := grpc.NewServer(grpc.Creds(insecure.NewCredentials()),
-		grpc.UnaryInterceptor(interceptor))
+		srv.interceptors(), srv.streamInterceptors())
 	srv.gs = gs
-	ps.RegisterShortenerServer(gs, srv)
+	srv.register(gs)
 	fmt.Println("gRPC server starts")
 
 	if err := gs.Serve(listen); err != nil {
@@ -75,23 +135,29 @@ func (srv *Server) listenAndServe() error {
 	return nil
 }
 
-func (srv *Server) listenAndServeTLS(cert, key string) error {
-	interceptor := grpc.UnaryServerInterceptor(srv.authInterceptor)
-
-	crt, err := tls.LoadX509KeyPair(cert, key)
-	if err != nil {
-		return err
+func (srv *Server) listenAndServeTLS() error {
+	if srv.tlsErr != nil {
+		return srv.tlsErr
 	}
 
-	creds := credentials.NewTLS(&tls.Config{
-		Certificates: []tls.Certificate{crt},
-		ClientAuth:   tls.NoClientCert,
-	})
+	tlsCfg := srv.tlsCfg.Clone()
 
-	gs := grpc.NewServer(grpc.Creds(creds),
-		grpc.UnaryInterceptor(interceptor))
+	if caPath := srv.cfg.ClientCAPath(); caPath != "" {
+		pool, err := loadCertPool(caPath)
+		if err != nil {
+			return err
+		}
 
-	ps.RegisterShortenerServer(gs, srv)
+		// clients without certificates still authenticate with session tokens
+		tlsCfg.ClientCAs = pool
+		tlsCfg.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+
+	gs := grpc.NewServer(grpc.Creds(credentials.NewTLS(tlsCfg)),
+		srv.interceptors(), srv.streamInterceptors())
+	srv.gs = gs
+
+	srv.register(gs)
 	fmt.Println("gRPC server starts")
 
 	listen, err := net.Listen("tcp", srv.cfg.SrvAddr())
@@ -106,12 +172,32 @@ func (srv *Server) listenAndServeTLS(cert, key string) error {
 	return nil
 }
 
+// register registers the shortener, its v2 and the admin services on gs.
+func (srv *Server) register(gs *grpc.Server) {
+	ps.RegisterShortenerServer(gs, srv)
+	psv2.RegisterShortenerServer(gs, &serverV2{srv: srv})
+	ps.RegisterShortenerAdminServer(gs, &adminServer{srv: srv})
+}
+
+// loadCertPool returns a pool of PEM certificates read from path.
+func loadCertPool(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client CA bundle: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in client CA bundle %v", path)
+	}
+
+	return pool, nil
+}
+
 func (srv *Server) Run() error {
 	// Run the server
 	if srv.cfg.UseTLS() {
-		return srv.listenAndServeTLS(
-			filepath.Join(srv.cfg.SslPath(), "example.crt"),
-			filepath.Join(srv.cfg.SslPath(), "example.key"))
+		return srv.listenAndServeTLS()
 	} else {
 		return srv.listenAndServe()
 	}
@@ -129,6 +215,222 @@ func (srv *Server) Shutdown(ctx context.Context) error {
 	return nil
 }
 
+// ApplyConfig applies trusted subnets, rate limits and shortener settings of c
+// without restarting the server. Nothing changes if any of them is invalid.
+func (srv *Server) ApplyConfig(c *conf.Config) error {
+	trusted, err := trustednet.New(c.TrustedSubnet(), c.TrustedProxies())
+	if err != nil {
+		return err
+	}
+
+	if err := srv.shortener.ApplyConfig(c); err != nil {
+		return err
+	}
+
+	srv.trusted.Set(trusted)
+	srv.limiter.SetRate(c.RateLimit(), c.RateBurst())
+
+	return nil
+}
+
+// ReloadCertificates rereads the TLS certificate files.
+// It does nothing if the server does not use TLS.
+func (srv *Server) ReloadCertificates() error {
+	if srv.certs == nil {
+		return nil
+	}
+
+	return srv.certs.Reload()
+}
+
+// interceptors limit clients by IP before a new session
+// can be opened and by user after the session is loaded.
+func (srv *Server) interceptors() grpc.ServerOption {
+	return grpc.ChainUnaryInterceptor(
+		srv.ipRateLimitInterceptor,
+		srv.trustedInterceptor,
+		srv.authInterceptor,
+		srv.userRateLimitInterceptor)
+}
+
+// streamInterceptors run the same checks once when a stream is opened,
+// rate limits also apply to every message received from the stream.
+func (srv *Server) streamInterceptors() grpc.ServerOption {
+	return grpc.ChainStreamInterceptor(
+		streamInterceptor(srv.ipRateLimitInterceptor),
+		streamInterceptor(srv.trustedInterceptor),
+		streamInterceptor(srv.authInterceptor),
+		streamInterceptor(srv.userRateLimitInterceptor),
+		srv.messageRateLimitInterceptor)
+}
+
+// serverStream replaces the context of a stream.
+type serverStream struct {
+	grpc.ServerStream
+
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// streamInterceptor adapts a unary interceptor to streams. The stream
+// handler gets the context the interceptor passes to the unary handler.
+func streamInterceptor(unary grpc.UnaryServerInterceptor) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		unaryInfo := &grpc.UnaryServerInfo{Server: srv, FullMethod: info.FullMethod}
+
+		_, err := unary(ss.Context(), nil, unaryInfo, func(ctx context.Context, _ interface{}) (interface{}, error) {
+			return nil, handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+		})
+
+		return err
+	}
+}
+
+// limitedStream charges rate limits of keys for every received message.
+type limitedStream struct {
+	grpc.ServerStream
+
+	srv  *Server
+	keys []string
+}
+
+func (s *limitedStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+
+	// headers are sent by now, the status carries RetryInfo
+	for _, key := range s.keys {
+		if _, err := s.srv.limit(key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// messageRateLimitInterceptor limits messages of a stream by client IP
+// and by user, so a stream counts the same as calls sending its messages.
+func (srv *Server) messageRateLimitInterceptor(srvInfo interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	var keys []string
+
+	if ip := srv.clientIP(ss.Context()); ip != nil {
+		keys = append(keys, "ip:"+ip.String())
+	}
+
+	if userID, err := getUserID(ss.Context()); err == nil {
+		keys = append(keys, "user:"+userID)
+	}
+
+	return handler(srvInfo, &limitedStream{ServerStream: ss, srv: srv, keys: keys})
+}
+
+func (srv *Server) ipRateLimitInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+	if ip := srv.clientIP(ctx); ip != nil {
+		if err := srv.allow(ctx, "ip:"+ip.String()); err != nil {
+			return nil, err
+		}
+	}
+
+	return handler(ctx, req)
+}
+
+// trustedInterceptor rejects calls of trustedMethods
+// from clients outside of trusted subnets unless
+// they have a certificate of an internal service.
+func (srv *Server) trustedInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+	if !trustedMethods[info.FullMethod] || srv.trusted.Contains(srv.clientIP(ctx)) {
+		return handler(ctx, req)
+	}
+
+	if id, ok := srv.serviceIdentity(ctx); ok && id.Allows(identity.RoleInternal) {
+		return handler(ctx, req)
+	}
+
+	return nil, status.Error(codes.PermissionDenied, "call from untrusted subnet")
+}
+
+// serviceIdentity returns the identity of a client
+// that presented a verified certificate.
+func (srv *Server) serviceIdentity(ctx context.Context) (identity.Identity, bool) {
+	pr, ok := peer.FromContext(ctx)
+	if !ok {
+		return identity.Identity{}, false
+	}
+
+	tlsInfo, ok := pr.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(tlsInfo.State.VerifiedChains) == 0 || len(tlsInfo.State.VerifiedChains[0]) == 0 {
+		return identity.Identity{}, false
+	}
+
+	return srv.identities.FromCert(tlsInfo.State.VerifiedChains[0][0])
+}
+
+// clientIP returns IP of the peer or, if the peer is a trusted proxy,
+// of the client it passed in x-forwarded-for or x-real-ip metadata.
+func (srv *Server) clientIP(ctx context.Context) net.IP {
+	pr, ok := peer.FromContext(ctx)
+	if !ok || pr.Addr == nil {
+		return nil
+	}
+
+	var (
+		forwardedFor []string
+		realIP       string
+	)
+
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		forwardedFor = md.Get("x-forwarded-for")
+		if v := md.Get("x-real-ip"); len(v) > 0 {
+			realIP = v[0]
+		}
+	}
+
+	return srv.trusted.ClientIP(pr.Addr.String(), forwardedFor, realIP)
+}
+
+func (srv *Server) userRateLimitInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+	if userID, err := getUserID(ctx); err == nil {
+		if err := srv.allow(ctx, "user:"+userID); err != nil {
+			return nil, err
+		}
+	}
+
+	return handler(ctx, req)
+}
+
+// allow returns ResourceExhausted error with RetryInfo details and sets
+// retry-after header if the client identified by key exceeds the limit.
+func (srv *Server) allow(ctx context.Context, key string) error {
+	wait, err := srv.limit(key)
+	if err == nil {
+		return nil
+	}
+
+	retryAfter := strconv.Itoa(ratelimit.RetryAfter(wait))
+	if err := grpc.SetHeader(ctx, metadata.Pairs("retry-after", retryAfter)); err != nil {
+		log.Printf("failed to set retry-after header: %v", err)
+	}
+
+	return err
+}
+
+// limit returns ResourceExhausted error with RetryInfo details
+// and the time to wait if the client identified by key exceeds the limit.
+func (srv *Server) limit(key string) (time.Duration, error) {
+	ok, wait := srv.limiter.Allow(key)
+	if ok {
+		return 0, nil
+	}
+
+	msg := fmt.Sprintf("too many requests, retry after %v seconds", ratelimit.RetryAfter(wait))
+
+	return wait, statusError(codes.ResourceExhausted, msg, retryInfo(wait))
+}
+
 func (srv *Server) authInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
 	var token, userID string
 
@@ -139,18 +441,45 @@ func (srv *Server) authInterceptor(ctx context.Context, req interface{}, info *g
 		}
 	}
 
+	// admin methods do not use sessions
+	if strings.HasPrefix(info.FullMethod, "/"+ps.ShortenerAdmin_ServiceDesc.ServiceName+"/") {
+		if id, ok := srv.serviceIdentity(ctx); ok && id.Allows(identity.RoleAdmin) {
+			return handler(ctx, req)
+		}
+
+		if !auth.IsAdmin(srv.cfg.AdminToken(), auth.BearerToken(token)) {
+			return nil, status.Error(codes.Unauthenticated, "invalid admin token")
+		}
+
+		return handler(ctx, req)
+	}
+
+	// trusted methods are authorized by trustedInterceptor
+	if trustedMethods[info.FullMethod] {
+		return handler(ctx, req)
+	}
+
 	if token != "" {
 		//token is set, look up the user
 		userID, err = auth.LoadUser(token, srv.sessionMgr)
 		if err != nil {
 			return nil, status.Error(codes.Unauthenticated, "failed to load user by token")
 		}
+
+		if userID == "" {
+			return nil, status.Error(codes.Unauthenticated, "session is not found or invalidated")
+		}
 	} else {
 		//token is not set, open new session
-		userID, _, err = auth.OpenSession(srv.sessionMgr)
+		userID, token, err = auth.OpenSession(srv.sessionMgr)
 		if err != nil {
 			return nil, status.Error(codes.Unauthenticated, "failed to open new session")
 		}
+
+		// the client passes the token in authorization metadata of next calls
+		if err := grpc.SetHeader(ctx, metadata.Pairs("authorization", token)); err != nil {
+			log.Printf("failed to set authorization header: %v", err)
+		}
 	}
 
 	md, _ := metadata.FromIncomingContext(ctx)
@@ -170,23 +499,44 @@ func (srv *Server) Shorten(ctx context.Context, in *ps.ShortenRequest) (*ps.Shor
 		return &res, status.Error(codes.Internal, err.Error())
 	}
 
-	id, _ := srv.shortener.ShortenURL(in.Url)
+	originalURL, err := srv.shortener.NormalizeURL(in.Url)
+	if err != nil {
+		res.Error = err.Error()
+		return &res, status.Error(normalizeErrCode(err), err.Error())
+	}
 
-	err = srv.shortener.StoreURL(id, in.Url, userID)
+	link, err := srv.shortener.Shorten(originalURL, userID)
 	if err != nil {
-		if errors.Is(err, storageerrors.ErrConflict) {
+		if errors.Is(err, shortener.ErrQuotaExceeded) {
+			res.Error = err.Error()
+			return &res, status.Error(codes.ResourceExhausted, err.Error())
+		}
+
+		if errors.Is(err, storageerrors.ErrConflict) && link.ID != "" {
+			// the id may differ from the one of the url if it is imported
+			// or the link it was stored with has changed its destination
 			res.Error = err.Error()
-			return &res, status.Errorf(codes.AlreadyExists, "url %v is already shortened. id: %v", in.Url, id)
+			return &res, status.Errorf(codes.AlreadyExists, "url %v is already shortened. id: %v", in.Url, link.ID)
 		}
 
 		return &res, status.Errorf(codes.Internal, "failed to store URL: %v", err.Error())
 	}
 
-	res.Id = id
+	res.Id = link.ID
+	res.ShortUrl = link.ShortURL
 
 	return &res, nil
 }
 
+// normalizeErrCode returns gRPC status code for an error returned by NormalizeURL.
+func normalizeErrCode(err error) codes.Code {
+	if errors.Is(err, policy.ErrBlocked) {
+		return codes.PermissionDenied
+	}
+
+	return codes.InvalidArgument
+}
+
 func getUserID(ctx context.Context) (string, error) {
 	val := metadata.ValueFromIncomingContext(ctx, "user_id")
 	if len(val) != 1 || val[0] == "" {
@@ -197,7 +547,6 @@ func getUserID(ctx context.Context) (string, error) {
 
 func (srv *Server) ShortenBatch(ctx context.Context, in *ps.ShortenBatchRequest) (*ps.ShortenBatchResponse, error) {
 	res := ps.ShortenBatchResponse{}
-	data := make([]*ps.URLwId, 0)
 
 	userID, err := getUserID(ctx)
 	if err != nil {
@@ -205,21 +554,206 @@ func (srv *Server) ShortenBatch(ctx context.Context, in *ps.ShortenBatchRequest)
 		return &res, status.Error(codes.Internal, err.Error())
 	}
 
-	for _, v := range in.Data {
-		id, url := srv.shortener.ShortenURL(v.Url)
-		data = append(data, &ps.URLwId{Id: v.Id, Url: url})
-		err = srv.shortener.StoreURL(id, v.Url, userID)
-		if err != nil {
+	links := make([]model.Link, len(in.Data))
+	for i, v := range in.Data {
+		links[i].OriginalURL = v.Url
+	}
+
+	errs, err := srv.shortener.StoreURLs(userID, links, !in.Partial)
+	if err != nil {
+		res.Error = err.Error()
+		return &res, status.Errorf(codes.Internal, "failed to store URLs: %v", err.Error())
+	}
+
+	if in.Partial {
+		res.Results = make([]*ps.BatchItemResult, len(in.Data))
+		for i, v := range in.Data {
+			res.Results[i] = srv.itemResult(v.Id, links[i], errs[i])
+		}
+
+		return &res, nil
+	}
+
+	for i, err := range errs {
+		if err != nil && !errors.Is(err, storageerrors.ErrBatchAborted) {
 			res.Error = err.Error()
-			return &res, status.Errorf(codes.Internal, "failed to store URL: %v", err.Error())
+			return &res, status.Errorf(batchErrCode(err), "id %v: %v", in.Data[i].Id, err.Error())
 		}
 	}
 
-	res.Data = data
+	res.Data = make([]*ps.URLwId, len(in.Data))
+	for i, v := range in.Data {
+		res.Data[i] = &ps.URLwId{Id: v.Id, Url: links[i].ShortURL}
+	}
 
 	return &res, nil
 }
 
+// itemResult returns the outcome of a batch item stored with err.
+func (srv *Server) itemResult(id string, link model.Link, err error) *ps.BatchItemResult {
+	res := &ps.BatchItemResult{Id: id}
+
+	switch {
+	case err == nil:
+		res.ShortUrl = link.ShortURL
+		return res
+	case errors.Is(err, storageerrors.ErrConflict):
+		res.Status = ps.ItemStatus_CONFLICT
+		// the URL may be stored with an imported id
+		if stored, err := srv.shortener.FindLinkByURL(link.OriginalURL); err == nil {
+			res.ShortUrl = stored.ShortURL
+		}
+	case errors.Is(err, shortener.ErrQuotaExceeded):
+		res.Status = ps.ItemStatus_QUOTA_EXCEEDED
+	default:
+		res.Status = ps.ItemStatus_INVALID
+	}
+
+	res.Error = err.Error()
+
+	return res
+}
+
+// batchErrCode returns status code for an error of a batch item.
+func batchErrCode(err error) codes.Code {
+	switch {
+	case errors.Is(err, shortener.ErrQuotaExceeded):
+		return codes.ResourceExhausted
+	case errors.Is(err, storageerrors.ErrConflict):
+		return codes.AlreadyExists
+	default:
+		return normalizeErrCode(err)
+	}
+}
+
+// ShortenStream stores urls one by one as they are received, so
+// a client does not have to wait for the results to send more.
+func (srv *Server) ShortenStream(stream ps.Shortener_ShortenStreamServer) error {
+	return shortenStream[*ps.URLwId, *ps.BatchItemResult](srv, stream,
+		func(in *ps.URLwId) (string, string) { return in.Id, in.Url },
+		srv.itemResult)
+}
+
+// itemStream is a stream of urls to shorten and results of storing them.
+type itemStream[In, Out any] interface {
+	Recv() (In, error)
+	Send(Out) error
+	grpc.ServerStream
+}
+
+// shortenStream stores urls as they are received from stream and sends
+// the result made by result for each of them. item returns the id the client
+// identifies an item with and its url. Items received while the previous
+// ones are being stored are stored together, up to streamBatchSize at once.
+func shortenStream[In, Out any](srv *Server, stream itemStream[In, Out],
+	item func(in In) (string, string), result func(id string, link model.Link, err error) Out) error {
+	userID, err := getUserID(stream.Context())
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+
+	// the client may wait for the token of a new session before sending urls
+	if err := stream.SendHeader(nil); err != nil {
+		return err
+	}
+
+	type received struct {
+		in  In
+		err error
+	}
+
+	ch := make(chan received, streamBatchSize)
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		defer close(ch)
+
+		for {
+			in, err := stream.Recv()
+
+			select {
+			case ch <- received{in, err}:
+			case <-done:
+				return
+			}
+
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	ids := make([]string, 0, streamBatchSize)
+	links := make([]model.Link, 0, streamBatchSize)
+
+	for r := range ch {
+		ids, links = ids[:0], links[:0]
+
+		// take the items received so far without waiting for more
+	batch:
+		for r.err == nil {
+			id, url := item(r.in)
+			ids = append(ids, id)
+			links = append(links, model.Link{OriginalURL: url})
+
+			if len(links) == streamBatchSize {
+				break
+			}
+
+			select {
+			case r = <-ch:
+			default:
+				break batch
+			}
+		}
+
+		if len(links) > 0 {
+			errs, err := srv.shortener.StoreURLs(userID, links, false)
+			if err != nil {
+				return status.Errorf(codes.Internal, "id %v: failed to store URL: %v", ids[0], err.Error())
+			}
+
+			for i := range links {
+				if err := stream.Send(result(ids[i], links[i], errs[i])); err != nil {
+					return err
+				}
+			}
+		}
+
+		if errors.Is(r.err, io.EOF) {
+			return nil
+		}
+
+		if r.err != nil {
+			return r.err
+		}
+	}
+
+	return nil
+}
+
+// ListMyURLs streams links of the session.
+func (srv *Server) ListMyURLs(in *ps.Dummy, stream ps.Shortener_ListMyURLsServer) error {
+	userID, err := getUserID(stream.Context())
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+
+	links, err := srv.shortener.LoadLinksByUser(userID)
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to load URLs by user: %v", err.Error())
+	}
+
+	for _, v := range links {
+		if err := stream.Send(linkToProto(v)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (srv *Server) GetLong(ctx context.Context, in *ps.GetLongRequest) (*ps.GetLongResponse, error) {
 	res := ps.GetLongResponse{}
 	redirect, err := srv.shortener.FindURL(in.Id)
@@ -227,12 +761,18 @@ func (srv *Server) GetLong(ctx context.Context, in *ps.GetLongRequest) (*ps.GetL
 	case errors.Is(err, storageerrors.ErrURLGone):
 		res.Error = err.Error()
 		return &res, status.Errorf(codes.Unavailable, "URL deleted: %v", err.Error())
-	case err != nil && !errors.Is(err, storageerrors.ErrURLGone):
+	case errors.Is(err, storageerrors.ErrURLDisabled):
+		res.Error = err.Error()
+		return &res, status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, policy.ErrBlocked):
+		res.Error = err.Error()
+		return &res, status.Error(codes.PermissionDenied, err.Error())
+	case err != nil:
 		res.Error = err.Error()
 		return &res, status.Error(codes.Internal, err.Error())
 	case redirect == "":
-		res.Error = err.Error()
-		return &res, status.Errorf(codes.NotFound, "URL not found; id: %v", in.Id)
+		res.Error = fmt.Sprintf("URL not found; id: %v", in.Id)
+		return &res, status.Error(codes.NotFound, res.Error)
 	}
 
 	res.Url = redirect
@@ -262,6 +802,7 @@ func (srv *Server) GetLongByUser(ctx context.Context, in *ps.Dummy) (*ps.GetLong
 
 	for _, v := range pairs {
 		data = append(data, v.ShortURL)
+		res.Links = append(res.Links, &ps.URLPair{ShortUrl: v.ShortURL, OriginalUrl: v.OriginalURL})
 	}
 
 	res.Urls = data
@@ -287,61 +828,95 @@ func (srv *Server) DeleteBatch(ctx context.Context, in *ps.DeleteBatchRequest) (
 	return &res, nil
 }
 
-func (srv *Server) Stats(ctx context.Context, in *ps.Dummy) (*ps.StatsResponse, error) {
-	res := ps.StatsResponse{}
-
-	pr, ok := peer.FromContext(ctx)
-	if !ok {
-		res.Error = "failed to get peer from ctx"
-		return &res, status.Error(codes.Unauthenticated, res.Error)
-
+func (srv *Server) UpdateURL(ctx context.Context, in *ps.UpdateURLRequest) (*ps.UpdateURLResponse, error) {
+	res := ps.UpdateURLResponse{}
+	userID, err := getUserID(ctx)
+	if err != nil {
+		res.Error = err.Error()
+		return &res, status.Error(codes.Internal, err.Error())
 	}
-	if pr.Addr == net.Addr(nil) {
-		res.Error = "failed to get peer address"
-		return &res, status.Error(codes.Unauthenticated, res.Error)
 
+	originalURL, err := srv.shortener.NormalizeURL(in.Url)
+	if err != nil {
+		res.Error = err.Error()
+		return &res, status.Error(normalizeErrCode(err), err.Error())
 	}
 
-	//parse subnet string
-	_, subnet, err := net.ParseCIDR(srv.cfg.TrustedSubnet())
+	err = srv.shortener.UpdateURL(userID, in.Id, originalURL)
 	if err != nil {
-		res.Error = fmt.Sprintf("failed to parse trusted subnet from config: %v", srv.cfg.TrustedSubnet())
-		return &res, status.Error(codes.Internal, res.Error)
+		res.Error = err.Error()
+	}
 
+	switch {
+	case errors.Is(err, storageerrors.ErrNotFound):
+		return &res, status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, storageerrors.ErrNotOwner):
+		return &res, status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, storageerrors.ErrURLGone):
+		return &res, status.Errorf(codes.Unavailable, "URL deleted: %v", err.Error())
+	case errors.Is(err, storageerrors.ErrConflict):
+		return &res, status.Error(codes.AlreadyExists, err.Error())
+	case err != nil:
+		return &res, status.Error(codes.Internal, err.Error())
 	}
 
-	ip := net.ParseIP(pr.Addr.String())
+	return &res, nil
+}
 
-	//check if ip belongs subnet
-	if !subnet.Contains(ip) {
-		res.Error = "call from unathorized subnet"
-		return &res, status.Error(codes.Unauthenticated, res.Error)
-	}
+// Stats returns the number of stored URLs and users.
+// It is only available to trusted subnets, see trustedInterceptor.
+func (srv *Server) Stats(ctx context.Context, in *ps.Dummy) (*ps.StatsResponse, error) {
+	res := ps.StatsResponse{}
 
-	//use SingleFlight
-	urls, err, _ := srv.sfgr.Do("CountURLs",
-		func() (interface{}, error) {
-			return srv.shortener.CountURLs()
-		})
+	urls, users, err := srv.counts()
 	if err != nil {
 		res.Error = err.Error()
 		return &res, status.Error(codes.Internal, err.Error())
 	}
 
-	users, err, _ := srv.sfgr.Do("CountUsers",
-		func() (interface{}, error) {
-			return srv.shortener.CountUsers()
-		})
+	res.Users = clampInt32(users)
+	res.Urls = clampInt32(urls)
+
+	return &res, nil
+}
 
+// counts returns numbers of stored URLs and users. Concurrent
+// calls share the results of one count using SingleFlight.
+func (srv *Server) counts() (urls, users int, err error) {
+	if urls, err = srv.count("CountURLs", srv.shortener.CountURLs); err != nil {
+		return 0, 0, err
+	}
+
+	if users, err = srv.count("CountUsers", srv.shortener.CountUsers); err != nil {
+		return 0, 0, err
+	}
+
+	return urls, users, nil
+}
+
+func (srv *Server) count(key string, f func() (int, error)) (int, error) {
+	v, err, _ := srv.sfgr.Do(key, func() (interface{}, error) {
+		return f()
+	})
 	if err != nil {
-		res.Error = err.Error()
-		return &res, status.Error(codes.Internal, err.Error())
+		return 0, err
 	}
 
-	res.Users = users.(int32)
-	res.Urls = urls.(int32)
+	n, ok := v.(int)
+	if !ok {
+		return 0, fmt.Errorf("unexpected %v result type %T", key, v)
+	}
 
-	return &res, nil
+	return n, nil
+}
+
+// clampInt32 limits n to the range of int32 fields of v1 messages.
+func clampInt32(n int) int32 {
+	if n > math.MaxInt32 {
+		return math.MaxInt32
+	}
+
+	return int32(n)
 }
 
 func (srv *Server) PingStorage(ctx context.Context, in *ps.Dummy) (*ps.PingStorageResponse, error) {
@@ -1,23 +1,42 @@
 package grpcserver
 
 import (
+	"bytes"
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/base64"
+	"encoding/pem"
 	"errors"
+	"image/png"
+	"io"
 	"log"
+	"math/big"
+	"net"
 	"os"
+	"path/filepath"
+	"strconv"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 
 	conf "github.com/usa4ev/urlshortner/internal/config"
 	ps "github.com/usa4ev/urlshortner/internal/server/grpcserver/protoshortener"
+	psv2 "github.com/usa4ev/urlshortner/internal/server/grpcserver/protoshortener/v2"
 	"github.com/usa4ev/urlshortner/internal/shortener"
 	"github.com/usa4ev/urlshortner/internal/storage"
 )
@@ -63,7 +82,10 @@ func newTestSrv(cfg *conf.Config) (*Server, error) {
 		return nil, err
 	}
 
-	s := shortener.NewShortener(cfg, strg)
+	s, err := shortener.NewShortener(cfg, strg)
+	if err != nil {
+		return nil, err
+	}
 
 	ts := New(cfg, s, strg)
 
@@ -72,7 +94,9 @@ func newTestSrv(cfg *conf.Config) (*Server, error) {
 
 	go func() {
 		wg.Done()
-		log.Fatal(ts.listenAndServe())
+		if err := ts.listenAndServe(); err != nil {
+			log.Fatal(err)
+		}
 	}()
 
 	wg.Wait()
@@ -81,7 +105,7 @@ func newTestSrv(cfg *conf.Config) (*Server, error) {
 }
 
 func TestServer_Shorten(t *testing.T) {
-	cfg := testcfg()
+	cfg := testcfg(t)
 
 	cases := getTests(cfg.BaseURL())
 	resetStorage(cfg.StoragePath(), cfg.DBDSN())
@@ -100,6 +124,7 @@ func TestServer_Shorten(t *testing.T) {
 			require.NoError(t, err, "url: %v", tt.url)
 
 			assert.Equal(t, tt.id, out.Id)
+			assert.Equal(t, tt.want, out.ShortUrl)
 		})
 	}
 
@@ -114,6 +139,382 @@ func TestServer_Shorten(t *testing.T) {
 			}
 		})
 	}
+
+	t.Run("shorten invalid URL", func(t *testing.T) {
+		in := &ps.ShortenRequest{Url: "javascript:alert(1)"}
+		_, err := cl.Shorten(ctx, in)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
+
+func TestServer_SessionToken(t *testing.T) {
+	cfg := testcfg(t)
+
+	cases := getTests(cfg.BaseURL())
+	resetStorage(cfg.StoragePath(), cfg.DBDSN())
+	ts, err := newTestSrv(cfg)
+	require.NoError(t, err)
+	defer ts.Shutdown(context.Background())
+
+	cl := newTestClient(cfg)
+
+	ctx := context.Background()
+
+	var header metadata.MD
+	_, err = cl.Shorten(ctx, &ps.ShortenRequest{Url: cases[0].url}, grpc.Header(&header))
+	require.NoError(t, err)
+
+	tokens := header.Get("authorization")
+	require.Len(t, tokens, 1, "new session token is not returned")
+
+	sessionCtx := metadata.AppendToOutgoingContext(ctx, "authorization", tokens[0])
+
+	header = nil
+	_, err = cl.Shorten(sessionCtx, &ps.ShortenRequest{Url: cases[1].url}, grpc.Header(&header))
+	require.NoError(t, err)
+	assert.Empty(t, header.Get("authorization"), "token is returned for an existing session")
+
+	out, err := cl.GetLongByUser(sessionCtx, &ps.Dummy{})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{cases[0].want, cases[1].want}, out.Urls)
+	require.Len(t, out.Links, 2)
+	for _, v := range out.Links {
+		assert.Contains(t, []string{cases[0].url, cases[1].url}, v.OriginalUrl)
+	}
+}
+
+func TestServer_ShortenBatch(t *testing.T) {
+	cfg := testcfg(t)
+
+	cases := getTests(cfg.BaseURL())
+	resetStorage(cfg.StoragePath(), cfg.DBDSN())
+	ts, err := newTestSrv(cfg)
+	require.NoError(t, err)
+	defer ts.Shutdown(context.Background())
+
+	cl := newTestClient(cfg)
+
+	ctx := context.Background()
+
+	_, err = cl.Shorten(ctx, &ps.ShortenRequest{Url: cases[0].url})
+	require.NoError(t, err)
+
+	t.Run("all-or-nothing", func(t *testing.T) {
+		in := &ps.ShortenBatchRequest{Data: []*ps.URLwId{
+			{Id: "1", Url: cases[1].url},
+			{Id: "2", Url: cases[0].url},
+		}}
+		_, err := cl.ShortenBatch(ctx, in)
+		assert.Equal(t, codes.AlreadyExists, status.Code(err))
+
+		_, err = cl.GetLong(ctx, &ps.GetLongRequest{Id: cases[1].id})
+		assert.Error(t, err, "batch is partially stored")
+
+		in = &ps.ShortenBatchRequest{Data: []*ps.URLwId{{Id: "1", Url: cases[1].url}}}
+		out, err := cl.ShortenBatch(ctx, in)
+		require.NoError(t, err)
+		require.Len(t, out.Data, 1)
+		assert.Equal(t, &ps.URLwId{Id: "1", Url: cases[1].want}, &ps.URLwId{Id: out.Data[0].Id, Url: out.Data[0].Url})
+	})
+
+	t.Run("partial", func(t *testing.T) {
+		in := &ps.ShortenBatchRequest{Partial: true, Data: []*ps.URLwId{
+			{Id: "1", Url: cases[2].url},
+			{Id: "2", Url: cases[0].url},
+			{Id: "3", Url: "javascript:alert(1)"},
+		}}
+		out, err := cl.ShortenBatch(ctx, in)
+		require.NoError(t, err)
+		require.Len(t, out.Results, 3)
+
+		assert.Equal(t, ps.ItemStatus_CREATED, out.Results[0].Status)
+		assert.Equal(t, cases[2].want, out.Results[0].ShortUrl)
+		assert.Equal(t, ps.ItemStatus_CONFLICT, out.Results[1].Status)
+		assert.Equal(t, cases[0].want, out.Results[1].ShortUrl)
+		assert.Equal(t, ps.ItemStatus_INVALID, out.Results[2].Status)
+		assert.NotEmpty(t, out.Results[2].Error)
+	})
+}
+
+func TestServer_Streams(t *testing.T) {
+	cfg := testcfg(t)
+
+	cases := getTests(cfg.BaseURL())
+	resetStorage(cfg.StoragePath(), cfg.DBDSN())
+	ts, err := newTestSrv(cfg)
+	require.NoError(t, err)
+	defer ts.Shutdown(context.Background())
+
+	cl := newTestClient(cfg)
+
+	ctx := context.Background()
+
+	stream, err := cl.ShortenStream(ctx)
+	require.NoError(t, err)
+
+	header, err := stream.Header()
+	require.NoError(t, err)
+
+	tokens := header.Get("authorization")
+	require.Len(t, tokens, 1, "new session token is not returned")
+
+	sessionCtx := metadata.AppendToOutgoingContext(ctx, "authorization", tokens[0])
+
+	in := []*ps.URLwId{
+		{Id: "1", Url: cases[0].url},
+		{Id: "2", Url: cases[1].url},
+		{Id: "3", Url: cases[0].url},
+		{Id: "4", Url: "javascript:alert(1)"},
+	}
+	want := []*ps.BatchItemResult{
+		{Id: "1", ShortUrl: cases[0].want, Status: ps.ItemStatus_CREATED},
+		{Id: "2", ShortUrl: cases[1].want, Status: ps.ItemStatus_CREATED},
+		{Id: "3", ShortUrl: cases[0].want, Status: ps.ItemStatus_CONFLICT},
+		{Id: "4", Status: ps.ItemStatus_INVALID},
+	}
+
+	for i, v := range in {
+		require.NoError(t, stream.Send(v))
+
+		out, err := stream.Recv()
+		require.NoError(t, err)
+
+		assert.Equal(t, want[i].Id, out.Id)
+		assert.Equal(t, want[i].ShortUrl, out.ShortUrl)
+		assert.Equal(t, want[i].Status, out.Status)
+		assert.Equal(t, want[i].Status != ps.ItemStatus_CREATED, out.Error != "", "error: %v", out.Error)
+	}
+
+	require.NoError(t, stream.CloseSend())
+
+	_, err = stream.Recv()
+	assert.ErrorIs(t, err, io.EOF)
+
+	t.Run("list", func(t *testing.T) {
+		list, err := cl.ListMyURLs(sessionCtx, &ps.Dummy{})
+		require.NoError(t, err)
+
+		var got []string
+		for {
+			link, err := list.Recv()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+
+			require.NoError(t, err)
+			got = append(got, link.ShortUrl)
+		}
+
+		assert.ElementsMatch(t, []string{cases[0].want, cases[1].want}, got)
+	})
+
+	t.Run("pipelined", func(t *testing.T) {
+		stream, err := cl.ShortenStream(sessionCtx)
+		require.NoError(t, err)
+
+		// items sent without waiting for results are stored in batches,
+		// the last 50 repeat the first ones
+		const n = 250
+
+		for i := 0; i < n; i++ {
+			url := "http://ya.ru/pipelined/" + strconv.Itoa(i%200)
+			require.NoError(t, stream.Send(&ps.URLwId{Id: strconv.Itoa(i), Url: url}))
+		}
+
+		require.NoError(t, stream.CloseSend())
+
+		for i := 0; i < n; i++ {
+			out, err := stream.Recv()
+			require.NoError(t, err)
+			require.Equal(t, strconv.Itoa(i), out.Id, "results must keep the order of items")
+
+			if i < 200 {
+				assert.Equal(t, ps.ItemStatus_CREATED, out.Status, "item %v", i)
+			} else {
+				assert.Equal(t, ps.ItemStatus_CONFLICT, out.Status, "item %v", i)
+			}
+
+			assert.NotEmpty(t, out.ShortUrl, "item %v", i)
+		}
+
+		_, err = stream.Recv()
+		assert.ErrorIs(t, err, io.EOF)
+	})
+
+	t.Run("invalid session", func(t *testing.T) {
+		wrongCtx := metadata.AppendToOutgoingContext(ctx, "authorization", "wrong")
+		list, err := cl.ListMyURLs(wrongCtx, &ps.Dummy{})
+		require.NoError(t, err)
+
+		_, err = list.Recv()
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+}
+
+func TestServerV2(t *testing.T) {
+	cfg := testcfg(t)
+
+	cases := getTests(cfg.BaseURL())
+	resetStorage(cfg.StoragePath(), cfg.DBDSN())
+	ts, err := newTestSrv(cfg)
+	require.NoError(t, err)
+	defer ts.Shutdown(context.Background())
+
+	conn, err := grpc.Dial(cfg.SrvAddr(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	cl := psv2.NewShortenerClient(conn)
+
+	ctx := context.Background()
+
+	var header metadata.MD
+	link, err := cl.Shorten(ctx, &psv2.ShortenRequest{Url: cases[0].url}, grpc.Header(&header))
+	require.NoError(t, err)
+	require.Len(t, header.Get("authorization"), 1, "new session token is not returned")
+
+	sessionCtx := metadata.AppendToOutgoingContext(ctx, "authorization", header.Get("authorization")[0])
+
+	t.Run("shorten", func(t *testing.T) {
+		assert.Equal(t, cases[0].id, link.Id)
+		assert.Equal(t, cases[0].want, link.ShortUrl)
+		assert.Equal(t, cases[0].url, link.OriginalUrl)
+		assert.WithinDuration(t, time.Now(), link.CreatedAt.AsTime(), time.Minute)
+
+		_, err := cl.Shorten(sessionCtx, &psv2.ShortenRequest{Url: cases[0].url})
+		info := errorDetail[*errdetails.ErrorInfo](t, err, codes.AlreadyExists)
+		assert.Equal(t, "URL_CONFLICT", info.Reason)
+		assert.Equal(t, cases[0].want, info.Metadata["short_url"])
+
+		_, err = cl.Shorten(sessionCtx, &psv2.ShortenRequest{Url: "javascript:alert(1)"})
+		badRequest := errorDetail[*errdetails.BadRequest](t, err, codes.InvalidArgument)
+		require.Len(t, badRequest.FieldViolations, 1)
+		assert.Equal(t, "url", badRequest.FieldViolations[0].Field)
+	})
+
+	t.Run("batch", func(t *testing.T) {
+		in := &psv2.ShortenBatchRequest{Items: []*psv2.BatchItem{
+			{CorrelationId: "1", Url: cases[1].url},
+			{CorrelationId: "2", Url: "javascript:alert(1)"},
+		}}
+		_, err := cl.ShortenBatch(sessionCtx, in)
+		badRequest := errorDetail[*errdetails.BadRequest](t, err, codes.InvalidArgument)
+		require.Len(t, badRequest.FieldViolations, 1)
+		assert.Equal(t, "items[1].url", badRequest.FieldViolations[0].Field)
+
+		in.Partial = true
+		out, err := cl.ShortenBatch(sessionCtx, in)
+		require.NoError(t, err)
+		require.Len(t, out.Results, 2)
+		assert.Equal(t, psv2.ItemStatus_ITEM_STATUS_CREATED, out.Results[0].Status)
+		assert.Equal(t, cases[1].want, out.Results[0].Link.ShortUrl)
+		assert.Equal(t, psv2.ItemStatus_ITEM_STATUS_INVALID, out.Results[1].Status)
+		assert.Nil(t, out.Results[1].Link)
+	})
+
+	t.Run("list", func(t *testing.T) {
+		out, err := cl.ListLinks(sessionCtx, &psv2.ListLinksRequest{})
+		require.NoError(t, err)
+
+		got := make(map[string]string)
+		for _, v := range out.Links {
+			got[v.ShortUrl] = v.OriginalUrl
+		}
+
+		assert.Equal(t, map[string]string{cases[0].want: cases[0].url, cases[1].want: cases[1].url}, got)
+	})
+
+	t.Run("get and update", func(t *testing.T) {
+		out, err := cl.GetLink(ctx, &psv2.GetLinkRequest{Id: cases[1].id})
+		require.NoError(t, err)
+		assert.Equal(t, cases[1].url, out.OriginalUrl)
+
+		_, err = cl.GetLink(ctx, &psv2.GetLinkRequest{Id: "nonexistent"})
+		resource := errorDetail[*errdetails.ResourceInfo](t, err, codes.NotFound)
+		assert.Equal(t, "nonexistent", resource.ResourceName)
+
+		out, err = cl.UpdateLink(sessionCtx, &psv2.UpdateLinkRequest{Id: cases[1].id, Url: cases[2].url})
+		require.NoError(t, err)
+		assert.Equal(t, cases[2].url, out.OriginalUrl)
+		assert.Equal(t, cases[1].want, out.ShortUrl)
+
+		_, err = cl.UpdateLink(ctx, &psv2.UpdateLinkRequest{Id: cases[1].id, Url: cases[1].url})
+		info := errorDetail[*errdetails.ErrorInfo](t, err, codes.PermissionDenied)
+		assert.Equal(t, "NOT_OWNER", info.Reason)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		_, err := cl.DeleteLinks(sessionCtx, &psv2.DeleteLinksRequest{Ids: []string{cases[0].id}})
+		require.NoError(t, err)
+
+		_, err = cl.GetLink(ctx, &psv2.GetLinkRequest{Id: cases[0].id})
+		info := errorDetail[*errdetails.ErrorInfo](t, err, codes.NotFound)
+		assert.Equal(t, "LINK_DELETED", info.Reason)
+	})
+
+	t.Run("qr code", func(t *testing.T) {
+		out, err := cl.GetQRCode(ctx, &psv2.GetQRCodeRequest{Id: cases[1].id})
+		require.NoError(t, err)
+		assert.Equal(t, "image/png", out.ContentType)
+
+		img, err := png.Decode(bytes.NewReader(out.Data))
+		require.NoError(t, err)
+		assert.Equal(t, 256, img.Bounds().Dx())
+
+		margin := int32(0)
+		out, err = cl.GetQRCode(ctx, &psv2.GetQRCodeRequest{
+			Id:     cases[1].id,
+			Format: psv2.QRFormat_QR_FORMAT_SVG,
+			Size:   512,
+			Margin: &margin,
+			Level:  psv2.QRLevel_QR_LEVEL_H,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "image/svg+xml", out.ContentType)
+		assert.True(t, bytes.HasPrefix(out.Data, []byte("<svg")))
+
+		_, err = cl.GetQRCode(ctx, &psv2.GetQRCodeRequest{Id: cases[1].id, Size: 10})
+		badRequest := errorDetail[*errdetails.BadRequest](t, err, codes.InvalidArgument)
+		require.Len(t, badRequest.FieldViolations, 1)
+		assert.Equal(t, "size", badRequest.FieldViolations[0].Field)
+
+		_, err = cl.GetQRCode(ctx, &psv2.GetQRCodeRequest{Id: cases[0].id})
+		info := errorDetail[*errdetails.ErrorInfo](t, err, codes.NotFound)
+		assert.Equal(t, "LINK_DELETED", info.Reason)
+
+		_, err = cl.GetQRCode(ctx, &psv2.GetQRCodeRequest{Id: "nonexistent"})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("stats", func(t *testing.T) {
+		trustedCtx := metadata.AppendToOutgoingContext(ctx, "x-real-ip", "192.168.0.1")
+		out, err := cl.GetStats(trustedCtx, &psv2.GetStatsRequest{})
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), out.Urls)
+
+		_, err = cl.GetStats(ctx, &psv2.GetStatsRequest{})
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+}
+
+// errorDetail returns the detail of type T of a status error with code.
+func errorDetail[T any](t *testing.T, err error, code codes.Code) T {
+	t.Helper()
+
+	st, ok := status.FromError(err)
+	require.True(t, ok, "not a status error: %v", err)
+	require.Equal(t, code, st.Code(), st.Message())
+
+	for _, detail := range st.Details() {
+		if v, ok := detail.(T); ok {
+			return v
+		}
+	}
+
+	var zero T
+	require.Fail(t, "detail is not found", "%T in %v", zero, st.Details())
+
+	return zero
 }
 
 func resetStorage(path, dsn string) error {
@@ -131,11 +532,274 @@ func resetStorage(path, dsn string) error {
 	return nil
 }
 
-func testcfg() *conf.Config {
-	return conf.New(conf.WithEnvVars(map[string]string{
+func testcfg(t *testing.T) *conf.Config {
+	cfg, err := conf.New(conf.WithEnvVars(map[string]string{
 		"BASE_URL":          "http://localhost:8080",
 		"SERVER_ADDRESS":    "localhost:8080",
 		"FILE_STORAGE_PATH": os.Getenv("HOME") + "/storage.csv",
+		"ADMIN_TOKEN":       "secret",
+		"TRUSTED_SUBNET":    "192.168.0.0/24",
+		"TRUSTED_PROXIES":   "127.0.0.1,::1",
+	}),
+		conf.IgnoreOsArgs())
+	require.NoError(t, err)
+
+	return cfg
+}
+
+func TestServer_Admin(t *testing.T) {
+	cfg := testcfg(t)
+
+	cases := getTests(cfg.BaseURL())
+	resetStorage(cfg.StoragePath(), cfg.DBDSN())
+	ts, err := newTestSrv(cfg)
+	require.NoError(t, err)
+	defer ts.Shutdown(context.Background())
+
+	conn, err := grpc.Dial(cfg.SrvAddr(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	cl := ps.NewShortenerClient(conn)
+	admin := ps.NewShortenerAdminClient(conn)
+
+	ctx := context.Background()
+	adminCtx := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer secret")
+
+	for _, tt := range cases {
+		_, err := cl.Shorten(ctx, &ps.ShortenRequest{Url: tt.url})
+		require.NoError(t, err, "url: %v", tt.url)
+	}
+
+	t.Run("wrong token", func(t *testing.T) {
+		wrongCtx := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer wrong")
+		_, err := admin.GetLink(wrongCtx, &ps.AdminGetLinkRequest{Id: cases[0].id})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("get link", func(t *testing.T) {
+		out, err := admin.GetLink(adminCtx, &ps.AdminGetLinkRequest{Url: cases[1].url})
+		require.NoError(t, err)
+		assert.Equal(t, cases[1].id, out.Link.Id)
+		assert.Equal(t, cases[1].want, out.Link.ShortUrl)
+
+		_, err = admin.GetLink(adminCtx, &ps.AdminGetLinkRequest{Id: "nonexistent"})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("disable", func(t *testing.T) {
+		_, err := admin.SetDisabled(adminCtx, &ps.AdminSetDisabledRequest{Id: cases[0].id, Disabled: true})
+		require.NoError(t, err)
+
+		_, err = cl.GetLong(ctx, &ps.GetLongRequest{Id: cases[0].id})
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+}
+
+func TestServer_Stats(t *testing.T) {
+	cfg := testcfg(t)
+
+	cases := getTests(cfg.BaseURL())
+	resetStorage(cfg.StoragePath(), cfg.DBDSN())
+	ts, err := newTestSrv(cfg)
+	require.NoError(t, err)
+	defer ts.Shutdown(context.Background())
+
+	cl := newTestClient(cfg)
+
+	ctx := context.Background()
+
+	for _, tt := range cases {
+		_, err := cl.Shorten(ctx, &ps.ShortenRequest{Url: tt.url})
+		require.NoError(t, err, "url: %v", tt.url)
+	}
+
+	t.Run("trusted call", func(t *testing.T) {
+		trustedCtx := metadata.AppendToOutgoingContext(ctx, "x-real-ip", "192.168.0.1")
+		out, err := cl.Stats(trustedCtx, &ps.Dummy{})
+		require.NoError(t, err)
+
+		assert.Equal(t, int32(len(cases)), out.Urls)
+		assert.Equal(t, int32(len(cases)), out.Users)
+	})
+
+	t.Run("untrusted call", func(t *testing.T) {
+		_, err := cl.Stats(ctx, &ps.Dummy{})
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+		spoofedCtx := metadata.AppendToOutgoingContext(ctx, "x-forwarded-for", "192.168.0.1, 100.168.0.1")
+		_, err = cl.Stats(spoofedCtx, &ps.Dummy{})
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+}
+
+func TestServer_MutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca, caKey := writeTestCert(t, dir, "ca", nil, nil)
+	writeTestCert(t, dir, "example", ca, caKey)
+	writeTestCert(t, dir, "stats-exporter", ca, caKey)
+	writeTestCert(t, dir, "ops", ca, caKey)
+
+	cfg, err := conf.New(conf.WithEnvVars(map[string]string{
+		"BASE_URL":           "http://localhost:8080",
+		"SERVER_ADDRESS":     "localhost:8080",
+		"ENABLE_HTTPS":       "true",
+		"SSL_PATH":           dir,
+		"CLIENT_CA_PATH":     filepath.Join(dir, "ca.crt"),
+		"SERVICE_IDENTITIES": "stats-exporter=internal,ops=admin",
 	}),
 		conf.IgnoreOsArgs())
+	require.NoError(t, err)
+
+	strg, err := storage.New(cfg)
+	require.NoError(t, err)
+
+	s, err := shortener.NewShortener(cfg, strg)
+	require.NoError(t, err)
+
+	ts := New(cfg, s, strg)
+	defer ts.Shutdown(context.Background())
+
+	go func() {
+		if err := ts.Run(); err != nil {
+			log.Fatal(err)
+		}
+	}()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+
+	dial := func(name string) *grpc.ClientConn {
+		tlsCfg := &tls.Config{RootCAs: roots}
+		if name != "" {
+			crt, err := tls.LoadX509KeyPair(filepath.Join(dir, name+".crt"), filepath.Join(dir, name+".key"))
+			require.NoError(t, err)
+
+			tlsCfg.Certificates = []tls.Certificate{crt}
+		}
+
+		conn, err := grpc.Dial(cfg.SrvAddr(),
+			grpc.WithTransportCredentials(credentials.NewTLS(tlsCfg)),
+			grpc.WithDefaultCallOptions(grpc.WaitForReady(true)))
+		require.NoError(t, err)
+		t.Cleanup(func() { conn.Close() })
+
+		return conn
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	t.Run("no certificate", func(t *testing.T) {
+		conn := dial("")
+
+		_, err := ps.NewShortenerClient(conn).Shorten(ctx, &ps.ShortenRequest{Url: "http://ya.ru/test"})
+		require.NoError(t, err, "clients without certificate must use sessions")
+
+		_, err = ps.NewShortenerClient(conn).Stats(ctx, &ps.Dummy{})
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+
+	t.Run("internal service", func(t *testing.T) {
+		conn := dial("stats-exporter")
+
+		out, err := ps.NewShortenerClient(conn).Stats(ctx, &ps.Dummy{})
+		require.NoError(t, err)
+		assert.Equal(t, int32(1), out.Urls)
+
+		_, err = ps.NewShortenerAdminClient(conn).GetLink(ctx, &ps.AdminGetLinkRequest{Id: "nonexistent"})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("admin service", func(t *testing.T) {
+		conn := dial("ops")
+
+		_, err := ps.NewShortenerAdminClient(conn).GetLink(ctx, &ps.AdminGetLinkRequest{Id: "nonexistent"})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+}
+
+// writeTestCert writes name.crt and name.key to dir with a certificate
+// signed by parent or a self-signed CA certificate if parent is nil.
+func writeTestCert(t *testing.T, dir, name string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+		DNSNames:     []string{"localhost"},
+	}
+
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.KeyUsage |= x509.KeyUsageCertSign
+		parent, parentKey = tmpl, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name+".crt"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name+".key"), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return cert, key
+}
+
+func TestServer_StreamRateLimit(t *testing.T) {
+	cfg, err := conf.New(conf.WithEnvVars(map[string]string{
+		"BASE_URL":       "http://localhost:8080",
+		"SERVER_ADDRESS": "localhost:8080",
+		"RATE_LIMIT":     "0.01",
+		"RATE_BURST":     "3",
+	}),
+		conf.IgnoreOsArgs())
+	require.NoError(t, err)
+
+	ts, err := newTestSrv(cfg)
+	require.NoError(t, err)
+	defer ts.Shutdown(context.Background())
+
+	cl := newTestClient(cfg)
+
+	stream, err := cl.ShortenStream(context.Background())
+	require.NoError(t, err)
+
+	// opening the stream takes the first request of the burst
+	for i, url := range []string{"http://ya.ru/1", "http://ya.ru/2", "http://ya.ru/3"} {
+		require.NoError(t, stream.Send(&ps.URLwId{Id: strconv.Itoa(i), Url: url}))
+	}
+
+	require.NoError(t, stream.CloseSend())
+
+	for i := 0; i < 2; i++ {
+		out, err := stream.Recv()
+		require.NoError(t, err)
+		assert.Equal(t, ps.ItemStatus_CREATED, out.Status)
+	}
+
+	_, err = stream.Recv()
+	require.Equal(t, codes.ResourceExhausted, status.Code(err), "error: %v", err)
+
+	var info *errdetails.RetryInfo
+	for _, d := range status.Convert(err).Details() {
+		if v, ok := d.(*errdetails.RetryInfo); ok {
+			info = v
+		}
+	}
+
+	require.NotNil(t, info, "no RetryInfo in %v", err)
+	assert.Positive(t, info.RetryDelay.AsDuration())
 }
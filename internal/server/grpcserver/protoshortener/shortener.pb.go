@@ -1,7 +1,7 @@
 // Code generated by protoc-gen-go. DO NOT EDIT.
 // versions:
 // 	protoc-gen-go v1.28.1
-// 	protoc        v3.12.4
+// 	protoc        (unknown)
 // source: internal/server/grpcserver/protoshortener/shortener.proto
 
 package protoshortener
@@ -20,6 +20,58 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+type ItemStatus int32
+
+const (
+	ItemStatus_CREATED        ItemStatus = 0
+	ItemStatus_CONFLICT       ItemStatus = 1
+	ItemStatus_INVALID        ItemStatus = 2
+	ItemStatus_QUOTA_EXCEEDED ItemStatus = 3
+)
+
+// Enum value maps for ItemStatus.
+var (
+	ItemStatus_name = map[int32]string{
+		0: "CREATED",
+		1: "CONFLICT",
+		2: "INVALID",
+		3: "QUOTA_EXCEEDED",
+	}
+	ItemStatus_value = map[string]int32{
+		"CREATED":        0,
+		"CONFLICT":       1,
+		"INVALID":        2,
+		"QUOTA_EXCEEDED": 3,
+	}
+)
+
+func (x ItemStatus) Enum() *ItemStatus {
+	p := new(ItemStatus)
+	*p = x
+	return p
+}
+
+func (x ItemStatus) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ItemStatus) Descriptor() protoreflect.EnumDescriptor {
+	return file_internal_server_grpcserver_protoshortener_shortener_proto_enumTypes[0].Descriptor()
+}
+
+func (ItemStatus) Type() protoreflect.EnumType {
+	return &file_internal_server_grpcserver_protoshortener_shortener_proto_enumTypes[0]
+}
+
+func (x ItemStatus) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ItemStatus.Descriptor instead.
+func (ItemStatus) EnumDescriptor() ([]byte, []int) {
+	return file_internal_server_grpcserver_protoshortener_shortener_proto_rawDescGZIP(), []int{0}
+}
+
 type ShortenRequest struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -31,7 +83,7 @@ type ShortenRequest struct {
 func (x *ShortenRequest) Reset() {
 	*x = ShortenRequest{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_internal_server_gRPCServer_protoshortener_shortener_proto_msgTypes[0]
+		mi := &file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes[0]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -44,7 +96,7 @@ func (x *ShortenRequest) String() string {
 func (*ShortenRequest) ProtoMessage() {}
 
 func (x *ShortenRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_internal_server_gRPCServer_protoshortener_shortener_proto_msgTypes[0]
+	mi := &file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes[0]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -57,7 +109,7 @@ func (x *ShortenRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ShortenRequest.ProtoReflect.Descriptor instead.
 func (*ShortenRequest) Descriptor() ([]byte, []int) {
-	return file_internal_server_gRPCServer_protoshortener_shortener_proto_rawDescGZIP(), []int{0}
+	return file_internal_server_grpcserver_protoshortener_shortener_proto_rawDescGZIP(), []int{0}
 }
 
 func (x *ShortenRequest) GetUrl() string {
@@ -72,14 +124,15 @@ type ShortenResponse struct {
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Id    string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
-	Error string `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	Id       string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Error    string `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	ShortUrl string `protobuf:"bytes,3,opt,name=short_url,json=shortUrl,proto3" json:"short_url,omitempty"`
 }
 
 func (x *ShortenResponse) Reset() {
 	*x = ShortenResponse{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_internal_server_gRPCServer_protoshortener_shortener_proto_msgTypes[1]
+		mi := &file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes[1]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -92,7 +145,7 @@ func (x *ShortenResponse) String() string {
 func (*ShortenResponse) ProtoMessage() {}
 
 func (x *ShortenResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_internal_server_gRPCServer_protoshortener_shortener_proto_msgTypes[1]
+	mi := &file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes[1]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -105,7 +158,7 @@ func (x *ShortenResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ShortenResponse.ProtoReflect.Descriptor instead.
 func (*ShortenResponse) Descriptor() ([]byte, []int) {
-	return file_internal_server_gRPCServer_protoshortener_shortener_proto_rawDescGZIP(), []int{1}
+	return file_internal_server_grpcserver_protoshortener_shortener_proto_rawDescGZIP(), []int{1}
 }
 
 func (x *ShortenResponse) GetId() string {
@@ -122,18 +175,28 @@ func (x *ShortenResponse) GetError() string {
 	return ""
 }
 
+func (x *ShortenResponse) GetShortUrl() string {
+	if x != nil {
+		return x.ShortUrl
+	}
+	return ""
+}
+
+// the batch is stored all-or-nothing unless partial is set,
+// then valid items are stored and every item gets a result
 type ShortenBatchRequest struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Data []*URLwId `protobuf:"bytes,1,rep,name=data,proto3" json:"data,omitempty"`
+	Data    []*URLwId `protobuf:"bytes,1,rep,name=data,proto3" json:"data,omitempty"`
+	Partial bool      `protobuf:"varint,2,opt,name=partial,proto3" json:"partial,omitempty"`
 }
 
 func (x *ShortenBatchRequest) Reset() {
 	*x = ShortenBatchRequest{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_internal_server_gRPCServer_protoshortener_shortener_proto_msgTypes[2]
+		mi := &file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes[2]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -146,7 +209,7 @@ func (x *ShortenBatchRequest) String() string {
 func (*ShortenBatchRequest) ProtoMessage() {}
 
 func (x *ShortenBatchRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_internal_server_gRPCServer_protoshortener_shortener_proto_msgTypes[2]
+	mi := &file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes[2]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -159,7 +222,7 @@ func (x *ShortenBatchRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ShortenBatchRequest.ProtoReflect.Descriptor instead.
 func (*ShortenBatchRequest) Descriptor() ([]byte, []int) {
-	return file_internal_server_gRPCServer_protoshortener_shortener_proto_rawDescGZIP(), []int{2}
+	return file_internal_server_grpcserver_protoshortener_shortener_proto_rawDescGZIP(), []int{2}
 }
 
 func (x *ShortenBatchRequest) GetData() []*URLwId {
@@ -169,19 +232,27 @@ func (x *ShortenBatchRequest) GetData() []*URLwId {
 	return nil
 }
 
+func (x *ShortenBatchRequest) GetPartial() bool {
+	if x != nil {
+		return x.Partial
+	}
+	return false
+}
+
 type ShortenBatchResponse struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Data  []*URLwId `protobuf:"bytes,1,rep,name=data,proto3" json:"data,omitempty"`
-	Error string    `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	Data    []*URLwId          `protobuf:"bytes,1,rep,name=data,proto3" json:"data,omitempty"`
+	Error   string             `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	Results []*BatchItemResult `protobuf:"bytes,3,rep,name=results,proto3" json:"results,omitempty"`
 }
 
 func (x *ShortenBatchResponse) Reset() {
 	*x = ShortenBatchResponse{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_internal_server_gRPCServer_protoshortener_shortener_proto_msgTypes[3]
+		mi := &file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes[3]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -194,7 +265,7 @@ func (x *ShortenBatchResponse) String() string {
 func (*ShortenBatchResponse) ProtoMessage() {}
 
 func (x *ShortenBatchResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_internal_server_gRPCServer_protoshortener_shortener_proto_msgTypes[3]
+	mi := &file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes[3]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -207,7 +278,7 @@ func (x *ShortenBatchResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ShortenBatchResponse.ProtoReflect.Descriptor instead.
 func (*ShortenBatchResponse) Descriptor() ([]byte, []int) {
-	return file_internal_server_gRPCServer_protoshortener_shortener_proto_rawDescGZIP(), []int{3}
+	return file_internal_server_grpcserver_protoshortener_shortener_proto_rawDescGZIP(), []int{3}
 }
 
 func (x *ShortenBatchResponse) GetData() []*URLwId {
@@ -224,6 +295,85 @@ func (x *ShortenBatchResponse) GetError() string {
 	return ""
 }
 
+func (x *ShortenBatchResponse) GetResults() []*BatchItemResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+// short_url of a conflicting item is the one the URL is stored with
+type BatchItemResult struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id       string     `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	ShortUrl string     `protobuf:"bytes,2,opt,name=short_url,json=shortUrl,proto3" json:"short_url,omitempty"`
+	Status   ItemStatus `protobuf:"varint,3,opt,name=status,proto3,enum=grpcserver.ItemStatus" json:"status,omitempty"`
+	Error    string     `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
+}
+
+func (x *BatchItemResult) Reset() {
+	*x = BatchItemResult{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BatchItemResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchItemResult) ProtoMessage() {}
+
+func (x *BatchItemResult) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchItemResult.ProtoReflect.Descriptor instead.
+func (*BatchItemResult) Descriptor() ([]byte, []int) {
+	return file_internal_server_grpcserver_protoshortener_shortener_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *BatchItemResult) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *BatchItemResult) GetShortUrl() string {
+	if x != nil {
+		return x.ShortUrl
+	}
+	return ""
+}
+
+func (x *BatchItemResult) GetStatus() ItemStatus {
+	if x != nil {
+		return x.Status
+	}
+	return ItemStatus_CREATED
+}
+
+func (x *BatchItemResult) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
 type URLwId struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -236,7 +386,7 @@ type URLwId struct {
 func (x *URLwId) Reset() {
 	*x = URLwId{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_internal_server_gRPCServer_protoshortener_shortener_proto_msgTypes[4]
+		mi := &file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes[5]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -249,7 +399,7 @@ func (x *URLwId) String() string {
 func (*URLwId) ProtoMessage() {}
 
 func (x *URLwId) ProtoReflect() protoreflect.Message {
-	mi := &file_internal_server_gRPCServer_protoshortener_shortener_proto_msgTypes[4]
+	mi := &file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes[5]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -262,7 +412,7 @@ func (x *URLwId) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use URLwId.ProtoReflect.Descriptor instead.
 func (*URLwId) Descriptor() ([]byte, []int) {
-	return file_internal_server_gRPCServer_protoshortener_shortener_proto_rawDescGZIP(), []int{4}
+	return file_internal_server_grpcserver_protoshortener_shortener_proto_rawDescGZIP(), []int{5}
 }
 
 func (x *URLwId) GetUrl() string {
@@ -290,7 +440,7 @@ type GetLongRequest struct {
 func (x *GetLongRequest) Reset() {
 	*x = GetLongRequest{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_internal_server_gRPCServer_protoshortener_shortener_proto_msgTypes[5]
+		mi := &file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes[6]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -303,7 +453,7 @@ func (x *GetLongRequest) String() string {
 func (*GetLongRequest) ProtoMessage() {}
 
 func (x *GetLongRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_internal_server_gRPCServer_protoshortener_shortener_proto_msgTypes[5]
+	mi := &file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes[6]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -316,7 +466,7 @@ func (x *GetLongRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetLongRequest.ProtoReflect.Descriptor instead.
 func (*GetLongRequest) Descriptor() ([]byte, []int) {
-	return file_internal_server_gRPCServer_protoshortener_shortener_proto_rawDescGZIP(), []int{5}
+	return file_internal_server_grpcserver_protoshortener_shortener_proto_rawDescGZIP(), []int{6}
 }
 
 func (x *GetLongRequest) GetId() string {
@@ -338,7 +488,7 @@ type GetLongResponse struct {
 func (x *GetLongResponse) Reset() {
 	*x = GetLongResponse{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_internal_server_gRPCServer_protoshortener_shortener_proto_msgTypes[6]
+		mi := &file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes[7]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -351,7 +501,7 @@ func (x *GetLongResponse) String() string {
 func (*GetLongResponse) ProtoMessage() {}
 
 func (x *GetLongResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_internal_server_gRPCServer_protoshortener_shortener_proto_msgTypes[6]
+	mi := &file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes[7]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -364,7 +514,7 @@ func (x *GetLongResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetLongResponse.ProtoReflect.Descriptor instead.
 func (*GetLongResponse) Descriptor() ([]byte, []int) {
-	return file_internal_server_gRPCServer_protoshortener_shortener_proto_rawDescGZIP(), []int{6}
+	return file_internal_server_grpcserver_protoshortener_shortener_proto_rawDescGZIP(), []int{7}
 }
 
 func (x *GetLongResponse) GetUrl() string {
@@ -381,19 +531,21 @@ func (x *GetLongResponse) GetError() string {
 	return ""
 }
 
+// urls are short urls, links pair them with their originals
 type GetLongByUserResponse struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Urls  []string `protobuf:"bytes,1,rep,name=urls,proto3" json:"urls,omitempty"`
-	Error string   `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	Urls  []string   `protobuf:"bytes,1,rep,name=urls,proto3" json:"urls,omitempty"`
+	Error string     `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	Links []*URLPair `protobuf:"bytes,3,rep,name=links,proto3" json:"links,omitempty"`
 }
 
 func (x *GetLongByUserResponse) Reset() {
 	*x = GetLongByUserResponse{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_internal_server_gRPCServer_protoshortener_shortener_proto_msgTypes[7]
+		mi := &file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes[8]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -406,7 +558,7 @@ func (x *GetLongByUserResponse) String() string {
 func (*GetLongByUserResponse) ProtoMessage() {}
 
 func (x *GetLongByUserResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_internal_server_gRPCServer_protoshortener_shortener_proto_msgTypes[7]
+	mi := &file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes[8]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -419,7 +571,7 @@ func (x *GetLongByUserResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetLongByUserResponse.ProtoReflect.Descriptor instead.
 func (*GetLongByUserResponse) Descriptor() ([]byte, []int) {
-	return file_internal_server_gRPCServer_protoshortener_shortener_proto_rawDescGZIP(), []int{7}
+	return file_internal_server_grpcserver_protoshortener_shortener_proto_rawDescGZIP(), []int{8}
 }
 
 func (x *GetLongByUserResponse) GetUrls() []string {
@@ -436,6 +588,68 @@ func (x *GetLongByUserResponse) GetError() string {
 	return ""
 }
 
+func (x *GetLongByUserResponse) GetLinks() []*URLPair {
+	if x != nil {
+		return x.Links
+	}
+	return nil
+}
+
+type URLPair struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	ShortUrl    string `protobuf:"bytes,1,opt,name=short_url,json=shortUrl,proto3" json:"short_url,omitempty"`
+	OriginalUrl string `protobuf:"bytes,2,opt,name=original_url,json=originalUrl,proto3" json:"original_url,omitempty"`
+}
+
+func (x *URLPair) Reset() {
+	*x = URLPair{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *URLPair) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*URLPair) ProtoMessage() {}
+
+func (x *URLPair) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use URLPair.ProtoReflect.Descriptor instead.
+func (*URLPair) Descriptor() ([]byte, []int) {
+	return file_internal_server_grpcserver_protoshortener_shortener_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *URLPair) GetShortUrl() string {
+	if x != nil {
+		return x.ShortUrl
+	}
+	return ""
+}
+
+func (x *URLPair) GetOriginalUrl() string {
+	if x != nil {
+		return x.OriginalUrl
+	}
+	return ""
+}
+
 type DeleteBatchRequest struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -447,7 +661,7 @@ type DeleteBatchRequest struct {
 func (x *DeleteBatchRequest) Reset() {
 	*x = DeleteBatchRequest{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_internal_server_gRPCServer_protoshortener_shortener_proto_msgTypes[8]
+		mi := &file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes[10]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -460,7 +674,7 @@ func (x *DeleteBatchRequest) String() string {
 func (*DeleteBatchRequest) ProtoMessage() {}
 
 func (x *DeleteBatchRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_internal_server_gRPCServer_protoshortener_shortener_proto_msgTypes[8]
+	mi := &file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes[10]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -473,7 +687,7 @@ func (x *DeleteBatchRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use DeleteBatchRequest.ProtoReflect.Descriptor instead.
 func (*DeleteBatchRequest) Descriptor() ([]byte, []int) {
-	return file_internal_server_gRPCServer_protoshortener_shortener_proto_rawDescGZIP(), []int{8}
+	return file_internal_server_grpcserver_protoshortener_shortener_proto_rawDescGZIP(), []int{10}
 }
 
 func (x *DeleteBatchRequest) GetIds() []string {
@@ -494,7 +708,7 @@ type DeleteBatchResponse struct {
 func (x *DeleteBatchResponse) Reset() {
 	*x = DeleteBatchResponse{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_internal_server_gRPCServer_protoshortener_shortener_proto_msgTypes[9]
+		mi := &file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes[11]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -507,7 +721,7 @@ func (x *DeleteBatchResponse) String() string {
 func (*DeleteBatchResponse) ProtoMessage() {}
 
 func (x *DeleteBatchResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_internal_server_gRPCServer_protoshortener_shortener_proto_msgTypes[9]
+	mi := &file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes[11]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -520,7 +734,7 @@ func (x *DeleteBatchResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use DeleteBatchResponse.ProtoReflect.Descriptor instead.
 func (*DeleteBatchResponse) Descriptor() ([]byte, []int) {
-	return file_internal_server_gRPCServer_protoshortener_shortener_proto_rawDescGZIP(), []int{9}
+	return file_internal_server_grpcserver_protoshortener_shortener_proto_rawDescGZIP(), []int{11}
 }
 
 func (x *DeleteBatchResponse) GetError() string {
@@ -530,6 +744,108 @@ func (x *DeleteBatchResponse) GetError() string {
 	return ""
 }
 
+type UpdateURLRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id  string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Url string `protobuf:"bytes,2,opt,name=url,proto3" json:"url,omitempty"`
+}
+
+func (x *UpdateURLRequest) Reset() {
+	*x = UpdateURLRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes[12]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *UpdateURLRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateURLRequest) ProtoMessage() {}
+
+func (x *UpdateURLRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes[12]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateURLRequest.ProtoReflect.Descriptor instead.
+func (*UpdateURLRequest) Descriptor() ([]byte, []int) {
+	return file_internal_server_grpcserver_protoshortener_shortener_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *UpdateURLRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *UpdateURLRequest) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+type UpdateURLResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Error string `protobuf:"bytes,1,opt,name=error,proto3" json:"error,omitempty"`
+}
+
+func (x *UpdateURLResponse) Reset() {
+	*x = UpdateURLResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes[13]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *UpdateURLResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateURLResponse) ProtoMessage() {}
+
+func (x *UpdateURLResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes[13]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateURLResponse.ProtoReflect.Descriptor instead.
+func (*UpdateURLResponse) Descriptor() ([]byte, []int) {
+	return file_internal_server_grpcserver_protoshortener_shortener_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *UpdateURLResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
 type StatsResponse struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -543,7 +859,7 @@ type StatsResponse struct {
 func (x *StatsResponse) Reset() {
 	*x = StatsResponse{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_internal_server_gRPCServer_protoshortener_shortener_proto_msgTypes[10]
+		mi := &file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes[14]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -556,7 +872,7 @@ func (x *StatsResponse) String() string {
 func (*StatsResponse) ProtoMessage() {}
 
 func (x *StatsResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_internal_server_gRPCServer_protoshortener_shortener_proto_msgTypes[10]
+	mi := &file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes[14]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -569,7 +885,7 @@ func (x *StatsResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use StatsResponse.ProtoReflect.Descriptor instead.
 func (*StatsResponse) Descriptor() ([]byte, []int) {
-	return file_internal_server_gRPCServer_protoshortener_shortener_proto_rawDescGZIP(), []int{10}
+	return file_internal_server_grpcserver_protoshortener_shortener_proto_rawDescGZIP(), []int{14}
 }
 
 func (x *StatsResponse) GetUrls() int32 {
@@ -604,7 +920,7 @@ type PingStorageResponse struct {
 func (x *PingStorageResponse) Reset() {
 	*x = PingStorageResponse{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_internal_server_gRPCServer_protoshortener_shortener_proto_msgTypes[11]
+		mi := &file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes[15]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -617,7 +933,7 @@ func (x *PingStorageResponse) String() string {
 func (*PingStorageResponse) ProtoMessage() {}
 
 func (x *PingStorageResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_internal_server_gRPCServer_protoshortener_shortener_proto_msgTypes[11]
+	mi := &file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes[15]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -630,7 +946,7 @@ func (x *PingStorageResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use PingStorageResponse.ProtoReflect.Descriptor instead.
 func (*PingStorageResponse) Descriptor() ([]byte, []int) {
-	return file_internal_server_gRPCServer_protoshortener_shortener_proto_rawDescGZIP(), []int{11}
+	return file_internal_server_grpcserver_protoshortener_shortener_proto_rawDescGZIP(), []int{15}
 }
 
 func (x *PingStorageResponse) GetError() string {
@@ -640,30 +956,36 @@ func (x *PingStorageResponse) GetError() string {
 	return ""
 }
 
-// for when we don't need in or out messages
-type Dummy struct {
+type Link struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
+
+	Id          string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	ShortUrl    string `protobuf:"bytes,2,opt,name=short_url,json=shortUrl,proto3" json:"short_url,omitempty"`
+	OriginalUrl string `protobuf:"bytes,3,opt,name=original_url,json=originalUrl,proto3" json:"original_url,omitempty"`
+	UserId      string `protobuf:"bytes,4,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Deleted     bool   `protobuf:"varint,5,opt,name=deleted,proto3" json:"deleted,omitempty"`
+	Disabled    bool   `protobuf:"varint,6,opt,name=disabled,proto3" json:"disabled,omitempty"`
 }
 
-func (x *Dummy) Reset() {
-	*x = Dummy{}
+func (x *Link) Reset() {
+	*x = Link{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_internal_server_gRPCServer_protoshortener_shortener_proto_msgTypes[12]
+		mi := &file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes[16]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
 }
 
-func (x *Dummy) String() string {
+func (x *Link) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*Dummy) ProtoMessage() {}
+func (*Link) ProtoMessage() {}
 
-func (x *Dummy) ProtoReflect() protoreflect.Message {
-	mi := &file_internal_server_gRPCServer_protoshortener_shortener_proto_msgTypes[12]
+func (x *Link) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes[16]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -674,179 +996,778 @@ func (x *Dummy) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use Dummy.ProtoReflect.Descriptor instead.
-func (*Dummy) Descriptor() ([]byte, []int) {
-	return file_internal_server_gRPCServer_protoshortener_shortener_proto_rawDescGZIP(), []int{12}
+// Deprecated: Use Link.ProtoReflect.Descriptor instead.
+func (*Link) Descriptor() ([]byte, []int) {
+	return file_internal_server_grpcserver_protoshortener_shortener_proto_rawDescGZIP(), []int{16}
 }
 
-var File_internal_server_gRPCServer_protoshortener_shortener_proto protoreflect.FileDescriptor
+func (x *Link) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
 
-var file_internal_server_gRPCServer_protoshortener_shortener_proto_rawDesc = []byte{
-	0x0a, 0x39, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x6e, 0x61, 0x6c, 0x2f, 0x73, 0x65, 0x72, 0x76, 0x65,
-	0x72, 0x2f, 0x67, 0x52, 0x50, 0x43, 0x53, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2f, 0x70, 0x72, 0x6f,
-	0x74, 0x6f, 0x73, 0x68, 0x6f, 0x72, 0x74, 0x65, 0x6e, 0x65, 0x72, 0x2f, 0x73, 0x68, 0x6f, 0x72,
-	0x74, 0x65, 0x6e, 0x65, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x0a, 0x67, 0x52, 0x50,
-	0x43, 0x53, 0x65, 0x72, 0x76, 0x65, 0x72, 0x22, 0x22, 0x0a, 0x0e, 0x53, 0x68, 0x6f, 0x72, 0x74,
-	0x65, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x10, 0x0a, 0x03, 0x75, 0x72, 0x6c,
-	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x75, 0x72, 0x6c, 0x22, 0x37, 0x0a, 0x0f, 0x53,
-	0x68, 0x6f, 0x72, 0x74, 0x65, 0x6e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x0e,
-	0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x14,
-	0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x65,
-	0x72, 0x72, 0x6f, 0x72, 0x22, 0x3d, 0x0a, 0x13, 0x53, 0x68, 0x6f, 0x72, 0x74, 0x65, 0x6e, 0x42,
-	0x61, 0x74, 0x63, 0x68, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x26, 0x0a, 0x04, 0x64,
-	0x61, 0x74, 0x61, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x12, 0x2e, 0x67, 0x52, 0x50, 0x43,
-	0x53, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2e, 0x55, 0x52, 0x4c, 0x77, 0x49, 0x64, 0x52, 0x04, 0x64,
-	0x61, 0x74, 0x61, 0x22, 0x54, 0x0a, 0x14, 0x53, 0x68, 0x6f, 0x72, 0x74, 0x65, 0x6e, 0x42, 0x61,
-	0x74, 0x63, 0x68, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x26, 0x0a, 0x04, 0x64,
-	0x61, 0x74, 0x61, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x12, 0x2e, 0x67, 0x52, 0x50, 0x43,
-	0x53, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2e, 0x55, 0x52, 0x4c, 0x77, 0x49, 0x64, 0x52, 0x04, 0x64,
-	0x61, 0x74, 0x61, 0x12, 0x14, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x02, 0x20, 0x01,
-	0x28, 0x09, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x22, 0x2a, 0x0a, 0x06, 0x55, 0x52, 0x4c,
-	0x77, 0x49, 0x64, 0x12, 0x10, 0x0a, 0x03, 0x75, 0x72, 0x6c, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
-	0x52, 0x03, 0x75, 0x72, 0x6c, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28,
-	0x09, 0x52, 0x02, 0x69, 0x64, 0x22, 0x20, 0x0a, 0x0e, 0x47, 0x65, 0x74, 0x4c, 0x6f, 0x6e, 0x67,
-	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20,
-	0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x22, 0x39, 0x0a, 0x0f, 0x47, 0x65, 0x74, 0x4c, 0x6f,
-	0x6e, 0x67, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x10, 0x0a, 0x03, 0x75, 0x72,
-	0x6c, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x75, 0x72, 0x6c, 0x12, 0x14, 0x0a, 0x05,
-	0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x65, 0x72, 0x72,
-	0x6f, 0x72, 0x22, 0x41, 0x0a, 0x15, 0x47, 0x65, 0x74, 0x4c, 0x6f, 0x6e, 0x67, 0x42, 0x79, 0x55,
-	0x73, 0x65, 0x72, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x75,
-	0x72, 0x6c, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x09, 0x52, 0x04, 0x75, 0x72, 0x6c, 0x73, 0x12,
-	0x14, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05,
-	0x65, 0x72, 0x72, 0x6f, 0x72, 0x22, 0x26, 0x0a, 0x12, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x42,
-	0x61, 0x74, 0x63, 0x68, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x10, 0x0a, 0x03, 0x69,
-	0x64, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x09, 0x52, 0x03, 0x69, 0x64, 0x73, 0x22, 0x2b, 0x0a,
-	0x13, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x42, 0x61, 0x74, 0x63, 0x68, 0x52, 0x65, 0x73, 0x70,
-	0x6f, 0x6e, 0x73, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x01, 0x20,
-	0x01, 0x28, 0x09, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x22, 0x4f, 0x0a, 0x0d, 0x53, 0x74,
-	0x61, 0x74, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x75,
-	0x72, 0x6c, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x75, 0x72, 0x6c, 0x73, 0x12,
-	0x14, 0x0a, 0x05, 0x75, 0x73, 0x65, 0x72, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x05,
-	0x75, 0x73, 0x65, 0x72, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x03,
-	0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x22, 0x2b, 0x0a, 0x13, 0x50,
-	0x69, 0x6e, 0x67, 0x53, 0x74, 0x6f, 0x72, 0x61, 0x67, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
-	0x73, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28,
-	0x09, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x22, 0x07, 0x0a, 0x05, 0x44, 0x75, 0x6d, 0x6d,
-	0x79, 0x32, 0xf7, 0x03, 0x0a, 0x09, 0x53, 0x68, 0x6f, 0x72, 0x74, 0x65, 0x6e, 0x65, 0x72, 0x12,
-	0x42, 0x0a, 0x07, 0x53, 0x68, 0x6f, 0x72, 0x74, 0x65, 0x6e, 0x12, 0x1a, 0x2e, 0x67, 0x52, 0x50,
-	0x43, 0x53, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2e, 0x53, 0x68, 0x6f, 0x72, 0x74, 0x65, 0x6e, 0x52,
-	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1b, 0x2e, 0x67, 0x52, 0x50, 0x43, 0x53, 0x65, 0x72,
-	0x76, 0x65, 0x72, 0x2e, 0x53, 0x68, 0x6f, 0x72, 0x74, 0x65, 0x6e, 0x52, 0x65, 0x73, 0x70, 0x6f,
-	0x6e, 0x73, 0x65, 0x12, 0x51, 0x0a, 0x0c, 0x53, 0x68, 0x6f, 0x72, 0x74, 0x65, 0x6e, 0x42, 0x61,
-	0x74, 0x63, 0x68, 0x12, 0x1f, 0x2e, 0x67, 0x52, 0x50, 0x43, 0x53, 0x65, 0x72, 0x76, 0x65, 0x72,
-	0x2e, 0x53, 0x68, 0x6f, 0x72, 0x74, 0x65, 0x6e, 0x42, 0x61, 0x74, 0x63, 0x68, 0x52, 0x65, 0x71,
-	0x75, 0x65, 0x73, 0x74, 0x1a, 0x20, 0x2e, 0x67, 0x52, 0x50, 0x43, 0x53, 0x65, 0x72, 0x76, 0x65,
-	0x72, 0x2e, 0x53, 0x68, 0x6f, 0x72, 0x74, 0x65, 0x6e, 0x42, 0x61, 0x74, 0x63, 0x68, 0x52, 0x65,
-	0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x42, 0x0a, 0x07, 0x47, 0x65, 0x74, 0x4c, 0x6f, 0x6e,
-	0x67, 0x12, 0x1a, 0x2e, 0x67, 0x52, 0x50, 0x43, 0x53, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2e, 0x47,
-	0x65, 0x74, 0x4c, 0x6f, 0x6e, 0x67, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1b, 0x2e,
-	0x67, 0x52, 0x50, 0x43, 0x53, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2e, 0x47, 0x65, 0x74, 0x4c, 0x6f,
-	0x6e, 0x67, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x45, 0x0a, 0x0d, 0x47, 0x65,
-	0x74, 0x4c, 0x6f, 0x6e, 0x67, 0x42, 0x79, 0x55, 0x73, 0x65, 0x72, 0x12, 0x11, 0x2e, 0x67, 0x52,
-	0x50, 0x43, 0x53, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2e, 0x44, 0x75, 0x6d, 0x6d, 0x79, 0x1a, 0x21,
-	0x2e, 0x67, 0x52, 0x50, 0x43, 0x53, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2e, 0x47, 0x65, 0x74, 0x4c,
-	0x6f, 0x6e, 0x67, 0x42, 0x79, 0x55, 0x73, 0x65, 0x72, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
-	0x65, 0x12, 0x4e, 0x0a, 0x0b, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x42, 0x61, 0x74, 0x63, 0x68,
-	0x12, 0x1e, 0x2e, 0x67, 0x52, 0x50, 0x43, 0x53, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2e, 0x44, 0x65,
-	0x6c, 0x65, 0x74, 0x65, 0x42, 0x61, 0x74, 0x63, 0x68, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
-	0x1a, 0x1f, 0x2e, 0x67, 0x52, 0x50, 0x43, 0x53, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2e, 0x44, 0x65,
-	0x6c, 0x65, 0x74, 0x65, 0x42, 0x61, 0x74, 0x63, 0x68, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
-	0x65, 0x12, 0x35, 0x0a, 0x05, 0x53, 0x74, 0x61, 0x74, 0x73, 0x12, 0x11, 0x2e, 0x67, 0x52, 0x50,
-	0x43, 0x53, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2e, 0x44, 0x75, 0x6d, 0x6d, 0x79, 0x1a, 0x19, 0x2e,
-	0x67, 0x52, 0x50, 0x43, 0x53, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2e, 0x53, 0x74, 0x61, 0x74, 0x73,
-	0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x41, 0x0a, 0x0b, 0x50, 0x69, 0x6e, 0x67,
-	0x53, 0x74, 0x6f, 0x72, 0x61, 0x67, 0x65, 0x12, 0x11, 0x2e, 0x67, 0x52, 0x50, 0x43, 0x53, 0x65,
-	0x72, 0x76, 0x65, 0x72, 0x2e, 0x44, 0x75, 0x6d, 0x6d, 0x79, 0x1a, 0x1f, 0x2e, 0x67, 0x52, 0x50,
-	0x43, 0x53, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2e, 0x50, 0x69, 0x6e, 0x67, 0x53, 0x74, 0x6f, 0x72,
-	0x61, 0x67, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x42, 0x1b, 0x5a, 0x19, 0x67,
-	0x52, 0x50, 0x43, 0x53, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x73,
-	0x68, 0x6f, 0x72, 0x74, 0x65, 0x6e, 0x65, 0x72, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+func (x *Link) GetShortUrl() string {
+	if x != nil {
+		return x.ShortUrl
+	}
+	return ""
 }
 
-var (
-	file_internal_server_gRPCServer_protoshortener_shortener_proto_rawDescOnce sync.Once
-	file_internal_server_gRPCServer_protoshortener_shortener_proto_rawDescData = file_internal_server_gRPCServer_protoshortener_shortener_proto_rawDesc
-)
+func (x *Link) GetOriginalUrl() string {
+	if x != nil {
+		return x.OriginalUrl
+	}
+	return ""
+}
 
-func file_internal_server_gRPCServer_protoshortener_shortener_proto_rawDescGZIP() []byte {
-	file_internal_server_gRPCServer_protoshortener_shortener_proto_rawDescOnce.Do(func() {
-		file_internal_server_gRPCServer_protoshortener_shortener_proto_rawDescData = protoimpl.X.CompressGZIP(file_internal_server_gRPCServer_protoshortener_shortener_proto_rawDescData)
-	})
-	return file_internal_server_gRPCServer_protoshortener_shortener_proto_rawDescData
-}
-
-var file_internal_server_gRPCServer_protoshortener_shortener_proto_msgTypes = make([]protoimpl.MessageInfo, 13)
-var file_internal_server_gRPCServer_protoshortener_shortener_proto_goTypes = []interface{}{
-	(*ShortenRequest)(nil),        // 0: grpcserver.ShortenRequest
-	(*ShortenResponse)(nil),       // 1: grpcserver.ShortenResponse
-	(*ShortenBatchRequest)(nil),   // 2: grpcserver.ShortenBatchRequest
-	(*ShortenBatchResponse)(nil),  // 3: grpcserver.ShortenBatchResponse
-	(*URLwId)(nil),                // 4: grpcserver.URLwId
-	(*GetLongRequest)(nil),        // 5: grpcserver.GetLongRequest
-	(*GetLongResponse)(nil),       // 6: grpcserver.GetLongResponse
-	(*GetLongByUserResponse)(nil), // 7: grpcserver.GetLongByUserResponse
-	(*DeleteBatchRequest)(nil),    // 8: grpcserver.DeleteBatchRequest
-	(*DeleteBatchResponse)(nil),   // 9: grpcserver.DeleteBatchResponse
-	(*StatsResponse)(nil),         // 10: grpcserver.StatsResponse
-	(*PingStorageResponse)(nil),   // 11: grpcserver.PingStorageResponse
-	(*Dummy)(nil),                 // 12: grpcserver.Dummy
-}
-var file_internal_server_gRPCServer_protoshortener_shortener_proto_depIdxs = []int32{
-	4,  // 0: grpcserver.ShortenBatchRequest.data:type_name -> grpcserver.URLwId
-	4,  // 1: grpcserver.ShortenBatchResponse.data:type_name -> grpcserver.URLwId
-	0,  // 2: grpcserver.Shortener.Shorten:input_type -> grpcserver.ShortenRequest
-	2,  // 3: grpcserver.Shortener.ShortenBatch:input_type -> grpcserver.ShortenBatchRequest
-	5,  // 4: grpcserver.Shortener.GetLong:input_type -> grpcserver.GetLongRequest
-	12, // 5: grpcserver.Shortener.GetLongByUser:input_type -> grpcserver.Dummy
-	8,  // 6: grpcserver.Shortener.DeleteBatch:input_type -> grpcserver.DeleteBatchRequest
-	12, // 7: grpcserver.Shortener.Stats:input_type -> grpcserver.Dummy
-	12, // 8: grpcserver.Shortener.PingStorage:input_type -> grpcserver.Dummy
-	1,  // 9: grpcserver.Shortener.Shorten:output_type -> grpcserver.ShortenResponse
-	3,  // 10: grpcserver.Shortener.ShortenBatch:output_type -> grpcserver.ShortenBatchResponse
-	6,  // 11: grpcserver.Shortener.GetLong:output_type -> grpcserver.GetLongResponse
-	7,  // 12: grpcserver.Shortener.GetLongByUser:output_type -> grpcserver.GetLongByUserResponse
-	9,  // 13: grpcserver.Shortener.DeleteBatch:output_type -> grpcserver.DeleteBatchResponse
-	10, // 14: grpcserver.Shortener.Stats:output_type -> grpcserver.StatsResponse
-	11, // 15: grpcserver.Shortener.PingStorage:output_type -> grpcserver.PingStorageResponse
-	9,  // [9:16] is the sub-list for method output_type
-	2,  // [2:9] is the sub-list for method input_type
-	2,  // [2:2] is the sub-list for extension type_name
-	2,  // [2:2] is the sub-list for extension extendee
-	0,  // [0:2] is the sub-list for field type_name
-}
-
-func init() { file_internal_server_gRPCServer_protoshortener_shortener_proto_init() }
-func file_internal_server_gRPCServer_protoshortener_shortener_proto_init() {
-	if File_internal_server_gRPCServer_protoshortener_shortener_proto != nil {
-		return
+func (x *Link) GetUserId() string {
+	if x != nil {
+		return x.UserId
 	}
-	if !protoimpl.UnsafeEnabled {
-		file_internal_server_gRPCServer_protoshortener_shortener_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*ShortenRequest); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
-		}
-		file_internal_server_gRPCServer_protoshortener_shortener_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*ShortenResponse); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
+	return ""
+}
+
+func (x *Link) GetDeleted() bool {
+	if x != nil {
+		return x.Deleted
+	}
+	return false
+}
+
+func (x *Link) GetDisabled() bool {
+	if x != nil {
+		return x.Disabled
+	}
+	return false
+}
+
+// either id or url (destination) must be set
+type AdminGetLinkRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id  string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Url string `protobuf:"bytes,2,opt,name=url,proto3" json:"url,omitempty"`
+}
+
+func (x *AdminGetLinkRequest) Reset() {
+	*x = AdminGetLinkRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes[17]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *AdminGetLinkRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AdminGetLinkRequest) ProtoMessage() {}
+
+func (x *AdminGetLinkRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes[17]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AdminGetLinkRequest.ProtoReflect.Descriptor instead.
+func (*AdminGetLinkRequest) Descriptor() ([]byte, []int) {
+	return file_internal_server_grpcserver_protoshortener_shortener_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *AdminGetLinkRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *AdminGetLinkRequest) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+type AdminGetLinkResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Link  *Link  `protobuf:"bytes,1,opt,name=link,proto3" json:"link,omitempty"`
+	Error string `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+}
+
+func (x *AdminGetLinkResponse) Reset() {
+	*x = AdminGetLinkResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes[18]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *AdminGetLinkResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AdminGetLinkResponse) ProtoMessage() {}
+
+func (x *AdminGetLinkResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes[18]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AdminGetLinkResponse.ProtoReflect.Descriptor instead.
+func (*AdminGetLinkResponse) Descriptor() ([]byte, []int) {
+	return file_internal_server_grpcserver_protoshortener_shortener_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *AdminGetLinkResponse) GetLink() *Link {
+	if x != nil {
+		return x.Link
+	}
+	return nil
+}
+
+func (x *AdminGetLinkResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type AdminSetDisabledRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id       string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Disabled bool   `protobuf:"varint,2,opt,name=disabled,proto3" json:"disabled,omitempty"`
+}
+
+func (x *AdminSetDisabledRequest) Reset() {
+	*x = AdminSetDisabledRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes[19]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *AdminSetDisabledRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AdminSetDisabledRequest) ProtoMessage() {}
+
+func (x *AdminSetDisabledRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes[19]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AdminSetDisabledRequest.ProtoReflect.Descriptor instead.
+func (*AdminSetDisabledRequest) Descriptor() ([]byte, []int) {
+	return file_internal_server_grpcserver_protoshortener_shortener_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *AdminSetDisabledRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *AdminSetDisabledRequest) GetDisabled() bool {
+	if x != nil {
+		return x.Disabled
+	}
+	return false
+}
+
+type AdminSetDisabledResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Error string `protobuf:"bytes,1,opt,name=error,proto3" json:"error,omitempty"`
+}
+
+func (x *AdminSetDisabledResponse) Reset() {
+	*x = AdminSetDisabledResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes[20]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *AdminSetDisabledResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AdminSetDisabledResponse) ProtoMessage() {}
+
+func (x *AdminSetDisabledResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes[20]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AdminSetDisabledResponse.ProtoReflect.Descriptor instead.
+func (*AdminSetDisabledResponse) Descriptor() ([]byte, []int) {
+	return file_internal_server_grpcserver_protoshortener_shortener_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *AdminSetDisabledResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type AdminUserRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	UserId string `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+}
+
+func (x *AdminUserRequest) Reset() {
+	*x = AdminUserRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes[21]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *AdminUserRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AdminUserRequest) ProtoMessage() {}
+
+func (x *AdminUserRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes[21]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AdminUserRequest.ProtoReflect.Descriptor instead.
+func (*AdminUserRequest) Descriptor() ([]byte, []int) {
+	return file_internal_server_grpcserver_protoshortener_shortener_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *AdminUserRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+type AdminGetUserLinksResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Links []*Link `protobuf:"bytes,1,rep,name=links,proto3" json:"links,omitempty"`
+	Error string  `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+}
+
+func (x *AdminGetUserLinksResponse) Reset() {
+	*x = AdminGetUserLinksResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes[22]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *AdminGetUserLinksResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AdminGetUserLinksResponse) ProtoMessage() {}
+
+func (x *AdminGetUserLinksResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes[22]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AdminGetUserLinksResponse.ProtoReflect.Descriptor instead.
+func (*AdminGetUserLinksResponse) Descriptor() ([]byte, []int) {
+	return file_internal_server_grpcserver_protoshortener_shortener_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *AdminGetUserLinksResponse) GetLinks() []*Link {
+	if x != nil {
+		return x.Links
+	}
+	return nil
+}
+
+func (x *AdminGetUserLinksResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type AdminDeleteResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Deleted int32  `protobuf:"varint,1,opt,name=deleted,proto3" json:"deleted,omitempty"`
+	Error   string `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+}
+
+func (x *AdminDeleteResponse) Reset() {
+	*x = AdminDeleteResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes[23]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *AdminDeleteResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AdminDeleteResponse) ProtoMessage() {}
+
+func (x *AdminDeleteResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes[23]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AdminDeleteResponse.ProtoReflect.Descriptor instead.
+func (*AdminDeleteResponse) Descriptor() ([]byte, []int) {
+	return file_internal_server_grpcserver_protoshortener_shortener_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *AdminDeleteResponse) GetDeleted() int32 {
+	if x != nil {
+		return x.Deleted
+	}
+	return 0
+}
+
+func (x *AdminDeleteResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+// for when we don't need in or out messages
+type Dummy struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *Dummy) Reset() {
+	*x = Dummy{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes[24]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Dummy) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Dummy) ProtoMessage() {}
+
+func (x *Dummy) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes[24]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Dummy.ProtoReflect.Descriptor instead.
+func (*Dummy) Descriptor() ([]byte, []int) {
+	return file_internal_server_grpcserver_protoshortener_shortener_proto_rawDescGZIP(), []int{24}
+}
+
+var File_internal_server_grpcserver_protoshortener_shortener_proto protoreflect.FileDescriptor
+
+var file_internal_server_grpcserver_protoshortener_shortener_proto_rawDesc = []byte{
+	0x0a, 0x39, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x6e, 0x61, 0x6c, 0x2f, 0x73, 0x65, 0x72, 0x76, 0x65,
+	0x72, 0x2f, 0x67, 0x72, 0x70, 0x63, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2f, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x73, 0x68, 0x6f, 0x72, 0x74, 0x65, 0x6e, 0x65, 0x72, 0x2f, 0x73, 0x68, 0x6f, 0x72,
+	0x74, 0x65, 0x6e, 0x65, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x0a, 0x67, 0x72, 0x70,
+	0x63, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x22, 0x22, 0x0a, 0x0e, 0x53, 0x68, 0x6f, 0x72, 0x74,
+	0x65, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x10, 0x0a, 0x03, 0x75, 0x72, 0x6c,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x75, 0x72, 0x6c, 0x22, 0x54, 0x0a, 0x0f, 0x53,
+	0x68, 0x6f, 0x72, 0x74, 0x65, 0x6e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x0e,
+	0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x14,
+	0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x65,
+	0x72, 0x72, 0x6f, 0x72, 0x12, 0x1b, 0x0a, 0x09, 0x73, 0x68, 0x6f, 0x72, 0x74, 0x5f, 0x75, 0x72,
+	0x6c, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x73, 0x68, 0x6f, 0x72, 0x74, 0x55, 0x72,
+	0x6c, 0x22, 0x57, 0x0a, 0x13, 0x53, 0x68, 0x6f, 0x72, 0x74, 0x65, 0x6e, 0x42, 0x61, 0x74, 0x63,
+	0x68, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x26, 0x0a, 0x04, 0x64, 0x61, 0x74, 0x61,
+	0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x12, 0x2e, 0x67, 0x72, 0x70, 0x63, 0x73, 0x65, 0x72,
+	0x76, 0x65, 0x72, 0x2e, 0x55, 0x52, 0x4c, 0x77, 0x49, 0x64, 0x52, 0x04, 0x64, 0x61, 0x74, 0x61,
+	0x12, 0x18, 0x0a, 0x07, 0x70, 0x61, 0x72, 0x74, 0x69, 0x61, 0x6c, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x08, 0x52, 0x07, 0x70, 0x61, 0x72, 0x74, 0x69, 0x61, 0x6c, 0x22, 0x8b, 0x01, 0x0a, 0x14, 0x53,
+	0x68, 0x6f, 0x72, 0x74, 0x65, 0x6e, 0x42, 0x61, 0x74, 0x63, 0x68, 0x52, 0x65, 0x73, 0x70, 0x6f,
+	0x6e, 0x73, 0x65, 0x12, 0x26, 0x0a, 0x04, 0x64, 0x61, 0x74, 0x61, 0x18, 0x01, 0x20, 0x03, 0x28,
+	0x0b, 0x32, 0x12, 0x2e, 0x67, 0x72, 0x70, 0x63, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2e, 0x55,
+	0x52, 0x4c, 0x77, 0x49, 0x64, 0x52, 0x04, 0x64, 0x61, 0x74, 0x61, 0x12, 0x14, 0x0a, 0x05, 0x65,
+	0x72, 0x72, 0x6f, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6f,
+	0x72, 0x12, 0x35, 0x0a, 0x07, 0x72, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x73, 0x18, 0x03, 0x20, 0x03,
+	0x28, 0x0b, 0x32, 0x1b, 0x2e, 0x67, 0x72, 0x70, 0x63, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2e,
+	0x42, 0x61, 0x74, 0x63, 0x68, 0x49, 0x74, 0x65, 0x6d, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x52,
+	0x07, 0x72, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x73, 0x22, 0x84, 0x01, 0x0a, 0x0f, 0x42, 0x61, 0x74,
+	0x63, 0x68, 0x49, 0x74, 0x65, 0x6d, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x12, 0x0e, 0x0a, 0x02,
+	0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x1b, 0x0a, 0x09,
+	0x73, 0x68, 0x6f, 0x72, 0x74, 0x5f, 0x75, 0x72, 0x6c, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x08, 0x73, 0x68, 0x6f, 0x72, 0x74, 0x55, 0x72, 0x6c, 0x12, 0x2e, 0x0a, 0x06, 0x73, 0x74, 0x61,
+	0x74, 0x75, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x16, 0x2e, 0x67, 0x72, 0x70, 0x63,
+	0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2e, 0x49, 0x74, 0x65, 0x6d, 0x53, 0x74, 0x61, 0x74, 0x75,
+	0x73, 0x52, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x65, 0x72, 0x72,
+	0x6f, 0x72, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x22,
+	0x2a, 0x0a, 0x06, 0x55, 0x52, 0x4c, 0x77, 0x49, 0x64, 0x12, 0x10, 0x0a, 0x03, 0x75, 0x72, 0x6c,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x75, 0x72, 0x6c, 0x12, 0x0e, 0x0a, 0x02, 0x69,
+	0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x22, 0x20, 0x0a, 0x0e, 0x47,
+	0x65, 0x74, 0x4c, 0x6f, 0x6e, 0x67, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x0e, 0x0a,
+	0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x22, 0x39, 0x0a,
+	0x0f, 0x47, 0x65, 0x74, 0x4c, 0x6f, 0x6e, 0x67, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65,
+	0x12, 0x10, 0x0a, 0x03, 0x75, 0x72, 0x6c, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x75,
+	0x72, 0x6c, 0x12, 0x14, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x22, 0x6c, 0x0a, 0x15, 0x47, 0x65, 0x74, 0x4c,
+	0x6f, 0x6e, 0x67, 0x42, 0x79, 0x55, 0x73, 0x65, 0x72, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
+	0x65, 0x12, 0x12, 0x0a, 0x04, 0x75, 0x72, 0x6c, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x09, 0x52,
+	0x04, 0x75, 0x72, 0x6c, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x12, 0x29, 0x0a, 0x05, 0x6c,
+	0x69, 0x6e, 0x6b, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x13, 0x2e, 0x67, 0x72, 0x70,
+	0x63, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2e, 0x55, 0x52, 0x4c, 0x50, 0x61, 0x69, 0x72, 0x52,
+	0x05, 0x6c, 0x69, 0x6e, 0x6b, 0x73, 0x22, 0x49, 0x0a, 0x07, 0x55, 0x52, 0x4c, 0x50, 0x61, 0x69,
+	0x72, 0x12, 0x1b, 0x0a, 0x09, 0x73, 0x68, 0x6f, 0x72, 0x74, 0x5f, 0x75, 0x72, 0x6c, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x73, 0x68, 0x6f, 0x72, 0x74, 0x55, 0x72, 0x6c, 0x12, 0x21,
+	0x0a, 0x0c, 0x6f, 0x72, 0x69, 0x67, 0x69, 0x6e, 0x61, 0x6c, 0x5f, 0x75, 0x72, 0x6c, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x6f, 0x72, 0x69, 0x67, 0x69, 0x6e, 0x61, 0x6c, 0x55, 0x72,
+	0x6c, 0x22, 0x26, 0x0a, 0x12, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x42, 0x61, 0x74, 0x63, 0x68,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x10, 0x0a, 0x03, 0x69, 0x64, 0x73, 0x18, 0x01,
+	0x20, 0x03, 0x28, 0x09, 0x52, 0x03, 0x69, 0x64, 0x73, 0x22, 0x2b, 0x0a, 0x13, 0x44, 0x65, 0x6c,
+	0x65, 0x74, 0x65, 0x42, 0x61, 0x74, 0x63, 0x68, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65,
+	0x12, 0x14, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x22, 0x34, 0x0a, 0x10, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65,
+	0x55, 0x52, 0x4c, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x10, 0x0a, 0x03, 0x75, 0x72,
+	0x6c, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x75, 0x72, 0x6c, 0x22, 0x29, 0x0a, 0x11,
+	0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x55, 0x52, 0x4c, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
+	0x65, 0x12, 0x14, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x22, 0x4f, 0x0a, 0x0d, 0x53, 0x74, 0x61, 0x74, 0x73,
+	0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x75, 0x72, 0x6c, 0x73,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x75, 0x72, 0x6c, 0x73, 0x12, 0x14, 0x0a, 0x05,
+	0x75, 0x73, 0x65, 0x72, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x05, 0x75, 0x73, 0x65,
+	0x72, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x03, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x22, 0x2b, 0x0a, 0x13, 0x50, 0x69, 0x6e, 0x67,
+	0x53, 0x74, 0x6f, 0x72, 0x61, 0x67, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12,
+	0x14, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05,
+	0x65, 0x72, 0x72, 0x6f, 0x72, 0x22, 0xa5, 0x01, 0x0a, 0x04, 0x4c, 0x69, 0x6e, 0x6b, 0x12, 0x0e,
+	0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x1b,
+	0x0a, 0x09, 0x73, 0x68, 0x6f, 0x72, 0x74, 0x5f, 0x75, 0x72, 0x6c, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x08, 0x73, 0x68, 0x6f, 0x72, 0x74, 0x55, 0x72, 0x6c, 0x12, 0x21, 0x0a, 0x0c, 0x6f,
+	0x72, 0x69, 0x67, 0x69, 0x6e, 0x61, 0x6c, 0x5f, 0x75, 0x72, 0x6c, 0x18, 0x03, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x0b, 0x6f, 0x72, 0x69, 0x67, 0x69, 0x6e, 0x61, 0x6c, 0x55, 0x72, 0x6c, 0x12, 0x17,
+	0x0a, 0x07, 0x75, 0x73, 0x65, 0x72, 0x5f, 0x69, 0x64, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x06, 0x75, 0x73, 0x65, 0x72, 0x49, 0x64, 0x12, 0x18, 0x0a, 0x07, 0x64, 0x65, 0x6c, 0x65, 0x74,
+	0x65, 0x64, 0x18, 0x05, 0x20, 0x01, 0x28, 0x08, 0x52, 0x07, 0x64, 0x65, 0x6c, 0x65, 0x74, 0x65,
+	0x64, 0x12, 0x1a, 0x0a, 0x08, 0x64, 0x69, 0x73, 0x61, 0x62, 0x6c, 0x65, 0x64, 0x18, 0x06, 0x20,
+	0x01, 0x28, 0x08, 0x52, 0x08, 0x64, 0x69, 0x73, 0x61, 0x62, 0x6c, 0x65, 0x64, 0x22, 0x37, 0x0a,
+	0x13, 0x41, 0x64, 0x6d, 0x69, 0x6e, 0x47, 0x65, 0x74, 0x4c, 0x69, 0x6e, 0x6b, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x02, 0x69, 0x64, 0x12, 0x10, 0x0a, 0x03, 0x75, 0x72, 0x6c, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x03, 0x75, 0x72, 0x6c, 0x22, 0x52, 0x0a, 0x14, 0x41, 0x64, 0x6d, 0x69, 0x6e, 0x47,
+	0x65, 0x74, 0x4c, 0x69, 0x6e, 0x6b, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x24,
+	0x0a, 0x04, 0x6c, 0x69, 0x6e, 0x6b, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x10, 0x2e, 0x67,
+	0x72, 0x70, 0x63, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2e, 0x4c, 0x69, 0x6e, 0x6b, 0x52, 0x04,
+	0x6c, 0x69, 0x6e, 0x6b, 0x12, 0x14, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x22, 0x45, 0x0a, 0x17, 0x41, 0x64,
+	0x6d, 0x69, 0x6e, 0x53, 0x65, 0x74, 0x44, 0x69, 0x73, 0x61, 0x62, 0x6c, 0x65, 0x64, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x1a, 0x0a, 0x08, 0x64, 0x69, 0x73, 0x61, 0x62, 0x6c, 0x65,
+	0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x08, 0x52, 0x08, 0x64, 0x69, 0x73, 0x61, 0x62, 0x6c, 0x65,
+	0x64, 0x22, 0x30, 0x0a, 0x18, 0x41, 0x64, 0x6d, 0x69, 0x6e, 0x53, 0x65, 0x74, 0x44, 0x69, 0x73,
+	0x61, 0x62, 0x6c, 0x65, 0x64, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x14, 0x0a,
+	0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x65, 0x72,
+	0x72, 0x6f, 0x72, 0x22, 0x2b, 0x0a, 0x10, 0x41, 0x64, 0x6d, 0x69, 0x6e, 0x55, 0x73, 0x65, 0x72,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x17, 0x0a, 0x07, 0x75, 0x73, 0x65, 0x72, 0x5f,
+	0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x75, 0x73, 0x65, 0x72, 0x49, 0x64,
+	0x22, 0x59, 0x0a, 0x19, 0x41, 0x64, 0x6d, 0x69, 0x6e, 0x47, 0x65, 0x74, 0x55, 0x73, 0x65, 0x72,
+	0x4c, 0x69, 0x6e, 0x6b, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x26, 0x0a,
+	0x05, 0x6c, 0x69, 0x6e, 0x6b, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x10, 0x2e, 0x67,
+	0x72, 0x70, 0x63, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2e, 0x4c, 0x69, 0x6e, 0x6b, 0x52, 0x05,
+	0x6c, 0x69, 0x6e, 0x6b, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x22, 0x45, 0x0a, 0x13, 0x41,
+	0x64, 0x6d, 0x69, 0x6e, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
+	0x73, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x64, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x64, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x05, 0x52, 0x07, 0x64, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x64, 0x12, 0x14, 0x0a, 0x05,
+	0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x65, 0x72, 0x72,
+	0x6f, 0x72, 0x22, 0x07, 0x0a, 0x05, 0x44, 0x75, 0x6d, 0x6d, 0x79, 0x2a, 0x48, 0x0a, 0x0a, 0x49,
+	0x74, 0x65, 0x6d, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x0b, 0x0a, 0x07, 0x43, 0x52, 0x45,
+	0x41, 0x54, 0x45, 0x44, 0x10, 0x00, 0x12, 0x0c, 0x0a, 0x08, 0x43, 0x4f, 0x4e, 0x46, 0x4c, 0x49,
+	0x43, 0x54, 0x10, 0x01, 0x12, 0x0b, 0x0a, 0x07, 0x49, 0x4e, 0x56, 0x41, 0x4c, 0x49, 0x44, 0x10,
+	0x02, 0x12, 0x12, 0x0a, 0x0e, 0x51, 0x55, 0x4f, 0x54, 0x41, 0x5f, 0x45, 0x58, 0x43, 0x45, 0x45,
+	0x44, 0x45, 0x44, 0x10, 0x03, 0x32, 0xbc, 0x05, 0x0a, 0x09, 0x53, 0x68, 0x6f, 0x72, 0x74, 0x65,
+	0x6e, 0x65, 0x72, 0x12, 0x42, 0x0a, 0x07, 0x53, 0x68, 0x6f, 0x72, 0x74, 0x65, 0x6e, 0x12, 0x1a,
+	0x2e, 0x67, 0x72, 0x70, 0x63, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2e, 0x53, 0x68, 0x6f, 0x72,
+	0x74, 0x65, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1b, 0x2e, 0x67, 0x72, 0x70,
+	0x63, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2e, 0x53, 0x68, 0x6f, 0x72, 0x74, 0x65, 0x6e, 0x52,
+	0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x51, 0x0a, 0x0c, 0x53, 0x68, 0x6f, 0x72, 0x74,
+	0x65, 0x6e, 0x42, 0x61, 0x74, 0x63, 0x68, 0x12, 0x1f, 0x2e, 0x67, 0x72, 0x70, 0x63, 0x73, 0x65,
+	0x72, 0x76, 0x65, 0x72, 0x2e, 0x53, 0x68, 0x6f, 0x72, 0x74, 0x65, 0x6e, 0x42, 0x61, 0x74, 0x63,
+	0x68, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x20, 0x2e, 0x67, 0x72, 0x70, 0x63, 0x73,
+	0x65, 0x72, 0x76, 0x65, 0x72, 0x2e, 0x53, 0x68, 0x6f, 0x72, 0x74, 0x65, 0x6e, 0x42, 0x61, 0x74,
+	0x63, 0x68, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x42, 0x0a, 0x07, 0x47, 0x65,
+	0x74, 0x4c, 0x6f, 0x6e, 0x67, 0x12, 0x1a, 0x2e, 0x67, 0x72, 0x70, 0x63, 0x73, 0x65, 0x72, 0x76,
+	0x65, 0x72, 0x2e, 0x47, 0x65, 0x74, 0x4c, 0x6f, 0x6e, 0x67, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x1a, 0x1b, 0x2e, 0x67, 0x72, 0x70, 0x63, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2e, 0x47,
+	0x65, 0x74, 0x4c, 0x6f, 0x6e, 0x67, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x45,
+	0x0a, 0x0d, 0x47, 0x65, 0x74, 0x4c, 0x6f, 0x6e, 0x67, 0x42, 0x79, 0x55, 0x73, 0x65, 0x72, 0x12,
+	0x11, 0x2e, 0x67, 0x72, 0x70, 0x63, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2e, 0x44, 0x75, 0x6d,
+	0x6d, 0x79, 0x1a, 0x21, 0x2e, 0x67, 0x72, 0x70, 0x63, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2e,
+	0x47, 0x65, 0x74, 0x4c, 0x6f, 0x6e, 0x67, 0x42, 0x79, 0x55, 0x73, 0x65, 0x72, 0x52, 0x65, 0x73,
+	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x4e, 0x0a, 0x0b, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x42,
+	0x61, 0x74, 0x63, 0x68, 0x12, 0x1e, 0x2e, 0x67, 0x72, 0x70, 0x63, 0x73, 0x65, 0x72, 0x76, 0x65,
+	0x72, 0x2e, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x42, 0x61, 0x74, 0x63, 0x68, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x1a, 0x1f, 0x2e, 0x67, 0x72, 0x70, 0x63, 0x73, 0x65, 0x72, 0x76, 0x65,
+	0x72, 0x2e, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x42, 0x61, 0x74, 0x63, 0x68, 0x52, 0x65, 0x73,
+	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x48, 0x0a, 0x09, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x55,
+	0x52, 0x4c, 0x12, 0x1c, 0x2e, 0x67, 0x72, 0x70, 0x63, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2e,
+	0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x55, 0x52, 0x4c, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
+	0x1a, 0x1d, 0x2e, 0x67, 0x72, 0x70, 0x63, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2e, 0x55, 0x70,
+	0x64, 0x61, 0x74, 0x65, 0x55, 0x52, 0x4c, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12,
+	0x35, 0x0a, 0x05, 0x53, 0x74, 0x61, 0x74, 0x73, 0x12, 0x11, 0x2e, 0x67, 0x72, 0x70, 0x63, 0x73,
+	0x65, 0x72, 0x76, 0x65, 0x72, 0x2e, 0x44, 0x75, 0x6d, 0x6d, 0x79, 0x1a, 0x19, 0x2e, 0x67, 0x72,
+	0x70, 0x63, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2e, 0x53, 0x74, 0x61, 0x74, 0x73, 0x52, 0x65,
+	0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x41, 0x0a, 0x0b, 0x50, 0x69, 0x6e, 0x67, 0x53, 0x74,
+	0x6f, 0x72, 0x61, 0x67, 0x65, 0x12, 0x11, 0x2e, 0x67, 0x72, 0x70, 0x63, 0x73, 0x65, 0x72, 0x76,
+	0x65, 0x72, 0x2e, 0x44, 0x75, 0x6d, 0x6d, 0x79, 0x1a, 0x1f, 0x2e, 0x67, 0x72, 0x70, 0x63, 0x73,
+	0x65, 0x72, 0x76, 0x65, 0x72, 0x2e, 0x50, 0x69, 0x6e, 0x67, 0x53, 0x74, 0x6f, 0x72, 0x61, 0x67,
+	0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x44, 0x0a, 0x0d, 0x53, 0x68, 0x6f,
+	0x72, 0x74, 0x65, 0x6e, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x12, 0x12, 0x2e, 0x67, 0x72, 0x70,
+	0x63, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2e, 0x55, 0x52, 0x4c, 0x77, 0x49, 0x64, 0x1a, 0x1b,
+	0x2e, 0x67, 0x72, 0x70, 0x63, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2e, 0x42, 0x61, 0x74, 0x63,
+	0x68, 0x49, 0x74, 0x65, 0x6d, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x28, 0x01, 0x30, 0x01, 0x12,
+	0x33, 0x0a, 0x0a, 0x4c, 0x69, 0x73, 0x74, 0x4d, 0x79, 0x55, 0x52, 0x4c, 0x73, 0x12, 0x11, 0x2e,
+	0x67, 0x72, 0x70, 0x63, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2e, 0x44, 0x75, 0x6d, 0x6d, 0x79,
+	0x1a, 0x10, 0x2e, 0x67, 0x72, 0x70, 0x63, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2e, 0x4c, 0x69,
+	0x6e, 0x6b, 0x30, 0x01, 0x32, 0xab, 0x03, 0x0a, 0x0e, 0x53, 0x68, 0x6f, 0x72, 0x74, 0x65, 0x6e,
+	0x65, 0x72, 0x41, 0x64, 0x6d, 0x69, 0x6e, 0x12, 0x4c, 0x0a, 0x07, 0x47, 0x65, 0x74, 0x4c, 0x69,
+	0x6e, 0x6b, 0x12, 0x1f, 0x2e, 0x67, 0x72, 0x70, 0x63, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2e,
+	0x41, 0x64, 0x6d, 0x69, 0x6e, 0x47, 0x65, 0x74, 0x4c, 0x69, 0x6e, 0x6b, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x1a, 0x20, 0x2e, 0x67, 0x72, 0x70, 0x63, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72,
+	0x2e, 0x41, 0x64, 0x6d, 0x69, 0x6e, 0x47, 0x65, 0x74, 0x4c, 0x69, 0x6e, 0x6b, 0x52, 0x65, 0x73,
+	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x58, 0x0a, 0x0b, 0x53, 0x65, 0x74, 0x44, 0x69, 0x73, 0x61,
+	0x62, 0x6c, 0x65, 0x64, 0x12, 0x23, 0x2e, 0x67, 0x72, 0x70, 0x63, 0x73, 0x65, 0x72, 0x76, 0x65,
+	0x72, 0x2e, 0x41, 0x64, 0x6d, 0x69, 0x6e, 0x53, 0x65, 0x74, 0x44, 0x69, 0x73, 0x61, 0x62, 0x6c,
+	0x65, 0x64, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x24, 0x2e, 0x67, 0x72, 0x70, 0x63,
+	0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2e, 0x41, 0x64, 0x6d, 0x69, 0x6e, 0x53, 0x65, 0x74, 0x44,
+	0x69, 0x73, 0x61, 0x62, 0x6c, 0x65, 0x64, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12,
+	0x53, 0x0a, 0x0c, 0x47, 0x65, 0x74, 0x55, 0x73, 0x65, 0x72, 0x4c, 0x69, 0x6e, 0x6b, 0x73, 0x12,
+	0x1c, 0x2e, 0x67, 0x72, 0x70, 0x63, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2e, 0x41, 0x64, 0x6d,
+	0x69, 0x6e, 0x55, 0x73, 0x65, 0x72, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x25, 0x2e,
+	0x67, 0x72, 0x70, 0x63, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2e, 0x41, 0x64, 0x6d, 0x69, 0x6e,
+	0x47, 0x65, 0x74, 0x55, 0x73, 0x65, 0x72, 0x4c, 0x69, 0x6e, 0x6b, 0x73, 0x52, 0x65, 0x73, 0x70,
+	0x6f, 0x6e, 0x73, 0x65, 0x12, 0x4b, 0x0a, 0x0a, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x55, 0x73,
+	0x65, 0x72, 0x12, 0x1c, 0x2e, 0x67, 0x72, 0x70, 0x63, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2e,
+	0x41, 0x64, 0x6d, 0x69, 0x6e, 0x55, 0x73, 0x65, 0x72, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
+	0x1a, 0x1f, 0x2e, 0x67, 0x72, 0x70, 0x63, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2e, 0x41, 0x64,
+	0x6d, 0x69, 0x6e, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
+	0x65, 0x12, 0x4f, 0x0a, 0x0e, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x53, 0x65, 0x73, 0x73, 0x69,
+	0x6f, 0x6e, 0x73, 0x12, 0x1c, 0x2e, 0x67, 0x72, 0x70, 0x63, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72,
+	0x2e, 0x41, 0x64, 0x6d, 0x69, 0x6e, 0x55, 0x73, 0x65, 0x72, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x1a, 0x1f, 0x2e, 0x67, 0x72, 0x70, 0x63, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2e, 0x41,
+	0x64, 0x6d, 0x69, 0x6e, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
+	0x73, 0x65, 0x42, 0x1b, 0x5a, 0x19, 0x67, 0x72, 0x70, 0x63, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72,
+	0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x73, 0x68, 0x6f, 0x72, 0x74, 0x65, 0x6e, 0x65, 0x72, 0x62,
+	0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_internal_server_grpcserver_protoshortener_shortener_proto_rawDescOnce sync.Once
+	file_internal_server_grpcserver_protoshortener_shortener_proto_rawDescData = file_internal_server_grpcserver_protoshortener_shortener_proto_rawDesc
+)
+
+func file_internal_server_grpcserver_protoshortener_shortener_proto_rawDescGZIP() []byte {
+	file_internal_server_grpcserver_protoshortener_shortener_proto_rawDescOnce.Do(func() {
+		file_internal_server_grpcserver_protoshortener_shortener_proto_rawDescData = protoimpl.X.CompressGZIP(file_internal_server_grpcserver_protoshortener_shortener_proto_rawDescData)
+	})
+	return file_internal_server_grpcserver_protoshortener_shortener_proto_rawDescData
+}
+
+var file_internal_server_grpcserver_protoshortener_shortener_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes = make([]protoimpl.MessageInfo, 25)
+var file_internal_server_grpcserver_protoshortener_shortener_proto_goTypes = []interface{}{
+	(ItemStatus)(0),                   // 0: grpcserver.ItemStatus
+	(*ShortenRequest)(nil),            // 1: grpcserver.ShortenRequest
+	(*ShortenResponse)(nil),           // 2: grpcserver.ShortenResponse
+	(*ShortenBatchRequest)(nil),       // 3: grpcserver.ShortenBatchRequest
+	(*ShortenBatchResponse)(nil),      // 4: grpcserver.ShortenBatchResponse
+	(*BatchItemResult)(nil),           // 5: grpcserver.BatchItemResult
+	(*URLwId)(nil),                    // 6: grpcserver.URLwId
+	(*GetLongRequest)(nil),            // 7: grpcserver.GetLongRequest
+	(*GetLongResponse)(nil),           // 8: grpcserver.GetLongResponse
+	(*GetLongByUserResponse)(nil),     // 9: grpcserver.GetLongByUserResponse
+	(*URLPair)(nil),                   // 10: grpcserver.URLPair
+	(*DeleteBatchRequest)(nil),        // 11: grpcserver.DeleteBatchRequest
+	(*DeleteBatchResponse)(nil),       // 12: grpcserver.DeleteBatchResponse
+	(*UpdateURLRequest)(nil),          // 13: grpcserver.UpdateURLRequest
+	(*UpdateURLResponse)(nil),         // 14: grpcserver.UpdateURLResponse
+	(*StatsResponse)(nil),             // 15: grpcserver.StatsResponse
+	(*PingStorageResponse)(nil),       // 16: grpcserver.PingStorageResponse
+	(*Link)(nil),                      // 17: grpcserver.Link
+	(*AdminGetLinkRequest)(nil),       // 18: grpcserver.AdminGetLinkRequest
+	(*AdminGetLinkResponse)(nil),      // 19: grpcserver.AdminGetLinkResponse
+	(*AdminSetDisabledRequest)(nil),   // 20: grpcserver.AdminSetDisabledRequest
+	(*AdminSetDisabledResponse)(nil),  // 21: grpcserver.AdminSetDisabledResponse
+	(*AdminUserRequest)(nil),          // 22: grpcserver.AdminUserRequest
+	(*AdminGetUserLinksResponse)(nil), // 23: grpcserver.AdminGetUserLinksResponse
+	(*AdminDeleteResponse)(nil),       // 24: grpcserver.AdminDeleteResponse
+	(*Dummy)(nil),                     // 25: grpcserver.Dummy
+}
+var file_internal_server_grpcserver_protoshortener_shortener_proto_depIdxs = []int32{
+	6,  // 0: grpcserver.ShortenBatchRequest.data:type_name -> grpcserver.URLwId
+	6,  // 1: grpcserver.ShortenBatchResponse.data:type_name -> grpcserver.URLwId
+	5,  // 2: grpcserver.ShortenBatchResponse.results:type_name -> grpcserver.BatchItemResult
+	0,  // 3: grpcserver.BatchItemResult.status:type_name -> grpcserver.ItemStatus
+	10, // 4: grpcserver.GetLongByUserResponse.links:type_name -> grpcserver.URLPair
+	17, // 5: grpcserver.AdminGetLinkResponse.link:type_name -> grpcserver.Link
+	17, // 6: grpcserver.AdminGetUserLinksResponse.links:type_name -> grpcserver.Link
+	1,  // 7: grpcserver.Shortener.Shorten:input_type -> grpcserver.ShortenRequest
+	3,  // 8: grpcserver.Shortener.ShortenBatch:input_type -> grpcserver.ShortenBatchRequest
+	7,  // 9: grpcserver.Shortener.GetLong:input_type -> grpcserver.GetLongRequest
+	25, // 10: grpcserver.Shortener.GetLongByUser:input_type -> grpcserver.Dummy
+	11, // 11: grpcserver.Shortener.DeleteBatch:input_type -> grpcserver.DeleteBatchRequest
+	13, // 12: grpcserver.Shortener.UpdateURL:input_type -> grpcserver.UpdateURLRequest
+	25, // 13: grpcserver.Shortener.Stats:input_type -> grpcserver.Dummy
+	25, // 14: grpcserver.Shortener.PingStorage:input_type -> grpcserver.Dummy
+	6,  // 15: grpcserver.Shortener.ShortenStream:input_type -> grpcserver.URLwId
+	25, // 16: grpcserver.Shortener.ListMyURLs:input_type -> grpcserver.Dummy
+	18, // 17: grpcserver.ShortenerAdmin.GetLink:input_type -> grpcserver.AdminGetLinkRequest
+	20, // 18: grpcserver.ShortenerAdmin.SetDisabled:input_type -> grpcserver.AdminSetDisabledRequest
+	22, // 19: grpcserver.ShortenerAdmin.GetUserLinks:input_type -> grpcserver.AdminUserRequest
+	22, // 20: grpcserver.ShortenerAdmin.DeleteUser:input_type -> grpcserver.AdminUserRequest
+	22, // 21: grpcserver.ShortenerAdmin.DeleteSessions:input_type -> grpcserver.AdminUserRequest
+	2,  // 22: grpcserver.Shortener.Shorten:output_type -> grpcserver.ShortenResponse
+	4,  // 23: grpcserver.Shortener.ShortenBatch:output_type -> grpcserver.ShortenBatchResponse
+	8,  // 24: grpcserver.Shortener.GetLong:output_type -> grpcserver.GetLongResponse
+	9,  // 25: grpcserver.Shortener.GetLongByUser:output_type -> grpcserver.GetLongByUserResponse
+	12, // 26: grpcserver.Shortener.DeleteBatch:output_type -> grpcserver.DeleteBatchResponse
+	14, // 27: grpcserver.Shortener.UpdateURL:output_type -> grpcserver.UpdateURLResponse
+	15, // 28: grpcserver.Shortener.Stats:output_type -> grpcserver.StatsResponse
+	16, // 29: grpcserver.Shortener.PingStorage:output_type -> grpcserver.PingStorageResponse
+	5,  // 30: grpcserver.Shortener.ShortenStream:output_type -> grpcserver.BatchItemResult
+	17, // 31: grpcserver.Shortener.ListMyURLs:output_type -> grpcserver.Link
+	19, // 32: grpcserver.ShortenerAdmin.GetLink:output_type -> grpcserver.AdminGetLinkResponse
+	21, // 33: grpcserver.ShortenerAdmin.SetDisabled:output_type -> grpcserver.AdminSetDisabledResponse
+	23, // 34: grpcserver.ShortenerAdmin.GetUserLinks:output_type -> grpcserver.AdminGetUserLinksResponse
+	24, // 35: grpcserver.ShortenerAdmin.DeleteUser:output_type -> grpcserver.AdminDeleteResponse
+	24, // 36: grpcserver.ShortenerAdmin.DeleteSessions:output_type -> grpcserver.AdminDeleteResponse
+	22, // [22:37] is the sub-list for method output_type
+	7,  // [7:22] is the sub-list for method input_type
+	7,  // [7:7] is the sub-list for extension type_name
+	7,  // [7:7] is the sub-list for extension extendee
+	0,  // [0:7] is the sub-list for field type_name
+}
+
+func init() { file_internal_server_grpcserver_protoshortener_shortener_proto_init() }
+func file_internal_server_grpcserver_protoshortener_shortener_proto_init() {
+	if File_internal_server_grpcserver_protoshortener_shortener_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ShortenRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
 				return &v.unknownFields
 			default:
 				return nil
 			}
 		}
-		file_internal_server_gRPCServer_protoshortener_shortener_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+		file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ShortenResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*ShortenBatchRequest); i {
 			case 0:
 				return &v.state
@@ -858,7 +1779,7 @@ func file_internal_server_gRPCServer_protoshortener_shortener_proto_init() {
 				return nil
 			}
 		}
-		file_internal_server_gRPCServer_protoshortener_shortener_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+		file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*ShortenBatchResponse); i {
 			case 0:
 				return &v.state
@@ -870,7 +1791,19 @@ func file_internal_server_gRPCServer_protoshortener_shortener_proto_init() {
 				return nil
 			}
 		}
-		file_internal_server_gRPCServer_protoshortener_shortener_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+		file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*BatchItemResult); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*URLwId); i {
 			case 0:
 				return &v.state
@@ -882,7 +1815,7 @@ func file_internal_server_gRPCServer_protoshortener_shortener_proto_init() {
 				return nil
 			}
 		}
-		file_internal_server_gRPCServer_protoshortener_shortener_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+		file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*GetLongRequest); i {
 			case 0:
 				return &v.state
@@ -894,7 +1827,7 @@ func file_internal_server_gRPCServer_protoshortener_shortener_proto_init() {
 				return nil
 			}
 		}
-		file_internal_server_gRPCServer_protoshortener_shortener_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+		file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*GetLongResponse); i {
 			case 0:
 				return &v.state
@@ -906,7 +1839,7 @@ func file_internal_server_gRPCServer_protoshortener_shortener_proto_init() {
 				return nil
 			}
 		}
-		file_internal_server_gRPCServer_protoshortener_shortener_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+		file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*GetLongByUserResponse); i {
 			case 0:
 				return &v.state
@@ -918,7 +1851,19 @@ func file_internal_server_gRPCServer_protoshortener_shortener_proto_init() {
 				return nil
 			}
 		}
-		file_internal_server_gRPCServer_protoshortener_shortener_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+		file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*URLPair); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*DeleteBatchRequest); i {
 			case 0:
 				return &v.state
@@ -930,7 +1875,7 @@ func file_internal_server_gRPCServer_protoshortener_shortener_proto_init() {
 				return nil
 			}
 		}
-		file_internal_server_gRPCServer_protoshortener_shortener_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+		file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*DeleteBatchResponse); i {
 			case 0:
 				return &v.state
@@ -942,7 +1887,31 @@ func file_internal_server_gRPCServer_protoshortener_shortener_proto_init() {
 				return nil
 			}
 		}
-		file_internal_server_gRPCServer_protoshortener_shortener_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
+		file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes[12].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*UpdateURLRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes[13].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*UpdateURLResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes[14].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*StatsResponse); i {
 			case 0:
 				return &v.state
@@ -954,7 +1923,7 @@ func file_internal_server_gRPCServer_protoshortener_shortener_proto_init() {
 				return nil
 			}
 		}
-		file_internal_server_gRPCServer_protoshortener_shortener_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
+		file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes[15].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*PingStorageResponse); i {
 			case 0:
 				return &v.state
@@ -966,7 +1935,103 @@ func file_internal_server_gRPCServer_protoshortener_shortener_proto_init() {
 				return nil
 			}
 		}
-		file_internal_server_gRPCServer_protoshortener_shortener_proto_msgTypes[12].Exporter = func(v interface{}, i int) interface{} {
+		file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes[16].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Link); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes[17].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*AdminGetLinkRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes[18].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*AdminGetLinkResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes[19].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*AdminSetDisabledRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes[20].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*AdminSetDisabledResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes[21].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*AdminUserRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes[22].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*AdminGetUserLinksResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes[23].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*AdminDeleteResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes[24].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*Dummy); i {
 			case 0:
 				return &v.state
@@ -983,18 +2048,19 @@ func file_internal_server_gRPCServer_protoshortener_shortener_proto_init() {
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
-			RawDescriptor: file_internal_server_gRPCServer_protoshortener_shortener_proto_rawDesc,
-			NumEnums:      0,
-			NumMessages:   13,
+			RawDescriptor: file_internal_server_grpcserver_protoshortener_shortener_proto_rawDesc,
+			NumEnums:      1,
+			NumMessages:   25,
 			NumExtensions: 0,
-			NumServices:   1,
+			NumServices:   2,
 		},
-		GoTypes:           file_internal_server_gRPCServer_protoshortener_shortener_proto_goTypes,
-		DependencyIndexes: file_internal_server_gRPCServer_protoshortener_shortener_proto_depIdxs,
-		MessageInfos:      file_internal_server_gRPCServer_protoshortener_shortener_proto_msgTypes,
+		GoTypes:           file_internal_server_grpcserver_protoshortener_shortener_proto_goTypes,
+		DependencyIndexes: file_internal_server_grpcserver_protoshortener_shortener_proto_depIdxs,
+		EnumInfos:         file_internal_server_grpcserver_protoshortener_shortener_proto_enumTypes,
+		MessageInfos:      file_internal_server_grpcserver_protoshortener_shortener_proto_msgTypes,
 	}.Build()
-	File_internal_server_gRPCServer_protoshortener_shortener_proto = out.File
-	file_internal_server_gRPCServer_protoshortener_shortener_proto_rawDesc = nil
-	file_internal_server_gRPCServer_protoshortener_shortener_proto_goTypes = nil
-	file_internal_server_gRPCServer_protoshortener_shortener_proto_depIdxs = nil
+	File_internal_server_grpcserver_protoshortener_shortener_proto = out.File
+	file_internal_server_grpcserver_protoshortener_shortener_proto_rawDesc = nil
+	file_internal_server_grpcserver_protoshortener_shortener_proto_goTypes = nil
+	file_internal_server_grpcserver_protoshortener_shortener_proto_depIdxs = nil
 }
@@ -1,7 +1,7 @@
 // Code generated by protoc-gen-go-grpc. DO NOT EDIT.
 // versions:
 // - protoc-gen-go-grpc v1.2.0
-// - protoc             v3.12.4
+// - protoc             (unknown)
 // source: internal/server/grpcserver/protoshortener/shortener.proto
 
 package protoshortener
@@ -27,8 +27,14 @@ type ShortenerClient interface {
 	GetLong(ctx context.Context, in *GetLongRequest, opts ...grpc.CallOption) (*GetLongResponse, error)
 	GetLongByUser(ctx context.Context, in *Dummy, opts ...grpc.CallOption) (*GetLongByUserResponse, error)
 	DeleteBatch(ctx context.Context, in *DeleteBatchRequest, opts ...grpc.CallOption) (*DeleteBatchResponse, error)
+	UpdateURL(ctx context.Context, in *UpdateURLRequest, opts ...grpc.CallOption) (*UpdateURLResponse, error)
 	Stats(ctx context.Context, in *Dummy, opts ...grpc.CallOption) (*StatsResponse, error)
 	PingStorage(ctx context.Context, in *Dummy, opts ...grpc.CallOption) (*PingStorageResponse, error)
+	// ShortenStream stores every received url as it arrives
+	// and returns its result with the id it was sent with.
+	ShortenStream(ctx context.Context, opts ...grpc.CallOption) (Shortener_ShortenStreamClient, error)
+	// ListMyURLs returns links of the session including deleted ones.
+	ListMyURLs(ctx context.Context, in *Dummy, opts ...grpc.CallOption) (Shortener_ListMyURLsClient, error)
 }
 
 type shortenerClient struct {
@@ -84,6 +90,15 @@ func (c *shortenerClient) DeleteBatch(ctx context.Context, in *DeleteBatchReques
 	return out, nil
 }
 
+func (c *shortenerClient) UpdateURL(ctx context.Context, in *UpdateURLRequest, opts ...grpc.CallOption) (*UpdateURLResponse, error) {
+	out := new(UpdateURLResponse)
+	err := c.cc.Invoke(ctx, "/grpcserver.Shortener/UpdateURL", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *shortenerClient) Stats(ctx context.Context, in *Dummy, opts ...grpc.CallOption) (*StatsResponse, error) {
 	out := new(StatsResponse)
 	err := c.cc.Invoke(ctx, "/grpcserver.Shortener/Stats", in, out, opts...)
@@ -102,6 +117,69 @@ func (c *shortenerClient) PingStorage(ctx context.Context, in *Dummy, opts ...gr
 	return out, nil
 }
 
+func (c *shortenerClient) ShortenStream(ctx context.Context, opts ...grpc.CallOption) (Shortener_ShortenStreamClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Shortener_ServiceDesc.Streams[0], "/grpcserver.Shortener/ShortenStream", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &shortenerShortenStreamClient{stream}
+	return x, nil
+}
+
+type Shortener_ShortenStreamClient interface {
+	Send(*URLwId) error
+	Recv() (*BatchItemResult, error)
+	grpc.ClientStream
+}
+
+type shortenerShortenStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *shortenerShortenStreamClient) Send(m *URLwId) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *shortenerShortenStreamClient) Recv() (*BatchItemResult, error) {
+	m := new(BatchItemResult)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *shortenerClient) ListMyURLs(ctx context.Context, in *Dummy, opts ...grpc.CallOption) (Shortener_ListMyURLsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Shortener_ServiceDesc.Streams[1], "/grpcserver.Shortener/ListMyURLs", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &shortenerListMyURLsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Shortener_ListMyURLsClient interface {
+	Recv() (*Link, error)
+	grpc.ClientStream
+}
+
+type shortenerListMyURLsClient struct {
+	grpc.ClientStream
+}
+
+func (x *shortenerListMyURLsClient) Recv() (*Link, error) {
+	m := new(Link)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // ShortenerServer is the server API for Shortener service.
 // All implementations must embed UnimplementedShortenerServer
 // for forward compatibility
@@ -111,8 +189,14 @@ type ShortenerServer interface {
 	GetLong(context.Context, *GetLongRequest) (*GetLongResponse, error)
 	GetLongByUser(context.Context, *Dummy) (*GetLongByUserResponse, error)
 	DeleteBatch(context.Context, *DeleteBatchRequest) (*DeleteBatchResponse, error)
+	UpdateURL(context.Context, *UpdateURLRequest) (*UpdateURLResponse, error)
 	Stats(context.Context, *Dummy) (*StatsResponse, error)
 	PingStorage(context.Context, *Dummy) (*PingStorageResponse, error)
+	// ShortenStream stores every received url as it arrives
+	// and returns its result with the id it was sent with.
+	ShortenStream(Shortener_ShortenStreamServer) error
+	// ListMyURLs returns links of the session including deleted ones.
+	ListMyURLs(*Dummy, Shortener_ListMyURLsServer) error
 	mustEmbedUnimplementedShortenerServer()
 }
 
@@ -135,12 +219,21 @@ func (UnimplementedShortenerServer) GetLongByUser(context.Context, *Dummy) (*Get
 func (UnimplementedShortenerServer) DeleteBatch(context.Context, *DeleteBatchRequest) (*DeleteBatchResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method DeleteBatch not implemented")
 }
+func (UnimplementedShortenerServer) UpdateURL(context.Context, *UpdateURLRequest) (*UpdateURLResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateURL not implemented")
+}
 func (UnimplementedShortenerServer) Stats(context.Context, *Dummy) (*StatsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Stats not implemented")
 }
 func (UnimplementedShortenerServer) PingStorage(context.Context, *Dummy) (*PingStorageResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method PingStorage not implemented")
 }
+func (UnimplementedShortenerServer) ShortenStream(Shortener_ShortenStreamServer) error {
+	return status.Errorf(codes.Unimplemented, "method ShortenStream not implemented")
+}
+func (UnimplementedShortenerServer) ListMyURLs(*Dummy, Shortener_ListMyURLsServer) error {
+	return status.Errorf(codes.Unimplemented, "method ListMyURLs not implemented")
+}
 func (UnimplementedShortenerServer) mustEmbedUnimplementedShortenerServer() {}
 
 // UnsafeShortenerServer may be embedded to opt out of forward compatibility for this service.
@@ -244,6 +337,24 @@ func _Shortener_DeleteBatch_Handler(srv interface{}, ctx context.Context, dec fu
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Shortener_UpdateURL_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateURLRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServer).UpdateURL(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/grpcserver.Shortener/UpdateURL",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServer).UpdateURL(ctx, req.(*UpdateURLRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _Shortener_Stats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(Dummy)
 	if err := dec(in); err != nil {
@@ -280,6 +391,53 @@ func _Shortener_PingStorage_Handler(srv interface{}, ctx context.Context, dec fu
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Shortener_ShortenStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(ShortenerServer).ShortenStream(&shortenerShortenStreamServer{stream})
+}
+
+type Shortener_ShortenStreamServer interface {
+	Send(*BatchItemResult) error
+	Recv() (*URLwId, error)
+	grpc.ServerStream
+}
+
+type shortenerShortenStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *shortenerShortenStreamServer) Send(m *BatchItemResult) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *shortenerShortenStreamServer) Recv() (*URLwId, error) {
+	m := new(URLwId)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _Shortener_ListMyURLs_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(Dummy)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ShortenerServer).ListMyURLs(m, &shortenerListMyURLsServer{stream})
+}
+
+type Shortener_ListMyURLsServer interface {
+	Send(*Link) error
+	grpc.ServerStream
+}
+
+type shortenerListMyURLsServer struct {
+	grpc.ServerStream
+}
+
+func (x *shortenerListMyURLsServer) Send(m *Link) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 // Shortener_ServiceDesc is the grpc.ServiceDesc for Shortener service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -307,6 +465,10 @@ var Shortener_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "DeleteBatch",
 			Handler:    _Shortener_DeleteBatch_Handler,
 		},
+		{
+			MethodName: "UpdateURL",
+			Handler:    _Shortener_UpdateURL_Handler,
+		},
 		{
 			MethodName: "Stats",
 			Handler:    _Shortener_Stats_Handler,
@@ -316,6 +478,248 @@ var Shortener_ServiceDesc = grpc.ServiceDesc{
 			Handler:    _Shortener_PingStorage_Handler,
 		},
 	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ShortenStream",
+			Handler:       _Shortener_ShortenStream_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "ListMyURLs",
+			Handler:       _Shortener_ListMyURLs_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "internal/server/grpcserver/protoshortener/shortener.proto",
+}
+
+// ShortenerAdminClient is the client API for ShortenerAdmin service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type ShortenerAdminClient interface {
+	GetLink(ctx context.Context, in *AdminGetLinkRequest, opts ...grpc.CallOption) (*AdminGetLinkResponse, error)
+	SetDisabled(ctx context.Context, in *AdminSetDisabledRequest, opts ...grpc.CallOption) (*AdminSetDisabledResponse, error)
+	GetUserLinks(ctx context.Context, in *AdminUserRequest, opts ...grpc.CallOption) (*AdminGetUserLinksResponse, error)
+	DeleteUser(ctx context.Context, in *AdminUserRequest, opts ...grpc.CallOption) (*AdminDeleteResponse, error)
+	DeleteSessions(ctx context.Context, in *AdminUserRequest, opts ...grpc.CallOption) (*AdminDeleteResponse, error)
+}
+
+type shortenerAdminClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewShortenerAdminClient(cc grpc.ClientConnInterface) ShortenerAdminClient {
+	return &shortenerAdminClient{cc}
+}
+
+func (c *shortenerAdminClient) GetLink(ctx context.Context, in *AdminGetLinkRequest, opts ...grpc.CallOption) (*AdminGetLinkResponse, error) {
+	out := new(AdminGetLinkResponse)
+	err := c.cc.Invoke(ctx, "/grpcserver.ShortenerAdmin/GetLink", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenerAdminClient) SetDisabled(ctx context.Context, in *AdminSetDisabledRequest, opts ...grpc.CallOption) (*AdminSetDisabledResponse, error) {
+	out := new(AdminSetDisabledResponse)
+	err := c.cc.Invoke(ctx, "/grpcserver.ShortenerAdmin/SetDisabled", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenerAdminClient) GetUserLinks(ctx context.Context, in *AdminUserRequest, opts ...grpc.CallOption) (*AdminGetUserLinksResponse, error) {
+	out := new(AdminGetUserLinksResponse)
+	err := c.cc.Invoke(ctx, "/grpcserver.ShortenerAdmin/GetUserLinks", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenerAdminClient) DeleteUser(ctx context.Context, in *AdminUserRequest, opts ...grpc.CallOption) (*AdminDeleteResponse, error) {
+	out := new(AdminDeleteResponse)
+	err := c.cc.Invoke(ctx, "/grpcserver.ShortenerAdmin/DeleteUser", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenerAdminClient) DeleteSessions(ctx context.Context, in *AdminUserRequest, opts ...grpc.CallOption) (*AdminDeleteResponse, error) {
+	out := new(AdminDeleteResponse)
+	err := c.cc.Invoke(ctx, "/grpcserver.ShortenerAdmin/DeleteSessions", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ShortenerAdminServer is the server API for ShortenerAdmin service.
+// All implementations must embed UnimplementedShortenerAdminServer
+// for forward compatibility
+type ShortenerAdminServer interface {
+	GetLink(context.Context, *AdminGetLinkRequest) (*AdminGetLinkResponse, error)
+	SetDisabled(context.Context, *AdminSetDisabledRequest) (*AdminSetDisabledResponse, error)
+	GetUserLinks(context.Context, *AdminUserRequest) (*AdminGetUserLinksResponse, error)
+	DeleteUser(context.Context, *AdminUserRequest) (*AdminDeleteResponse, error)
+	DeleteSessions(context.Context, *AdminUserRequest) (*AdminDeleteResponse, error)
+	mustEmbedUnimplementedShortenerAdminServer()
+}
+
+// UnimplementedShortenerAdminServer must be embedded to have forward compatible implementations.
+type UnimplementedShortenerAdminServer struct {
+}
+
+func (UnimplementedShortenerAdminServer) GetLink(context.Context, *AdminGetLinkRequest) (*AdminGetLinkResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetLink not implemented")
+}
+func (UnimplementedShortenerAdminServer) SetDisabled(context.Context, *AdminSetDisabledRequest) (*AdminSetDisabledResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetDisabled not implemented")
+}
+func (UnimplementedShortenerAdminServer) GetUserLinks(context.Context, *AdminUserRequest) (*AdminGetUserLinksResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetUserLinks not implemented")
+}
+func (UnimplementedShortenerAdminServer) DeleteUser(context.Context, *AdminUserRequest) (*AdminDeleteResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteUser not implemented")
+}
+func (UnimplementedShortenerAdminServer) DeleteSessions(context.Context, *AdminUserRequest) (*AdminDeleteResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteSessions not implemented")
+}
+func (UnimplementedShortenerAdminServer) mustEmbedUnimplementedShortenerAdminServer() {}
+
+// UnsafeShortenerAdminServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ShortenerAdminServer will
+// result in compilation errors.
+type UnsafeShortenerAdminServer interface {
+	mustEmbedUnimplementedShortenerAdminServer()
+}
+
+func RegisterShortenerAdminServer(s grpc.ServiceRegistrar, srv ShortenerAdminServer) {
+	s.RegisterService(&ShortenerAdmin_ServiceDesc, srv)
+}
+
+func _ShortenerAdmin_GetLink_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AdminGetLinkRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerAdminServer).GetLink(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/grpcserver.ShortenerAdmin/GetLink",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerAdminServer).GetLink(ctx, req.(*AdminGetLinkRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ShortenerAdmin_SetDisabled_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AdminSetDisabledRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerAdminServer).SetDisabled(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/grpcserver.ShortenerAdmin/SetDisabled",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerAdminServer).SetDisabled(ctx, req.(*AdminSetDisabledRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ShortenerAdmin_GetUserLinks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AdminUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerAdminServer).GetUserLinks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/grpcserver.ShortenerAdmin/GetUserLinks",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerAdminServer).GetUserLinks(ctx, req.(*AdminUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ShortenerAdmin_DeleteUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AdminUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerAdminServer).DeleteUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/grpcserver.ShortenerAdmin/DeleteUser",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerAdminServer).DeleteUser(ctx, req.(*AdminUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ShortenerAdmin_DeleteSessions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AdminUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerAdminServer).DeleteSessions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/grpcserver.ShortenerAdmin/DeleteSessions",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerAdminServer).DeleteSessions(ctx, req.(*AdminUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// ShortenerAdmin_ServiceDesc is the grpc.ServiceDesc for ShortenerAdmin service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var ShortenerAdmin_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "grpcserver.ShortenerAdmin",
+	HandlerType: (*ShortenerAdminServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetLink",
+			Handler:    _ShortenerAdmin_GetLink_Handler,
+		},
+		{
+			MethodName: "SetDisabled",
+			Handler:    _ShortenerAdmin_SetDisabled_Handler,
+		},
+		{
+			MethodName: "GetUserLinks",
+			Handler:    _ShortenerAdmin_GetUserLinks_Handler,
+		},
+		{
+			MethodName: "DeleteUser",
+			Handler:    _ShortenerAdmin_DeleteUser_Handler,
+		},
+		{
+			MethodName: "DeleteSessions",
+			Handler:    _ShortenerAdmin_DeleteSessions_Handler,
+		},
+	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "internal/server/grpcserver/protoshortener/shortener.proto",
 }
@@ -0,0 +1,386 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/runtime/protoiface"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/usa4ev/urlshortner/internal/qrcode"
+	psv2 "github.com/usa4ev/urlshortner/internal/server/grpcserver/protoshortener/v2"
+	"github.com/usa4ev/urlshortner/internal/shortener"
+	"github.com/usa4ev/urlshortner/internal/shortener/policy"
+	"github.com/usa4ev/urlshortner/internal/storage/database"
+	"github.com/usa4ev/urlshortner/internal/storage/model"
+	"github.com/usa4ev/urlshortner/internal/storage/storageerrors"
+)
+
+// errDomain is the domain of google.rpc.ErrorInfo reasons.
+const errDomain = "urlshortner"
+
+// Reasons of google.rpc.ErrorInfo details.
+const (
+	reasonBlocked  = "URL_BLOCKED"
+	reasonConflict = "URL_CONFLICT"
+	reasonDeleted  = "LINK_DELETED"
+	reasonDisabled = "LINK_DISABLED"
+	reasonNotOwner = "NOT_OWNER"
+)
+
+// serverV2 implements grpcserver.v2.Shortener service.
+// Calls are authorized by authInterceptor the same way as of Server.
+type serverV2 struct {
+	psv2.UnimplementedShortenerServer
+
+	srv *Server
+}
+
+func (s *serverV2) Shorten(ctx context.Context, in *psv2.ShortenRequest) (*psv2.Link, error) {
+	userID, err := getUserID(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	links := []model.Link{{OriginalURL: in.Url}}
+
+	errs, err := s.srv.shortener.StoreURLs(userID, links, true)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to store URL: %v", err.Error())
+	}
+
+	if errs[0] != nil {
+		return nil, s.linkError(errs[0], "url", links[0])
+	}
+
+	return linkToProtoV2(links[0]), nil
+}
+
+func (s *serverV2) ShortenBatch(ctx context.Context, in *psv2.ShortenBatchRequest) (*psv2.ShortenBatchResponse, error) {
+	userID, err := getUserID(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	links := make([]model.Link, len(in.Items))
+	for i, v := range in.Items {
+		links[i].OriginalURL = v.Url
+	}
+
+	errs, err := s.srv.shortener.StoreURLs(userID, links, !in.Partial)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to store URLs: %v", err.Error())
+	}
+
+	if !in.Partial {
+		for i, err := range errs {
+			if err != nil && !errors.Is(err, storageerrors.ErrBatchAborted) {
+				return nil, s.linkError(err, fmt.Sprintf("items[%v].url", i), links[i])
+			}
+		}
+	}
+
+	res := &psv2.ShortenBatchResponse{Results: make([]*psv2.BatchItemResult, len(in.Items))}
+	for i, v := range in.Items {
+		res.Results[i] = s.itemResult(v.CorrelationId, links[i], errs[i])
+	}
+
+	return res, nil
+}
+
+// ShortenStream stores items one by one as they are received.
+func (s *serverV2) ShortenStream(stream psv2.Shortener_ShortenStreamServer) error {
+	return shortenStream[*psv2.BatchItem, *psv2.BatchItemResult](s.srv, stream,
+		func(in *psv2.BatchItem) (string, string) { return in.CorrelationId, in.Url },
+		s.itemResult)
+}
+
+// itemResult returns the outcome of a batch item stored with err.
+func (s *serverV2) itemResult(correlationID string, link model.Link, err error) *psv2.BatchItemResult {
+	res := &psv2.BatchItemResult{CorrelationId: correlationID}
+
+	switch {
+	case err == nil:
+		res.Status = psv2.ItemStatus_ITEM_STATUS_CREATED
+		res.Link = linkToProtoV2(link)
+
+		return res
+	case errors.Is(err, storageerrors.ErrConflict):
+		res.Status = psv2.ItemStatus_ITEM_STATUS_CONFLICT
+		if stored, ok := s.storedLink(link); ok {
+			res.Link = linkToProtoV2(stored)
+		}
+	case errors.Is(err, shortener.ErrQuotaExceeded):
+		res.Status = psv2.ItemStatus_ITEM_STATUS_QUOTA_EXCEEDED
+	default:
+		res.Status = psv2.ItemStatus_ITEM_STATUS_INVALID
+	}
+
+	res.Description = err.Error()
+
+	return res
+}
+
+// storedLink returns the link the destination of link is stored with,
+// it may have an imported id.
+func (s *serverV2) storedLink(link model.Link) (model.Link, bool) {
+	stored, err := s.srv.shortener.FindLinkByURL(link.OriginalURL)
+	if err != nil {
+		return model.Link{}, false
+	}
+
+	return stored, true
+}
+
+// GetLink returns the link the id redirects to. Deleted, disabled
+// and blocked links are reported with the reason in ErrorInfo.
+func (s *serverV2) GetLink(ctx context.Context, in *psv2.GetLinkRequest) (*psv2.Link, error) {
+	link, err := s.srv.shortener.LoadLink(in.Id)
+	if err != nil {
+		return nil, s.linkError(err, "id", model.Link{ID: in.Id})
+	}
+
+	// FindURL checks the link the same way redirects do
+	if _, err := s.srv.shortener.FindURL(in.Id); err != nil {
+		return nil, s.linkError(err, "id", link)
+	}
+
+	return linkToProtoV2(link), nil
+}
+
+func (s *serverV2) ListLinks(ctx context.Context, in *psv2.ListLinksRequest) (*psv2.ListLinksResponse, error) {
+	userID, err := getUserID(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	links, err := s.srv.shortener.LoadLinksByUser(userID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to load URLs by user: %v", err.Error())
+	}
+
+	res := &psv2.ListLinksResponse{Links: make([]*psv2.Link, len(links))}
+	for i, v := range links {
+		res.Links[i] = linkToProtoV2(v)
+	}
+
+	return res, nil
+}
+
+func (s *serverV2) UpdateLink(ctx context.Context, in *psv2.UpdateLinkRequest) (*psv2.Link, error) {
+	userID, err := getUserID(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	link := model.Link{ID: in.Id, OriginalURL: in.Url}
+
+	originalURL, err := s.srv.shortener.NormalizeURL(in.Url)
+	if err != nil {
+		return nil, s.linkError(err, "url", link)
+	}
+
+	if err := s.srv.shortener.UpdateURL(userID, in.Id, originalURL); err != nil {
+		link.OriginalURL = originalURL
+		return nil, s.linkError(err, "id", link)
+	}
+
+	updated, err := s.srv.shortener.LoadLink(in.Id)
+	if err != nil {
+		return nil, s.linkError(err, "id", link)
+	}
+
+	return linkToProtoV2(updated), nil
+}
+
+func (s *serverV2) DeleteLinks(ctx context.Context, in *psv2.DeleteLinksRequest) (*psv2.DeleteLinksResponse, error) {
+	userID, err := getUserID(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	if err := s.srv.shortener.DeleteURLs(userID, in.Ids); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &psv2.DeleteLinksResponse{}, nil
+}
+
+// GetStats is only available to trusted subnets, see trustedInterceptor.
+func (s *serverV2) GetStats(ctx context.Context, in *psv2.GetStatsRequest) (*psv2.Stats, error) {
+	urls, users, err := s.srv.counts()
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &psv2.Stats{Urls: int64(urls), Users: int64(users)}, nil
+}
+
+func (s *serverV2) PingStorage(ctx context.Context, in *psv2.PingStorageRequest) (*psv2.PingStorageResponse, error) {
+	if err := database.Pingdb(s.srv.cfg.DBDSN()); err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+
+	return &psv2.PingStorageResponse{}, nil
+}
+
+// GetQRCode returns the QR code of the short url of the link,
+// it checks the link the same way GetLink does.
+func (s *serverV2) GetQRCode(ctx context.Context, in *psv2.GetQRCodeRequest) (*psv2.QRCode, error) {
+	opts, field, err := qrOptionsV2(in)
+	if err != nil {
+		return nil, invalidArgument(field, err)
+	}
+
+	link, err := s.GetLink(ctx, &psv2.GetLinkRequest{Id: in.Id})
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := qrcode.Encode(link.ShortUrl, opts)
+	switch {
+	case errors.Is(err, qrcode.ErrInvalidOptions):
+		// modules of the code do not fit in the size
+		return nil, invalidArgument("size", err)
+	case err != nil:
+		return nil, status.Errorf(codes.Internal, "failed to encode QR code: %v", err)
+	}
+
+	return &psv2.QRCode{ContentType: opts.Format.ContentType(), Data: data}, nil
+}
+
+var (
+	qrFormats = map[psv2.QRFormat]qrcode.Format{
+		psv2.QRFormat_QR_FORMAT_UNSPECIFIED: qrcode.PNG,
+		psv2.QRFormat_QR_FORMAT_PNG:         qrcode.PNG,
+		psv2.QRFormat_QR_FORMAT_SVG:         qrcode.SVG,
+	}
+	qrLevels = map[psv2.QRLevel]qrcode.Level{
+		psv2.QRLevel_QR_LEVEL_UNSPECIFIED: qrcode.M,
+		psv2.QRLevel_QR_LEVEL_L:           qrcode.L,
+		psv2.QRLevel_QR_LEVEL_M:           qrcode.M,
+		psv2.QRLevel_QR_LEVEL_Q:           qrcode.Q,
+		psv2.QRLevel_QR_LEVEL_H:           qrcode.H,
+	}
+)
+
+// qrOptionsV2 returns QR code options of the request, defaults for unset
+// fields, or the error and the name of the invalid field.
+func qrOptionsV2(in *psv2.GetQRCodeRequest) (qrcode.Options, string, error) {
+	var ok bool
+
+	opts := qrcode.DefaultOptions()
+
+	if opts.Format, ok = qrFormats[in.Format]; !ok {
+		return opts, "format", fmt.Errorf("unsupported format %v", in.Format)
+	}
+
+	if opts.Level, ok = qrLevels[in.Level]; !ok {
+		return opts, "level", fmt.Errorf("unsupported error correction level %v", in.Level)
+	}
+
+	if in.Size != 0 {
+		opts.Size = int(in.Size)
+	}
+
+	if in.Margin != nil {
+		opts.Margin = int(*in.Margin)
+	}
+
+	if err := opts.Validate(); err != nil {
+		if opts.Margin < 0 || opts.Margin > qrcode.MaxMargin {
+			return opts, "margin", err
+		}
+
+		return opts, "size", err
+	}
+
+	return opts, "", nil
+}
+
+// invalidArgument returns an InvalidArgument status error
+// with BadRequest details of the field.
+func invalidArgument(field string, err error) error {
+	return statusError(codes.InvalidArgument, err.Error(), &errdetails.BadRequest{
+		FieldViolations: []*errdetails.BadRequest_FieldViolation{{Field: field, Description: err.Error()}},
+	})
+}
+
+// linkError returns a status error for err with google.rpc error details.
+// field names the request field that holds an invalid value.
+func (s *serverV2) linkError(err error, field string, link model.Link) error {
+	switch {
+	case errors.Is(err, shortener.ErrInvalidURL), errors.Is(err, shortener.ErrInvalidID):
+		return invalidArgument(field, err)
+	case errors.Is(err, policy.ErrBlocked):
+		return statusError(codes.PermissionDenied, err.Error(), errorInfo(reasonBlocked, nil))
+	case errors.Is(err, shortener.ErrQuotaExceeded):
+		return statusError(codes.ResourceExhausted, err.Error(), &errdetails.QuotaFailure{
+			Violations: []*errdetails.QuotaFailure_Violation{{Subject: "user", Description: err.Error()}},
+		})
+	case errors.Is(err, storageerrors.ErrConflict):
+		var metadata map[string]string
+		if stored, ok := s.storedLink(link); ok {
+			metadata = map[string]string{"id": stored.ID, "short_url": stored.ShortURL}
+		}
+
+		return statusError(codes.AlreadyExists, err.Error(), errorInfo(reasonConflict, metadata))
+	case errors.Is(err, storageerrors.ErrNotFound):
+		return statusError(codes.NotFound, err.Error(), &errdetails.ResourceInfo{
+			ResourceType: "link",
+			ResourceName: link.ID,
+			Description:  err.Error(),
+		})
+	case errors.Is(err, storageerrors.ErrURLGone):
+		return statusError(codes.NotFound, err.Error(), errorInfo(reasonDeleted, map[string]string{"id": link.ID}))
+	case errors.Is(err, storageerrors.ErrURLDisabled):
+		return statusError(codes.PermissionDenied, err.Error(), errorInfo(reasonDisabled, map[string]string{"id": link.ID}))
+	case errors.Is(err, storageerrors.ErrNotOwner):
+		return statusError(codes.PermissionDenied, err.Error(), errorInfo(reasonNotOwner, map[string]string{"id": link.ID}))
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
+
+func errorInfo(reason string, metadata map[string]string) *errdetails.ErrorInfo {
+	return &errdetails.ErrorInfo{Reason: reason, Domain: errDomain, Metadata: metadata}
+}
+
+// statusError returns a status error with details attached.
+func statusError(code codes.Code, msg string, details ...protoiface.MessageV1) error {
+	st := status.New(code, msg)
+
+	withDetails, err := st.WithDetails(details...)
+	if err != nil {
+		// details are optional
+		return st.Err()
+	}
+
+	return withDetails.Err()
+}
+
+// retryInfo returns details of a rate limited call.
+func retryInfo(wait time.Duration) *errdetails.RetryInfo {
+	return &errdetails.RetryInfo{RetryDelay: durationpb.New(wait)}
+}
+
+func linkToProtoV2(link model.Link) *psv2.Link {
+	res := &psv2.Link{
+		Id:          link.ID,
+		ShortUrl:    link.ShortURL,
+		OriginalUrl: link.OriginalURL,
+		Deleted:     link.Deleted,
+	}
+
+	if !link.CreatedAt.IsZero() {
+		res.CreatedAt = timestamppb.New(link.CreatedAt)
+	}
+
+	return res
+}
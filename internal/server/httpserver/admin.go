@@ -0,0 +1,107 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/go-chi/chi"
+
+	"github.com/usa4ev/urlshortner/internal/server/httpserver/apierror"
+)
+
+// adminLoadLink responds with the link stored by id
+// as a model.Link JSON structure.
+func (srv *Server) adminLoadLink(w http.ResponseWriter, r *http.Request) {
+	link, err := srv.shortener.LoadLink(chi.URLParam(r, "id"))
+	if err != nil {
+		apierror.Write(w, r, err)
+
+		return
+	}
+
+	writeJSON(w, link)
+}
+
+// adminFindLink responds with the link that has the destination
+// passed in the url query parameter.
+func (srv *Server) adminFindLink(w http.ResponseWriter, r *http.Request) {
+	url := r.URL.Query().Get("url")
+	if url == "" {
+		apierror.Write(w, r, apierror.BadRequest("url query parameter is required"))
+
+		return
+	}
+
+	link, err := srv.shortener.FindLinkByURL(url)
+	if err != nil {
+		apierror.Write(w, r, err)
+
+		return
+	}
+
+	writeJSON(w, link)
+}
+
+// adminSetDisabled returns a handler that disables or enables redirects
+// for the link stored by id.
+func (srv *Server) adminSetDisabled(disabled bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := srv.shortener.SetDisabled(chi.URLParam(r, "id"), disabled); err != nil {
+			apierror.Write(w, r, err)
+
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// adminLoadUserLinks responds with all links of the user
+// including deleted and disabled ones.
+func (srv *Server) adminLoadUserLinks(w http.ResponseWriter, r *http.Request) {
+	links, err := srv.shortener.LoadLinksByUser(chi.URLParam(r, "userID"))
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal("failed to load data: %v", err))
+
+		return
+	}
+
+	writeJSON(w, links)
+}
+
+// adminDeleteUser removes all links and sessions of the user.
+func (srv *Server) adminDeleteUser(w http.ResponseWriter, r *http.Request) {
+	n, err := srv.shortener.DeleteUser(chi.URLParam(r, "userID"))
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal("deletion failed: %v", err))
+
+		return
+	}
+
+	writeJSON(w, deletedData{Deleted: n})
+}
+
+// adminDeleteSessions invalidates all sessions of the user.
+// The next request of the user opens a new session.
+func (srv *Server) adminDeleteSessions(w http.ResponseWriter, r *http.Request) {
+	n, err := srv.shortener.DeleteSessions(chi.URLParam(r, "userID"))
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal("deletion failed: %v", err))
+
+		return
+	}
+
+	writeJSON(w, deletedData{Deleted: n})
+}
+
+// writeJSON responds with v encoded as JSON.
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", ctJSON)
+	enc := json.NewEncoder(w)
+
+	// the status is already sent, the error can only be logged
+	if err := enc.Encode(v); err != nil {
+		log.Printf("failed to encode message: %v", err)
+	}
+}
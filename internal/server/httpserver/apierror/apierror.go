@@ -0,0 +1,165 @@
+// Package apierror writes error responses of the HTTP API
+// as a {code, message, details} JSON object.
+package apierror
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/usa4ev/urlshortner/internal/shortener"
+	"github.com/usa4ev/urlshortner/internal/shortener/policy"
+	"github.com/usa4ev/urlshortner/internal/storage/storageerrors"
+)
+
+// Codes of errors, they identify the error regardless of the message.
+const (
+	CodeBadRequest    = "bad_request"
+	CodeInvalidURL    = "invalid_url"
+	CodeInvalidID     = "invalid_id"
+	CodeInvalidTitle  = "invalid_title"
+	CodeBlocked       = "blocked"
+	CodeConflict      = "conflict"
+	CodeNotFound      = "not_found"
+	CodeGone          = "gone"
+	CodeDisabled      = "disabled"
+	CodeNotOwner      = "not_owner"
+	CodeQuotaExceeded = "quota_exceeded"
+	CodeRateLimited   = "rate_limited"
+	CodeUnauthorized  = "unauthorized"
+	CodeForbidden     = "forbidden"
+	CodeInternal      = "internal"
+)
+
+const (
+	ctJSON = "application/json"
+	ctText = "text/plain"
+)
+
+// Error is an error response with HTTP status Status.
+type Error struct {
+	Status  int                    `json:"-"`
+	Code    string                 `json:"code"`
+	Message string                 `json:"message"`
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+// New returns an error response.
+func New(status int, code, message string) *Error {
+	return &Error{Status: status, Code: code, Message: message}
+}
+
+// BadRequest returns a 400 Bad Request error response.
+func BadRequest(format string, a ...interface{}) *Error {
+	return New(http.StatusBadRequest, CodeBadRequest, fmt.Sprintf(format, a...))
+}
+
+// Internal returns a 500 Internal Server Error response.
+func Internal(format string, a ...interface{}) *Error {
+	return New(http.StatusInternalServerError, CodeInternal, fmt.Sprintf(format, a...))
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// With sets detail key of e to v and returns e.
+func (e *Error) With(key string, v interface{}) *Error {
+	if e.Details == nil {
+		e.Details = make(map[string]interface{})
+	}
+
+	e.Details[key] = v
+
+	return e
+}
+
+// From returns the error response of err. Errors of URL validation,
+// the shortener and storages are mapped to their statuses,
+// unknown errors are 500 Internal Server Error.
+func From(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+
+	msg := err.Error()
+
+	switch {
+	case errors.Is(err, shortener.ErrInvalidURL):
+		return New(http.StatusBadRequest, CodeInvalidURL, msg)
+	case errors.Is(err, shortener.ErrInvalidID):
+		return New(http.StatusBadRequest, CodeInvalidID, msg)
+	case errors.Is(err, shortener.ErrInvalidTitle):
+		return New(http.StatusBadRequest, CodeInvalidTitle, msg)
+	case errors.Is(err, policy.ErrBlocked):
+		return New(http.StatusForbidden, CodeBlocked, msg)
+	case errors.Is(err, shortener.ErrQuotaExceeded):
+		// the quota does not reset, retrying is of no use
+		return New(http.StatusForbidden, CodeQuotaExceeded, msg)
+	case errors.Is(err, storageerrors.ErrConflict):
+		return New(http.StatusConflict, CodeConflict, msg)
+	case errors.Is(err, storageerrors.ErrNotFound):
+		return New(http.StatusNotFound, CodeNotFound, msg)
+	case errors.Is(err, storageerrors.ErrURLGone):
+		return New(http.StatusGone, CodeGone, msg)
+	case errors.Is(err, storageerrors.ErrURLDisabled):
+		return New(http.StatusForbidden, CodeDisabled, msg)
+	case errors.Is(err, storageerrors.ErrNotOwner):
+		return New(http.StatusForbidden, CodeNotOwner, msg)
+	default:
+		return New(http.StatusInternalServerError, CodeInternal, msg)
+	}
+}
+
+// Write writes the error response of err, see From. The body is JSON
+// unless the client only accepts plain text, the message is written then.
+func Write(w http.ResponseWriter, r *http.Request, err error) {
+	e := From(err)
+
+	w.Header().Del("Content-Length")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	if !acceptsJSON(r.Header.Get("Accept")) {
+		w.Header().Set("Content-Type", ctText+"; charset=utf-8")
+		w.WriteHeader(e.Status)
+		fmt.Fprintln(w, e.Message)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", ctJSON)
+	w.WriteHeader(e.Status)
+	// the status is already sent
+	_ = json.NewEncoder(w).Encode(e)
+}
+
+// acceptsJSON reports whether a client with the Accept header accept
+// takes a JSON response, that is any client that does not ask
+// for plain text only.
+func acceptsJSON(accept string) bool {
+	if accept == "" {
+		return true
+	}
+
+	text := false
+
+	for _, v := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(v))
+		if err != nil || params["q"] == "0" {
+			continue
+		}
+
+		switch mediaType {
+		case ctJSON, "application/*", "*/*":
+			return true
+		case ctText, "text/*":
+			text = true
+		}
+	}
+
+	return !text
+}
@@ -0,0 +1,74 @@
+package apierror
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/usa4ev/urlshortner/internal/shortener"
+	"github.com/usa4ev/urlshortner/internal/shortener/policy"
+	"github.com/usa4ev/urlshortner/internal/storage/storageerrors"
+)
+
+func TestFrom(t *testing.T) {
+	tests := []struct {
+		err        error
+		wantStatus int
+		wantCode   string
+	}{
+		{fmt.Errorf("%w: URL is empty", shortener.ErrInvalidURL), http.StatusBadRequest, CodeInvalidURL},
+		{shortener.ErrInvalidID, http.StatusBadRequest, CodeInvalidID},
+		{fmt.Errorf("%w: title has control characters", shortener.ErrInvalidTitle), http.StatusBadRequest, CodeInvalidTitle},
+		{fmt.Errorf("%w: example.com", policy.ErrBlocked), http.StatusForbidden, CodeBlocked},
+		{shortener.ErrQuotaExceeded, http.StatusForbidden, CodeQuotaExceeded},
+		{storageerrors.ErrConflict, http.StatusConflict, CodeConflict},
+		{storageerrors.ErrNotFound, http.StatusNotFound, CodeNotFound},
+		{storageerrors.ErrURLGone, http.StatusGone, CodeGone},
+		{storageerrors.ErrURLDisabled, http.StatusForbidden, CodeDisabled},
+		{storageerrors.ErrNotOwner, http.StatusForbidden, CodeNotOwner},
+		{errors.New("connection refused"), http.StatusInternalServerError, CodeInternal},
+		{fmt.Errorf("wrapped: %w", BadRequest("no url")), http.StatusBadRequest, CodeBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			got := From(tt.err)
+			assert.Equal(t, tt.wantStatus, got.Status)
+			assert.Equal(t, tt.wantCode, got.Code)
+		})
+	}
+}
+
+func TestWrite(t *testing.T) {
+	tests := []struct {
+		name   string
+		accept string
+		wantCT string
+		want   string
+	}{
+		{"no accept", "", "application/json", `{"code":"conflict","message":"already exists","details":{"id":"a"}}` + "\n"},
+		{"any", "*/*", "application/json", `{"code":"conflict","message":"already exists","details":{"id":"a"}}` + "\n"},
+		{"browser", "text/html,application/xhtml+xml,*/*;q=0.8", "application/json", `{"code":"conflict","message":"already exists","details":{"id":"a"}}` + "\n"},
+		{"plain text", "text/plain", "text/plain; charset=utf-8", "already exists\n"},
+		{"json refused", "text/plain, application/json;q=0", "text/plain; charset=utf-8", "already exists\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			if tt.accept != "" {
+				r.Header.Set("Accept", tt.accept)
+			}
+
+			w := httptest.NewRecorder()
+			Write(w, r, New(http.StatusConflict, CodeConflict, "already exists").With("id", "a"))
+
+			require.Equal(t, http.StatusConflict, w.Code)
+			assert.Equal(t, tt.wantCT, w.Header().Get("Content-Type"))
+			assert.Equal(t, tt.want, w.Body.String())
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package httpserver
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/usa4ev/urlshortner/internal/bulk"
+	"github.com/usa4ev/urlshortner/internal/server/httpserver/apierror"
+	"github.com/usa4ev/urlshortner/internal/server/httpserver/middleware"
+	"github.com/usa4ev/urlshortner/internal/storage/model"
+)
+
+// importURLs stores links of text/csv or application/x-ndjson body
+// keeping their ids and responds with an importReport JSON structure.
+func (srv *Server) importURLs(w http.ResponseWriter, r *http.Request) {
+	format, err := bulk.FormatByContentType(r.Header.Get("Content-Type"))
+	if err != nil {
+		apierror.Write(w, r, apierror.BadRequest("%v", err))
+
+		return
+	}
+
+	userID := r.Context().Value(middleware.CtxKeyUserID).(string)
+
+	body, err := bodyReader(r)
+	if err != nil {
+		apierror.Write(w, r, errReadBody(err))
+
+		return
+	}
+	defer body.Close()
+
+	report := importReport{Rows: make([]bulk.Result, 0)}
+	store := func(links []model.Link) ([]error, error) {
+		return srv.shortener.StoreURLs(userID, links, false)
+	}
+
+	report.Summary, err = bulk.Import(bulk.NewReader(body, format), store, func(res bulk.Result) error {
+		report.Rows = append(report.Rows, res)
+
+		return nil
+	})
+	if err != nil {
+		// links imported before the error stay stored
+		apierror.Write(w, r, apierror.Internal("import failed: %v", err))
+
+		return
+	}
+
+	writeJSON(w, report)
+}
+
+// exportURLs responds with all links of the user including deleted ones
+// in the format set by the format query parameter, ndjson by default.
+func (srv *Server) exportURLs(w http.ResponseWriter, r *http.Request) {
+	format, ok := exportFormat(w, r)
+	if !ok {
+		return
+	}
+
+	userID := r.Context().Value(middleware.CtxKeyUserID).(string)
+
+	links, err := srv.shortener.LoadLinksByUser(userID)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal("failed to load data: %v", err))
+
+		return
+	}
+
+	w.Header().Set("Content-Type", format.ContentType())
+	bw := bulk.NewWriter(w, format)
+
+	for _, link := range links {
+		if err := bw.Write(link); err != nil {
+			log.Printf("failed to export links: %v", err)
+
+			return
+		}
+	}
+
+	if err := bw.Flush(); err != nil {
+		log.Printf("failed to export links: %v", err)
+	}
+}
+
+// adminExportURLs streams all stored links in the format
+// set by the format query parameter, ndjson by default.
+func (srv *Server) adminExportURLs(w http.ResponseWriter, r *http.Request) {
+	format, ok := exportFormat(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", format.ContentType())
+	bw := bulk.NewWriter(w, format)
+
+	// the status is sent with the first link, later errors can only be logged
+	if err := srv.shortener.RangeLinks(bw.Write); err != nil {
+		log.Printf("failed to export links: %v", err)
+
+		return
+	}
+
+	if err := bw.Flush(); err != nil {
+		log.Printf("failed to export links: %v", err)
+	}
+}
+
+// exportFormat returns the format set by the format query parameter
+// or responds with 400 status if it is not supported.
+func exportFormat(w http.ResponseWriter, r *http.Request) (bulk.Format, bool) {
+	name := r.URL.Query().Get("format")
+	if name == "" {
+		return bulk.NDJSON, true
+	}
+
+	format, err := bulk.ParseFormat(name)
+	if err != nil {
+		apierror.Write(w, r, apierror.BadRequest("%v", err))
+
+		return "", false
+	}
+
+	return format, true
+}
@@ -22,14 +22,14 @@ func Example() {
 		"SERVER_ADDRESS": addr,
 		"BASE_URL":       url}
 
-	cfg := conf.New(conf.IgnoreOsArgs(),
+	cfg, _ := conf.New(conf.IgnoreOsArgs(),
 		conf.WithEnvVars(vars))
 
 	strg, _ := storage.New(cfg)
 
-	myShortner := shortener.NewShortener(cfg, strg)
+	myShortner, _ := shortener.NewShortener(cfg, strg)
 
-	server := New(cfg, myShortner, strg)
+	server, _ := New(cfg, myShortner, strg)
 
 	server.Run()
 	defer server.Shutdown(context.Background())
@@ -37,7 +37,7 @@ func Example() {
 	// Getting a short URL
 	res, _ := http.Post(url+"/",
 		"text/plain",
-		bytes.NewBuffer([]byte("example.com")))
+		bytes.NewBuffer([]byte("http://example.com/")))
 
 	shortURL, _ := io.ReadAll(res.Body)
 
@@ -46,7 +46,7 @@ func Example() {
 	// Getting a short URL using JSON-encoded message
 	message := struct {
 		URL string `json:"url"`
-	}{"exampleJSON.com"}
+	}{"http://exampleJSON.com/"}
 	buf := bytes.NewBuffer(nil)
 	enc := json.NewEncoder(buf)
 	enc.Encode(message)
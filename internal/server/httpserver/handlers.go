@@ -7,12 +7,21 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"net"
+	"log"
+	"mime"
 	"net/http"
+	"strconv"
 	"strings"
 
+	"github.com/go-chi/chi"
+
+	"github.com/usa4ev/urlshortner/internal/bulk"
+	"github.com/usa4ev/urlshortner/internal/server/httpserver/apierror"
 	"github.com/usa4ev/urlshortner/internal/server/httpserver/middleware"
+	"github.com/usa4ev/urlshortner/internal/shortener/policy"
+	"github.com/usa4ev/urlshortner/internal/storage"
 	"github.com/usa4ev/urlshortner/internal/storage/database"
+	"github.com/usa4ev/urlshortner/internal/storage/model"
 	"github.com/usa4ev/urlshortner/internal/storage/storageerrors"
 )
 
@@ -20,58 +29,120 @@ import (
 func (srv *Server) pingStorage(w http.ResponseWriter, r *http.Request) {
 	err := database.Pingdb(srv.cfg.DBDSN())
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apierror.Write(w, r, apierror.Internal("%v", err))
+
+		return
 	}
 
 	w.WriteHeader(http.StatusOK)
 }
 
-// makeShort responds with a short URL as a plain text.
+// makeShort responds with a short URL as a plain text, or with an HTML page
+// if the client asks for it. The URL is the raw body or the url field of
+// an application/x-www-form-urlencoded or multipart/form-data body.
 func (srv *Server) makeShort(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
 	userID := r.Context().Value(middleware.CtxKeyUserID).(string)
-	originalURL, err := readBody(r)
+	html := acceptsHTML(r)
+
+	fail := func(err error) {
+		if !html {
+			apierror.Write(w, r, err)
+
+			return
+		}
+
+		e := apierror.From(err)
+		renderPage(w, e.Status, "shortened.html", shortenedPage{Error: e.Message})
+	}
+
+	rawURL, err := urlFromBody(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		fail(err)
 
 		return
 	}
 
-	id, url := srv.shortener.ShortenURL(string(originalURL))
-	err = srv.shortener.StoreURL(id, string(originalURL), userID)
+	originalURL, err := srv.shortener.NormalizeURL(rawURL)
 	if err != nil {
-		if errors.Is(err, storageerrors.ErrConflict) {
-			w.WriteHeader(http.StatusConflict)
+		fail(err)
 
-			_, err = io.WriteString(w, url)
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 
-				return
-			}
+	link, err := srv.shortener.Shorten(originalURL, userID)
+	status := http.StatusCreated
 
-			return
-		}
+	switch {
+	case errors.Is(err, storageerrors.ErrConflict) && link.ShortURL != "":
+		// the body is the short URL the destination is already stored with
+		status = http.StatusConflict
+	case err != nil:
+		fail(err)
 
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	url := link.ShortURL
+
+	if html {
+		renderPage(w, status, "shortened.html", shortenedPage{
+			OriginalURL: originalURL,
+			ShortURL:    url,
+			QR:          qrDataURL(url),
+			Existed:     status == http.StatusConflict,
+		})
 
 		return
 	}
 
-	w.WriteHeader(http.StatusCreated)
+	w.WriteHeader(status)
+
+	if _, err := io.WriteString(w, url); err != nil {
+		log.Printf("failed to write response: %v", err)
+	}
+}
+
+// urlFromBody returns the URL to shorten sent with makeShort.
+func urlFromBody(r *http.Request) (string, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != ctForm && mediaType != ctMultipart {
+		body, err := readBody(r)
+		if err != nil {
+			return "", errReadBody(err)
+		}
+
+		return string(body), nil
+	}
 
-	_, err = io.WriteString(w, url)
+	body, err := bodyReader(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return "", errReadBody(err)
+	}
 
-		return
+	r.Body = body
+
+	if mediaType == ctMultipart {
+		err = r.ParseMultipartForm(maxFormMemory)
+	} else {
+		err = r.ParseForm()
+	}
+
+	if err != nil {
+		return "", apierror.BadRequest("failed to parse form: %v", err)
 	}
+
+	if _, ok := r.PostForm["url"]; !ok {
+		return "", apierror.BadRequest("form field \"url\" is required").With("field", "url")
+	}
+
+	return r.PostFormValue("url"), nil
 }
 
 // makeShortJSON responds with a short URL as a urlres JSON structure.
 func (srv *Server) makeShortJSON(w http.ResponseWriter, r *http.Request) {
-	if ct := r.Header.Get("Content-Type"); ct != ctJSON {
-		http.Error(w, "unsupported content type", http.StatusBadRequest)
+	if !hasContentType(r, ctJSON) {
+		apierror.Write(w, r, errContentType(r, ctJSON))
 
 		return
 	}
@@ -85,60 +156,76 @@ func (srv *Server) makeShortJSON(w http.ResponseWriter, r *http.Request) {
 
 	body, err := readBody(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apierror.Write(w, r, errReadBody(err))
+
+		return
 	}
 
 	message := urlreq{}
 	dec := json.NewDecoder(bytes.NewBuffer(body))
 
 	if err := dec.Decode(&message); err != nil {
-		http.Error(w, "failed to decode message: "+err.Error(), http.StatusBadRequest)
+		apierror.Write(w, r, apierror.BadRequest("failed to decode message: %v", err))
 
 		return
 	}
 
-	enc := json.NewEncoder(w)
-	id, url := srv.shortener.ShortenURL(message.URL)
-	res := urlres{url}
-	err = srv.shortener.StoreURL(id, message.URL, userID)
+	originalURL, err := srv.shortener.NormalizeURL(message.URL)
 	if err != nil {
-		if errors.Is(err, storageerrors.ErrConflict) {
-			w.Header().Set("Content-Type", ctJSON)
-			w.WriteHeader(http.StatusConflict)
-			if err := enc.Encode(res); err != nil {
-				http.Error(w, "failed to encode message: "+err.Error(), http.StatusInternalServerError)
+		apierror.Write(w, r, err)
+
+		return
+	}
 
-				return
-			}
+	enc := json.NewEncoder(w)
+	link, err := srv.shortener.Shorten(originalURL, userID)
+	res := urlres{link.ShortURL}
+	status := http.StatusCreated
 
-		} else {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		}
+	switch {
+	case errors.Is(err, storageerrors.ErrConflict) && link.ShortURL != "":
+		// the result is the short URL the destination is already stored with
+		status = http.StatusConflict
+	case err != nil:
+		apierror.Write(w, r, err)
 
 		return
 	}
 
 	w.Header().Set("Content-Type", ctJSON)
-	w.WriteHeader(http.StatusCreated)
+	w.WriteHeader(status)
 
 	if err := enc.Encode(res); err != nil {
-		http.Error(w, "failed to encode message: "+err.Error(), http.StatusInternalServerError)
+		log.Printf("failed to encode message: %v", err)
 
 		return
 	}
 }
 
-// shortenBatchJSON responds with a short URL as an urlwidres JSON structure
-// setting correlation_id with respective value from recieved urlwid.
+// shortenBatchJSON stores a batch of urlwid items all-or-nothing and responds
+// with short URLs as an urlwidres JSON structure setting correlation_id
+// with respective value from recieved urlwid. With partial=true query
+// parameter valid items are stored regardless of the others and
+// the response is an urlwidstatus JSON structure for every item.
 func (srv *Server) shortenBatchJSON(w http.ResponseWriter, r *http.Request) {
-	if ct := r.Header.Get("Content-Type"); ct != ctJSON {
-		http.Error(w, "unsupported content type", http.StatusBadRequest)
+	if !hasContentType(r, ctJSON) {
+		apierror.Write(w, r, errContentType(r, ctJSON))
 
 		return
 	}
 
 	defer r.Body.Close()
 
+	partial := false
+	if v := r.URL.Query().Get("partial"); v != "" {
+		var err error
+		if partial, err = strconv.ParseBool(v); err != nil {
+			apierror.Write(w, r, apierror.BadRequest("invalid partial query parameter: %v", err))
+
+			return
+		}
+	}
+
 	var userID string
 	if rawUserID := r.Context().Value(middleware.CtxKeyUserID); rawUserID != nil {
 		userID = rawUserID.(string)
@@ -146,62 +233,150 @@ func (srv *Server) shortenBatchJSON(w http.ResponseWriter, r *http.Request) {
 
 	body, err := readBody(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apierror.Write(w, r, errReadBody(err))
+
+		return
 	}
 
 	message := make([]urlwid, 0)
-	res := make([]urlwidres, 0)
 	dec := json.NewDecoder(bytes.NewBuffer(body))
 
 	if err := dec.Decode(&message); err != nil {
-		http.Error(w, "failed to decode message: "+err.Error(), http.StatusBadRequest)
+		apierror.Write(w, r, apierror.BadRequest("failed to decode message: %v", err))
 
 		return
 	}
 
-	for _, v := range message {
-		id, url := srv.shortener.ShortenURL(v.OriginalURL)
-		res = append(res, urlwidres{v.CorrelationID, url})
-		err = srv.shortener.StoreURL(id, v.OriginalURL, userID)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+	links := make([]model.Link, len(message))
+	for i, v := range message {
+		links[i].OriginalURL = v.OriginalURL
+	}
+
+	errs, err := srv.shortener.StoreURLs(userID, links, !partial)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal("failed to store URLs: %v", err))
+
+		return
+	}
+
+	if partial {
+		res := make([]urlwidstatus, len(message))
+		for i, v := range message {
+			res[i] = srv.itemStatus(v.CorrelationID, links[i], errs[i])
+		}
+
+		writeJSON(w, res)
+
+		return
+	}
+
+	for i, err := range errs {
+		if err != nil && !errors.Is(err, storageerrors.ErrBatchAborted) {
+			e := apierror.From(err)
+			e.Message = fmt.Sprintf("correlation_id %v: %v", message[i].CorrelationID, err)
+			apierror.Write(w, r, e.With("correlation_id", message[i].CorrelationID))
 
 			return
 		}
 	}
 
+	res := make([]urlwidres, len(message))
+	for i, v := range message {
+		res[i] = urlwidres{v.CorrelationID, links[i].ShortURL}
+	}
+
 	w.Header().Set("Content-Type", ctJSON)
 	w.WriteHeader(http.StatusCreated)
 	enc := json.NewEncoder(w)
 
 	if err := enc.Encode(res); err != nil {
-		http.Error(w, "failed to encode message: "+err.Error(), http.StatusInternalServerError)
+		log.Printf("failed to encode message: %v", err)
 
 		return
 	}
 }
 
+// itemStatus returns the outcome of a batch item stored with err.
+func (srv *Server) itemStatus(correlationID string, link model.Link, err error) urlwidstatus {
+	res := urlwidstatus{CorrelationID: correlationID, Status: bulk.Status(err)}
+
+	switch {
+	case err == nil:
+		res.ShortURL = link.ShortURL
+	case errors.Is(err, storageerrors.ErrConflict):
+		res.Error = err.Error()
+		// the URL may be stored with an imported id
+		if stored, err := srv.shortener.FindLinkByURL(link.OriginalURL); err == nil {
+			res.ShortURL = stored.ShortURL
+		}
+	default:
+		res.Error = err.Error()
+	}
+
+	return res
+}
+
 // makeLong load URL from storage by ID and, if found, redirects client.
+// A trailing + or the preview query parameter shows the preview page of the link
+// instead, so does the interstitial setting of the link unless the continue
+// query parameter is set. Previews do not count as clicks.
 func (srv *Server) makeLong(w http.ResponseWriter, r *http.Request) {
 	id := r.URL.Path[1:]
-	redirect, err := srv.shortener.FindURL(id)
-	switch {
-	case errors.Is(err, storageerrors.ErrURLGone):
-		http.Error(w, err.Error(), http.StatusGone)
 
-		return
-	case err != nil && !errors.Is(err, storageerrors.ErrURLGone):
-		http.Error(w, err.Error(), http.StatusNotFound)
+	preview := strings.HasSuffix(id, "+")
+	id = strings.TrimSuffix(id, "+")
 
+	query := r.URL.Query()
+	if !preview {
+		preview, _ = strconv.ParseBool(query.Get("preview"))
+	}
+
+	link, ok := srv.findURL(w, r, id)
+	if !ok {
 		return
-	case redirect == "":
+	}
 
-		http.Error(w, fmt.Sprintf("id %v not found", id), http.StatusNotFound)
+	if proceed, _ := strconv.ParseBool(query.Get("continue")); preview || link.Interstitial && !proceed {
+		renderPage(w, http.StatusOK, "preview.html", link)
 
 		return
 	}
 
-	http.Redirect(w, r, redirect, http.StatusTemporaryRedirect)
+	// a lost click is not worth a failed redirect
+	if err := srv.shortener.TrackClick(id); err != nil {
+		log.Printf("failed to count click on %v: %v", id, err)
+	}
+
+	http.Redirect(w, r, link.OriginalURL, http.StatusTemporaryRedirect)
+}
+
+// findURL returns the link stored by id or responds with the error
+// if id cannot be followed: 410 Gone if the link is deleted,
+// 403 Forbidden if it is disabled, 451 if the destination is blocked
+// and 404 Not Found if there is no such link.
+func (srv *Server) findURL(w http.ResponseWriter, r *http.Request, id string) (model.Link, bool) {
+	link, err := srv.shortener.FindLink(id)
+	switch {
+	case errors.Is(err, storageerrors.ErrURLGone), errors.Is(err, storageerrors.ErrURLDisabled):
+		apierror.Write(w, r, err)
+
+		return model.Link{}, false
+	case errors.Is(err, policy.ErrBlocked):
+		apierror.Write(w, r, apierror.New(http.StatusUnavailableForLegalReasons, apierror.CodeBlocked, err.Error()))
+
+		return model.Link{}, false
+	case err != nil:
+		// storages report unknown ids with different errors
+		apierror.Write(w, r, apierror.New(http.StatusNotFound, apierror.CodeNotFound, err.Error()))
+
+		return model.Link{}, false
+	case link.OriginalURL == "":
+		apierror.Write(w, r, apierror.New(http.StatusNotFound, apierror.CodeNotFound, fmt.Sprintf("id %v not found", id)))
+
+		return model.Link{}, false
+	}
+
+	return link, true
 }
 
 // makeLongByUser responds with encoded JSON collection
@@ -210,7 +385,7 @@ func (srv *Server) makeLongByUser(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value(middleware.CtxKeyUserID)
 	res, err := srv.shortener.LoadByUser(userID.(string))
 	if err != nil {
-		http.Error(w, "failed to load data: "+err.Error(), http.StatusInternalServerError)
+		apierror.Write(w, r, apierror.Internal("failed to load data: %v", err))
 
 		return
 	}
@@ -224,7 +399,7 @@ func (srv *Server) makeLongByUser(w http.ResponseWriter, r *http.Request) {
 	enc := json.NewEncoder(w)
 
 	if err := enc.Encode(res); err != nil {
-		http.Error(w, "failed to encode message: "+err.Error(), http.StatusInternalServerError)
+		log.Printf("failed to encode message: %v", err)
 
 		return
 	}
@@ -233,8 +408,8 @@ func (srv *Server) makeLongByUser(w http.ResponseWriter, r *http.Request) {
 // deleteBatch receives list of URL ids that need to be deleted.
 // Deletion is executed asynchronously.
 func (srv *Server) deleteBatch(w http.ResponseWriter, r *http.Request) {
-	if ct := r.Header.Get("Content-Type"); ct != ctJSON {
-		http.Error(w, "unsupported content type", http.StatusBadRequest)
+	if !hasContentType(r, ctJSON) {
+		apierror.Write(w, r, errContentType(r, ctJSON))
 
 		return
 	}
@@ -248,7 +423,8 @@ func (srv *Server) deleteBatch(w http.ResponseWriter, r *http.Request) {
 
 	body, err := readBody(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apierror.Write(w, r, errReadBody(err))
+
 		return
 	}
 
@@ -256,58 +432,184 @@ func (srv *Server) deleteBatch(w http.ResponseWriter, r *http.Request) {
 	dec := json.NewDecoder(bytes.NewBuffer(body))
 
 	if err := dec.Decode(&message); err != nil {
-		http.Error(w, "failed to decode message: "+err.Error(), http.StatusBadRequest)
+		apierror.Write(w, r, apierror.BadRequest("failed to decode message: %v", err))
+
 		return
 	}
 
 	err = srv.shortener.DeleteURLs(userID, message)
 
 	if err != nil {
-		http.Error(w, "deletion failed: "+err.Error(), http.StatusBadRequest)
+		apierror.Write(w, r, apierror.BadRequest("deletion failed: %v", err))
+
 		return
 	}
 
 	w.WriteHeader(http.StatusAccepted)
 }
 
-// stats returns JSON encoded statsData.
-// Request will be accepted from trusted subnet only.
-func (srv *Server) stats(w http.ResponseWriter, r *http.Request) {
-	rawXFF := r.Header.Get("X-Real-IP")
-	xff := strings.Split(rawXFF, ",")
+// restoreBatch receives list of URL ids that need to be undeleted.
+func (srv *Server) restoreBatch(w http.ResponseWriter, r *http.Request) {
+	if !hasContentType(r, ctJSON) {
+		apierror.Write(w, r, errContentType(r, ctJSON))
+
+		return
+	}
 
-	//take the rightmost IP
-	ipstr := xff[len(xff)-1]
-	ip := net.ParseIP(ipstr)
+	defer r.Body.Close()
+
+	var userID string
+	if rawUserID := r.Context().Value(middleware.CtxKeyUserID); rawUserID != nil {
+		userID = rawUserID.(string)
+	}
 
-	//parse subnet string
-	_, net, err := net.ParseCIDR(srv.cfg.TrustedSubnet())
+	body, err := readBody(r)
 	if err != nil {
-		http.Error(
-			w,
-			fmt.Sprintf("failed to parse trusted subnet from config: %v",
-				srv.cfg.TrustedSubnet()),
-			http.StatusInternalServerError)
+		apierror.Write(w, r, errReadBody(err))
+
 		return
 	}
 
-	//check if ip belongs subnet
-	if ip == nil || !net.Contains(ip) {
-		http.Error(w, "", http.StatusForbidden)
+	message := make([]string, 0)
+	dec := json.NewDecoder(bytes.NewBuffer(body))
+
+	if err := dec.Decode(&message); err != nil {
+		apierror.Write(w, r, apierror.BadRequest("failed to decode message: %v", err))
+
+		return
+	}
+
+	err = srv.shortener.RestoreURLs(userID, message)
+
+	if errors.Is(err, storageerrors.ErrQuotaExceeded) {
+		apierror.Write(w, r, err)
+
+		return
+	} else if err != nil {
+		apierror.Write(w, r, apierror.Internal("restoration failed: %v", err))
+
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// updateURL replaces the destination of a link owned by the user
+// and responds with the updated link as a storage.Pair JSON structure.
+func (srv *Server) updateURL(w http.ResponseWriter, r *http.Request) {
+	if !hasContentType(r, ctJSON) {
+		apierror.Write(w, r, errContentType(r, ctJSON))
+
+		return
+	}
+
+	defer r.Body.Close()
+
+	var userID string
+	if rawUserID := r.Context().Value(middleware.CtxKeyUserID); rawUserID != nil {
+		userID = rawUserID.(string)
+	}
+
+	body, err := readBody(r)
+	if err != nil {
+		apierror.Write(w, r, errReadBody(err))
+
+		return
+	}
+
+	message := urlreq{}
+	dec := json.NewDecoder(bytes.NewBuffer(body))
+
+	if err := dec.Decode(&message); err != nil {
+		apierror.Write(w, r, apierror.BadRequest("failed to decode message: %v", err))
+
+		return
+	}
+
+	originalURL, err := srv.shortener.NormalizeURL(message.URL)
+	if err != nil {
+		apierror.Write(w, r, err)
+
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	if err := srv.shortener.UpdateURL(userID, id, originalURL); err != nil {
+		apierror.Write(w, r, err)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", ctJSON)
+	enc := json.NewEncoder(w)
+
+	if err := enc.Encode(storage.Pair{ShortURL: srv.shortener.MakeURL(id), OriginalURL: originalURL}); err != nil {
+		log.Printf("failed to encode message: %v", err)
+
+		return
+	}
+}
+
+// updateSettings replaces the title and interstitial setting of a link
+// owned by the user and responds with the link.
+func (srv *Server) updateSettings(w http.ResponseWriter, r *http.Request) {
+	if !hasContentType(r, ctJSON) {
+		apierror.Write(w, r, errContentType(r, ctJSON))
+
+		return
+	}
+
+	defer r.Body.Close()
+
+	body, err := readBody(r)
+	if err != nil {
+		apierror.Write(w, r, errReadBody(err))
+
+		return
+	}
+
+	settings := model.LinkSettings{}
+	if err := json.Unmarshal(body, &settings); err != nil {
+		apierror.Write(w, r, apierror.BadRequest("failed to decode message: %v", err))
+
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	if err := srv.shortener.UpdateSettings(userID(r), id, settings); err != nil {
+		apierror.Write(w, r, err)
+
+		return
+	}
+
+	link, err := srv.shortener.LoadLink(id)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal("failed to load link: %v", err))
+
+		return
+	}
+
+	w.Header().Set("Content-Type", ctJSON)
+	enc := json.NewEncoder(w)
+
+	if err := enc.Encode(link); err != nil {
+		log.Printf("failed to encode message: %v", err)
+
 		return
 	}
+}
 
+// stats returns JSON encoded statsData.
+// It has to be used with TrustedSubnetMW.
+func (srv *Server) stats(w http.ResponseWriter, r *http.Request) {
 	//use SingleFlight
 	urls, err, _ := srv.sfgr.Do("CountURLs",
 		func() (interface{}, error) {
 			return srv.shortener.CountURLs()
 		})
 	if err != nil {
-		http.Error(
-			w,
-			fmt.Sprintf("failed to get data from storage: %v",
-				err.Error()),
-			http.StatusInternalServerError)
+		apierror.Write(w, r, apierror.Internal("failed to get data from storage: %v", err))
+
 		return
 	}
 
@@ -317,11 +619,8 @@ func (srv *Server) stats(w http.ResponseWriter, r *http.Request) {
 		})
 
 	if err != nil {
-		http.Error(
-			w,
-			fmt.Sprintf("failed to get data from storage: %v",
-				err.Error()),
-			http.StatusInternalServerError)
+		apierror.Write(w, r, apierror.Internal("failed to get data from storage: %v", err))
+
 		return
 	}
 
@@ -330,33 +629,34 @@ func (srv *Server) stats(w http.ResponseWriter, r *http.Request) {
 		Users: users.(int),
 	}
 
-	buf := bytes.NewBuffer(nil)
-	err = json.NewEncoder(buf).Encode(data)
-	if err != nil {
-		http.Error(
-			w,
-			fmt.Sprintf("failed to encode stats data response: %v", data),
-			http.StatusInternalServerError)
-		return
-	}
+	writeJSON(w, data)
+}
+
+// hasContentType reports whether the request body has media type
+// mediaType regardless of its parameters, e.g. charset.
+func hasContentType(r *http.Request, mediaType string) bool {
+	got, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
 
-	w.Write(buf.Bytes())
+	return err == nil && got == mediaType
 }
 
-func readBody(r *http.Request) ([]byte, error) {
-	var reader io.Reader
+// errContentType returns the error of a request body that is not mediaType.
+func errContentType(r *http.Request, mediaType string) error {
+	return apierror.BadRequest("unsupported content type %q, want %v", r.Header.Get("Content-Type"), mediaType)
+}
 
-	if r.Header.Get(`Content-Encoding`) == `gzip` {
-		gz, err := gzip.NewReader(r.Body)
-		if err != nil {
-			return nil, err
-		}
+// errReadBody returns the error of a request body that cannot be read,
+// e.g. because it is not gzipped while Content-Encoding says so.
+func errReadBody(err error) error {
+	return apierror.BadRequest("failed to read body: %v", err)
+}
 
-		reader = gz
-		defer gz.Close()
-	} else {
-		reader = r.Body
+func readBody(r *http.Request) ([]byte, error) {
+	reader, err := bodyReader(r)
+	if err != nil {
+		return nil, err
 	}
+	defer reader.Close()
 
 	body, err := io.ReadAll(reader)
 	if err != nil {
@@ -365,3 +665,12 @@ func readBody(r *http.Request) ([]byte, error) {
 
 	return body, nil
 }
+
+// bodyReader returns the request body decompressing it if it is gzipped.
+func bodyReader(r *http.Request) (io.ReadCloser, error) {
+	if r.Header.Get(`Content-Encoding`) == `gzip` {
+		return gzip.NewReader(r.Body)
+	}
+
+	return r.Body, nil
+}
@@ -1,5 +1,7 @@
 package httpserver
 
+import "github.com/usa4ev/urlshortner/internal/bulk"
+
 // urlreq & urlres are, respectively, request and response structures
 // used to decode and encode messages when dealing with JSON content-type.
 type (
@@ -22,9 +24,28 @@ type (
 		CorrelationID string `json:"correlation_id"`
 		ShortURL      string `json:"short_url"`
 	}
+	// urlwidstatus reports the outcome of a batch item stored with partial=true.
+	// ShortURL of a conflicting item is the one the URL is stored with.
+	urlwidstatus struct {
+		CorrelationID string `json:"correlation_id"`
+		ShortURL      string `json:"short_url,omitempty"`
+		Status        string `json:"status"`
+		Error         string `json:"error,omitempty"`
+	}
 )
 
 type statsData struct {
 	Urls  int `json:"urls"`
 	Users int `json:"users"`
-}
\ No newline at end of file
+}
+
+// deletedData reports the number of removed items.
+type deletedData struct {
+	Deleted int `json:"deleted"`
+}
+
+// importReport lists imported records that are not created.
+type importReport struct {
+	bulk.Summary
+	Rows []bulk.Result `json:"rows"`
+}
@@ -2,28 +2,51 @@ package httpserver
 
 import (
 	"context"
+	"crypto/tls"
+	"fmt"
+	"log"
 	"net/http"
-	"path/filepath"
 
 	"github.com/go-chi/chi"
 	"golang.org/x/sync/singleflight"
 
+	conf "github.com/usa4ev/urlshortner/internal/config"
 	"github.com/usa4ev/urlshortner/internal/router"
 	"github.com/usa4ev/urlshortner/internal/server/auth"
+	"github.com/usa4ev/urlshortner/internal/server/grpcserver"
 	"github.com/usa4ev/urlshortner/internal/server/httpserver/middleware"
+	"github.com/usa4ev/urlshortner/internal/server/ratelimit"
+	"github.com/usa4ev/urlshortner/internal/server/tlsconfig"
+	"github.com/usa4ev/urlshortner/internal/server/trustednet"
 	"github.com/usa4ev/urlshortner/internal/shortener"
 )
 
 const (
-	ctJSON string = "application/json"
+	ctJSON      string = "application/json"
+	ctForm      string = "application/x-www-form-urlencoded"
+	ctMultipart string = "multipart/form-data"
+
+	// maxFormMemory is the size of multipart form parts kept in memory,
+	// the rest is stored in temporary files.
+	maxFormMemory = 1 << 20
 )
 
 type config interface {
 	TrustedSubnet() string
+	TrustedProxies() string
 	DBDSN() string
-	SslPath() string
 	UseTLS() bool
+	TLSCertPath() string
+	TLSKeyPath() string
+	TLSMinVersion() string
+	TLSCipherSuites() string
 	SrvAddr() string
+	RateLimit() float64
+	RateBurst() int
+	AdminToken() string
+	ClientCAPath() string
+	ServiceIdentities() string
+	ValidateRequests() bool
 }
 
 type Server struct {
@@ -32,49 +55,179 @@ type Server struct {
 	sessionMgr auth.SessionStoreLoader
 	cfg        config
 	sfgr       *singleflight.Group
+	limiter    *ratelimit.Limiter
+	trusted    *trustednet.Network
+	certs      *tlsconfig.Loader
+	tlsErr     error
 	handlers   []router.HandlerDesc //list of handlers that serve HTTP methods
 }
 
-func New(c config, s shortener.Shortener, sm auth.SessionStoreLoader) *Server {
+func New(c config, s shortener.Shortener, sm auth.SessionStoreLoader) (*Server, error) {
 	srv := Server{}
 
 	srv.cfg = c
 	srv.shortener = s
 	srv.sessionMgr = sm
 	srv.sfgr = new(singleflight.Group)
-	srv.handlers = []router.HandlerDesc{
-		{Method: "POST", Path: "/", Handler: http.HandlerFunc(srv.makeShort), Middlewares: chi.Middlewares{middleware.GzipMW, middleware.AuthMW(sm)}},
-		{Method: "GET", Path: "/{id}", Handler: http.HandlerFunc(srv.makeLong), Middlewares: chi.Middlewares{middleware.GzipMW, middleware.AuthMW(sm)}},
-		{Method: "POST", Path: "/api/shorten", Handler: http.HandlerFunc(srv.makeShortJSON), Middlewares: chi.Middlewares{middleware.GzipMW, middleware.AuthMW(sm)}},
-		{Method: "POST", Path: "/api/shorten/batch", Handler: http.HandlerFunc(srv.shortenBatchJSON), Middlewares: chi.Middlewares{middleware.GzipMW, middleware.AuthMW(sm)}},
-		{Method: "GET", Path: "/api/user/urls", Handler: http.HandlerFunc(srv.makeLongByUser), Middlewares: chi.Middlewares{middleware.GzipMW, middleware.AuthMW(sm)}},
-		{Method: "DELETE", Path: "/api/user/urls", Handler: http.HandlerFunc(srv.deleteBatch), Middlewares: chi.Middlewares{middleware.GzipMW, middleware.AuthMW(sm)}},
-		{Method: "GET", Path: "/ping", Handler: http.HandlerFunc(srv.pingStorage), Middlewares: chi.Middlewares{middleware.GzipMW, middleware.AuthMW(sm)}},
-		{Method: "GET", Path: "/api/internal/stats", Handler: http.HandlerFunc(srv.stats), Middlewares: chi.Middlewares{middleware.GzipMW}},
+	srv.limiter = ratelimit.New(c.RateLimit(), c.RateBurst())
+
+	trusted, err := trustednet.New(c.TrustedSubnet(), c.TrustedProxies())
+	if err != nil {
+		// empty network trusts nobody
+		log.Printf("internal methods are disabled: %v", err)
+		trusted = &trustednet.Network{}
+	}
+
+	srv.trusted = trusted
+
+	srv.handlers, err = srv.newHandlers()
+	if err != nil {
+		return nil, fmt.Errorf("failed to enable request validation: %w", err)
 	}
 
 	r := router.NewRouter(&srv)
 	srv.httpsrv = &http.Server{Addr: c.SrvAddr(), Handler: r}
 
-	return &srv
+	if c.UseTLS() {
+		var tlsCfg *tls.Config
+		// the error is returned by Run
+		tlsCfg, srv.certs, srv.tlsErr = tlsconfig.New(c)
+		srv.httpsrv.TLSConfig = tlsCfg
+	}
+
+	return &srv, nil
+}
+
+// newHandlers returns the list of handlers that serve HTTP methods.
+func (srv *Server) newHandlers() ([]router.HandlerDesc, error) {
+	sm := srv.sessionMgr
+	// session middlewares limit clients by IP before a new session
+	// can be opened and by user after the session is loaded
+	session := chi.Middlewares{
+		middleware.GzipMW,
+		middleware.RateLimitMW(srv.limiter, middleware.ByIP(srv.trusted)),
+		middleware.AuthMW(sm),
+		middleware.RateLimitMW(srv.limiter, middleware.ByUser),
+	}
+	// browsers post forms to / with the session cookie from any site
+	form := append(session[:len(session):len(session)], middleware.SameOriginMW)
+	internal := chi.Middlewares{
+		middleware.GzipMW,
+		middleware.TrustedSubnetMW(srv.trusted),
+	}
+	admin := chi.Middlewares{
+		middleware.GzipMW,
+		middleware.AdminMW(srv.cfg.AdminToken()),
+	}
+
+	handlers := []router.HandlerDesc{
+		{Method: "POST", Path: "/", Handler: http.HandlerFunc(srv.makeShort), Middlewares: form},
+		{Method: "GET", Path: "/{id}", Handler: http.HandlerFunc(srv.makeLong), Middlewares: session},
+		{Method: "GET", Path: "/{id}/qr", Handler: http.HandlerFunc(srv.qrCode), Middlewares: session},
+		{Method: "POST", Path: "/api/shorten", Handler: http.HandlerFunc(srv.makeShortJSON), Middlewares: session},
+		{Method: "POST", Path: "/api/shorten/batch", Handler: http.HandlerFunc(srv.shortenBatchJSON), Middlewares: session},
+		{Method: "GET", Path: "/api/user/urls", Handler: http.HandlerFunc(srv.makeLongByUser), Middlewares: session},
+		{Method: "DELETE", Path: "/api/user/urls", Handler: http.HandlerFunc(srv.deleteBatch), Middlewares: session},
+		{Method: "PATCH", Path: "/api/user/urls/{id}", Handler: http.HandlerFunc(srv.updateURL), Middlewares: session},
+		{Method: "PUT", Path: "/api/user/urls/{id}/settings", Handler: http.HandlerFunc(srv.updateSettings), Middlewares: session},
+		{Method: "POST", Path: "/api/user/urls/restore", Handler: http.HandlerFunc(srv.restoreBatch), Middlewares: session},
+		{Method: "POST", Path: "/api/user/urls/import", Handler: http.HandlerFunc(srv.importURLs), Middlewares: session},
+		{Method: "GET", Path: "/api/user/urls/export", Handler: http.HandlerFunc(srv.exportURLs), Middlewares: session},
+		{Method: "GET", Path: "/ping", Handler: http.HandlerFunc(srv.pingStorage), Middlewares: session},
+		{Method: "GET", Path: "/api/openapi.json", Handler: http.HandlerFunc(srv.openAPI), Middlewares: chi.Middlewares{middleware.GzipMW}},
+		{Method: "GET", Path: "/api/internal/stats", Handler: http.HandlerFunc(srv.stats), Middlewares: internal},
+		{Method: "GET", Path: "/api/admin/urls", Handler: http.HandlerFunc(srv.adminFindLink), Middlewares: admin},
+		{Method: "GET", Path: "/api/admin/urls/export", Handler: http.HandlerFunc(srv.adminExportURLs), Middlewares: admin},
+		{Method: "GET", Path: "/api/admin/urls/{id}", Handler: http.HandlerFunc(srv.adminLoadLink), Middlewares: admin},
+		{Method: "POST", Path: "/api/admin/urls/{id}/disable", Handler: srv.adminSetDisabled(true), Middlewares: admin},
+		{Method: "POST", Path: "/api/admin/urls/{id}/enable", Handler: srv.adminSetDisabled(false), Middlewares: admin},
+		{Method: "GET", Path: "/api/admin/users/{userID}/urls", Handler: http.HandlerFunc(srv.adminLoadUserLinks), Middlewares: admin},
+		{Method: "DELETE", Path: "/api/admin/users/{userID}", Handler: http.HandlerFunc(srv.adminDeleteUser), Middlewares: admin},
+		{Method: "DELETE", Path: "/api/admin/users/{userID}/sessions", Handler: http.HandlerFunc(srv.adminDeleteSessions), Middlewares: admin},
+	}
+
+	handlers = append(handlers, srv.gatewayHandlers(session, internal)...)
+	handlers = append(handlers, srv.uiHandlers(session)...)
+
+	if srv.cfg.ValidateRequests() {
+		return withValidation(handlers)
+	}
+
+	return handlers, nil
+}
+
+// gatewayHandlers returns routes of the REST/JSON gateway of grpc v2 API.
+// They are authorized by the same middlewares as the routes above.
+func (srv *Server) gatewayHandlers(session, internal chi.Middlewares) []router.HandlerDesc {
+	gw, err := grpcserver.NewGateway(srv.cfg, srv.shortener, func(r *http.Request) string {
+		userID, _ := r.Context().Value(middleware.CtxKeyUserID).(string)
+		return userID
+	})
+	if err != nil {
+		log.Printf("v2 gateway is disabled: %v", err)
+		return nil
+	}
+
+	return []router.HandlerDesc{
+		{Method: "POST", Path: "/v2/links", Handler: gw, Middlewares: session},
+		{Method: "POST", Path: "/v2/links:batch", Handler: gw, Middlewares: session},
+		{Method: "GET", Path: "/v2/links", Handler: gw, Middlewares: session},
+		{Method: "GET", Path: "/v2/links/{id}", Handler: gw, Middlewares: session},
+		{Method: "PATCH", Path: "/v2/links/{id}", Handler: gw, Middlewares: session},
+		{Method: "GET", Path: "/v2/links/{id}/qr", Handler: gw, Middlewares: session},
+		{Method: "POST", Path: "/v2/links:delete", Handler: gw, Middlewares: session},
+		{Method: "GET", Path: "/v2/ping", Handler: gw, Middlewares: session},
+		{Method: "GET", Path: "/v2/stats", Handler: gw, Middlewares: internal},
+	}
 }
 
 func (srv *Server) Run() error {
 	// Run the server
 	if srv.cfg.UseTLS() {
-		return srv.httpsrv.ListenAndServeTLS(
-			filepath.Join(srv.cfg.SslPath(), "example.crt"), 
-			filepath.Join(srv.cfg.SslPath(), "example.key"))
+		if srv.tlsErr != nil {
+			return srv.tlsErr
+		}
+
+		// the certificate is served by TLSConfig.GetCertificate
+		return srv.httpsrv.ListenAndServeTLS("", "")
 	} else {
 		return srv.httpsrv.ListenAndServe()
 	}
 }
 
-func (srv *Server) Shutdown(ctx context.Context)error {
+func (srv *Server) Shutdown(ctx context.Context) error {
 	srv.httpsrv.Shutdown(ctx)
 	return nil
 }
 
+// ApplyConfig applies trusted subnets, rate limits and shortener settings of c
+// without restarting the server. Nothing changes if any of them is invalid.
+func (srv *Server) ApplyConfig(c *conf.Config) error {
+	trusted, err := trustednet.New(c.TrustedSubnet(), c.TrustedProxies())
+	if err != nil {
+		return err
+	}
+
+	if err := srv.shortener.ApplyConfig(c); err != nil {
+		return err
+	}
+
+	srv.trusted.Set(trusted)
+	srv.limiter.SetRate(c.RateLimit(), c.RateBurst())
+
+	return nil
+}
+
+// ReloadCertificates rereads the TLS certificate files.
+// It does nothing if the server does not use TLS.
+func (srv *Server) ReloadCertificates() error {
+	if srv.certs == nil {
+		return nil
+	}
+
+	return srv.certs.Reload()
+}
+
 func (srv *Server) Handlers() []router.HandlerDesc {
 	return srv.handlers
 }
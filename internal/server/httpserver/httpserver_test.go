@@ -2,27 +2,36 @@ package httpserver
 
 import (
 	"bytes"
+	"compress/gzip"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"image/png"
 	"io"
+	"mime/multipart"
 	"net"
 	"net/http"
+	"net/http/cookiejar"
 	"net/http/httptest"
+	neturl "net/url"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
 
-	"github.com/go-chi/chi"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"golang.org/x/sync/singleflight"
 
 	conf "github.com/usa4ev/urlshortner/internal/config"
 	"github.com/usa4ev/urlshortner/internal/router"
-	"github.com/usa4ev/urlshortner/internal/server/httpserver/middleware"
+	"github.com/usa4ev/urlshortner/internal/server/ratelimit"
+	"github.com/usa4ev/urlshortner/internal/server/trustednet"
 	"github.com/usa4ev/urlshortner/internal/shortener"
 	"github.com/usa4ev/urlshortner/internal/storage"
+	"github.com/usa4ev/urlshortner/internal/storage/model"
 )
 
 const (
@@ -50,12 +59,22 @@ func newTestClient(ts *httptest.Server) *http.Client {
 }
 
 func newTestSrv(cfg *conf.Config) (*httptest.Server, error) {
+	_, ts, err := newTestServer(cfg)
+
+	return ts, err
+}
+
+// newTestServer returns the started test server along with Server it runs.
+func newTestServer(cfg *conf.Config) (*Server, *httptest.Server, error) {
 	strg, err := storage.New(cfg)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	s := shortener.NewShortener(cfg, strg)
+	s, err := shortener.NewShortener(cfg, strg)
+	if err != nil {
+		return nil, nil, err
+	}
 
 	srv := Server{}
 
@@ -63,15 +82,15 @@ func newTestSrv(cfg *conf.Config) (*httptest.Server, error) {
 	srv.shortener = s
 	srv.sessionMgr = strg
 	srv.sfgr = new(singleflight.Group)
-	srv.handlers = []router.HandlerDesc{
-		{Method: "POST", Path: "/", Handler: http.HandlerFunc(srv.makeShort), Middlewares: chi.Middlewares{middleware.GzipMW, middleware.AuthMW(strg)}},
-		{Method: "GET", Path: "/{id}", Handler: http.HandlerFunc(srv.makeLong), Middlewares: chi.Middlewares{middleware.GzipMW, middleware.AuthMW(strg)}},
-		{Method: "POST", Path: "/api/shorten", Handler: http.HandlerFunc(srv.makeShortJSON), Middlewares: chi.Middlewares{middleware.GzipMW, middleware.AuthMW(strg)}},
-		{Method: "POST", Path: "/api/shorten/batch", Handler: http.HandlerFunc(srv.shortenBatchJSON), Middlewares: chi.Middlewares{middleware.GzipMW, middleware.AuthMW(strg)}},
-		{Method: "GET", Path: "/api/user/urls", Handler: http.HandlerFunc(srv.makeLongByUser), Middlewares: chi.Middlewares{middleware.GzipMW, middleware.AuthMW(strg)}},
-		{Method: "DELETE", Path: "/api/user/urls", Handler: http.HandlerFunc(srv.deleteBatch), Middlewares: chi.Middlewares{middleware.GzipMW, middleware.AuthMW(strg)}},
-		{Method: "GET", Path: "/ping", Handler: http.HandlerFunc(srv.pingStorage), Middlewares: chi.Middlewares{middleware.GzipMW, middleware.AuthMW(strg)}},
-		{Method: "GET", Path: "/api/internal/stats", Handler: http.HandlerFunc(srv.stats), Middlewares: chi.Middlewares{middleware.GzipMW}},
+	srv.limiter = ratelimit.New(cfg.RateLimit(), cfg.RateBurst())
+	srv.trusted, err = trustednet.New(cfg.TrustedSubnet(), cfg.TrustedProxies())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	srv.handlers, err = srv.newHandlers()
+	if err != nil {
+		return nil, nil, err
 	}
 
 	r := router.NewRouter(&srv)
@@ -85,7 +104,7 @@ func newTestSrv(cfg *conf.Config) (*httptest.Server, error) {
 	ts.Listener = l
 	ts.Start()
 
-	return ts, nil
+	return &srv, ts, nil
 }
 
 func getTests(baseURL string) tests {
@@ -106,7 +125,7 @@ func getTests(baseURL string) tests {
 }
 
 func Test_MakeShort(t *testing.T) {
-	cfg := testcfg()
+	cfg := testcfg(t)
 
 	cases := getTests(cfg.BaseURL())
 	resetStorage(cfg.StoragePath(), cfg.DBDSN())
@@ -156,10 +175,144 @@ func Test_MakeShort(t *testing.T) {
 		require.NoError(t, res.Body.Close())
 		assert.Equal(t, tt.want, string(body))
 	})
+
+	for _, url := range []string{"", "plain text", "javascript:alert(1)", cfg.BaseURL() + "/abc"} {
+		t.Run("POST invalid URL", func(t *testing.T) {
+			res, err := cl.Post(ts.URL, ctText, bytes.NewBuffer([]byte(url)))
+			require.NoError(t, err, "url: %v", url)
+			require.NoError(t, res.Body.Close())
+			assert.Equal(t, http.StatusBadRequest, res.StatusCode, "got wrong status code\nurl: %v", url)
+		})
+	}
+
+	for _, url := range []string{"http://127.0.0.1/admin", "http://192.168.0.1/", "http://localhost/"} {
+		t.Run("POST blocked URL", func(t *testing.T) {
+			res, err := cl.Post(ts.URL, ctText, bytes.NewBuffer([]byte(url)))
+			require.NoError(t, err, "url: %v", url)
+			require.NoError(t, res.Body.Close())
+			assert.Equal(t, http.StatusForbidden, res.StatusCode, "got wrong status code\nurl: %v", url)
+		})
+	}
+}
+
+func Test_MakeShortForm(t *testing.T) {
+	cfg := testcfg(t)
+
+	cases := getTests(cfg.BaseURL())
+	resetStorage(cfg.StoragePath(), cfg.DBDSN())
+	ts, err := newTestSrv(cfg)
+	require.NoError(t, err)
+	defer ts.Close()
+
+	cl := newTestClient(ts)
+
+	defer resetStorage(cfg.StoragePath(), cfg.DBDSN())
+
+	multipartBody := func(t *testing.T, url string) (string, io.Reader) {
+		var buf bytes.Buffer
+		mw := multipart.NewWriter(&buf)
+		require.NoError(t, mw.WriteField("url", url))
+		require.NoError(t, mw.Close())
+
+		return mw.FormDataContentType(), &buf
+	}
+
+	gzipBody := func(t *testing.T, s string) io.Reader {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		_, err := zw.Write([]byte(s))
+		require.NoError(t, err)
+		require.NoError(t, zw.Close())
+
+		return &buf
+	}
+
+	post := func(t *testing.T, contentType string, body io.Reader, header http.Header) (*http.Response, string) {
+		req, err := http.NewRequest(http.MethodPost, ts.URL, body)
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", contentType)
+
+		for k, v := range header {
+			req.Header[k] = v
+		}
+
+		res, err := cl.Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+
+		got, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+
+		return res, string(got)
+	}
+
+	t.Run("urlencoded", func(t *testing.T) {
+		res, body := post(t, ctForm+"; charset=utf-8", strings.NewReader(neturl.Values{"url": {cases[0].url}}.Encode()), nil)
+		assert.Equal(t, http.StatusCreated, res.StatusCode)
+		assert.Equal(t, cases[0].want, body)
+	})
+
+	t.Run("multipart", func(t *testing.T) {
+		ct, form := multipartBody(t, cases[1].url)
+		res, body := post(t, ct, form, nil)
+		assert.Equal(t, http.StatusCreated, res.StatusCode)
+		assert.Equal(t, cases[1].want, body)
+	})
+
+	t.Run("gzipped urlencoded", func(t *testing.T) {
+		form := gzipBody(t, neturl.Values{"url": {cases[2].url}}.Encode())
+		res, body := post(t, ctForm, form, http.Header{"Content-Encoding": {"gzip"}})
+		assert.Equal(t, http.StatusCreated, res.StatusCode)
+		assert.Equal(t, cases[2].want, body)
+	})
+
+	t.Run("conflict", func(t *testing.T) {
+		res, body := post(t, ctForm, strings.NewReader(neturl.Values{"url": {cases[0].url}}.Encode()), nil)
+		assert.Equal(t, http.StatusConflict, res.StatusCode)
+		assert.Equal(t, cases[0].want, body)
+	})
+
+	t.Run("no url field", func(t *testing.T) {
+		res, body := post(t, ctForm, strings.NewReader("link=http%3A%2F%2Fgo.dev"), nil)
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+		assert.Contains(t, body, `form field \"url\" is required`)
+	})
+
+	t.Run("HTML page", func(t *testing.T) {
+		ct, form := multipartBody(t, cases[0].url)
+		res, body := post(t, ct, form, http.Header{"Accept": {"text/html,application/xhtml+xml,*/*;q=0.8"}})
+		assert.Equal(t, http.StatusConflict, res.StatusCode)
+		assert.Equal(t, "text/html; charset=utf-8", res.Header.Get("Content-Type"))
+		assert.Contains(t, body, "Already shortened")
+		assert.Contains(t, body, `value="`+cases[0].want+`"`)
+		assert.Contains(t, body, `src="data:image/png;base64,`)
+	})
+
+	t.Run("HTML error page", func(t *testing.T) {
+		res, body := post(t, ctForm, strings.NewReader("url=javascript:alert(1)"), http.Header{"Accept": {"text/html"}})
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+		assert.Equal(t, "text/html; charset=utf-8", res.Header.Get("Content-Type"))
+		assert.Contains(t, body, `class="error"`)
+		assert.NotContains(t, body, "<script")
+	})
+
+	t.Run("cross-origin", func(t *testing.T) {
+		form := neturl.Values{"url": {"http://ya.ru/csrf"}}.Encode()
+
+		res, _ := post(t, ctForm, strings.NewReader(form), http.Header{"Origin": {"http://evil.example"}})
+		assert.Equal(t, http.StatusForbidden, res.StatusCode)
+
+		ct, multipartForm := multipartBody(t, "http://ya.ru/csrf")
+		res, _ = post(t, ct, multipartForm, http.Header{"Sec-Fetch-Site": {"cross-site"}})
+		assert.Equal(t, http.StatusForbidden, res.StatusCode)
+
+		res, _ = post(t, ctForm, strings.NewReader(form), http.Header{"Origin": {ts.URL}, "Sec-Fetch-Site": {"same-origin"}})
+		assert.Equal(t, http.StatusCreated, res.StatusCode)
+	})
 }
 
 func Test_MakeShortJSON(t *testing.T) {
-	cfg := testcfg()
+	cfg := testcfg(t)
 
 	cases := getTests(cfg.BaseURL())
 	resetStorage(cfg.StoragePath(), cfg.DBDSN())
@@ -279,7 +432,7 @@ func Test_MakeShortJSON(t *testing.T) {
 }
 
 func Test_MakeLong_EmptyStorage(t *testing.T) {
-	cfg := testcfg()
+	cfg := testcfg(t)
 
 	cases := getTests(cfg.BaseURL())
 	resetStorage(cfg.StoragePath(), cfg.DBDSN())
@@ -298,7 +451,7 @@ func Test_MakeLong_EmptyStorage(t *testing.T) {
 }
 
 func Test_GetURLsByUser(t *testing.T) {
-	cfg := testcfg()
+	cfg := testcfg(t)
 
 	cases := getTests(cfg.BaseURL())
 	resetStorage(cfg.StoragePath(), cfg.DBDSN())
@@ -358,7 +511,7 @@ func Test_GetURLsByUser(t *testing.T) {
 }
 
 func Test_DeleteBatch(t *testing.T) {
-	cfg := testcfg()
+	cfg := testcfg(t)
 
 	cases := getTests(cfg.BaseURL())
 	resetStorage(cfg.StoragePath(), cfg.DBDSN())
@@ -367,7 +520,8 @@ func Test_DeleteBatch(t *testing.T) {
 	strg, err := storage.New(cfg)
 	require.NoError(t, err)
 
-	s := shortener.NewShortener(cfg, strg)
+	s, err := shortener.NewShortener(cfg, strg)
+	require.NoError(t, err)
 
 	srv := Server{}
 
@@ -375,16 +529,9 @@ func Test_DeleteBatch(t *testing.T) {
 	srv.shortener = s
 	srv.sessionMgr = strg
 	srv.sfgr = new(singleflight.Group)
-	srv.handlers = []router.HandlerDesc{
-		{Method: "POST", Path: "/", Handler: http.HandlerFunc(srv.makeShort), Middlewares: chi.Middlewares{middleware.GzipMW, middleware.AuthMW(strg)}},
-		{Method: "GET", Path: "/{id}", Handler: http.HandlerFunc(srv.makeLong), Middlewares: chi.Middlewares{middleware.GzipMW, middleware.AuthMW(strg)}},
-		{Method: "POST", Path: "/api/shorten", Handler: http.HandlerFunc(srv.makeShortJSON), Middlewares: chi.Middlewares{middleware.GzipMW, middleware.AuthMW(strg)}},
-		{Method: "POST", Path: "/api/shorten/batch", Handler: http.HandlerFunc(srv.shortenBatchJSON), Middlewares: chi.Middlewares{middleware.GzipMW, middleware.AuthMW(strg)}},
-		{Method: "GET", Path: "/api/user/urls", Handler: http.HandlerFunc(srv.makeLongByUser), Middlewares: chi.Middlewares{middleware.GzipMW, middleware.AuthMW(strg)}},
-		{Method: "DELETE", Path: "/api/user/urls", Handler: http.HandlerFunc(srv.deleteBatch), Middlewares: chi.Middlewares{middleware.GzipMW, middleware.AuthMW(strg)}},
-		{Method: "GET", Path: "/ping", Handler: http.HandlerFunc(srv.pingStorage), Middlewares: chi.Middlewares{middleware.GzipMW, middleware.AuthMW(strg)}},
-		{Method: "GET", Path: "/api/internal/stats", Handler: http.HandlerFunc(srv.stats), Middlewares: chi.Middlewares{middleware.GzipMW}},
-	}
+	srv.limiter = ratelimit.New(cfg.RateLimit(), cfg.RateBurst())
+	srv.handlers, err = srv.newHandlers()
+	require.NoError(t, err)
 
 	r := router.NewRouter(&srv)
 
@@ -480,7 +627,7 @@ func Test_DeleteBatch(t *testing.T) {
 }
 
 func Test_MakeLong(t *testing.T) {
-	cfg := testcfg()
+	cfg := testcfg(t)
 
 	cases := getTests(cfg.BaseURL())
 	resetStorage(cfg.StoragePath(), cfg.DBDSN())
@@ -525,7 +672,7 @@ func Test_MakeLong(t *testing.T) {
 	tt := struct {
 		url  string
 		want string
-	}{"gzip.org/test", cfg.BaseURL() + "/" + base64.RawURLEncoding.EncodeToString([]byte("gzip.org/test"))}
+	}{"http://gzip.org/test", cfg.BaseURL() + "/" + base64.RawURLEncoding.EncodeToString([]byte("http://gzip.org/test"))}
 
 	t.Run("Get gzipMW", func(t *testing.T) {
 		resetStorage(cfg.StoragePath(), cfg.DBDSN())
@@ -565,18 +712,22 @@ func resetStorage(path, dsn string) error {
 	return nil
 }
 
-func testcfg() *conf.Config {
-	return conf.New(conf.WithEnvVars(map[string]string{
+func testcfg(t *testing.T) *conf.Config {
+	cfg, err := conf.New(conf.WithEnvVars(map[string]string{
 		"BASE_URL":          "http://localhost:8080",
 		"SERVER_ADDRESS":    "localhost:8080",
 		"FILE_STORAGE_PATH": os.Getenv("HOME") + "/storage.csv",
 		"TRUSTED_SUBNET":    "192.168.0.0/24",
+		"TRUSTED_PROXIES":   "127.0.0.1,::1",
 	}),
 		conf.IgnoreOsArgs())
+	require.NoError(t, err)
+
+	return cfg
 }
 
 func Test_Stats(t *testing.T) {
-	cfg := testcfg()
+	cfg := testcfg(t)
 
 	cases := getTests(cfg.BaseURL())
 	resetStorage(cfg.StoragePath(), cfg.DBDSN())
@@ -592,8 +743,8 @@ func Test_Stats(t *testing.T) {
 	}
 
 	t.Run("trusted call", func(t *testing.T) {
-		
-		req, _ := http.NewRequest("GET", cfg.BaseURL() + "/api/internal/stats", nil)
+
+		req, _ := http.NewRequest("GET", cfg.BaseURL()+"/api/internal/stats", nil)
 		req.Header.Add("X-Real-IP", "192.168.0.1")
 
 		res, err := cl.Do(req)
@@ -614,14 +765,102 @@ func Test_Stats(t *testing.T) {
 	})
 
 	t.Run("untrusted call", func(t *testing.T) {
-		
-		req, _ := http.NewRequest("GET", cfg.BaseURL() + "/api/internal/stats", nil)
+
+		req, _ := http.NewRequest("GET", cfg.BaseURL()+"/api/internal/stats", nil)
 		req.Header.Add("X-Real-IP", "100.168.0.1")
 
 		res, err := cl.Do(req)
 		require.NoError(t, err, "/stats call failed")
 
-		require.Equal(t, http.StatusForbidden, res.StatusCode) 
+		require.Equal(t, http.StatusForbidden, res.StatusCode)
+	})
+
+	t.Run("spoofed forwarded for", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", cfg.BaseURL()+"/api/internal/stats", nil)
+		req.Header.Add("X-Forwarded-For", "192.168.0.1, 100.168.0.1")
+
+		res, err := cl.Do(req)
+		require.NoError(t, err, "/stats call failed")
+		require.NoError(t, res.Body.Close())
+
+		require.Equal(t, http.StatusForbidden, res.StatusCode)
+	})
+}
+
+func Test_ApplyConfig(t *testing.T) {
+	cfg := testcfg(t)
+
+	resetStorage(cfg.StoragePath(), cfg.DBDSN())
+	srv, ts, err := newTestServer(cfg)
+	require.NoError(t, err)
+	defer ts.Close()
+
+	cl := newTestClient(ts)
+
+	stats := func() int {
+		req, _ := http.NewRequest("GET", ts.URL+"/api/internal/stats", nil)
+		req.Header.Add("X-Real-IP", "10.0.0.1")
+
+		res, err := cl.Do(req)
+		require.NoError(t, err, "/stats call failed")
+		require.NoError(t, res.Body.Close())
+
+		return res.StatusCode
+	}
+
+	require.Equal(t, http.StatusForbidden, stats())
+
+	blocklist := filepath.Join(t.TempDir(), "blocklist.txt")
+	require.NoError(t, os.WriteFile(blocklist, []byte("ya.ru\n"), 0o644))
+
+	next, err := conf.New(conf.WithEnvVars(map[string]string{
+		"BASE_URL":          "https://short.example",
+		"SERVER_ADDRESS":    "localhost:8080",
+		"FILE_STORAGE_PATH": cfg.StoragePath(),
+		"TRUSTED_SUBNET":    "10.0.0.0/8",
+		"TRUSTED_PROXIES":   "127.0.0.1,::1",
+		"BLOCKLIST_PATH":    blocklist,
+	}),
+		conf.IgnoreOsArgs())
+	require.NoError(t, err)
+	require.NoError(t, srv.ApplyConfig(next))
+
+	t.Run("trusted subnet", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, stats())
+	})
+
+	t.Run("base URL", func(t *testing.T) {
+		res, err := cl.Post(ts.URL, ctText, bytes.NewBuffer([]byte("http://vk.com/test")))
+		require.NoError(t, err)
+
+		body, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		assert.True(t, strings.HasPrefix(string(body), "https://short.example/"), "got %v", string(body))
+	})
+
+	t.Run("blocklist", func(t *testing.T) {
+		res, err := cl.Post(ts.URL, ctText, bytes.NewBuffer([]byte("http://ya.ru/test")))
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		assert.Equal(t, http.StatusForbidden, res.StatusCode)
+	})
+
+	t.Run("failed apply keeps settings", func(t *testing.T) {
+		bad, err := conf.New(conf.WithEnvVars(map[string]string{
+			"TRUSTED_SUBNET": "172.16.0.0/12",
+			"BLOCKLIST_PATH": blocklist,
+		}),
+			conf.IgnoreOsArgs())
+		require.NoError(t, err)
+
+		// the blocklist is gone by the time the config is applied
+		require.NoError(t, os.Remove(blocklist))
+		require.Error(t, srv.ApplyConfig(bad))
+
+		assert.Equal(t, http.StatusOK, stats())
 	})
 }
 
@@ -633,3 +872,1424 @@ func Test_Stats(t *testing.T) {
 //	}),
 //		cfg.IgnoreOsArgs())
 //}
+
+func Test_RateLimit(t *testing.T) {
+	cfg, err := conf.New(conf.WithEnvVars(map[string]string{
+		"BASE_URL":       "http://localhost:8080",
+		"SERVER_ADDRESS": "localhost:8080",
+		"RATE_LIMIT":     "0.01",
+		"RATE_BURST":     "2",
+	}),
+		conf.IgnoreOsArgs())
+	require.NoError(t, err)
+
+	cases := getTests(cfg.BaseURL())
+	ts, err := newTestSrv(cfg)
+	require.NoError(t, err)
+	defer ts.Close()
+
+	cl := newTestClient(ts)
+
+	t.Run("too many requests", func(t *testing.T) {
+		for _, tt := range cases[:2] {
+			res, err := cl.Post(ts.URL, ctText, bytes.NewBuffer([]byte(tt.url)))
+			require.NoError(t, err, "url: %v", tt.url)
+			require.NoError(t, res.Body.Close())
+			require.Equal(t, http.StatusCreated, res.StatusCode)
+		}
+
+		tt := cases[2]
+		res, err := cl.Post(ts.URL, ctText, bytes.NewBuffer([]byte(tt.url)))
+		require.NoError(t, err, "url: %v", tt.url)
+		require.NoError(t, res.Body.Close())
+		assert.Equal(t, http.StatusTooManyRequests, res.StatusCode, "got wrong status code")
+		assert.NotEmpty(t, res.Header.Get("Retry-After"), "Retry-After header is not set")
+	})
+}
+
+func Test_UserQuota(t *testing.T) {
+	cfg, err := conf.New(conf.WithEnvVars(map[string]string{
+		"BASE_URL":       "http://localhost:8080",
+		"SERVER_ADDRESS": "localhost:8080",
+		"USER_QUOTA":     "2",
+	}),
+		conf.IgnoreOsArgs())
+	require.NoError(t, err)
+
+	cases := getTests(cfg.BaseURL())
+	ts, err := newTestSrv(cfg)
+	require.NoError(t, err)
+	defer ts.Close()
+
+	cl := newTestClient(ts)
+
+	var userID string
+
+	post := func(path, contentType, body string) (*http.Response, string) {
+		req, err := http.NewRequest("POST", ts.URL+path, strings.NewReader(body))
+		require.NoError(t, err, "failed when creating request")
+		req.Header.Set("Content-Type", contentType)
+		req.AddCookie(&http.Cookie{Name: "userID", Value: userID})
+
+		res, err := cl.Do(req)
+		require.NoError(t, err, "body: %v", body)
+		defer res.Body.Close()
+
+		resBody, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+
+		if userID == "" {
+			userID = getUserID(res.Cookies())
+		}
+
+		return res, string(resBody)
+	}
+
+	t.Run("quota exceeded", func(t *testing.T) {
+		for i, tt := range cases {
+			res, body := post("/", ctText, tt.url)
+
+			if i < 2 {
+				require.Equal(t, http.StatusCreated, res.StatusCode)
+			} else {
+				assert.Equal(t, http.StatusForbidden, res.StatusCode, "got wrong status code")
+				assert.Contains(t, body, `"quota_exceeded"`)
+			}
+		}
+	})
+
+	t.Run("stored url is a conflict", func(t *testing.T) {
+		res, body := post("/", ctText, cases[0].url)
+		assert.Equal(t, http.StatusConflict, res.StatusCode, "got wrong status code")
+		assert.Equal(t, cases[0].want, body)
+	})
+
+	t.Run("gateway", func(t *testing.T) {
+		res, body := post("/v2/links", ctJSON, fmt.Sprintf(`{"url": %q}`, cases[2].url))
+		assert.Equal(t, http.StatusForbidden, res.StatusCode, "got wrong status code, body: %v", body)
+		assert.Empty(t, res.Header.Get("Retry-After"))
+	})
+}
+
+func Test_UpdateURL(t *testing.T) {
+	cfg := testcfg(t)
+
+	cases := getTests(cfg.BaseURL())
+	resetStorage(cfg.StoragePath(), cfg.DBDSN())
+	ts, err := newTestSrv(cfg)
+	require.NoError(t, err)
+	defer ts.Close()
+
+	cl := newTestClient(ts)
+
+	defer resetStorage(cfg.StoragePath(), cfg.DBDSN())
+
+	var userID string
+	for _, tt := range cases {
+		req, err := http.NewRequest("POST", ts.URL, bytes.NewBuffer([]byte(tt.url)))
+		require.NoError(t, err, "failed when creating request")
+		req.Header.Set("Content-Type", ctText)
+		req.AddCookie(&http.Cookie{Name: "userID", Value: userID})
+
+		res, err := cl.Do(req)
+		require.NoError(t, err, "url: %v", tt.url)
+		require.NoError(t, res.Body.Close())
+		require.Equal(t, http.StatusCreated, res.StatusCode)
+
+		if userID == "" {
+			userID = getUserID(res.Cookies())
+		}
+	}
+
+	patch := func(id, url, userID string) *http.Response {
+		w := bytes.NewBuffer(nil)
+		require.NoError(t, json.NewEncoder(w).Encode(struct {
+			URL string `json:"url"`
+		}{url}))
+
+		req, err := http.NewRequest("PATCH", ts.URL+"/api/user/urls/"+id, w)
+		require.NoError(t, err, "failed when creating request")
+		req.Header.Set("Content-Type", ctJSON)
+		req.AddCookie(&http.Cookie{Name: "userID", Value: userID})
+
+		res, err := cl.Do(req)
+		require.NoError(t, err)
+
+		return res
+	}
+
+	t.Run("update destination", func(t *testing.T) {
+		tt := cases[0]
+		newURL := "http://ya.ru/new"
+
+		res := patch(tt.id, newURL, userID)
+		message := storage.Pair{}
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&message))
+		require.NoError(t, res.Body.Close())
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, tt.want, message.ShortURL, "short URL must not change")
+		assert.Equal(t, newURL, message.OriginalURL)
+
+		res, err = cl.Get(tt.want)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+		assert.Equal(t, http.StatusTemporaryRedirect, res.StatusCode)
+		assert.Equal(t, newURL, res.Header.Get("Location"), "got wrong location")
+	})
+
+	t.Run("shorten after update", func(t *testing.T) {
+		shorten := func(url string) (int, string) {
+			res, err := cl.Post(ts.URL, ctText, strings.NewReader(url))
+			require.NoError(t, err)
+			defer res.Body.Close()
+
+			body, err := io.ReadAll(res.Body)
+			require.NoError(t, err)
+
+			return res.StatusCode, string(body)
+		}
+
+		// the new destination is stored with the id of the old one
+		status, body := shorten("http://ya.ru/new")
+		assert.Equal(t, http.StatusConflict, status)
+		assert.Equal(t, cases[0].want, body)
+
+		res, err := cl.Post(ts.URL+"/api/shorten", ctJSON, strings.NewReader(`{"url": "http://ya.ru/new"}`))
+		require.NoError(t, err)
+		message := urlres{}
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&message))
+		require.NoError(t, res.Body.Close())
+		assert.Equal(t, http.StatusConflict, res.StatusCode)
+		assert.Equal(t, cases[0].want, message.Result)
+
+		// the id of the old destination is taken
+		status, body = shorten(cases[0].url)
+		require.Equal(t, http.StatusCreated, status)
+		assert.Equal(t, cases[0].want+"-1", body)
+
+		res, err = cl.Get(body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+		assert.Equal(t, http.StatusTemporaryRedirect, res.StatusCode)
+		assert.Equal(t, cases[0].url, res.Header.Get("Location"))
+
+		// batches take the next free id the same way
+		res = patch(cases[1].id, "http://ya.ru/newer", userID)
+		require.NoError(t, res.Body.Close())
+		require.Equal(t, http.StatusOK, res.StatusCode)
+
+		batch, err := json.Marshal([]urlwid{{"1", cases[1].url}})
+		require.NoError(t, err)
+
+		req, err := http.NewRequest("POST", ts.URL+"/api/shorten/batch", bytes.NewBuffer(batch))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", ctJSON)
+		req.AddCookie(&http.Cookie{Name: "userID", Value: userID})
+
+		res, err = cl.Do(req)
+		require.NoError(t, err)
+		var got []urlwidres
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&got))
+		require.NoError(t, res.Body.Close())
+		require.Equal(t, http.StatusCreated, res.StatusCode)
+		assert.Equal(t, []urlwidres{{"1", cases[1].want + "-1"}}, got)
+
+		res, err = cl.Get(got[0].ShortURL)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+		assert.Equal(t, http.StatusTemporaryRedirect, res.StatusCode)
+		assert.Equal(t, cases[1].url, res.Header.Get("Location"))
+	})
+
+	t.Run("conflict", func(t *testing.T) {
+		res := patch(cases[0].id, cases[1].url, userID)
+		require.NoError(t, res.Body.Close())
+		assert.Equal(t, http.StatusConflict, res.StatusCode)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		res := patch("nonexistent", "http://ya.ru/other", userID)
+		require.NoError(t, res.Body.Close())
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+	})
+
+	t.Run("another user", func(t *testing.T) {
+		res := patch(cases[2].id, "http://ya.ru/other", "")
+		require.NoError(t, res.Body.Close())
+		assert.Equal(t, http.StatusForbidden, res.StatusCode)
+	})
+
+	t.Run("invalid URL", func(t *testing.T) {
+		res := patch(cases[2].id, "javascript:alert(1)", userID)
+		require.NoError(t, res.Body.Close())
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+}
+
+func Test_RestoreBatch(t *testing.T) {
+	cfg := testcfg(t)
+
+	cases := getTests(cfg.BaseURL())
+	resetStorage(cfg.StoragePath(), cfg.DBDSN())
+	ts, err := newTestSrv(cfg)
+	require.NoError(t, err)
+	defer ts.Close()
+
+	cl := newTestClient(ts)
+
+	defer resetStorage(cfg.StoragePath(), cfg.DBDSN())
+
+	var userID string
+	ids := make([]string, 0, len(cases))
+	for _, tt := range cases {
+		req, err := http.NewRequest("POST", ts.URL, bytes.NewBuffer([]byte(tt.url)))
+		require.NoError(t, err, "failed when creating request")
+		req.Header.Set("Content-Type", ctText)
+		req.AddCookie(&http.Cookie{Name: "userID", Value: userID})
+
+		res, err := cl.Do(req)
+		require.NoError(t, err, "url: %v", tt.url)
+		require.NoError(t, res.Body.Close())
+		require.Equal(t, http.StatusCreated, res.StatusCode)
+
+		if userID == "" {
+			userID = getUserID(res.Cookies())
+		}
+		ids = append(ids, tt.id)
+	}
+
+	send := func(method, path string) *http.Response {
+		w := bytes.NewBuffer(nil)
+		require.NoError(t, json.NewEncoder(w).Encode(ids), "failed to encode message")
+
+		req, err := http.NewRequest(method, ts.URL+path, w)
+		require.NoError(t, err, "failed when creating request")
+		req.Header.Set("Content-Type", ctJSON)
+		req.AddCookie(&http.Cookie{Name: "userID", Value: userID})
+
+		res, err := cl.Do(req)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		return res
+	}
+
+	t.Run("Restore batch", func(t *testing.T) {
+		res := send("DELETE", "/api/user/urls")
+		require.Equal(t, http.StatusAccepted, res.StatusCode)
+
+		for _, tt := range cases {
+			res, err := cl.Get(tt.want)
+			require.NoError(t, err, "url: %v", tt.url)
+			require.NoError(t, res.Body.Close())
+			require.Equal(t, http.StatusGone, res.StatusCode, "url: %v", tt.url)
+		}
+
+		res = send("POST", "/api/user/urls/restore")
+		require.Equal(t, http.StatusOK, res.StatusCode)
+
+		for _, tt := range cases {
+			res, err := cl.Get(tt.want)
+			require.NoError(t, err, "url: %v", tt.url)
+			require.NoError(t, res.Body.Close())
+			assert.Equal(t, http.StatusTemporaryRedirect, res.StatusCode, "url: %v", tt.url)
+		}
+	})
+}
+
+func Test_Admin(t *testing.T) {
+	cfg, err := conf.New(conf.WithEnvVars(map[string]string{
+		"BASE_URL":          "http://localhost:8080",
+		"SERVER_ADDRESS":    "localhost:8080",
+		"FILE_STORAGE_PATH": os.Getenv("HOME") + "/storage.csv",
+		"ADMIN_TOKEN":       "secret",
+	}),
+		conf.IgnoreOsArgs())
+	require.NoError(t, err)
+
+	cases := getTests(cfg.BaseURL())
+	resetStorage(cfg.StoragePath(), cfg.DBDSN())
+	ts, err := newTestSrv(cfg)
+	require.NoError(t, err)
+	defer ts.Close()
+
+	cl := newTestClient(ts)
+
+	defer resetStorage(cfg.StoragePath(), cfg.DBDSN())
+
+	var token string
+	for _, tt := range cases {
+		req, err := http.NewRequest("POST", ts.URL, bytes.NewBuffer([]byte(tt.url)))
+		require.NoError(t, err, "failed when creating request")
+		req.AddCookie(&http.Cookie{Name: "userID", Value: token})
+
+		res, err := cl.Do(req)
+		require.NoError(t, err, "url: %v", tt.url)
+		require.NoError(t, res.Body.Close())
+		require.Equal(t, http.StatusCreated, res.StatusCode)
+
+		if token == "" {
+			token = getUserID(res.Cookies())
+		}
+	}
+
+	admin := func(method, path, adminToken string, v interface{}) int {
+		req, err := http.NewRequest(method, ts.URL+path, nil)
+		require.NoError(t, err, "failed when creating request")
+		req.Header.Set("Authorization", "Bearer "+adminToken)
+
+		res, err := cl.Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+
+		if v != nil && res.StatusCode == http.StatusOK {
+			require.NoError(t, json.NewDecoder(res.Body).Decode(v))
+		}
+
+		return res.StatusCode
+	}
+
+	redirect := func(shortURL string) int {
+		res, err := cl.Get(shortURL)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		return res.StatusCode
+	}
+
+	t.Run("wrong token", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, admin("GET", "/api/admin/urls/"+cases[0].id, "", nil))
+		assert.Equal(t, http.StatusUnauthorized, admin("GET", "/api/admin/urls/"+cases[0].id, "wrong", nil))
+	})
+
+	var link model.Link
+	require.Equal(t, http.StatusOK, admin("GET", "/api/admin/urls/"+cases[0].id, "secret", &link))
+	assert.Equal(t, cases[0].url, link.OriginalURL)
+	assert.Equal(t, cases[0].want, link.ShortURL)
+	require.NotEmpty(t, link.UserID)
+
+	userID := link.UserID
+
+	t.Run("lookup by destination", func(t *testing.T) {
+		var found model.Link
+		require.Equal(t, http.StatusOK, admin("GET", "/api/admin/urls?url=HTTP://YA.RU/test", "secret", &found))
+		assert.Equal(t, link, found)
+
+		assert.Equal(t, http.StatusNotFound, admin("GET", "/api/admin/urls?url=http://unknown.org/", "secret", nil))
+	})
+
+	t.Run("disable and enable", func(t *testing.T) {
+		require.Equal(t, http.StatusNoContent, admin("POST", "/api/admin/urls/"+cases[0].id+"/disable", "secret", nil))
+		assert.Equal(t, http.StatusForbidden, redirect(cases[0].want))
+
+		require.Equal(t, http.StatusNoContent, admin("POST", "/api/admin/urls/"+cases[0].id+"/enable", "secret", nil))
+		assert.Equal(t, http.StatusTemporaryRedirect, redirect(cases[0].want))
+
+		assert.Equal(t, http.StatusNotFound, admin("POST", "/api/admin/urls/nonexistent/disable", "secret", nil))
+	})
+
+	t.Run("list user links", func(t *testing.T) {
+		links := make([]model.Link, 0)
+		require.Equal(t, http.StatusOK, admin("GET", "/api/admin/users/"+userID+"/urls", "secret", &links))
+		assert.Len(t, links, len(cases))
+	})
+
+	t.Run("invalidate sessions", func(t *testing.T) {
+		var res deletedData
+		require.Equal(t, http.StatusOK, admin("DELETE", "/api/admin/users/"+userID+"/sessions", "secret", &res))
+		assert.Equal(t, 1, res.Deleted)
+
+		req, err := http.NewRequest("GET", ts.URL+"/api/user/urls", nil)
+		require.NoError(t, err, "failed when creating request")
+		req.AddCookie(&http.Cookie{Name: "userID", Value: token})
+
+		resp, err := cl.Do(req)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		assert.Equal(t, http.StatusNoContent, resp.StatusCode, "invalidated session must not see user's links")
+		assert.NotEqual(t, token, getUserID(resp.Cookies()), "new session must be opened")
+	})
+
+	t.Run("delete user", func(t *testing.T) {
+		var res deletedData
+		require.Equal(t, http.StatusOK, admin("DELETE", "/api/admin/users/"+userID, "secret", &res))
+		assert.Equal(t, len(cases), res.Deleted)
+
+		assert.Equal(t, http.StatusNotFound, redirect(cases[1].want))
+		assert.Equal(t, http.StatusNotFound, admin("GET", "/api/admin/urls/"+cases[1].id, "secret", nil))
+	})
+}
+
+func Test_ImportExport(t *testing.T) {
+	cfg, err := conf.New(conf.WithEnvVars(map[string]string{
+		"BASE_URL":          "http://localhost:8080",
+		"SERVER_ADDRESS":    "localhost:8080",
+		"FILE_STORAGE_PATH": os.Getenv("HOME") + "/storage.csv",
+		"ADMIN_TOKEN":       "secret",
+	}),
+		conf.IgnoreOsArgs())
+	require.NoError(t, err)
+
+	resetStorage(cfg.StoragePath(), cfg.DBDSN())
+	ts, err := newTestSrv(cfg)
+	require.NoError(t, err)
+	defer ts.Close()
+
+	cl := newTestClient(ts)
+
+	defer resetStorage(cfg.StoragePath(), cfg.DBDSN())
+
+	var token string
+
+	do := func(method, path, ct string, body string) *http.Response {
+		req, err := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+		require.NoError(t, err, "failed when creating request")
+		req.Header.Set("Content-Type", ct)
+		req.AddCookie(&http.Cookie{Name: "userID", Value: token})
+
+		res, err := cl.Do(req)
+		require.NoError(t, err)
+
+		if token == "" {
+			token = getUserID(res.Cookies())
+		}
+
+		return res
+	}
+
+	t.Run("import csv", func(t *testing.T) {
+		res := do("POST", "/api/user/urls/import", "text/csv",
+			"id,original_url\nold1,http://ya.ru/old\nold1,http://ya.ru/other\nold2,javascript:alert(1)\n,http://go.dev\n")
+		defer res.Body.Close()
+		require.Equal(t, http.StatusOK, res.StatusCode)
+
+		var report importReport
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&report))
+
+		assert.Equal(t, 2, report.Created)
+		assert.Equal(t, 1, report.Conflicts)
+		assert.Equal(t, 1, report.Invalid)
+		require.Len(t, report.Rows, 2)
+		assert.Equal(t, 3, report.Rows[0].Line)
+		assert.Equal(t, "conflict", report.Rows[0].Status)
+		assert.Equal(t, 4, report.Rows[1].Line)
+		assert.Equal(t, "invalid", report.Rows[1].Status)
+
+		redirect, err := cl.Get(ts.URL + "/old1")
+		require.NoError(t, err)
+		require.NoError(t, redirect.Body.Close())
+		assert.Equal(t, http.StatusTemporaryRedirect, redirect.StatusCode)
+		assert.Equal(t, "http://ya.ru/old", redirect.Header.Get("Location"))
+	})
+
+	t.Run("import ndjson", func(t *testing.T) {
+		res := do("POST", "/api/user/urls/import", "application/x-ndjson", `{"id":"old3","url":"http://vk.com/old"}`)
+		defer res.Body.Close()
+		require.Equal(t, http.StatusOK, res.StatusCode)
+
+		var report importReport
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&report))
+		assert.Equal(t, 1, report.Created)
+		assert.Empty(t, report.Rows)
+	})
+
+	t.Run("unsupported content type", func(t *testing.T) {
+		res := do("POST", "/api/user/urls/import", ctJSON, "[]")
+		require.NoError(t, res.Body.Close())
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+
+	t.Run("export", func(t *testing.T) {
+		res := do("GET", "/api/user/urls/export?format=csv", "", "")
+		defer res.Body.Close()
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "text/csv", res.Header.Get("Content-Type"))
+
+		body, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		assert.Contains(t, string(body), "old1,http://localhost:8080/old1,http://ya.ru/old,")
+		assert.Equal(t, 4, strings.Count(string(body), "\n"), "header and 3 links")
+
+		res = do("GET", "/api/user/urls/export?format=xml", "", "")
+		require.NoError(t, res.Body.Close())
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+
+	t.Run("admin export", func(t *testing.T) {
+		token = ""
+		do("POST", "/", ctText, "http://another.user/").Body.Close()
+
+		req, err := http.NewRequest("GET", ts.URL+"/api/admin/urls/export", nil)
+		require.NoError(t, err, "failed when creating request")
+		req.Header.Set("Authorization", "Bearer secret")
+
+		res, err := cl.Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		require.Equal(t, http.StatusOK, res.StatusCode)
+
+		links := make(map[string]model.Link)
+		dec := json.NewDecoder(res.Body)
+		for dec.More() {
+			var link model.Link
+			require.NoError(t, dec.Decode(&link))
+			links[link.ID] = link
+		}
+
+		assert.Len(t, links, 4)
+		assert.Equal(t, "http://localhost:8080/old3", links["old3"].ShortURL)
+	})
+}
+
+func Test_ShortenBatch(t *testing.T) {
+	cfg := testcfg(t)
+
+	cases := getTests(cfg.BaseURL())
+	resetStorage(cfg.StoragePath(), cfg.DBDSN())
+	ts, err := newTestSrv(cfg)
+	require.NoError(t, err)
+	defer ts.Close()
+
+	cl := newTestClient(ts)
+
+	defer resetStorage(cfg.StoragePath(), cfg.DBDSN())
+
+	var userID string
+
+	post := func(path string, batch []urlwid) *http.Response {
+		body, err := json.Marshal(batch)
+		require.NoError(t, err, "failed to encode message")
+
+		req, err := http.NewRequest("POST", ts.URL+path, bytes.NewBuffer(body))
+		require.NoError(t, err, "failed when creating request")
+		req.Header.Set("Content-Type", ctJSON)
+		req.AddCookie(&http.Cookie{Name: "userID", Value: userID})
+
+		res, err := cl.Do(req)
+		require.NoError(t, err)
+
+		if userID == "" {
+			userID = getUserID(res.Cookies())
+		}
+
+		return res
+	}
+
+	res := post("/api/shorten/batch", []urlwid{{"1", cases[0].url}})
+	require.NoError(t, res.Body.Close())
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+
+	t.Run("all-or-nothing", func(t *testing.T) {
+		res := post("/api/shorten/batch", []urlwid{{"1", cases[1].url}, {"2", cases[0].url}})
+		require.NoError(t, res.Body.Close())
+		assert.Equal(t, http.StatusConflict, res.StatusCode)
+
+		redirect, err := cl.Get(cases[1].want)
+		require.NoError(t, err)
+		require.NoError(t, redirect.Body.Close())
+		assert.NotEqual(t, http.StatusTemporaryRedirect, redirect.StatusCode, "batch is partially stored")
+
+		res = post("/api/shorten/batch", []urlwid{{"1", cases[1].url}})
+		defer res.Body.Close()
+		require.Equal(t, http.StatusCreated, res.StatusCode)
+
+		var got []urlwidres
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&got))
+		assert.Equal(t, []urlwidres{{"1", cases[1].want}}, got)
+	})
+
+	t.Run("partial", func(t *testing.T) {
+		res := post("/api/shorten/batch?partial=true",
+			[]urlwid{{"1", cases[2].url}, {"2", cases[0].url}, {"3", "javascript:alert(1)"}})
+		defer res.Body.Close()
+		require.Equal(t, http.StatusOK, res.StatusCode)
+
+		var got []urlwidstatus
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&got))
+		require.Len(t, got, 3)
+
+		assert.Equal(t, urlwidstatus{CorrelationID: "1", ShortURL: cases[2].want, Status: "created"}, got[0])
+		assert.Equal(t, "conflict", got[1].Status)
+		assert.Equal(t, cases[0].want, got[1].ShortURL)
+		assert.Equal(t, "invalid", got[2].Status)
+		assert.NotEmpty(t, got[2].Error)
+	})
+
+	t.Run("invalid partial", func(t *testing.T) {
+		res := post("/api/shorten/batch?partial=maybe", []urlwid{{"1", cases[2].url}})
+		require.NoError(t, res.Body.Close())
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+}
+
+func Test_Gateway(t *testing.T) {
+	cfg := testcfg(t)
+
+	cases := getTests(cfg.BaseURL())
+	resetStorage(cfg.StoragePath(), cfg.DBDSN())
+	ts, err := newTestSrv(cfg)
+	require.NoError(t, err)
+	defer ts.Close()
+
+	cl := newTestClient(ts)
+
+	defer resetStorage(cfg.StoragePath(), cfg.DBDSN())
+
+	type link struct {
+		ID          string `json:"id"`
+		ShortURL    string `json:"short_url"`
+		OriginalURL string `json:"original_url"`
+		CreatedAt   string `json:"created_at"`
+	}
+
+	var userID string
+
+	call := func(method, path, body string, header http.Header) *http.Response {
+		req, err := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+		require.NoError(t, err, "failed when creating request")
+		req.Header.Set("Content-Type", ctJSON)
+		req.AddCookie(&http.Cookie{Name: "userID", Value: userID})
+
+		for k, v := range header {
+			req.Header[k] = v
+		}
+
+		res, err := cl.Do(req)
+		require.NoError(t, err)
+
+		if userID == "" {
+			userID = getUserID(res.Cookies())
+		}
+
+		return res
+	}
+
+	t.Run("shorten opens session", func(t *testing.T) {
+		res := call("POST", "/v2/links", fmt.Sprintf(`{"url": %q}`, cases[0].url), nil)
+		defer res.Body.Close()
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.NotEmpty(t, userID, "session cookie is not set")
+
+		var got link
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&got))
+		assert.Equal(t, cases[0].id, got.ID)
+		assert.Equal(t, cases[0].want, got.ShortURL)
+		assert.NotEmpty(t, got.CreatedAt)
+	})
+
+	t.Run("conflict details", func(t *testing.T) {
+		res := call("POST", "/v2/links", fmt.Sprintf(`{"url": %q}`, cases[0].url), nil)
+		defer res.Body.Close()
+		require.Equal(t, http.StatusConflict, res.StatusCode)
+
+		var got struct {
+			Details []map[string]interface{} `json:"details"`
+		}
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&got))
+		require.Len(t, got.Details, 1)
+		assert.Equal(t, "URL_CONFLICT", got.Details[0]["reason"])
+	})
+
+	t.Run("same session as HTTP API", func(t *testing.T) {
+		res := call("POST", "/api/shorten", fmt.Sprintf(`{"url": %q}`, cases[1].url), nil)
+		require.NoError(t, res.Body.Close())
+		require.Equal(t, http.StatusCreated, res.StatusCode)
+
+		res = call("GET", "/v2/links", "", nil)
+		defer res.Body.Close()
+		require.Equal(t, http.StatusOK, res.StatusCode)
+
+		var got struct {
+			Links []link `json:"links"`
+		}
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&got))
+		assert.Len(t, got.Links, 2)
+	})
+
+	t.Run("metadata headers are ignored", func(t *testing.T) {
+		req, err := http.NewRequest("GET", ts.URL+"/v2/links", nil)
+		require.NoError(t, err)
+		req.Header.Set("Grpc-Metadata-User_id", userID)
+
+		res, err := cl.Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		require.Equal(t, http.StatusOK, res.StatusCode)
+
+		var got struct {
+			Links []link `json:"links"`
+		}
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&got))
+		assert.Empty(t, got.Links, "links of another session are listed")
+	})
+
+	t.Run("get and update", func(t *testing.T) {
+		res := call("PATCH", "/v2/links/"+cases[0].id, fmt.Sprintf(`{"url": %q}`, cases[2].url), nil)
+		require.NoError(t, res.Body.Close())
+		require.Equal(t, http.StatusOK, res.StatusCode)
+
+		res = call("GET", "/v2/links/"+cases[0].id, "", nil)
+		defer res.Body.Close()
+		require.Equal(t, http.StatusOK, res.StatusCode)
+
+		var got link
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&got))
+		assert.Equal(t, cases[2].url, got.OriginalURL)
+	})
+
+	t.Run("stats", func(t *testing.T) {
+		res := call("GET", "/v2/stats", "", http.Header{"X-Real-Ip": {"192.168.0.1"}})
+		defer res.Body.Close()
+		require.Equal(t, http.StatusOK, res.StatusCode)
+
+		var got map[string]string
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&got))
+		assert.Equal(t, map[string]string{"urls": "2", "users": "1"}, got)
+
+		res = call("GET", "/v2/stats", "", http.Header{"X-Real-Ip": {"100.168.0.1"}})
+		require.NoError(t, res.Body.Close())
+		assert.Equal(t, http.StatusForbidden, res.StatusCode)
+	})
+}
+
+func Test_OpenAPI(t *testing.T) {
+	cfg, err := conf.New(conf.WithEnvVars(map[string]string{
+		"BASE_URL":          "http://localhost:8080",
+		"SERVER_ADDRESS":    "localhost:8080",
+		"FILE_STORAGE_PATH": os.Getenv("HOME") + "/storage.csv",
+		"VALIDATE_REQUESTS": "true",
+	}),
+		conf.IgnoreOsArgs())
+	require.NoError(t, err)
+
+	resetStorage(cfg.StoragePath(), cfg.DBDSN())
+	srv, ts, err := newTestServer(cfg)
+	require.NoError(t, err)
+	defer ts.Close()
+
+	cl := newTestClient(ts)
+
+	defer resetStorage(cfg.StoragePath(), cfg.DBDSN())
+
+	t.Run("every route is described", func(t *testing.T) {
+		doc, err := loadSpec()
+		require.NoError(t, err)
+
+		for _, h := range srv.handlers {
+			_, err := specRoute(doc, h)
+			assert.NoError(t, err)
+		}
+	})
+
+	t.Run("undescribed route fails", func(t *testing.T) {
+		handlers := append(srv.handlers[:len(srv.handlers):len(srv.handlers)],
+			router.HandlerDesc{Method: "GET", Path: "/api/undescribed", Handler: http.HandlerFunc(srv.openAPI)})
+
+		_, err := withValidation(handlers)
+		assert.ErrorContains(t, err, "/api/undescribed")
+	})
+
+	t.Run("served", func(t *testing.T) {
+		res, err := cl.Get(ts.URL + "/api/openapi.json")
+		require.NoError(t, err)
+		defer res.Body.Close()
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, ctJSON, res.Header.Get("Content-Type"))
+
+		var got map[string]interface{}
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&got))
+		assert.Equal(t, "3.0.3", got["openapi"])
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		res, err := cl.Post(ts.URL+"/api/shorten", ctJSON, strings.NewReader(`{"url": 1}`))
+		require.NoError(t, err)
+		body, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+		assert.JSONEq(t, `{"code": "bad_request", "message": "request body field \"url\": field must be set to string or not be present", "details": {"field": "url"}}`, string(body))
+
+		res, err = cl.Get(ts.URL + "/api/user/urls/export?format=xml")
+		require.NoError(t, err)
+		body, err = io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+		assert.Contains(t, string(body), `parameter \"format\" in query`)
+
+		res, err = cl.Post(ts.URL+"/api/shorten", ctJSON, strings.NewReader(`{"url": "http://ya.ru"}`))
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+		assert.Equal(t, http.StatusCreated, res.StatusCode)
+
+		res, err = cl.Post(ts.URL+"/api/user/urls/import", "text/csv", strings.NewReader("http://vk.com\n"))
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+
+		res, err = cl.PostForm(ts.URL, neturl.Values{"url": {"http://mail.ru"}})
+		require.NoError(t, err)
+		body, err = io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+		assert.Equal(t, http.StatusCreated, res.StatusCode)
+		assert.True(t, strings.HasPrefix(string(body), cfg.BaseURL()), "got %s", body)
+	})
+}
+
+func Test_ErrorResponses(t *testing.T) {
+	cfg := testcfg(t)
+
+	cases := getTests(cfg.BaseURL())
+	resetStorage(cfg.StoragePath(), cfg.DBDSN())
+	ts, err := newTestSrv(cfg)
+	require.NoError(t, err)
+	defer ts.Close()
+
+	cl := newTestClient(ts)
+
+	defer resetStorage(cfg.StoragePath(), cfg.DBDSN())
+
+	type errorBody struct {
+		Code    string                 `json:"code"`
+		Message string                 `json:"message"`
+		Details map[string]interface{} `json:"details"`
+	}
+
+	decode := func(t *testing.T, res *http.Response) errorBody {
+		defer res.Body.Close()
+
+		assert.Equal(t, ctJSON, res.Header.Get("Content-Type"))
+
+		var got errorBody
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&got))
+
+		return got
+	}
+
+	t.Run("media type parameters", func(t *testing.T) {
+		res, err := cl.Post(ts.URL+"/api/shorten", ctJSON+"; charset=utf-8",
+			strings.NewReader(fmt.Sprintf(`{"url": %q}`, cases[0].url)))
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+		assert.Equal(t, http.StatusCreated, res.StatusCode)
+	})
+
+	t.Run("unsupported content type", func(t *testing.T) {
+		res, err := cl.Post(ts.URL+"/api/shorten", ctXML, strings.NewReader("<url/>"))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusBadRequest, res.StatusCode)
+		assert.Equal(t, "bad_request", decode(t, res).Code)
+	})
+
+	t.Run("invalid url", func(t *testing.T) {
+		res, err := cl.Post(ts.URL+"/api/shorten", ctJSON, strings.NewReader(`{"url": "javascript:alert(1)"}`))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusBadRequest, res.StatusCode)
+		assert.Equal(t, "invalid_url", decode(t, res).Code)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		res, err := cl.Get(ts.URL + "/" + cases[1].id)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusNotFound, res.StatusCode)
+		assert.Equal(t, "not_found", decode(t, res).Code)
+	})
+
+	t.Run("batch item", func(t *testing.T) {
+		body, err := json.Marshal([]urlwid{{"1", cases[1].url}, {"2", cases[0].url}})
+		require.NoError(t, err)
+
+		res, err := cl.Post(ts.URL+"/api/shorten/batch", ctJSON, bytes.NewBuffer(body))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusConflict, res.StatusCode)
+
+		got := decode(t, res)
+		assert.Equal(t, "conflict", got.Code)
+		assert.Equal(t, map[string]interface{}{"correlation_id": "2"}, got.Details)
+	})
+
+	t.Run("plain text", func(t *testing.T) {
+		req, err := http.NewRequest("GET", ts.URL+"/"+cases[1].id, nil)
+		require.NoError(t, err)
+		req.Header.Set("Accept", "text/plain")
+
+		res, err := cl.Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		require.Equal(t, http.StatusNotFound, res.StatusCode)
+		assert.Equal(t, "text/plain; charset=utf-8", res.Header.Get("Content-Type"))
+
+		body, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		assert.False(t, json.Valid(body), "plain text is expected, got %s", body)
+	})
+}
+
+func Test_UI(t *testing.T) {
+	cfg := testcfg(t)
+
+	resetStorage(cfg.StoragePath(), cfg.DBDSN())
+	ts, err := newTestSrv(cfg)
+	require.NoError(t, err)
+	defer ts.Close()
+
+	defer resetStorage(cfg.StoragePath(), cfg.DBDSN())
+
+	// browsers keep the session cookie and do not follow redirects here
+	newBrowser := func(t *testing.T) *http.Client {
+		jar, err := cookiejar.New(nil)
+		require.NoError(t, err)
+
+		return &http.Client{
+			Transport: ts.Client().Transport,
+			Jar:       jar,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		}
+	}
+
+	get := func(t *testing.T, cl *http.Client, path string) (*http.Response, string) {
+		res, err := cl.Get(ts.URL + path)
+		require.NoError(t, err)
+		defer res.Body.Close()
+
+		body, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+
+		return res, string(body)
+	}
+
+	post := func(t *testing.T, cl *http.Client, path string, form neturl.Values) *http.Response {
+		res, err := cl.PostForm(ts.URL+path, form)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		return res
+	}
+
+	cl := newBrowser(t)
+
+	res, body := get(t, cl, "/ui")
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "text/html; charset=utf-8", res.Header.Get("Content-Type"))
+	assert.Contains(t, body, "No links yet.")
+
+	url := "http://ya.ru/ui"
+	id := base64.RawURLEncoding.EncodeToString([]byte(url))
+	shortURL := cfg.BaseURL() + "/" + id
+
+	t.Run("shorten", func(t *testing.T) {
+		res := post(t, cl, "/ui/links", neturl.Values{"url": {url}})
+		require.Equal(t, http.StatusSeeOther, res.StatusCode)
+		assert.Equal(t, "/ui?shortened="+id, res.Header.Get("Location"))
+
+		res, body := get(t, cl, res.Header.Get("Location"))
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Contains(t, body, `class="notice"`)
+		assert.Contains(t, body, shortURL)
+		assert.Contains(t, body, "1 links, 0 clicks")
+	})
+
+	t.Run("invalid url", func(t *testing.T) {
+		res, err := cl.PostForm(ts.URL+"/ui/links", neturl.Values{"url": {"javascript:alert(1)"}})
+		require.NoError(t, err)
+		defer res.Body.Close()
+
+		body, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+		assert.Contains(t, string(body), `class="error"`)
+	})
+
+	t.Run("clicks", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			res, _ := get(t, cl, "/"+id)
+			require.Equal(t, http.StatusTemporaryRedirect, res.StatusCode)
+		}
+
+		res, body := get(t, cl, "/ui/links/"+id)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Contains(t, body, "<dt>Clicks</dt><dd>2</dd>")
+		assert.Contains(t, body, `src="data:image/png;base64,`)
+
+		_, body = get(t, cl, "/ui")
+		assert.Contains(t, body, "1 links, 2 clicks")
+	})
+
+	t.Run("other user", func(t *testing.T) {
+		res, _ := get(t, newBrowser(t), "/ui/links/"+id)
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+	})
+
+	t.Run("delete and restore", func(t *testing.T) {
+		res := post(t, cl, "/ui/links/"+id+"/delete", neturl.Values{"page": {"1"}})
+		require.Equal(t, http.StatusSeeOther, res.StatusCode)
+		assert.Equal(t, "/ui?page=1", res.Header.Get("Location"))
+
+		res, _ = get(t, cl, "/"+id)
+		assert.Equal(t, http.StatusGone, res.StatusCode)
+
+		_, body := get(t, cl, "/ui")
+		assert.Contains(t, body, `action="/ui/links/`+id+`/restore"`)
+
+		res = post(t, cl, "/ui/links/"+id+"/restore", nil)
+		require.Equal(t, http.StatusSeeOther, res.StatusCode)
+		assert.Equal(t, "/ui", res.Header.Get("Location"))
+
+		res, _ = get(t, cl, "/"+id)
+		assert.Equal(t, http.StatusTemporaryRedirect, res.StatusCode)
+	})
+
+	t.Run("cross origin", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodPost, ts.URL+"/ui/links/"+id+"/delete", nil)
+		require.NoError(t, err)
+		req.Header.Set("Origin", "http://example.com")
+
+		res, err := cl.Do(req)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+		assert.Equal(t, http.StatusForbidden, res.StatusCode)
+
+		req, err = http.NewRequest(http.MethodPost, ts.URL+"/ui/links/"+id+"/delete", nil)
+		require.NoError(t, err)
+		req.Header.Set("Sec-Fetch-Site", "cross-site")
+
+		res, err = cl.Do(req)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+		assert.Equal(t, http.StatusForbidden, res.StatusCode)
+
+		res, _ = get(t, cl, "/"+id)
+		assert.Equal(t, http.StatusTemporaryRedirect, res.StatusCode)
+	})
+
+	t.Run("pages", func(t *testing.T) {
+		batch := make([]urlwid, uiPageSize)
+		for i := range batch {
+			batch[i] = urlwid{strconv.Itoa(i), fmt.Sprintf("http://ya.ru/ui/%v", i)}
+		}
+
+		body, err := json.Marshal(batch)
+		require.NoError(t, err)
+
+		res, err := cl.Post(ts.URL+"/api/shorten/batch", ctJSON, bytes.NewBuffer(body))
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+		require.Equal(t, http.StatusCreated, res.StatusCode)
+
+		_, page := get(t, cl, "/ui")
+		assert.Contains(t, page, "Page 1 of 2")
+		assert.Contains(t, page, `href="/ui?page=2"`)
+
+		_, page = get(t, cl, "/ui?page=9")
+		assert.Contains(t, page, "Page 2 of 2")
+		assert.Contains(t, page, `href="/ui?page=1"`)
+		// every row has a delete or restore form
+		assert.Equal(t, 1, strings.Count(page, `action="/ui/links/`), "the last page has the last link only")
+	})
+
+	t.Run("static", func(t *testing.T) {
+		res, body := get(t, cl, "/ui/static/ui.css")
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "text/css; charset=utf-8", res.Header.Get("Content-Type"))
+		assert.Contains(t, body, "body {")
+
+		res, _ = get(t, cl, "/ui/static/missing.css")
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+	})
+}
+
+func Test_QRCode(t *testing.T) {
+	cfg := testcfg(t)
+
+	cases := getTests(cfg.BaseURL())
+	resetStorage(cfg.StoragePath(), cfg.DBDSN())
+	ts, err := newTestSrv(cfg)
+	require.NoError(t, err)
+	defer ts.Close()
+
+	cl := newTestClient(ts)
+
+	defer resetStorage(cfg.StoragePath(), cfg.DBDSN())
+
+	var userID string
+
+	for _, tt := range cases[:2] {
+		req, err := http.NewRequest(http.MethodPost, ts.URL, strings.NewReader(tt.url))
+		require.NoError(t, err)
+		req.AddCookie(&http.Cookie{Name: "userID", Value: userID})
+
+		res, err := cl.Do(req)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+		require.Equal(t, http.StatusCreated, res.StatusCode)
+
+		if userID == "" {
+			userID = getUserID(res.Cookies())
+		}
+	}
+
+	get := func(t *testing.T, path string) (*http.Response, []byte) {
+		res, err := cl.Get(ts.URL + path)
+		require.NoError(t, err)
+		defer res.Body.Close()
+
+		body, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+
+		return res, body
+	}
+
+	t.Run("png", func(t *testing.T) {
+		res, body := get(t, "/"+cases[0].id+"/qr?size=300&level=h")
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "image/png", res.Header.Get("Content-Type"))
+
+		img, err := png.Decode(bytes.NewReader(body))
+		require.NoError(t, err)
+		assert.Equal(t, 300, img.Bounds().Dx())
+	})
+
+	t.Run("svg", func(t *testing.T) {
+		res, body := get(t, "/"+cases[0].id+"/qr?format=svg&margin=0")
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "image/svg+xml", res.Header.Get("Content-Type"))
+		assert.True(t, bytes.HasPrefix(body, []byte("<svg")))
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		for _, query := range []string{"format=gif", "size=0", "size=ten", "size=10", "margin=-1", "level=X"} {
+			res, _ := get(t, "/"+cases[0].id+"/qr?"+query)
+			assert.Equal(t, http.StatusBadRequest, res.StatusCode, query)
+		}
+	})
+
+	t.Run("same errors as redirects", func(t *testing.T) {
+		body := fmt.Sprintf(`[%q]`, cases[1].id)
+		req, err := http.NewRequest(http.MethodDelete, ts.URL+"/api/user/urls", strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", ctJSON)
+		req.AddCookie(&http.Cookie{Name: "userID", Value: userID})
+
+		res, err := cl.Do(req)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+		require.Equal(t, http.StatusAccepted, res.StatusCode)
+
+		res, _ = get(t, "/"+cases[1].id+"/qr")
+		assert.Equal(t, http.StatusGone, res.StatusCode)
+
+		res, _ = get(t, "/"+cases[2].id+"/qr")
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+	})
+
+	t.Run("gateway", func(t *testing.T) {
+		res, body := get(t, "/v2/links/"+cases[0].id+"/qr?format=QR_FORMAT_SVG&size=128")
+		require.Equal(t, http.StatusOK, res.StatusCode, string(body))
+
+		var got struct {
+			ContentType string `json:"content_type"`
+			Data        []byte `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(body, &got))
+		assert.Equal(t, "image/svg+xml", got.ContentType)
+		assert.Contains(t, string(got.Data), `width="128"`)
+	})
+}
+
+func Test_Preview(t *testing.T) {
+	cfg, err := conf.New(conf.WithEnvVars(map[string]string{
+		"BASE_URL":          "http://localhost:8080",
+		"SERVER_ADDRESS":    "localhost:8080",
+		"FILE_STORAGE_PATH": os.Getenv("HOME") + "/storage.csv",
+		"VALIDATE_REQUESTS": "true",
+	}),
+		conf.IgnoreOsArgs())
+	require.NoError(t, err)
+
+	cases := getTests(cfg.BaseURL())
+	resetStorage(cfg.StoragePath(), cfg.DBDSN())
+	ts, err := newTestSrv(cfg)
+	require.NoError(t, err)
+	defer ts.Close()
+
+	defer resetStorage(cfg.StoragePath(), cfg.DBDSN())
+
+	jar, err := cookiejar.New(nil)
+	require.NoError(t, err)
+
+	cl := &http.Client{
+		Transport: ts.Client().Transport,
+		Jar:       jar,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	for _, tt := range cases[:2] {
+		res, err := cl.Post(ts.URL, ctText, strings.NewReader(tt.url))
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+		require.Equal(t, http.StatusCreated, res.StatusCode)
+	}
+
+	get := func(t *testing.T, path string) (*http.Response, string) {
+		res, err := cl.Get(ts.URL + path)
+		require.NoError(t, err)
+		defer res.Body.Close()
+
+		body, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+
+		return res, string(body)
+	}
+
+	settings := func(t *testing.T, id, body string) (*http.Response, []byte) {
+		req, err := http.NewRequest(http.MethodPut, ts.URL+"/api/user/urls/"+id+"/settings", strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", ctJSON)
+
+		res, err := cl.Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+
+		resBody, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+
+		return res, resBody
+	}
+
+	clicks := func(t *testing.T, id string) int64 {
+		res, body := get(t, "/api/user/urls/export?format=ndjson")
+		require.Equal(t, http.StatusOK, res.StatusCode)
+
+		dec := json.NewDecoder(strings.NewReader(body))
+		for dec.More() {
+			var link model.Link
+			require.NoError(t, dec.Decode(&link))
+
+			if link.ID == id {
+				return link.Clicks
+			}
+		}
+
+		t.Fatalf("link %v is not exported", id)
+
+		return 0
+	}
+
+	id := cases[0].id
+
+	t.Run("preview", func(t *testing.T) {
+		for _, path := range []string{"/" + id + "+", "/" + id + "?preview=1", "/" + id + "?preview=true"} {
+			res, body := get(t, path)
+			require.Equal(t, http.StatusOK, res.StatusCode, path)
+			assert.Equal(t, "text/html; charset=utf-8", res.Header.Get("Content-Type"), path)
+			assert.Contains(t, body, cases[0].url, path)
+			assert.Contains(t, body, `href="`+cases[0].want+`?continue=1"`, path)
+		}
+
+		assert.Zero(t, clicks(t, id), "previews are not clicks")
+
+		res, _ := get(t, "/"+cases[2].id+"+")
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+	})
+
+	t.Run("settings", func(t *testing.T) {
+		res, body := settings(t, id, `{"title": "  Yandex <search>  ", "interstitial": true}`)
+		require.Equal(t, http.StatusOK, res.StatusCode, string(body))
+
+		var link model.Link
+		require.NoError(t, json.Unmarshal(body, &link))
+		assert.Equal(t, "Yandex <search>", link.Title)
+		assert.True(t, link.Interstitial)
+		assert.Equal(t, cases[0].want, link.ShortURL)
+
+		res, body = settings(t, id, `{"title": "`+strings.Repeat("a", 201)+`"}`)
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode, string(body))
+
+		res, body = settings(t, id, `{"title": "line\nbreak"}`)
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+		assert.Contains(t, string(body), `"invalid_title"`)
+
+		res, _ = settings(t, cases[2].id, `{"title": "missing"}`)
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+
+		other, err := cookiejar.New(nil)
+		require.NoError(t, err)
+
+		req, err := http.NewRequest(http.MethodPut, ts.URL+"/api/user/urls/"+id+"/settings", strings.NewReader(`{}`))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", ctJSON)
+
+		res, err = (&http.Client{Transport: ts.Client().Transport, Jar: other}).Do(req)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+		assert.Equal(t, http.StatusForbidden, res.StatusCode)
+	})
+
+	t.Run("interstitial", func(t *testing.T) {
+		res, body := get(t, "/"+id)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Contains(t, body, "Yandex &lt;search&gt;")
+		assert.Zero(t, clicks(t, id))
+
+		res, _ = get(t, "/"+id+"?continue=1")
+		require.Equal(t, http.StatusTemporaryRedirect, res.StatusCode)
+		assert.Equal(t, cases[0].url, res.Header.Get("Location"))
+		assert.Equal(t, int64(1), clicks(t, id))
+
+		// other links redirect right away
+		res, _ = get(t, "/"+cases[1].id)
+		assert.Equal(t, http.StatusTemporaryRedirect, res.StatusCode)
+	})
+
+	t.Run("web UI", func(t *testing.T) {
+		res, err := cl.PostForm(ts.URL+"/ui/links/"+id+"/settings", neturl.Values{"title": {"Search"}})
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+		require.Equal(t, http.StatusSeeOther, res.StatusCode)
+		assert.Equal(t, "/ui/links/"+id, res.Header.Get("Location"))
+
+		_, body := get(t, "/ui/links/"+id)
+		assert.Contains(t, body, `value="Search"`)
+		assert.NotContains(t, body, " checked")
+
+		res, _ = get(t, "/"+id)
+		assert.Equal(t, http.StatusTemporaryRedirect, res.StatusCode)
+	})
+}
+
+func Test_LongURL(t *testing.T) {
+	cfg := testcfg(t)
+
+	resetStorage(cfg.StoragePath(), cfg.DBDSN())
+	ts, err := newTestSrv(cfg)
+	require.NoError(t, err)
+	defer ts.Close()
+
+	cl := newTestClient(ts)
+
+	defer resetStorage(cfg.StoragePath(), cfg.DBDSN())
+
+	// longer than columns of tables created by older versions
+	for _, length := range []int{101, 2048} {
+		longURL := "http://ya.ru/" + strings.Repeat("a", length-len("http://ya.ru/"))
+
+		res, err := cl.Post(ts.URL, ctText, strings.NewReader(longURL))
+		require.NoError(t, err)
+		body, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+		require.Equal(t, http.StatusCreated, res.StatusCode, "length %v", length)
+
+		res, err = cl.Get(ts.URL + strings.TrimPrefix(string(body), cfg.BaseURL()))
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+		assert.Equal(t, http.StatusTemporaryRedirect, res.StatusCode, "length %v", length)
+		assert.Equal(t, longURL, res.Header.Get("Location"), "length %v", length)
+	}
+}
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/usa4ev/urlshortner/internal/server/auth"
+	"github.com/usa4ev/urlshortner/internal/server/httpserver/apierror"
+)
+
+// AdminMW returns middleware that only lets through requests
+// with the admin token in the Authorization header.
+// All requests are rejected if adminToken is empty.
+func AdminMW(adminToken string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if adminToken == "" {
+				apierror.Write(w, r, apierror.New(http.StatusForbidden, apierror.CodeForbidden, "admin API is disabled"))
+
+				return
+			}
+
+			if !auth.IsAdmin(adminToken, auth.BearerToken(r.Header.Get("Authorization"))) {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				apierror.Write(w, r, apierror.New(http.StatusUnauthorized, apierror.CodeUnauthorized, "invalid admin token"))
+
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
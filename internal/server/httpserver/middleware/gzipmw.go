@@ -4,6 +4,8 @@ import (
 	"compress/gzip"
 	"io"
 	"net/http"
+
+	"github.com/usa4ev/urlshortner/internal/server/httpserver/apierror"
 )
 
 // gzipWriter is used to replace default http.ResponseWriter
@@ -28,7 +30,7 @@ func GzipMW(next http.Handler) http.Handler {
 				if v == "gzip" {
 					writer, err := gzip.NewWriterLevel(w, gzip.BestSpeed)
 					if err != nil {
-						http.Error(w, err.Error(), http.StatusInternalServerError)
+						apierror.Write(w, r, apierror.Internal("%v", err))
 
 						return
 					}
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/usa4ev/urlshortner/internal/server/httpserver/apierror"
+	"github.com/usa4ev/urlshortner/internal/server/ratelimit"
+	"github.com/usa4ev/urlshortner/internal/server/trustednet"
+)
+
+// RateLimitMW returns middleware that responds with 429 Too Many Requests
+// when the client identified by keyFunc exceeds the limit.
+// Requests with an empty key are not limited.
+func RateLimitMW(l *ratelimit.Limiter, keyFunc func(r *http.Request) string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := keyFunc(r)
+			if key == "" {
+				next.ServeHTTP(w, r)
+
+				return
+			}
+
+			if ok, wait := l.Allow(key); !ok {
+				retryAfter := ratelimit.RetryAfter(wait)
+				w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+				apierror.Write(w, r, apierror.New(http.StatusTooManyRequests, apierror.CodeRateLimited, "too many requests").
+					With("retry_after", retryAfter))
+
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ByIP returns a RateLimitMW key function that identifies clients by IP
+// resolved with n, so clients behind a trusted proxy are limited separately.
+func ByIP(n *trustednet.Network) func(r *http.Request) string {
+	return func(r *http.Request) string {
+		if ip := n.RequestIP(r); ip != nil {
+			return "ip:" + ip.String()
+		}
+
+		return "ip:" + r.RemoteAddr
+	}
+}
+
+// ByUser is a RateLimitMW key function that identifies clients by user ID.
+// It has to be used after AuthMW.
+func ByUser(r *http.Request) string {
+	userID, _ := r.Context().Value(CtxKeyUserID).(string)
+	if userID == "" {
+		return ""
+	}
+
+	return "user:" + userID
+}
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/usa4ev/urlshortner/internal/server/httpserver/apierror"
+)
+
+// SameOriginMW returns middleware that responds with 403 Forbidden to
+// requests with unsafe methods sent by browsers from other sites, so pages
+// of other sites cannot post forms with the session cookie of the user.
+// Browsers tell the origin with the Sec-Fetch-Site or Origin header,
+// requests without them, e.g. made by curl, are let through.
+func SameOriginMW(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+
+			return
+		}
+
+		if !sameOrigin(r) {
+			apierror.Write(w, r, apierror.New(http.StatusForbidden, apierror.CodeForbidden, "cross-origin request"))
+
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// sameOrigin reports whether r is not sent from another site.
+func sameOrigin(r *http.Request) bool {
+	switch r.Header.Get("Sec-Fetch-Site") {
+	case "same-origin", "none":
+		return true
+	case "":
+	default:
+		return false
+	}
+
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+
+	return err == nil && u.Host == r.Host
+}
@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/usa4ev/urlshortner/internal/server/httpserver/apierror"
+	"github.com/usa4ev/urlshortner/internal/server/trustednet"
+)
+
+// TrustedSubnetMW returns middleware that responds with 403 Forbidden
+// unless the client IP belongs to one of the trusted subnets.
+func TrustedSubnetMW(n *trustednet.Network) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !n.Contains(n.RequestIP(r)) {
+				apierror.Write(w, r, apierror.New(http.StatusForbidden, apierror.CodeForbidden, "call from untrusted subnet"))
+
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/go-chi/chi"
+
+	"github.com/usa4ev/urlshortner/internal/server/httpserver/apierror"
+)
+
+// ValidateMW returns middleware that responds with 400 Bad Request
+// if the request does not match the operation of route.
+// Authentication is left to other middlewares. Bodies of media types
+// without a decoder, e.g. text/csv, and gzipped bodies are not validated.
+func ValidateMW(route *routers.Route) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			input := &openapi3filter.RequestValidationInput{
+				Request:    r,
+				PathParams: make(map[string]string),
+				Route:      route,
+				Options: &openapi3filter.Options{
+					ExcludeRequestBody: skipBody(r),
+					AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+				},
+			}
+
+			if rctx := chi.RouteContext(r.Context()); rctx != nil {
+				for i, key := range rctx.URLParams.Keys {
+					input.PathParams[key] = rctx.URLParams.Values[i]
+				}
+			}
+
+			if err := openapi3filter.ValidateRequest(r.Context(), input); err != nil {
+				apierror.Write(w, r, validationError(err))
+
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// validationError returns the error response of a request that does not
+// match the specification. It names the invalid parameter or body field
+// instead of dumping the schema.
+func validationError(err error) *apierror.Error {
+	var reqErr *openapi3filter.RequestError
+	if !errors.As(err, &reqErr) {
+		return apierror.BadRequest("%v", err)
+	}
+
+	reason := reqErr.Reason
+
+	var schemaErr *openapi3.SchemaError
+	if errors.As(reqErr.Err, &schemaErr) {
+		reason = schemaErr.Reason
+	} else if reqErr.Err != nil && reason == "" {
+		reason = reqErr.Err.Error()
+	}
+
+	switch {
+	case reqErr.Parameter != nil:
+		return apierror.BadRequest("parameter %q in %v: %v", reqErr.Parameter.Name, reqErr.Parameter.In, reason).
+			With("parameter", reqErr.Parameter.Name)
+	case schemaErr != nil:
+		field := strings.Join(schemaErr.JSONPointer(), ".")
+
+		return apierror.BadRequest("request body field %q: %v", field, reason).With("field", field)
+	default:
+		return apierror.BadRequest("request body: %v", reason)
+	}
+}
+
+// skipBody reports whether the request body cannot be validated.
+func skipBody(r *http.Request) bool {
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		return true
+	}
+
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	return err == nil && openapi3filter.RegisteredBodyDecoder(mediaType) == nil
+}
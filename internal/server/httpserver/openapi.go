@@ -0,0 +1,84 @@
+package httpserver
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"net/http"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/routers"
+
+	"github.com/usa4ev/urlshortner/internal/router"
+	"github.com/usa4ev/urlshortner/internal/server/httpserver/middleware"
+)
+
+// openAPISpec is the OpenAPI 3 document that describes every route of handlers.
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// loadSpec returns the parsed and validated OpenAPI document.
+func loadSpec() (*openapi3.T, error) {
+	doc, err := openapi3.NewLoader().LoadFromData(openAPISpec)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := doc.Validate(context.Background()); err != nil {
+		return nil, err
+	}
+
+	return doc, nil
+}
+
+// specRoute returns the operation of doc that describes h.
+func specRoute(doc *openapi3.T, h router.HandlerDesc) (*routers.Route, error) {
+	pathItem := doc.Paths.Find(h.Path)
+	if pathItem == nil {
+		return nil, fmt.Errorf("path %v is not described", h.Path)
+	}
+
+	op := pathItem.GetOperation(h.Method)
+	if op == nil {
+		return nil, fmt.Errorf("%v %v is not described", h.Method, h.Path)
+	}
+
+	return &routers.Route{
+		Spec:      doc,
+		Path:      h.Path,
+		PathItem:  pathItem,
+		Method:    h.Method,
+		Operation: op,
+	}, nil
+}
+
+// withValidation returns handlers that validate requests against the OpenAPI
+// document after other middlewares of the route, so requests are authorized
+// and rate limited before they are validated. It fails if the document
+// is invalid or does not describe some of the handlers.
+func withValidation(handlers []router.HandlerDesc) ([]router.HandlerDesc, error) {
+	doc, err := loadSpec()
+	if err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI document: %w", err)
+	}
+
+	res := make([]router.HandlerDesc, len(handlers))
+	for i, h := range handlers {
+		route, err := specRoute(doc, h)
+		if err != nil {
+			return nil, err
+		}
+
+		res[i] = h
+		res[i].Middlewares = append(h.Middlewares[:len(h.Middlewares):len(h.Middlewares)], middleware.ValidateMW(route))
+	}
+
+	return res, nil
+}
+
+// openAPI responds with the OpenAPI document of the server.
+func (srv *Server) openAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", ctJSON)
+	w.Write(openAPISpec)
+}
@@ -0,0 +1,80 @@
+package httpserver
+
+import (
+	"bytes"
+	"embed"
+	"encoding/base64"
+	"html/template"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/usa4ev/urlshortner/internal/qrcode"
+)
+
+const ctHTML string = "text/html"
+
+// templatesFS holds HTML pages rendered for browsers.
+//
+//go:embed templates
+var templatesFS embed.FS
+
+var pages = template.Must(template.ParseFS(templatesFS, "templates/*.html"))
+
+// shortenedPage is the data of templates/shortened.html.
+type shortenedPage struct {
+	OriginalURL string
+	ShortURL    string
+	// QR is a data URL of the PNG QR code of ShortURL.
+	QR      template.URL
+	Existed bool
+	Error   string
+}
+
+// acceptsHTML reports whether the client asks for an HTML page explicitly,
+// as browsers do; */* does not count, so curl keeps plain text responses.
+func acceptsHTML(r *http.Request) bool {
+	for _, v := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(v))
+		if err == nil && params["q"] != "0" && (mediaType == ctHTML || mediaType == "application/xhtml+xml") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// qrDataURL returns the default QR code of s as a data URL,
+// or an empty URL if s is too long to encode.
+func qrDataURL(s string) template.URL {
+	code, err := qrcode.Encode(s, qrcode.DefaultOptions())
+	if err != nil {
+		log.Printf("failed to encode QR code: %v", err)
+
+		return ""
+	}
+
+	// the PNG is trusted, it is not user input
+	return template.URL("data:image/png;base64," + base64.StdEncoding.EncodeToString(code))
+}
+
+// renderPage responds with the HTML page name rendered with data.
+// The page is rendered before the status is sent,
+// so a broken template results in 500 Internal Server Error.
+func renderPage(w http.ResponseWriter, status int, name string, data interface{}) {
+	var buf bytes.Buffer
+	if err := pages.ExecuteTemplate(&buf, name, data); err != nil {
+		log.Printf("failed to render %v: %v", name, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", ctHTML+"; charset=utf-8")
+	w.WriteHeader(status)
+
+	if _, err := buf.WriteTo(w); err != nil {
+		log.Printf("failed to write response: %v", err)
+	}
+}
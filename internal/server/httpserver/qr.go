@@ -0,0 +1,84 @@
+package httpserver
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	neturl "net/url"
+	"strconv"
+
+	"github.com/go-chi/chi"
+
+	"github.com/usa4ev/urlshortner/internal/qrcode"
+	"github.com/usa4ev/urlshortner/internal/server/httpserver/apierror"
+)
+
+// qrCode responds with the QR code of the short URL of id. Query parameters
+// format (png or svg), size in pixels, margin in modules and error correction
+// level (L, M, Q or H) define the image. Links that cannot be followed
+// get the same errors as redirects.
+func (srv *Server) qrCode(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	opts, err := qrOptions(r.URL.Query())
+	if err != nil {
+		apierror.Write(w, r, apierror.BadRequest("%v", err))
+
+		return
+	}
+
+	if _, ok := srv.findURL(w, r, id); !ok {
+		return
+	}
+
+	data, err := qrcode.Encode(srv.shortener.MakeURL(id), opts)
+	switch {
+	case errors.Is(err, qrcode.ErrInvalidOptions):
+		apierror.Write(w, r, apierror.BadRequest("%v", err))
+
+		return
+	case err != nil:
+		apierror.Write(w, r, apierror.Internal("failed to encode QR code: %v", err))
+
+		return
+	}
+
+	w.Header().Set("Content-Type", opts.Format.ContentType())
+
+	if _, err := w.Write(data); err != nil {
+		log.Printf("failed to write response: %v", err)
+	}
+}
+
+// qrOptions returns QR code options of query, defaults for missing ones.
+func qrOptions(query neturl.Values) (qrcode.Options, error) {
+	var err error
+
+	opts := qrcode.DefaultOptions()
+
+	if v := query.Get("format"); v != "" {
+		if opts.Format, err = qrcode.ParseFormat(v); err != nil {
+			return opts, err
+		}
+	}
+
+	if v := query.Get("level"); v != "" {
+		if opts.Level, err = qrcode.ParseLevel(v); err != nil {
+			return opts, err
+		}
+	}
+
+	if v := query.Get("size"); v != "" {
+		if opts.Size, err = strconv.Atoi(v); err != nil {
+			return opts, errors.New("size is not a number")
+		}
+	}
+
+	if v := query.Get("margin"); v != "" {
+		if opts.Margin, err = strconv.Atoi(v); err != nil {
+			return opts, errors.New("margin is not a number")
+		}
+	}
+
+	return opts, opts.Validate()
+}
@@ -0,0 +1,256 @@
+package httpserver
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"math"
+	"net/http"
+	neturl "net/url"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi"
+
+	"github.com/usa4ev/urlshortner/internal/router"
+	"github.com/usa4ev/urlshortner/internal/server/httpserver/apierror"
+	"github.com/usa4ev/urlshortner/internal/server/httpserver/middleware"
+	"github.com/usa4ev/urlshortner/internal/storage/model"
+	"github.com/usa4ev/urlshortner/internal/storage/storageerrors"
+)
+
+// uiPageSize is the number of links on a page of the web UI.
+const uiPageSize = 20
+
+// staticFS holds assets of the web UI.
+//
+//go:embed static
+var staticFS embed.FS
+
+type (
+	// linksPage is the data of templates/links.html.
+	linksPage struct {
+		Links []model.Link
+		// Page is the number of the page starting with 1,
+		// Prev and Next are 0 if there is no such page.
+		Page, Pages, Prev, Next int
+		Total                   int
+		Clicks                  int64
+		// Shortened is the link just stored.
+		Shortened *model.Link
+		// URL is the rejected destination of Error.
+		URL   string
+		Error string
+	}
+
+	// linkPage is the data of templates/link.html.
+	linkPage struct {
+		Link model.Link
+		// QR is a data URL of the PNG QR code of the link.
+		QR template.URL
+	}
+)
+
+// uiHandlers returns routes of the web UI. Its forms work without
+// JavaScript, every action is a POST that redirects back to a page.
+func (srv *Server) uiHandlers(session chi.Middlewares) []router.HandlerDesc {
+	ui := append(session[:len(session):len(session)], middleware.SameOriginMW)
+
+	return []router.HandlerDesc{
+		{Method: "GET", Path: "/ui", Handler: http.HandlerFunc(srv.uiLinks), Middlewares: ui},
+		{Method: "POST", Path: "/ui/links", Handler: http.HandlerFunc(srv.uiShorten), Middlewares: ui},
+		{Method: "GET", Path: "/ui/links/{id}", Handler: http.HandlerFunc(srv.uiLink), Middlewares: ui},
+		{Method: "POST", Path: "/ui/links/{id}/delete", Handler: http.HandlerFunc(srv.uiDelete), Middlewares: ui},
+		{Method: "POST", Path: "/ui/links/{id}/restore", Handler: http.HandlerFunc(srv.uiRestore), Middlewares: ui},
+		{Method: "POST", Path: "/ui/links/{id}/settings", Handler: http.HandlerFunc(srv.uiSettings), Middlewares: ui},
+		{Method: "GET", Path: "/ui/static/{name}", Handler: http.HandlerFunc(uiStatic), Middlewares: chi.Middlewares{middleware.GzipMW}},
+	}
+}
+
+// uiLinks renders the page with the shorten form and links of the user,
+// deleted ones included so they can be restored, newest first.
+func (srv *Server) uiLinks(w http.ResponseWriter, r *http.Request) {
+	data := linksPage{}
+
+	if id := r.URL.Query().Get("shortened"); id != "" {
+		if link, err := srv.userLink(r, id); err == nil {
+			data.Shortened = &link
+		}
+	}
+
+	srv.renderLinks(w, r, http.StatusOK, data)
+}
+
+// renderLinks renders the page of links requested by r with data.
+func (srv *Server) renderLinks(w http.ResponseWriter, r *http.Request, status int, data linksPage) {
+	data.Page = pageNumber(r.URL.Query().Get("page"))
+
+	page, err := srv.shortener.LoadLinksPage(userID(r), (data.Page-1)*uiPageSize, uiPageSize)
+	if err == nil && page.Total > 0 && len(page.Links) == 0 {
+		// the page is past the last one, show the last one
+		data.Page = (page.Total + uiPageSize - 1) / uiPageSize
+		page, err = srv.shortener.LoadLinksPage(userID(r), (data.Page-1)*uiPageSize, uiPageSize)
+	}
+
+	if err != nil {
+		renderError(w, apierror.Internal("failed to load links: %v", err))
+
+		return
+	}
+
+	data.Total = page.Total
+	data.Clicks = page.Clicks
+	data.Pages = (page.Total + uiPageSize - 1) / uiPageSize
+
+	if data.Page > 1 {
+		data.Prev = data.Page - 1
+	}
+
+	if data.Page < data.Pages {
+		data.Next = data.Page + 1
+	}
+
+	data.Links = page.Links
+
+	renderPage(w, status, "links.html", data)
+}
+
+// pageNumber returns page number s, the first one if s is not a positive number.
+// Numbers are limited so that the offset of the page fits int32.
+func pageNumber(s string) int {
+	n, err := strconv.Atoi(s)
+
+	switch {
+	case err != nil || n < 1:
+		return 1
+	case n > math.MaxInt32/uiPageSize:
+		return math.MaxInt32 / uiPageSize
+	default:
+		return n
+	}
+}
+
+// uiShorten stores the url form field and redirects to the page of links
+// showing the short URL, or renders the page with the error.
+func (srv *Server) uiShorten(w http.ResponseWriter, r *http.Request) {
+	rawURL := r.PostFormValue("url")
+
+	originalURL, err := srv.shortener.NormalizeURL(rawURL)
+	if err != nil {
+		srv.uiShortenError(w, r, rawURL, err)
+
+		return
+	}
+
+	link, err := srv.shortener.Shorten(originalURL, userID(r))
+	if err != nil && !(errors.Is(err, storageerrors.ErrConflict) && link.ID != "") {
+		srv.uiShortenError(w, r, rawURL, err)
+
+		return
+	}
+
+	http.Redirect(w, r, "/ui?shortened="+neturl.QueryEscape(link.ID), http.StatusSeeOther)
+}
+
+func (srv *Server) uiShortenError(w http.ResponseWriter, r *http.Request, rawURL string, err error) {
+	e := apierror.From(err)
+
+	srv.renderLinks(w, r, e.Status, linksPage{URL: rawURL, Error: e.Message})
+}
+
+// uiLink renders the page with the link and its click statistics.
+func (srv *Server) uiLink(w http.ResponseWriter, r *http.Request) {
+	link, err := srv.userLink(r, chi.URLParam(r, "id"))
+	if err != nil {
+		renderError(w, err)
+
+		return
+	}
+
+	renderPage(w, http.StatusOK, "link.html", linkPage{Link: link, QR: qrDataURL(link.ShortURL)})
+}
+
+// uiDelete deletes the link and redirects back to the page of links.
+func (srv *Server) uiDelete(w http.ResponseWriter, r *http.Request) {
+	srv.uiUpdate(w, r, srv.shortener.DeleteURLs)
+}
+
+// uiRestore restores the link and redirects back to the page of links.
+func (srv *Server) uiRestore(w http.ResponseWriter, r *http.Request) {
+	srv.uiUpdate(w, r, srv.shortener.RestoreURLs)
+}
+
+func (srv *Server) uiUpdate(w http.ResponseWriter, r *http.Request, update func(userID string, ids []string) error) {
+	if err := update(userID(r), []string{chi.URLParam(r, "id")}); err != nil {
+		renderError(w, apierror.From(err))
+
+		return
+	}
+
+	target := "/ui"
+	if page := r.PostFormValue("page"); page != "" {
+		target += "?page=" + neturl.QueryEscape(page)
+	}
+
+	http.Redirect(w, r, target, http.StatusSeeOther)
+}
+
+// uiSettings saves the title and interstitial form fields
+// and redirects back to the page of the link.
+func (srv *Server) uiSettings(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	interstitial, _ := strconv.ParseBool(r.PostFormValue("interstitial"))
+
+	settings := model.LinkSettings{Title: r.PostFormValue("title"), Interstitial: interstitial}
+	if err := srv.shortener.UpdateSettings(userID(r), id, settings); err != nil {
+		renderError(w, apierror.From(err))
+
+		return
+	}
+
+	http.Redirect(w, r, "/ui/links/"+neturl.PathEscape(id), http.StatusSeeOther)
+}
+
+// userLink returns the link stored by id if it belongs to the user of r.
+// Links of other users are not found, so their ids are not disclosed.
+func (srv *Server) userLink(r *http.Request, id string) (model.Link, error) {
+	link, err := srv.shortener.LoadLink(id)
+	if err != nil || link.UserID != userID(r) {
+		return model.Link{}, apierror.New(http.StatusNotFound, apierror.CodeNotFound, fmt.Sprintf("link %v not found", id))
+	}
+
+	return link, nil
+}
+
+// uiStatic serves the asset name of the web UI.
+func uiStatic(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	data, err := fs.ReadFile(staticFS, path.Join("static", path.Base(name)))
+	if err != nil {
+		apierror.Write(w, r, apierror.New(http.StatusNotFound, apierror.CodeNotFound, fmt.Sprintf("asset %v not found", name)))
+
+		return
+	}
+
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	http.ServeContent(w, r, name, time.Time{}, bytes.NewReader(data))
+}
+
+// renderError renders the error page of err.
+func renderError(w http.ResponseWriter, err error) {
+	e := apierror.From(err)
+
+	renderPage(w, e.Status, "error.html", e)
+}
+
+// userID returns the user of the session loaded by AuthMW.
+func userID(r *http.Request) string {
+	id, _ := r.Context().Value(middleware.CtxKeyUserID).(string)
+
+	return id
+}
@@ -0,0 +1,94 @@
+// Package identity maps client certificates to service identities.
+//
+// Identities are configured as a comma-separated list of name=role pairs,
+// e.g. "stats-exporter=internal,ops.example.com=admin". A certificate
+// matches a name if its subject common name or any of its DNS or URI
+// subject alternative names equals the name.
+package identity
+
+import (
+	"crypto/x509"
+	"fmt"
+	"strings"
+)
+
+// Role defines what a service identity is allowed to call.
+type Role int
+
+const (
+	RoleNone     Role = iota
+	RoleInternal      // internal methods such as stats
+	RoleAdmin         // internal and admin methods
+)
+
+var roles = map[string]Role{
+	"internal": RoleInternal,
+	"admin":    RoleAdmin,
+}
+
+// Identity is a service authenticated by a client certificate.
+type Identity struct {
+	Name string
+	Role Role
+}
+
+// Map holds configured service identities.
+// A nil Map has no identities.
+type Map struct {
+	names map[string]Role
+}
+
+// Parse returns a Map from a comma-separated list of name=role pairs.
+func Parse(s string) (*Map, error) {
+	m := Map{names: make(map[string]Role)}
+
+	for _, v := range strings.Split(s, ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+
+		name, roleName, ok := strings.Cut(v, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid service identity %q, want name=role", v)
+		}
+
+		role, ok := roles[roleName]
+		if !ok {
+			return nil, fmt.Errorf("invalid role %q of service identity %v", roleName, name)
+		}
+
+		m.names[name] = role
+	}
+
+	return &m, nil
+}
+
+// FromCert returns the identity of a verified client certificate.
+// Common name is checked first, then DNS and URI SANs.
+func (m *Map) FromCert(cert *x509.Certificate) (Identity, bool) {
+	if m == nil || cert == nil {
+		return Identity{}, false
+	}
+
+	names := make([]string, 0, 1+len(cert.DNSNames)+len(cert.URIs))
+	names = append(names, cert.Subject.CommonName)
+	names = append(names, cert.DNSNames...)
+
+	for _, u := range cert.URIs {
+		names = append(names, u.String())
+	}
+
+	for _, name := range names {
+		if role, ok := m.names[name]; ok && name != "" {
+			return Identity{Name: name, Role: role}, true
+		}
+	}
+
+	return Identity{}, false
+}
+
+// Allows reports whether the identity may call methods requiring role.
+func (id Identity) Allows(role Role) bool {
+	return id.Role >= role
+}
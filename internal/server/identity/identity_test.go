@@ -0,0 +1,56 @@
+package identity
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	_, err := Parse("stats-exporter=internal, ops=admin")
+	require.NoError(t, err)
+
+	_, err = Parse("stats-exporter")
+	assert.Error(t, err)
+
+	_, err = Parse("stats-exporter=root")
+	assert.Error(t, err)
+}
+
+func TestMap_FromCert(t *testing.T) {
+	m, err := Parse("stats-exporter=internal,ops.example.com=admin,spiffe://example.com/cli=admin")
+	require.NoError(t, err)
+
+	spiffe, err := url.Parse("spiffe://example.com/cli")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		cert *x509.Certificate
+		want Identity
+		ok   bool
+	}{
+		{"common name", &x509.Certificate{Subject: pkix.Name{CommonName: "stats-exporter"}}, Identity{"stats-exporter", RoleInternal}, true},
+		{"dns san", &x509.Certificate{DNSNames: []string{"other", "ops.example.com"}}, Identity{"ops.example.com", RoleAdmin}, true},
+		{"uri san", &x509.Certificate{URIs: []*url.URL{spiffe}}, Identity{"spiffe://example.com/cli", RoleAdmin}, true},
+		{"unknown", &x509.Certificate{Subject: pkix.Name{CommonName: "someone"}}, Identity{}, false},
+		{"no certificate", nil, Identity{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := m.FromCert(tt.cert)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	t.Run("roles", func(t *testing.T) {
+		assert.True(t, Identity{Role: RoleAdmin}.Allows(RoleInternal))
+		assert.False(t, Identity{Role: RoleInternal}.Allows(RoleAdmin))
+	})
+}
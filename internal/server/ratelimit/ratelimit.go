@@ -0,0 +1,119 @@
+// Package ratelimit implements a keyed token-bucket rate limiter
+// shared by HTTP and gRPC servers.
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepInterval defines how often idle buckets are removed.
+const sweepInterval = time.Minute
+
+type (
+	// Limiter keeps a token bucket per key, e.g. per client IP or per user ID.
+	Limiter struct {
+		rate      float64 // tokens added per second
+		burst     float64 // bucket capacity
+		mx        sync.Mutex
+		buckets   map[string]*bucket
+		lastSweep time.Time
+		now       func() time.Time
+	}
+
+	bucket struct {
+		tokens float64
+		last   time.Time
+	}
+)
+
+// New returns a Limiter that allows rate requests per second
+// with bursts of up to burst requests for every key.
+// Limiter with non-positive rate allows everything.
+func New(rate float64, burst int) *Limiter {
+	if burst < 1 {
+		burst = int(math.Max(1, math.Ceil(rate)))
+	}
+
+	return &Limiter{
+		rate:    rate,
+		burst:   float64(burst),
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// SetRate changes the rate and the burst of l keeping the state of its buckets.
+// Arguments are treated the same way as by New.
+func (l *Limiter) SetRate(rate float64, burst int) {
+	if burst < 1 {
+		burst = int(math.Max(1, math.Ceil(rate)))
+	}
+
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
+	l.rate = rate
+	l.burst = float64(burst)
+}
+
+// Allow reports whether a request identified by key may proceed.
+// If it may not, Allow returns how long the client should wait before retrying.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	if l == nil {
+		return true, 0
+	}
+
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
+	if l.rate <= 0 {
+		return true, 0
+	}
+
+	now := l.now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+
+		return false, wait
+	}
+
+	b.tokens--
+
+	return true, 0
+}
+
+// sweep removes buckets that have been refilled completely,
+// since they are no different from new ones.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+
+	l.lastSweep = now
+	full := time.Duration(l.burst / l.rate * float64(time.Second))
+
+	for k, b := range l.buckets {
+		if now.Sub(b.last) >= full {
+			delete(l.buckets, k)
+		}
+	}
+}
+
+// RetryAfter formats wait as a number of whole seconds
+// suitable for a Retry-After header.
+func RetryAfter(wait time.Duration) int {
+	return int(math.Ceil(wait.Seconds()))
+}
@@ -0,0 +1,60 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiter_Allow(t *testing.T) {
+	now := time.Now()
+	l := New(1, 2)
+	l.now = func() time.Time { return now }
+
+	t.Run("burst", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			ok, _ := l.Allow("ip:1")
+			assert.True(t, ok, "request %v within burst is rejected", i)
+		}
+
+		ok, wait := l.Allow("ip:1")
+		assert.False(t, ok, "request over burst is allowed")
+		assert.Equal(t, time.Second, wait)
+		assert.Equal(t, 1, RetryAfter(wait))
+	})
+
+	t.Run("keys are independent", func(t *testing.T) {
+		ok, _ := l.Allow("ip:2")
+		assert.True(t, ok)
+	})
+
+	t.Run("refill", func(t *testing.T) {
+		now = now.Add(time.Second)
+		ok, _ := l.Allow("ip:1")
+		assert.True(t, ok)
+	})
+
+	t.Run("set rate", func(t *testing.T) {
+		ok, _ := l.Allow("ip:3")
+		assert.True(t, ok)
+
+		l.SetRate(0, 0)
+		ok, _ = l.Allow("ip:3")
+		assert.True(t, ok, "request is limited after limiting is disabled")
+
+		l.SetRate(1, 1)
+		ok, _ = l.Allow("ip:3")
+		assert.True(t, ok)
+		ok, _ = l.Allow("ip:3")
+		assert.False(t, ok, "bucket state is lost after rate change")
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		l := New(0, 0)
+		for i := 0; i < 100; i++ {
+			ok, _ := l.Allow("ip:1")
+			assert.True(t, ok)
+		}
+	})
+}
@@ -3,6 +3,7 @@ package server
 import (
 	"context"
 
+	conf "github.com/usa4ev/urlshortner/internal/config"
 	"github.com/usa4ev/urlshortner/internal/server/auth"
 	"github.com/usa4ev/urlshortner/internal/server/grpcserver"
 	"github.com/usa4ev/urlshortner/internal/server/httpserver"
@@ -13,22 +14,36 @@ type (
 	Server interface {
 		Run() error
 		Shutdown(ctx context.Context) error
+		// ApplyConfig applies settings that can change without a restart.
+		ApplyConfig(c *conf.Config) error
+		// ReloadCertificates rereads TLS certificate files.
+		ReloadCertificates() error
 	}
 
 	config interface {
 		GRPC() bool
 		UseTLS() bool
-		SslPath() string
+		TLSCertPath() string
+		TLSKeyPath() string
+		TLSMinVersion() string
+		TLSCipherSuites() string
 		DBDSN() string
 		SrvAddr() string
 		TrustedSubnet() string
+		TrustedProxies() string
+		RateLimit() float64
+		RateBurst() int
+		AdminToken() string
+		ClientCAPath() string
+		ServiceIdentities() string
+		ValidateRequests() bool
 	}
 )
 
-func New(c config, s shortener.Shortener, sm auth.SessionStoreLoader) Server {
+func New(c config, s shortener.Shortener, sm auth.SessionStoreLoader) (Server, error) {
 
 	if c.GRPC() {
-		return grpcserver.New(c, s, sm)
+		return grpcserver.New(c, s, sm), nil
 	} else {
 		return httpserver.New(c, s, sm)
 	}
@@ -0,0 +1,197 @@
+// Package tlsconfig builds TLS configuration shared by HTTP and gRPC servers.
+// The server certificate is served through tls.Config.GetCertificate
+// and is reloaded whenever its files change or Reload is called,
+// so certificates can be renewed without restarting the server.
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// reloadInterval defines how often certificate files are checked for changes.
+const reloadInterval = 5 * time.Second
+
+var versions = map[string]uint16{
+	"":    tls.VersionTLS12,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+type (
+	config interface {
+		TLSCertPath() string
+		TLSKeyPath() string
+		TLSMinVersion() string
+		TLSCipherSuites() string
+	}
+
+	// Loader keeps the server certificate in sync with its files.
+	Loader struct {
+		certPath string
+		keyPath  string
+		cert     atomic.Value // holds *tls.Certificate
+		mx       sync.Mutex
+		modTime  time.Time
+	}
+)
+
+// New returns TLS configuration with the minimum version and cipher suites
+// set by c and the Loader of the certificate it serves.
+func New(c config) (*tls.Config, *Loader, error) {
+	minVersion, err := ParseVersion(c.TLSMinVersion())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ciphers, err := ParseCipherSuites(c.TLSCipherSuites())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	l, err := NewLoader(c.TLSCertPath(), c.TLSKeyPath())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &tls.Config{
+		GetCertificate: l.GetCertificate,
+		MinVersion:     minVersion,
+		// cipher suites of TLS 1.3 are not configurable
+		CipherSuites: ciphers,
+	}, l, nil
+}
+
+// NewLoader loads the certificate from certPath and keyPath
+// and reloads it whenever the files change.
+func NewLoader(certPath, keyPath string) (*Loader, error) {
+	l := &Loader{certPath: certPath, keyPath: keyPath}
+
+	if err := l.Reload(); err != nil {
+		return nil, err
+	}
+
+	go func() {
+		t := time.NewTicker(reloadInterval)
+		defer t.Stop()
+
+		for range t.C {
+			if err := l.reloadIfChanged(); err != nil {
+				log.Printf("failed to reload TLS certificate %v: %v", certPath, err)
+			}
+		}
+	}()
+
+	return l, nil
+}
+
+// GetCertificate returns the last successfully loaded certificate.
+func (l *Loader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return l.cert.Load().(*tls.Certificate), nil
+}
+
+// Reload reads the certificate files. A certificate that fails
+// to load leaves the previous one in place.
+func (l *Loader) Reload() error {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
+	modTime, err := l.lastModified()
+	if err != nil {
+		return err
+	}
+
+	return l.load(modTime)
+}
+
+func (l *Loader) reloadIfChanged() error {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
+	modTime, err := l.lastModified()
+	if err != nil {
+		return err
+	}
+
+	if modTime.Equal(l.modTime) {
+		return nil
+	}
+
+	return l.load(modTime)
+}
+
+func (l *Loader) load(modTime time.Time) error {
+	cert, err := tls.LoadX509KeyPair(l.certPath, l.keyPath)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+
+	l.cert.Store(&cert)
+	l.modTime = modTime
+
+	return nil
+}
+
+// lastModified returns the latest modification time of the certificate files.
+func (l *Loader) lastModified() (time.Time, error) {
+	var modTime time.Time
+
+	for _, p := range []string{l.certPath, l.keyPath} {
+		fi, err := os.Stat(p)
+		if err != nil {
+			return time.Time{}, err
+		}
+
+		if fi.ModTime().After(modTime) {
+			modTime = fi.ModTime()
+		}
+	}
+
+	return modTime, nil
+}
+
+// ParseVersion returns the TLS version of "1.2" or "1.3".
+// Empty string results in TLS 1.2.
+func ParseVersion(s string) (uint16, error) {
+	v, ok := versions[s]
+	if !ok {
+		return 0, fmt.Errorf("unsupported minimum TLS version %q, want 1.2 or 1.3", s)
+	}
+
+	return v, nil
+}
+
+// ParseCipherSuites returns IDs of comma-separated cipher suite names.
+// Only suites without known security issues are accepted.
+// Empty list results in Go defaults.
+func ParseCipherSuites(s string) ([]uint16, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	known := make(map[string]uint16)
+	for _, cs := range tls.CipherSuites() {
+		known[cs.Name] = cs.ID
+	}
+
+	ids := make([]uint16, 0)
+
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+
+		id, ok := known[name]
+		if !ok {
+			return nil, fmt.Errorf("unsupported or insecure cipher suite %q", name)
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
@@ -0,0 +1,133 @@
+package tlsconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testConfig struct {
+	cert, key, minVersion, ciphers string
+}
+
+func (c testConfig) TLSCertPath() string     { return c.cert }
+func (c testConfig) TLSKeyPath() string      { return c.key }
+func (c testConfig) TLSMinVersion() string   { return c.minVersion }
+func (c testConfig) TLSCipherSuites() string { return c.ciphers }
+
+func TestNew(t *testing.T) {
+	dir := t.TempDir()
+	cfg := testConfig{cert: filepath.Join(dir, "example.crt"), key: filepath.Join(dir, "example.key")}
+	writeCert(t, cfg.cert, cfg.key, "first")
+
+	t.Run("defaults", func(t *testing.T) {
+		tlsCfg, _, err := New(cfg)
+		require.NoError(t, err)
+		assert.Equal(t, uint16(tls.VersionTLS12), tlsCfg.MinVersion)
+		assert.Nil(t, tlsCfg.CipherSuites)
+	})
+
+	t.Run("version and ciphers", func(t *testing.T) {
+		c := cfg
+		c.minVersion = "1.3"
+		c.ciphers = "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256, TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384"
+
+		tlsCfg, _, err := New(c)
+		require.NoError(t, err)
+		assert.Equal(t, uint16(tls.VersionTLS13), tlsCfg.MinVersion)
+		assert.Equal(t, []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256, tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384}, tlsCfg.CipherSuites)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		c := cfg
+		c.minVersion = "1.0"
+		_, _, err := New(c)
+		assert.Error(t, err)
+
+		c = cfg
+		c.ciphers = "TLS_RSA_WITH_RC4_128_SHA"
+		_, _, err = New(c)
+		assert.Error(t, err, "insecure suites must be rejected")
+
+		c = cfg
+		c.cert = filepath.Join(dir, "missing.crt")
+		_, _, err = New(c)
+		assert.Error(t, err)
+	})
+}
+
+func TestLoader_Reload(t *testing.T) {
+	dir := t.TempDir()
+	certPath, keyPath := filepath.Join(dir, "example.crt"), filepath.Join(dir, "example.key")
+	writeCert(t, certPath, keyPath, "first")
+
+	l, err := NewLoader(certPath, keyPath)
+	require.NoError(t, err)
+	assert.Equal(t, "first", commonName(t, l))
+
+	t.Run("changed files", func(t *testing.T) {
+		writeCert(t, certPath, keyPath, "second")
+		// make sure modification time differs on coarse-grained file systems
+		require.NoError(t, os.Chtimes(certPath, time.Now(), time.Now().Add(time.Second)))
+		require.NoError(t, l.reloadIfChanged())
+
+		assert.Equal(t, "second", commonName(t, l))
+	})
+
+	t.Run("forced", func(t *testing.T) {
+		writeCert(t, certPath, keyPath, "third")
+		require.NoError(t, l.Reload())
+
+		assert.Equal(t, "third", commonName(t, l))
+	})
+
+	t.Run("broken files keep previous certificate", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(certPath, []byte("garbage"), 0o644))
+		assert.Error(t, l.Reload())
+
+		assert.Equal(t, "third", commonName(t, l))
+	})
+}
+
+func commonName(t *testing.T, l *Loader) string {
+	cert, err := l.GetCertificate(nil)
+	require.NoError(t, err)
+
+	x, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+
+	return x.Subject.CommonName
+}
+
+func writeCert(t *testing.T, certPath, keyPath, cn string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o644))
+	require.NoError(t, os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+}
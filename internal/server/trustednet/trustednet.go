@@ -0,0 +1,179 @@
+// Package trustednet resolves client IP addresses of requests
+// passed through trusted proxies and checks them against trusted subnets.
+package trustednet
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+type (
+	// Network holds trusted subnets and trusted proxies.
+	// A nil or zero Network trusts nobody and resolves client IP
+	// from the remote address only.
+	Network struct {
+		lists atomic.Value // holds *lists
+	}
+
+	lists struct {
+		subnets []*net.IPNet
+		proxies []*net.IPNet
+	}
+)
+
+// New returns a Network parsing subnets and proxies
+// from comma-separated lists of CIDRs or single IPs.
+func New(subnets, proxies string) (*Network, error) {
+	var (
+		l   lists
+		err error
+	)
+
+	if l.subnets, err = parseNets(subnets); err != nil {
+		return nil, fmt.Errorf("failed to parse trusted subnets: %w", err)
+	}
+
+	if l.proxies, err = parseNets(proxies); err != nil {
+		return nil, fmt.Errorf("failed to parse trusted proxies: %w", err)
+	}
+
+	n := &Network{}
+	n.lists.Store(&l)
+
+	return n, nil
+}
+
+// Set replaces subnets and proxies of n with those of next atomically,
+// so requests in flight see either the old or the new lists.
+func (n *Network) Set(next *Network) {
+	l := next.load()
+	if l == nil {
+		l = &lists{}
+	}
+
+	n.lists.Store(l)
+}
+
+// load returns the current lists or nil if n trusts nobody.
+func (n *Network) load() *lists {
+	if n == nil {
+		return nil
+	}
+
+	l, _ := n.lists.Load().(*lists)
+
+	return l
+}
+
+// Contains reports whether ip belongs to one of the trusted subnets.
+func (n *Network) Contains(ip net.IP) bool {
+	l := n.load()
+
+	return l != nil && contains(l.subnets, ip)
+}
+
+// ClientIP returns IP of the client that sent a request from remoteAddr.
+// Forwarding headers are only taken into account if remoteAddr is a trusted proxy.
+// The X-Forwarded-For chain is walked from the nearest hop, skipping
+// trusted proxies, so entries added by the client itself are never used
+// unless the whole chain consists of trusted proxies.
+// X-Real-IP is used if X-Forwarded-For is empty.
+func (n *Network) ClientIP(remoteAddr string, forwardedFor []string, realIP string) net.IP {
+	ip := parseIP(remoteAddr)
+
+	l := n.load()
+	if l == nil || ip == nil || !contains(l.proxies, ip) {
+		return ip
+	}
+
+	hops := make([]string, 0)
+	for _, v := range forwardedFor {
+		hops = append(hops, strings.Split(v, ",")...)
+	}
+
+	if len(hops) == 0 && realIP != "" {
+		hops = append(hops, realIP)
+	}
+
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := parseIP(strings.TrimSpace(hops[i]))
+		if hop == nil {
+			// the rest of the chain cannot be trusted
+			return ip
+		}
+
+		ip = hop
+		if !contains(l.proxies, ip) {
+			return ip
+		}
+	}
+
+	return ip
+}
+
+// RequestIP returns IP of the client that sent r.
+func (n *Network) RequestIP(r *http.Request) net.IP {
+	return n.ClientIP(r.RemoteAddr, r.Header.Values("X-Forwarded-For"), r.Header.Get("X-Real-IP"))
+}
+
+// parseIP parses IP from a string that may contain a port.
+func parseIP(s string) net.IP {
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		s = host
+	}
+
+	return net.ParseIP(s)
+}
+
+func contains(nets []*net.IPNet, ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func parseNets(s string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0)
+
+	for _, v := range strings.Split(s, ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+
+		if !strings.Contains(v, "/") {
+			ip := net.ParseIP(v)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IP address %q", v)
+			}
+
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+
+			continue
+		}
+
+		_, n, err := net.ParseCIDR(v)
+		if err != nil {
+			return nil, err
+		}
+
+		nets = append(nets, n)
+	}
+
+	return nets, nil
+}
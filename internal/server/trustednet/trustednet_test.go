@@ -0,0 +1,78 @@
+package trustednet
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	_, err := New("192.168.0.0/24, 10.0.0.1", "::1")
+	require.NoError(t, err)
+
+	_, err = New("192.168.0.0/33", "")
+	assert.Error(t, err)
+
+	_, err = New("", "localhost")
+	assert.Error(t, err)
+}
+
+func TestNetwork_Contains(t *testing.T) {
+	n, err := New("192.168.0.0/24,10.0.0.1", "")
+	require.NoError(t, err)
+
+	assert.True(t, n.Contains(net.ParseIP("192.168.0.15")))
+	assert.True(t, n.Contains(net.ParseIP("10.0.0.1")))
+	assert.False(t, n.Contains(net.ParseIP("10.0.0.2")))
+	assert.False(t, n.Contains(nil))
+
+	var empty *Network
+	assert.False(t, empty.Contains(net.ParseIP("192.168.0.15")))
+}
+
+func TestNetwork_Set(t *testing.T) {
+	n := &Network{}
+	assert.False(t, n.Contains(net.ParseIP("192.168.0.15")))
+
+	next, err := New("192.168.0.0/24", "")
+	require.NoError(t, err)
+
+	n.Set(next)
+	assert.True(t, n.Contains(net.ParseIP("192.168.0.15")))
+
+	n.Set(nil)
+	assert.False(t, n.Contains(net.ParseIP("192.168.0.15")))
+}
+
+func TestNetwork_ClientIP(t *testing.T) {
+	n, err := New("", "127.0.0.1, 10.0.0.0/8")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor []string
+		realIP       string
+		want         string
+	}{
+		{"direct", "8.8.8.8:1234", nil, "", "8.8.8.8"},
+		{"untrusted peer headers are ignored", "8.8.8.8:1234", []string{"1.1.1.1"}, "1.1.1.1", "8.8.8.8"},
+		{"real ip via proxy", "127.0.0.1:1234", nil, "1.1.1.1", "1.1.1.1"},
+		{"forwarded via proxy", "127.0.0.1:1234", []string{"1.1.1.1"}, "2.2.2.2", "1.1.1.1"},
+		{"spoofed entry is skipped", "127.0.0.1:1234", []string{"192.168.0.1, 1.1.1.1"}, "", "1.1.1.1"},
+		{"chain of proxies", "127.0.0.1:1234", []string{"1.1.1.1, 10.0.0.2", "10.0.0.3"}, "", "1.1.1.1"},
+		{"only proxies", "127.0.0.1:1234", []string{"10.0.0.2"}, "", "10.0.0.2"},
+		{"malformed entry", "127.0.0.1:1234", []string{"1.1.1.1, junk"}, "", "127.0.0.1"},
+		{"no headers", "127.0.0.1:1234", nil, "", "127.0.0.1"},
+		{"address without port", "8.8.8.8", nil, "", "8.8.8.8"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := n.ClientIP(tt.remoteAddr, tt.forwardedFor, tt.realIP)
+			assert.Equal(t, tt.want, got.String())
+		})
+	}
+}
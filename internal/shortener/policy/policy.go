@@ -0,0 +1,423 @@
+// Package policy decides whether the shortener may redirect to a destination.
+// Destinations are checked against domain and regexp blocklists
+// loaded from a file, and private, loopback and link-local addresses
+// are always rejected, including IPv4 addresses written as numbers
+// (http://2130706433/) and hostnames that resolve to such addresses.
+//
+// The blocklist file contains one rule per line. Empty lines and lines
+// starting with # are ignored. Lines starting with "regexp:" are regular
+// expressions matched against the whole URL; any other line is a domain
+// that is blocked along with all of its subdomains.
+package policy
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// reloadInterval defines how often the blocklist file is checked for changes.
+const reloadInterval = 5 * time.Second
+
+const (
+	// lookupTimeout limits the time CheckResolved waits for DNS.
+	lookupTimeout = 2 * time.Second
+	// resolvedTTL defines how long CheckResolved relies on a lookup of a host.
+	resolvedTTL = time.Minute
+	// maxResolved limits the number of hosts whose lookups are cached.
+	maxResolved = 10000
+)
+
+const regexpPrefix = "regexp:"
+
+// ErrBlocked is returned when a destination is not allowed by the policy.
+var ErrBlocked = errors.New("destination is blocked by policy")
+
+// cgnat is the shared address space that is not routable on the Internet.
+var cgnat = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+type (
+	Engine struct {
+		path     string
+		rules    atomic.Value // holds *rules
+		mx       sync.Mutex
+		modTime  time.Time
+		watching bool          // the file is checked for changes
+		done     chan struct{} // closed by Close to stop watching
+		closed   bool
+
+		// lookup resolves hostnames for CheckResolved.
+		lookup     func(ctx context.Context, host string) ([]net.IPAddr, error)
+		resolvedMx sync.Mutex
+		resolved   map[string]resolved // results of lookups by host
+	}
+
+	resolved struct {
+		err     error // the result of checking the addresses of the host
+		expires time.Time
+	}
+
+	rules struct {
+		domains  map[string]struct{}
+		patterns []*regexp.Regexp
+	}
+)
+
+// New returns an Engine that loads the blocklist from path
+// and reloads it whenever the file changes.
+// Engine with an empty path only rejects non-public addresses.
+func New(path string) (*Engine, error) {
+	e := &Engine{
+		done:     make(chan struct{}),
+		lookup:   net.DefaultResolver.LookupIPAddr,
+		resolved: make(map[string]resolved),
+	}
+	e.rules.Store(&rules{})
+
+	if err := e.SetPath(path); err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+// SetPath switches e to the blocklist file at path.
+// Empty path clears the blocklist. A file that fails
+// to load leaves the previous path and rules in place.
+func (e *Engine) SetPath(path string) error {
+	e.mx.Lock()
+	defer e.mx.Unlock()
+
+	r := &rules{}
+
+	var modTime time.Time
+
+	if path != "" {
+		fi, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+
+		if r, err = parseFile(path); err != nil {
+			return err
+		}
+
+		modTime = fi.ModTime()
+	}
+
+	e.rules.Store(r)
+	e.path = path
+	e.modTime = modTime
+
+	if path != "" && !e.watching && !e.closed {
+		e.watching = true
+		go e.watch()
+	}
+
+	return nil
+}
+
+// Close stops watching the blocklist file for changes.
+// Rules loaded so far stay in effect.
+func (e *Engine) Close() {
+	e.mx.Lock()
+	defer e.mx.Unlock()
+
+	if !e.closed {
+		e.closed = true
+		close(e.done)
+	}
+}
+
+// watch reloads the blocklist whenever the file changes until e is closed.
+func (e *Engine) watch() {
+	t := time.NewTicker(reloadInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-e.done:
+			return
+		case <-t.C:
+			if err := e.Reload(); err != nil {
+				log.Printf("failed to reload blocklist: %v", err)
+			}
+		}
+	}
+}
+
+// Reload reads the blocklist file if it has changed since the last load.
+// Rules are replaced atomically, so a file that fails to parse
+// leaves the previous rules in place.
+func (e *Engine) Reload() error {
+	e.mx.Lock()
+	defer e.mx.Unlock()
+
+	if e.path == "" {
+		return nil
+	}
+
+	fi, err := os.Stat(e.path)
+	if err != nil {
+		return err
+	}
+
+	if fi.ModTime().Equal(e.modTime) {
+		return nil
+	}
+
+	r, err := parseFile(e.path)
+	if err != nil {
+		return err
+	}
+
+	e.rules.Store(r)
+	e.modTime = fi.ModTime()
+
+	return nil
+}
+
+// Check returns ErrBlocked if rawURL is not allowed as a destination.
+func (e *Engine) Check(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrBlocked, err)
+	}
+
+	host := hostname(u)
+
+	if ip := parseIP(host); ip != nil {
+		if !isPublic(ip) {
+			return fmt.Errorf("%w: %v is not a public address", ErrBlocked, host)
+		}
+	} else if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("%w: %v is not a public address", ErrBlocked, host)
+	}
+
+	r := e.rules.Load().(*rules)
+
+	for d := host; d != ""; {
+		if _, ok := r.domains[d]; ok {
+			return fmt.Errorf("%w: domain %v is blocked", ErrBlocked, d)
+		}
+
+		i := strings.IndexByte(d, '.')
+		if i < 0 {
+			break
+		}
+		d = d[i+1:]
+	}
+
+	for _, p := range r.patterns {
+		if p.MatchString(rawURL) {
+			return fmt.Errorf("%w: URL matches %v", ErrBlocked, p)
+		}
+	}
+
+	return nil
+}
+
+// CheckResolved is Check that also resolves the host of rawURL
+// and returns ErrBlocked if any of its addresses is not public
+// or the host fails to resolve for reasons other than not existing.
+// Hosts that do not exist are not blocked, clients cannot reach them either.
+// Lookups are cached for resolvedTTL.
+func (e *Engine) CheckResolved(rawURL string) error {
+	if err := e.Check(rawURL); err != nil {
+		return err
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrBlocked, err)
+	}
+
+	host := hostname(u)
+	if host == "" || parseIP(host) != nil {
+		return nil
+	}
+
+	now := time.Now()
+
+	e.resolvedMx.Lock()
+	r, ok := e.resolved[host]
+	e.resolvedMx.Unlock()
+
+	if ok && now.Before(r.expires) {
+		return r.err
+	}
+
+	err = e.checkHost(host)
+
+	e.resolvedMx.Lock()
+	defer e.resolvedMx.Unlock()
+
+	if len(e.resolved) >= maxResolved {
+		for h, r := range e.resolved {
+			if !now.Before(r.expires) {
+				delete(e.resolved, h)
+			}
+		}
+
+		// none has expired, start over rather than grow
+		if len(e.resolved) >= maxResolved {
+			e.resolved = make(map[string]resolved)
+		}
+	}
+
+	e.resolved[host] = resolved{err: err, expires: now.Add(resolvedTTL)}
+
+	return err
+}
+
+// checkHost resolves host and returns ErrBlocked
+// if any of its addresses is not public.
+func (e *Engine) checkHost(host string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), lookupTimeout)
+	defer cancel()
+
+	addrs, err := e.lookup(ctx, host)
+	if err != nil {
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			return nil
+		}
+
+		return fmt.Errorf("%w: failed to resolve %v: %v", ErrBlocked, host, err)
+	}
+
+	for _, addr := range addrs {
+		if !isPublic(addr.IP) {
+			return fmt.Errorf("%w: %v resolves to %v, which is not a public address", ErrBlocked, host, addr.IP)
+		}
+	}
+
+	return nil
+}
+
+func hostname(u *url.URL) string {
+	return strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+}
+
+// parseIP parses host as an IP address. Besides the standard notations
+// it accepts IPv4 addresses the way browsers do: one to four parts,
+// each decimal, octal with a leading 0 or hexadecimal with a leading 0x,
+// the last part filling the remaining bytes, e.g. 2130706433,
+// 0x7f000001 or 0177.0.0.1. It returns nil if host is not an address.
+func parseIP(host string) net.IP {
+	if ip := net.ParseIP(host); ip != nil {
+		return ip
+	}
+
+	parts := strings.Split(host, ".")
+	if len(parts) > 4 {
+		return nil
+	}
+
+	nums := make([]uint64, len(parts))
+
+	for i, p := range parts {
+		n, ok := parseIPv4Part(p)
+		if !ok {
+			return nil
+		}
+
+		nums[i] = n
+	}
+
+	last := nums[len(nums)-1]
+	if last >= 1<<(8*(5-len(nums))) {
+		return nil
+	}
+
+	ip := make(net.IP, 4)
+
+	for i, n := range nums[:len(nums)-1] {
+		if n > 255 {
+			return nil
+		}
+
+		ip[i] = byte(n)
+	}
+
+	for i := 3; i >= len(nums)-1; i-- {
+		ip[i] = byte(last)
+		last >>= 8
+	}
+
+	return ip
+}
+
+func parseIPv4Part(p string) (uint64, bool) {
+	base := 10
+
+	switch {
+	case strings.HasPrefix(p, "0x"):
+		base, p = 16, p[2:]
+		if p == "" {
+			return 0, true
+		}
+	case len(p) > 1 && p[0] == '0':
+		base, p = 8, p[1:]
+	}
+
+	n, err := strconv.ParseUint(p, base, 32)
+
+	return n, err == nil
+}
+
+func isPublic(ip net.IP) bool {
+	return !(ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() ||
+		cgnat.Contains(ip))
+}
+
+func parseFile(path string) (*rules, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := &rules{domains: make(map[string]struct{})}
+	scanner := bufio.NewScanner(f)
+
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+			continue
+		case strings.HasPrefix(line, regexpPrefix):
+			p, err := regexp.Compile(strings.TrimPrefix(line, regexpPrefix))
+			if err != nil {
+				return nil, fmt.Errorf("line %v: %w", n, err)
+			}
+
+			r.patterns = append(r.patterns, p)
+		default:
+			r.domains[strings.TrimSuffix(strings.ToLower(line), ".")] = struct{}{}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
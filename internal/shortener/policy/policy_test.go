@@ -0,0 +1,158 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEngine_Check(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocklist.txt")
+	require.NoError(t, os.WriteFile(path, []byte(`# phishing
+evil.com
+regexp:^https?://[^/]+/login\.php
+`), 0o644))
+
+	e, err := New(path)
+	require.NoError(t, err)
+
+	tests := []struct {
+		url     string
+		blocked bool
+	}{
+		{"http://ya.ru/test", false},
+		{"http://evil.com/", true},
+		{"http://www.EVIL.com/path", true},
+		{"http://notevil.com/", false},
+		{"http://example.com/login.php", true},
+		{"http://127.0.0.1/", true},
+		{"http://10.0.0.1:8080/", true},
+		{"http://192.168.1.1/", true},
+		{"http://[::1]/", true},
+		{"http://169.254.169.254/latest/meta-data", true},
+		{"http://localhost/", true},
+		{"http://8.8.8.8/", false},
+		{"http://2130706433/", true},
+		{"http://0x7f000001/", true},
+		{"http://0x7F000001/", true},
+		{"http://0177.0.0.1/", true},
+		{"http://127.1/", true},
+		{"http://0xa.0.0.1/", true},
+		{"http://[::ffff:127.0.0.1]/", true},
+		{"http://134744072/", false},
+		{"http://4294967296/", false},
+		{"http://123.com/", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			err := e.Check(tt.url)
+			assert.Equal(t, tt.blocked, errors.Is(err, ErrBlocked), "got %v", err)
+		})
+	}
+
+	t.Run("reload", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(path, []byte("ya.ru\n"), 0o644))
+		// make sure modification time differs on coarse-grained file systems
+		require.NoError(t, os.Chtimes(path, time.Now(), time.Now().Add(time.Second)))
+		require.NoError(t, e.Reload())
+
+		assert.ErrorIs(t, e.Check("http://ya.ru/test"), ErrBlocked)
+		assert.NoError(t, e.Check("http://evil.com/"))
+	})
+
+	t.Run("bad regexp keeps previous rules", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(path, []byte("regexp:(\n"), 0o644))
+		require.NoError(t, os.Chtimes(path, time.Now(), time.Now().Add(2*time.Second)))
+		assert.Error(t, e.Reload())
+
+		assert.ErrorIs(t, e.Check("http://ya.ru/test"), ErrBlocked)
+	})
+}
+
+func TestEngine_SetPath(t *testing.T) {
+	e, err := New("")
+	require.NoError(t, err)
+	assert.NoError(t, e.Check("http://evil.com/"))
+
+	path := filepath.Join(t.TempDir(), "blocklist.txt")
+	require.NoError(t, os.WriteFile(path, []byte("evil.com\n"), 0o644))
+
+	require.NoError(t, e.SetPath(path))
+	assert.ErrorIs(t, e.Check("http://evil.com/"), ErrBlocked)
+
+	assert.Error(t, e.SetPath(filepath.Join(t.TempDir(), "missing.txt")))
+	assert.ErrorIs(t, e.Check("http://evil.com/"), ErrBlocked, "failed switch must keep previous rules")
+
+	require.NoError(t, e.SetPath(""))
+	assert.NoError(t, e.Check("http://evil.com/"))
+}
+
+func TestEngine_CheckResolved(t *testing.T) {
+	e, err := New("")
+	require.NoError(t, err)
+
+	lookups := make(map[string]int)
+	e.lookup = func(ctx context.Context, host string) ([]net.IPAddr, error) {
+		lookups[host]++
+
+		switch host {
+		case "public.test":
+			return []net.IPAddr{{IP: net.ParseIP("8.8.8.8")}}, nil
+		case "mixed.test":
+			return []net.IPAddr{{IP: net.ParseIP("8.8.8.8")}, {IP: net.ParseIP("10.0.0.1")}}, nil
+		case "timeout.test":
+			return nil, &net.DNSError{Err: "i/o timeout", Name: host, IsTimeout: true}
+		default:
+			return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+		}
+	}
+
+	assert.NoError(t, e.CheckResolved("http://public.test/"))
+	assert.ErrorIs(t, e.CheckResolved("http://mixed.test/"), ErrBlocked)
+	assert.NoError(t, e.CheckResolved("http://missing.test/"))
+	assert.ErrorIs(t, e.CheckResolved("http://timeout.test/"), ErrBlocked, "hosts failing to resolve must be blocked")
+	assert.ErrorIs(t, e.CheckResolved("http://0x7f000001/"), ErrBlocked)
+	assert.NoError(t, e.Check("http://mixed.test/"), "Check must not resolve hosts")
+
+	t.Run("cache", func(t *testing.T) {
+		assert.NoError(t, e.CheckResolved("http://public.test/other"))
+		assert.ErrorIs(t, e.CheckResolved("http://mixed.test/"), ErrBlocked)
+		assert.Equal(t, 1, lookups["public.test"])
+		assert.Equal(t, 1, lookups["mixed.test"])
+
+		e.resolved["public.test"] = resolved{expires: time.Now().Add(-time.Second)}
+		assert.NoError(t, e.CheckResolved("http://public.test/"))
+		assert.Equal(t, 2, lookups["public.test"], "expired lookups must be repeated")
+	})
+
+	t.Run("cache size", func(t *testing.T) {
+		for i := 0; i <= maxResolved; i++ {
+			require.NoError(t, e.CheckResolved(fmt.Sprintf("http://host%v.test/", i)))
+		}
+
+		assert.LessOrEqual(t, len(e.resolved), maxResolved)
+	})
+}
+
+func TestEngine_Close(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocklist.txt")
+	require.NoError(t, os.WriteFile(path, []byte("evil.com\n"), 0o644))
+
+	e, err := New(path)
+	require.NoError(t, err)
+
+	e.Close()
+	e.Close()
+
+	assert.ErrorIs(t, e.Check("http://evil.com/"), ErrBlocked, "closed engine must keep its rules")
+	assert.NoError(t, e.SetPath(path))
+}
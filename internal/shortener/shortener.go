@@ -4,34 +4,132 @@ package shortener
 
 import (
 	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/usa4ev/urlshortner/internal/config"
+	"github.com/usa4ev/urlshortner/internal/shortener/policy"
 	"github.com/usa4ev/urlshortner/internal/storage"
+	"github.com/usa4ev/urlshortner/internal/storage/model"
+	"github.com/usa4ev/urlshortner/internal/storage/storageerrors"
+)
+
+var (
+	// ErrQuotaExceeded is returned when a user tries to store
+	// more links than config.UserQuota allows.
+	ErrQuotaExceeded = storageerrors.ErrQuotaExceeded
+	// ErrInvalidID is returned when an imported link has an id
+	// that cannot be used in a short URL.
+	ErrInvalidID = errors.New("invalid id")
+	// ErrInvalidTitle is returned for a link title that is too long
+	// or has control characters.
+	ErrInvalidTitle = errors.New("invalid title")
+)
+
+const (
+	// maxIDLength limits the length of an imported id.
+	maxIDLength = 100
+	// maxIDAttempts limits ids tried for a URL whose id is taken.
+	maxIDAttempts = 10
+	// maxTitleLength limits the length of a link title in characters.
+	maxTitleLength = 200
 )
 
 type Shortener interface {
-	ShortenURL(url string) (string, string) // ShortenURL returns a short id and a short URL.
+	NormalizeURL(url string) (string, error) // NormalizeURL validates url and returns it in a normalized form.
+	ShortenURL(url string) (string, string)  // ShortenURL returns a short id and a short URL.
+	MakeURL(id string) string                // MakeURL returns a short URL for id.
 	StoreURL(id, url, userID string) error
+	Shorten(url, userID string) (model.Link, error)
+	StoreURLs(userID string, links []model.Link, atomic bool) ([]error, error)
+	UpdateURL(userID, id, url string) error
+	UpdateSettings(userID, id string, settings model.LinkSettings) error
 	FindURL(key string) (string, error)
+	FindLink(id string) (model.Link, error) // FindLink returns the link stored by id if it can be followed.
 	LoadByUser(userID string) (storage.Pairs, error)
+	// LoadLinksPage returns up to limit links of the user including deleted ones,
+	// newest first, skipping offset of them.
+	LoadLinksPage(userID string, offset, limit int) (model.LinksPage, error)
 	DeleteURLs(userID string, ids []string) error
+	RestoreURLs(userID string, ids []string) error
+	TrackClick(id string) error // TrackClick counts a redirect to the link stored by id.
 	CountUsers() (int, error)
 	CountURLs() (int, error)
 	FlushStorage() error
+	ApplyConfig(c Settings) error // ApplyConfig applies settings that can change at runtime.
+	Admin
+}
+
+// Settings are the shortener settings that can change while the service runs.
+type Settings interface {
+	BaseURL() string
+	UserQuota() int
+	BlocklistPath() string
+}
+
+// Admin lists operations available to operators via the admin API.
+type Admin interface {
+	LoadLink(id string) (model.Link, error)
+	FindLinkByURL(url string) (model.Link, error)
+	LoadLinksByUser(userID string) ([]model.Link, error)
+	RangeLinks(f func(link model.Link) error) error
+	SetDisabled(id string, disabled bool) error
+	DeleteUser(userID string) (int, error)
+	DeleteSessions(userID string) (int, error)
 }
 type (
 	MyShortener struct {
 		storage *storage.Storage
-		config  *config.Config
+		config  atomic.Value // holds liveSettings
+		policy  *policy.Engine
+	}
+
+	// liveSettings keeps the concrete type stored in atomic.Value the same.
+	liveSettings struct {
+		Settings
 	}
 )
 
-func NewShortener(c *config.Config, s *storage.Storage) *MyShortener {
+func NewShortener(c *config.Config, s *storage.Storage) (*MyShortener, error) {
+	var err error
+
 	myShortener := &MyShortener{}
-	myShortener.config = c
+	myShortener.config.Store(liveSettings{c})
 	myShortener.storage = s
 
-	return myShortener
+	myShortener.policy, err = policy.New(c.BlocklistPath())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load blocklist: %w", err)
+	}
+
+	return myShortener, nil
+}
+
+// ApplyConfig switches the shortener to base URL, user quota and blocklist of c.
+// Nothing changes if the blocklist fails to load.
+func (myShortener *MyShortener) ApplyConfig(c Settings) error {
+	if err := myShortener.policy.SetPath(c.BlocklistPath()); err != nil {
+		return fmt.Errorf("failed to load blocklist: %w", err)
+	}
+
+	myShortener.config.Store(liveSettings{c})
+
+	return nil
+}
+
+// Close stops reloading the blocklist.
+func (myShortener *MyShortener) Close() {
+	myShortener.policy.Close()
+}
+
+// settings returns the current settings.
+func (myShortener *MyShortener) settings() Settings {
+	return myShortener.config.Load().(liveSettings)
 }
 
 // ShortenURL returns a short id and a short URL.
@@ -41,16 +139,285 @@ func (myShortener *MyShortener) ShortenURL(url string) (string, string) {
 	return id, myShortener.makeURL(id)
 }
 
+// StoreURL stores url by id unless the user has exceeded their quota.
+// A stored id or url results in storageerrors.ErrConflict even if
+// the quota is exceeded.
 func (myShortener *MyShortener) StoreURL(id, url, userID string) error {
-	return myShortener.storage.StoreURL(id, url, userID)
+	return myShortener.storage.StoreURL(id, url, userID, myShortener.settings().UserQuota())
+}
+
+// Shorten stores url for the user with the id ShortenURL returns.
+// If url is already stored, it returns the stored link, whose id may differ
+// from the one ShortenURL returns, and storageerrors.ErrConflict.
+// If the id is taken by a link whose destination has changed since,
+// the id gets a numeric suffix.
+func (myShortener *MyShortener) Shorten(url, userID string) (model.Link, error) {
+	id, shortURL := myShortener.ShortenURL(url)
+	links := []model.Link{{ID: id, ShortURL: shortURL, OriginalURL: url, UserID: userID}}
+
+	errs, _ := myShortener.storeSuffixed(links, []bool{true}, false, func(links []model.Link) ([]error, error) {
+		return []error{myShortener.StoreURL(links[0].ID, url, userID)}, nil
+	})
+
+	err := errs[0]
+	if err == nil {
+		return links[0], nil
+	}
+
+	if !errors.Is(err, storageerrors.ErrConflict) {
+		return model.Link{}, err
+	}
+
+	stored, findErr := myShortener.storage.FindLinkByURL(url)
+	switch {
+	case findErr == nil:
+		stored.ShortURL = myShortener.makeURL(stored.ID)
+
+		return stored, err
+	case !errors.Is(findErr, storageerrors.ErrNotFound):
+		return model.Link{}, findErr
+	}
+
+	return model.Link{}, err
+}
+
+// storeSuffixed stores links with store. Links marked as generated whose id
+// is taken by a link with another destination, which happens when
+// the destination of that link has changed, are stored again with
+// a numeric suffix of the id, up to maxIDAttempts ids per link.
+// If atomic is set, the whole batch is stored again
+// unless it failed for other reasons too.
+func (myShortener *MyShortener) storeSuffixed(links []model.Link, generated []bool, atomic bool,
+	store func(links []model.Link) ([]error, error)) ([]error, error) {
+	errs := make([]error, len(links))
+	bases := make([]string, len(links))
+	pending := make([]int, len(links))
+
+	for i := range links {
+		bases[i] = links[i].ID
+		pending[i] = i
+	}
+
+	for attempt := 1; ; attempt++ {
+		batch := make([]model.Link, len(pending))
+		for k, i := range pending {
+			batch[k] = links[i]
+		}
+
+		storeErrs, err := store(batch)
+		if err != nil {
+			return nil, err
+		}
+
+		taken := make([]int, 0)
+		failed := false
+
+		for k, i := range pending {
+			errs[i] = storeErrs[k]
+
+			switch {
+			case storeErrs[k] == nil || errors.Is(storeErrs[k], storageerrors.ErrBatchAborted):
+			case generated[i] && errors.Is(storeErrs[k], storageerrors.ErrConflict) && myShortener.idTaken(links[i]):
+				taken = append(taken, i)
+			default:
+				failed = true
+			}
+		}
+
+		if len(taken) == 0 || atomic && failed {
+			return errs, nil
+		}
+
+		if attempt == maxIDAttempts {
+			for _, i := range taken {
+				errs[i] = fmt.Errorf("%w: ids %v to %v are taken", errs[i], bases[i], links[i].ID)
+			}
+
+			return errs, nil
+		}
+
+		for _, i := range taken {
+			links[i].ID = fmt.Sprintf("%v-%v", bases[i], attempt)
+			links[i].ShortURL = myShortener.makeURL(links[i].ID)
+		}
+
+		if !atomic {
+			pending = taken
+		}
+	}
+}
+
+// idTaken reports whether the id of link is stored with another destination.
+func (myShortener *MyShortener) idTaken(link model.Link) bool {
+	stored, err := myShortener.storage.LoadLink(link.ID)
+
+	return err == nil && stored.OriginalURL != link.OriginalURL
+}
+
+// StoreURLs stores links of the user keeping their ids.
+// Links without id get the one ShortenURL returns, with a numeric suffix
+// if it is taken by a link whose destination has changed since.
+// It fills ID, ShortURL, OriginalURL and UserID of valid links in place and returns
+// an error for every link that is not stored: ErrInvalidURL, ErrInvalidID,
+// policy.ErrBlocked, ErrQuotaExceeded or storageerrors.ErrConflict.
+// If atomic is set, nothing is stored unless every link is
+// and the other links get storageerrors.ErrBatchAborted.
+func (myShortener *MyShortener) StoreURLs(userID string, links []model.Link, atomic bool) ([]error, error) {
+	errs := make([]error, len(links))
+	valid := make([]int, 0, len(links))
+	generated := make([]bool, len(links))
+
+	now := time.Now()
+
+	for i := range links {
+		link := &links[i]
+
+		url, err := myShortener.NormalizeURL(link.OriginalURL)
+		if err != nil {
+			errs[i] = err
+
+			continue
+		}
+
+		if link.ID == "" {
+			link.ID, _ = myShortener.ShortenURL(url)
+			generated[i] = true
+		} else if err := validateID(link.ID); err != nil {
+			errs[i] = err
+
+			continue
+		}
+
+		link.OriginalURL = url
+		link.UserID = userID
+		link.ShortURL = myShortener.makeURL(link.ID)
+		if link.CreatedAt.IsZero() {
+			link.CreatedAt = now
+		}
+
+		valid = append(valid, i)
+	}
+
+	if atomic && len(valid) < len(links) {
+		for _, i := range valid {
+			errs[i] = storageerrors.ErrBatchAborted
+		}
+
+		return errs, nil
+	}
+
+	batch := make([]model.Link, len(valid))
+	batchGenerated := make([]bool, len(valid))
+
+	for i, j := range valid {
+		batch[i] = links[j]
+		batchGenerated[i] = generated[j]
+	}
+
+	quota := myShortener.settings().UserQuota()
+
+	storeErrs, err := myShortener.storeSuffixed(batch, batchGenerated, atomic, func(links []model.Link) ([]error, error) {
+		return myShortener.storage.StoreURLs(links, atomic, quota)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i, j := range valid {
+		links[j] = batch[i]
+		errs[j] = storeErrs[i]
+	}
+
+	return errs, nil
+}
+
+// validateID checks that id is not longer than maxIDLength
+// and only has characters of base64 URL encoding.
+func validateID(id string) error {
+	if len(id) > maxIDLength {
+		return fmt.Errorf("%w: id is longer than %v characters", ErrInvalidID, maxIDLength)
+	}
+
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			return fmt.Errorf("%w: %q has characters other than letters, digits, - and _", ErrInvalidID, id)
+		}
+	}
+
+	return nil
+}
+
+// UpdateURL replaces the destination of a link owned by the user.
+// The short URL stays the same.
+func (myShortener *MyShortener) UpdateURL(userID, id, url string) error {
+	return myShortener.storage.UpdateURL(userID, id, url)
+}
+
+// UpdateSettings replaces settings of a link owned by the user.
+// The title is trimmed, a title that is too long or has control
+// characters results in ErrInvalidTitle.
+func (myShortener *MyShortener) UpdateSettings(userID, id string, settings model.LinkSettings) error {
+	settings.Title = strings.TrimSpace(settings.Title)
+
+	if n := utf8.RuneCountInString(settings.Title); n > maxTitleLength {
+		return fmt.Errorf("%w: title is longer than %v characters", ErrInvalidTitle, maxTitleLength)
+	}
+
+	for _, c := range settings.Title {
+		if unicode.IsControl(c) {
+			return fmt.Errorf("%w: title has control characters", ErrInvalidTitle)
+		}
+	}
+
+	return myShortener.storage.UpdateSettings(userID, id, settings)
+}
+
+// MakeURL returns a short URL for id.
+func (myShortener *MyShortener) MakeURL(id string) string {
+	return myShortener.makeURL(id)
 }
 
 func (myShortener *MyShortener) makeURL(id string) string {
-	return myShortener.config.BaseURL() + "/" + id
+	return myShortener.settings().BaseURL() + "/" + id
 }
 
+// FindURL returns the destination stored by key.
+// Destinations blocked since they were stored or resolving
+// to non-public addresses result in policy.ErrBlocked.
 func (myShortener *MyShortener) FindURL(key string) (string, error) {
-	return myShortener.storage.LoadURL(key)
+	url, err := myShortener.storage.LoadURL(key)
+	if err != nil || url == "" {
+		return url, err
+	}
+
+	if err := myShortener.policy.CheckResolved(url); err != nil {
+		return "", err
+	}
+
+	return url, nil
+}
+
+// FindLink returns the link stored by id if it can be followed
+// with the same errors FindURL returns.
+func (myShortener *MyShortener) FindLink(id string) (model.Link, error) {
+	link, err := myShortener.storage.LoadLink(id)
+
+	switch {
+	case err != nil:
+		return model.Link{}, err
+	case link.Deleted:
+		return model.Link{}, storageerrors.ErrURLGone
+	case link.Disabled:
+		return model.Link{}, storageerrors.ErrURLDisabled
+	}
+
+	if err := myShortener.policy.CheckResolved(link.OriginalURL); err != nil {
+		return model.Link{}, err
+	}
+
+	link.ShortURL = myShortener.makeURL(link.ID)
+
+	return link, nil
 }
 
 func (myShortener *MyShortener) FlushStorage() error {
@@ -65,6 +432,17 @@ func (myShortener *MyShortener) DeleteURLs(userID string, ids []string) error {
 	return myShortener.storage.DeleteURLs(userID, ids)
 }
 
+// RestoreURLs undeletes links of the user unless it would exceed their quota,
+// ErrQuotaExceeded is returned then.
+func (myShortener *MyShortener) RestoreURLs(userID string, ids []string) error {
+	return myShortener.storage.RestoreURLs(userID, ids, myShortener.settings().UserQuota())
+}
+
+// TrackClick counts a redirect to the link stored by id.
+func (myShortener *MyShortener) TrackClick(id string) error {
+	return myShortener.storage.AddClick(id, time.Now())
+}
+
 func (myShortener *MyShortener) CountURLs() (int, error) {
 	return myShortener.storage.CountURLs()
 }
@@ -72,3 +450,87 @@ func (myShortener *MyShortener) CountURLs() (int, error) {
 func (myShortener *MyShortener) CountUsers() (int, error) {
 	return myShortener.storage.CountUsers()
 }
+
+// LoadLink returns the link stored by id including deleted and disabled ones.
+func (myShortener *MyShortener) LoadLink(id string) (model.Link, error) {
+	link, err := myShortener.storage.LoadLink(id)
+	if err != nil {
+		return model.Link{}, err
+	}
+
+	link.ShortURL = myShortener.makeURL(link.ID)
+
+	return link, nil
+}
+
+// FindLinkByURL returns the link with the destination url.
+// url is normalized the same way it was when the link was stored.
+func (myShortener *MyShortener) FindLinkByURL(url string) (model.Link, error) {
+	if normalized, err := normalizeURL(url, myShortener.settings().BaseURL()); err == nil {
+		url = normalized
+	}
+
+	link, err := myShortener.storage.FindLinkByURL(url)
+	if err != nil {
+		return model.Link{}, err
+	}
+
+	link.ShortURL = myShortener.makeURL(link.ID)
+
+	return link, nil
+}
+
+// LoadLinksByUser returns all links of the user including deleted and disabled ones.
+func (myShortener *MyShortener) LoadLinksByUser(userID string) ([]model.Link, error) {
+	links, err := myShortener.storage.LoadLinksByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range links {
+		links[i].ShortURL = myShortener.makeURL(links[i].ID)
+	}
+
+	return links, nil
+}
+
+// LoadLinksPage returns up to limit links of the user including deleted ones,
+// newest first, skipping offset of them.
+func (myShortener *MyShortener) LoadLinksPage(userID string, offset, limit int) (model.LinksPage, error) {
+	page, err := myShortener.storage.LoadLinksPage(userID, offset, limit)
+	if err != nil {
+		return model.LinksPage{}, err
+	}
+
+	for i := range page.Links {
+		page.Links[i].ShortURL = myShortener.makeURL(page.Links[i].ID)
+	}
+
+	return page, nil
+}
+
+// RangeLinks calls f for every stored link including deleted and disabled ones
+// until f returns an error.
+func (myShortener *MyShortener) RangeLinks(f func(link model.Link) error) error {
+	return myShortener.storage.RangeLinks(func(link model.Link) error {
+		link.ShortURL = myShortener.makeURL(link.ID)
+
+		return f(link)
+	})
+}
+
+// SetDisabled disables or enables redirects for the link stored by id.
+func (myShortener *MyShortener) SetDisabled(id string, disabled bool) error {
+	return myShortener.storage.SetDisabled(id, disabled)
+}
+
+// DeleteUser removes all links and sessions of the user
+// and returns the number of removed links.
+func (myShortener *MyShortener) DeleteUser(userID string) (int, error) {
+	return myShortener.storage.DeleteUser(userID)
+}
+
+// DeleteSessions invalidates all sessions of the user.
+func (myShortener *MyShortener) DeleteSessions(userID string) (int, error) {
+	return myShortener.storage.DeleteSessions(userID)
+}
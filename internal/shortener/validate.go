@@ -0,0 +1,118 @@
+package shortener
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// maxURLLength limits the length of a destination URL.
+const maxURLLength = 2048
+
+// ErrInvalidURL is returned when a destination URL does not pass validation.
+var ErrInvalidURL = errors.New("invalid URL")
+
+// defaultPorts are omitted from normalized URLs.
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// NormalizeURL validates a destination URL and returns it in a normalized form,
+// so the same destination always gets the same id.
+// Destinations that are not allowed by the policy result in policy.ErrBlocked.
+func (myShortener *MyShortener) NormalizeURL(rawURL string) (string, error) {
+	normalized, err := normalizeURL(rawURL, myShortener.settings().BaseURL())
+	if err != nil {
+		return "", err
+	}
+
+	if err := myShortener.policy.Check(normalized); err != nil {
+		return "", err
+	}
+
+	return normalized, nil
+}
+
+// normalizeURL checks scheme, host and length of rawURL and rejects URLs
+// that point back to baseURL. Scheme and host are lowercased,
+// IDN hosts are converted to punycode, default ports are dropped
+// and an empty path is replaced with a slash.
+func normalizeURL(rawURL, baseURL string) (string, error) {
+	rawURL = strings.TrimSpace(rawURL)
+
+	switch {
+	case rawURL == "":
+		return "", fmt.Errorf("%w: URL is empty", ErrInvalidURL)
+	case len(rawURL) > maxURLLength:
+		return "", fmt.Errorf("%w: URL is longer than %v characters", ErrInvalidURL, maxURLLength)
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidURL, err)
+	}
+
+	if _, ok := defaultPorts[u.Scheme]; !ok || u.Opaque != "" {
+		return "", fmt.Errorf("%w: only absolute http and https URLs are supported", ErrInvalidURL)
+	}
+
+	if u.User != nil {
+		return "", fmt.Errorf("%w: URL must not contain user info", ErrInvalidURL)
+	}
+
+	host, err := normalizeHost(u)
+	if err != nil {
+		return "", err
+	}
+
+	u.Host = host
+	if u.Path == "" {
+		u.Path = "/"
+	}
+
+	if base, err := url.Parse(baseURL); err == nil && base.Host != "" {
+		if baseHost, err := normalizeHost(base); err == nil && baseHost == host {
+			return "", fmt.Errorf("%w: URL points to the shortener itself", ErrInvalidURL)
+		}
+	}
+
+	return u.String(), nil
+}
+
+// normalizeHost returns lowercased punycode host of u
+// with port if it is not the default one for u.Scheme.
+func normalizeHost(u *url.URL) (string, error) {
+	host := u.Hostname()
+	if host == "" {
+		return "", fmt.Errorf("%w: URL has no host", ErrInvalidURL)
+	}
+
+	if ip := net.ParseIP(host); ip == nil {
+		var err error
+
+		host, err = idna.Lookup.ToASCII(strings.TrimSuffix(host, "."))
+		if err != nil {
+			return "", fmt.Errorf("%w: bad host %v: %v", ErrInvalidURL, u.Hostname(), err)
+		}
+	}
+
+	port := u.Port()
+	if port == defaultPorts[strings.ToLower(u.Scheme)] {
+		port = ""
+	}
+
+	switch {
+	case port != "":
+		return net.JoinHostPort(host, port), nil
+	case strings.Contains(host, ":"):
+		// IPv6 literal
+		return "[" + host + "]", nil
+	default:
+		return host, nil
+	}
+}
@@ -0,0 +1,55 @@
+package shortener
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_normalizeURL(t *testing.T) {
+	const baseURL = "http://localhost:8080"
+
+	tests := []struct {
+		name    string
+		url     string
+		want    string
+		invalid bool
+	}{
+		{name: "unchanged", url: "http://ya.ru/test", want: "http://ya.ru/test"},
+		{name: "scheme and host case", url: "HTTPS://Ya.RU/Test", want: "https://ya.ru/Test"},
+		{name: "default port", url: "http://ya.ru:80/test", want: "http://ya.ru/test"},
+		{name: "default https port", url: "https://ya.ru:443", want: "https://ya.ru/"},
+		{name: "custom port", url: "http://ya.ru:8081/test", want: "http://ya.ru:8081/test"},
+		{name: "trailing slash", url: "http://ya.ru", want: "http://ya.ru/"},
+		{name: "query kept", url: "http://ya.ru?q=1#top", want: "http://ya.ru/?q=1#top"},
+		{name: "IDN", url: "https://пример.рф/test", want: "https://xn--e1afmkfd.xn--p1ai/test"},
+		{name: "IPv6", url: "http://[::1]:80/", want: "http://[::1]/"},
+		{name: "spaces trimmed", url: " http://ya.ru/ \n", want: "http://ya.ru/"},
+		{name: "empty", url: "", invalid: true},
+		{name: "plain text", url: "not a url", invalid: true},
+		{name: "no scheme", url: "ya.ru/test", invalid: true},
+		{name: "javascript", url: "javascript:alert(1)", invalid: true},
+		{name: "ftp", url: "ftp://ya.ru/file", invalid: true},
+		{name: "no host", url: "http:///test", invalid: true},
+		{name: "user info", url: "http://ya.ru@evil.com/", invalid: true},
+		{name: "long", url: "http://ya.ru/" + strings.Repeat("a", maxURLLength-13), want: "http://ya.ru/" + strings.Repeat("a", maxURLLength-13)},
+		{name: "too long", url: "http://ya.ru/" + string(make([]byte, maxURLLength)), invalid: true},
+		{name: "loop", url: "http://LOCALHOST:8080/abc", invalid: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := normalizeURL(tt.url, baseURL)
+			if tt.invalid {
+				assert.True(t, errors.Is(err, ErrInvalidURL), "want ErrInvalidURL, got %v", err)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -10,21 +10,30 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/usa4ev/urlshortner/internal/storage/model"
 	"github.com/usa4ev/urlshortner/internal/storage/storageerrors"
 
 	_ "github.com/jackc/pgx/stdlib"
 )
 
+// storeURLsBatch limits rows of one insert, every row takes 4 of 65535 parameters.
+const storeURLsBatch = 1000
+
+// clickFlushInterval defines how often counted clicks are written to the database.
+const clickFlushInterval = time.Second
+
 type (
 	database struct {
 		*sql.DB
 		ctx    context.Context
 		stmnts statements
 		buffer *asyncBuf
+		clicks *clickBuf
 	}
 	statements struct {
 		storeURL     *sql.Stmt
@@ -42,6 +51,16 @@ type (
 		ew  *bytes.Buffer
 		t   *time.Ticker
 	}
+	// clickBuf collects clicks until they are written by flushClicks,
+	// so redirects do not wait for the database.
+	clickBuf struct {
+		mx     sync.Mutex
+		counts map[string]clickCount
+	}
+	clickCount struct {
+		n    int64
+		last time.Time
+	}
 )
 
 func New(dsn string, ctx context.Context) (database, error) {
@@ -67,6 +86,7 @@ func New(dsn string, ctx context.Context) (database, error) {
 	}
 
 	db.initBuffer()
+	db.initClicks()
 
 	return db, nil
 }
@@ -82,8 +102,8 @@ func (db database) initDB() error {
 	}
 
 	query = `CREATE TABLE IF NOT EXISTS urls (
-					url VARCHAR(100) NOT NULL UNIQUE,
-					id VARCHAR(100) PRIMARY KEY UNIQUE,
+					url TEXT NOT NULL UNIQUE,
+					id TEXT PRIMARY KEY UNIQUE,
 					user_id VARCHAR(38),
 					deleted BOOLEAN,
 					FOREIGN KEY (user_id)
@@ -94,6 +114,70 @@ func (db database) initDB() error {
 		return err
 	}
 
+	// deleted_at is used to purge URLs after the retention period,
+	// the period for URLs deleted before starts from now
+	query = `ALTER TABLE urls ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+				UPDATE urls SET deleted_at = NOW() WHERE deleted AND deleted_at IS NULL;`
+
+	_, err = db.Exec(query)
+	if err != nil {
+		return err
+	}
+
+	query = "ALTER TABLE urls ADD COLUMN IF NOT EXISTS disabled BOOLEAN NOT NULL DEFAULT FALSE;"
+
+	_, err = db.Exec(query)
+	if err != nil {
+		return err
+	}
+
+	// URLs stored before created_at was tracked keep it NULL
+	query = `ALTER TABLE urls ADD COLUMN IF NOT EXISTS created_at TIMESTAMP;
+				ALTER TABLE urls ALTER COLUMN created_at SET DEFAULT NOW();`
+
+	_, err = db.Exec(query)
+	if err != nil {
+		return err
+	}
+
+	query = `ALTER TABLE urls ADD COLUMN IF NOT EXISTS clicks BIGINT NOT NULL DEFAULT 0;
+				ALTER TABLE urls ADD COLUMN IF NOT EXISTS last_click_at TIMESTAMP;`
+
+	_, err = db.Exec(query)
+	if err != nil {
+		return err
+	}
+
+	query = `ALTER TABLE urls ADD COLUMN IF NOT EXISTS title TEXT NOT NULL DEFAULT '';
+				ALTER TABLE urls ADD COLUMN IF NOT EXISTS interstitial BOOLEAN NOT NULL DEFAULT FALSE;`
+
+	_, err = db.Exec(query)
+	if err != nil {
+		return err
+	}
+
+	query = `CREATE TABLE IF NOT EXISTS url_history (
+					id TEXT NOT NULL,
+					url TEXT NOT NULL,
+					replaced_at TIMESTAMP NOT NULL,
+					FOREIGN KEY (id)
+				REFERENCES urls (id) ON DELETE CASCADE);`
+
+	_, err = db.Exec(query)
+	if err != nil {
+		return err
+	}
+
+	// destinations may be up to 2048 characters long and ids are
+	// base64 of them, tables created by older versions limited both to 100
+	query = `ALTER TABLE urls ALTER COLUMN url TYPE TEXT, ALTER COLUMN id TYPE TEXT;
+				ALTER TABLE url_history ALTER COLUMN url TYPE TEXT, ALTER COLUMN id TYPE TEXT;`
+
+	_, err = db.Exec(query)
+	if err != nil {
+		return err
+	}
+
 	return err
 }
 
@@ -120,8 +204,28 @@ func (db *database) initBuffer() {
 	}
 }
 
+func (db *database) initClicks() {
+	db.clicks = &clickBuf{counts: make(map[string]clickCount)}
+
+	go func() {
+		t := time.NewTicker(clickFlushInterval)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-db.ctx.Done():
+				return
+			case <-t.C:
+				if err := db.flushClicks(); err != nil {
+					log.Printf("failed to write clicks: %v", err)
+				}
+			}
+		}
+	}()
+}
+
 func (db database) prepareStatements() (statements, error) {
-	storeURL, err := db.PrepareContext(db.ctx, "INSERT INTO urls(id, url, user_id, deleted) VALUES ($1, $2, $3, FALSE) ON CONFLICT (url) DO NOTHING")
+	storeURL, err := db.PrepareContext(db.ctx, "INSERT INTO urls(id, url, user_id, deleted) VALUES ($1, $2, $3, FALSE) ON CONFLICT DO NOTHING")
 	if err != nil {
 		return statements{}, err
 	}
@@ -134,7 +238,10 @@ func (db database) prepareStatements() (statements, error) {
 	return statements{storeURL, storeSession}, nil
 }
 
-func (db database) StoreURL(id, url, userid string) error {
+// StoreURL inserts url by id and returns storageerrors.ErrConflict
+// if the id or the url is already stored, otherwise
+// storageerrors.ErrQuotaExceeded if the user has quota links.
+func (db database) StoreURL(id, url, userid string, quota int) error {
 	tx, err := db.Begin()
 	if err != nil {
 		return err
@@ -144,6 +251,11 @@ func (db database) StoreURL(id, url, userid string) error {
 	ctx, cancelfunc := context.WithTimeout(db.ctx, 5*time.Second)
 	defer cancelfunc()
 
+	left, err := lockQuota(ctx, tx, []string{userid}, quota)
+	if err != nil {
+		return err
+	}
+
 	txStmt := tx.StmtContext(ctx, db.stmnts.storeURL)
 
 	res, err := txStmt.ExecContext(ctx, id, url, userid)
@@ -160,6 +272,305 @@ func (db database) StoreURL(id, url, userid string) error {
 		return storageerrors.ErrConflict
 	}
 
+	if quota > 0 && left[userid] <= 0 {
+		return storageerrors.ErrQuotaExceeded
+	}
+
+	return tx.Commit()
+}
+
+// lockQuota locks rows of users in tx, so links of a user are counted
+// and stored by one transaction at a time, and returns how many links
+// each of them may still store. It does nothing if quota is not positive.
+func lockQuota(ctx context.Context, tx *sql.Tx, userIDs []string, quota int) (map[string]int, error) {
+	if quota <= 0 {
+		return nil, nil
+	}
+
+	left := make(map[string]int)
+	for _, userID := range userIDs {
+		left[userID] = 0
+	}
+
+	ids := make([]string, 0, len(left))
+	for userID := range left {
+		ids = append(ids, userID)
+	}
+
+	// users are locked in the same order by every transaction
+	sort.Strings(ids)
+
+	for _, userID := range ids {
+		if _, err := tx.ExecContext(ctx, "SELECT id FROM users WHERE id = $1 FOR UPDATE", userID); err != nil {
+			return nil, fmt.Errorf("error when locking row in users table %w", err)
+		}
+
+		var stored int
+
+		query := "SELECT COUNT(id) FROM urls WHERE user_id = $1 AND NOT deleted"
+		if err := tx.QueryRowContext(ctx, query, userID).Scan(&stored); err != nil {
+			return nil, fmt.Errorf("error when counting URLs of user %v: %w", userID, err)
+		}
+
+		left[userID] = quota - stored
+	}
+
+	return left, nil
+}
+
+// StoreURLs inserts links in one transaction with multi-row inserts
+// of up to storeURLsBatch rows. Links whose id or url is already stored,
+// or repeats in links, get storageerrors.ErrConflict. Links inserted
+// over the quota of their user are deleted and get
+// storageerrors.ErrQuotaExceeded. If atomic is set and any link fails,
+// the transaction is rolled back and the other links
+// get storageerrors.ErrBatchAborted.
+func (db database) StoreURLs(links []model.Link, atomic bool, quota int) ([]error, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	ctx, cancelfunc := context.WithTimeout(db.ctx, 5*time.Minute)
+	defer cancelfunc()
+
+	userIDs := make([]string, len(links))
+	for i, link := range links {
+		userIDs[i] = link.UserID
+	}
+
+	left, err := lockQuota(ctx, tx, userIDs, quota)
+	if err != nil {
+		return nil, err
+	}
+
+	errs := make([]error, len(links))
+	failed := false
+	now := time.Now()
+
+	for start := 0; start < len(links); start += storeURLsBatch {
+		end := start + storeURLsBatch
+		if end > len(links) {
+			end = len(links)
+		}
+
+		batch := links[start:end]
+		valueStrings := make([]string, 0, len(batch))
+		valueArgs := make([]interface{}, 0, len(batch)*4)
+
+		for i, link := range batch {
+			createdAt := link.CreatedAt
+			if createdAt.IsZero() {
+				createdAt = now
+			}
+
+			valueStrings = append(valueStrings, fmt.Sprintf("($%v, $%v, $%v, FALSE, $%v)", i*4+1, i*4+2, i*4+3, i*4+4))
+			valueArgs = append(valueArgs, link.ID, link.OriginalURL, link.UserID, createdAt)
+		}
+
+		query := fmt.Sprintf("INSERT INTO urls(id, url, user_id, deleted, created_at) VALUES %s "+
+			"ON CONFLICT DO NOTHING RETURNING id, url",
+			strings.Join(valueStrings, ","))
+
+		rows, err := tx.QueryContext(ctx, query, valueArgs...)
+		if err != nil {
+			return nil, fmt.Errorf("error when inserting rows into urls table %w", err)
+		}
+
+		inserted := make(map[model.Link]bool)
+
+		for rows.Next() {
+			var link model.Link
+			if err := rows.Scan(&link.ID, &link.OriginalURL); err != nil {
+				rows.Close()
+
+				return nil, fmt.Errorf("error when scanning query results: %w", err)
+			}
+
+			inserted[link] = true
+		}
+
+		rows.Close()
+
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+
+		for i, link := range batch {
+			key := model.Link{ID: link.ID, OriginalURL: link.OriginalURL}
+			if !inserted[key] {
+				errs[start+i] = storageerrors.ErrConflict
+				failed = true
+
+				continue
+			}
+
+			// a repeated link is a conflict
+			delete(inserted, key)
+		}
+	}
+
+	if quota > 0 {
+		// links inserted over the quota
+		var over []string
+
+		for i, link := range links {
+			if errs[i] != nil {
+				continue
+			}
+
+			if left[link.UserID] <= 0 {
+				errs[i] = storageerrors.ErrQuotaExceeded
+				failed = true
+				over = append(over, link.ID)
+
+				continue
+			}
+
+			left[link.UserID]--
+		}
+
+		if !atomic {
+			if err := deleteURLs(ctx, tx, over); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if atomic && failed {
+		for i := range errs {
+			if errs[i] == nil {
+				errs[i] = storageerrors.ErrBatchAborted
+			}
+		}
+
+		return errs, tx.Rollback()
+	}
+
+	return errs, tx.Commit()
+}
+
+// deleteURLs removes urls by ids in tx with up to storeURLsBatch ids per statement.
+func deleteURLs(ctx context.Context, tx *sql.Tx, ids []string) error {
+	for len(ids) > 0 {
+		n := len(ids)
+		if n > storeURLsBatch {
+			n = storeURLsBatch
+		}
+
+		valueStrings := make([]string, 0, n)
+		valueArgs := make([]interface{}, 0, n)
+
+		for i, id := range ids[:n] {
+			valueStrings = append(valueStrings, fmt.Sprintf("$%v", i+1))
+			valueArgs = append(valueArgs, id)
+		}
+
+		query := "DELETE FROM urls WHERE id IN (" + strings.Join(valueStrings, ",") + ")"
+		if _, err := tx.ExecContext(ctx, query, valueArgs...); err != nil {
+			return fmt.Errorf("error when deleting rows from urls table %w", err)
+		}
+
+		ids = ids[n:]
+	}
+
+	return nil
+}
+
+// UpdateURL replaces the destination of the URL stored by id
+// keeping the previous one in url_history table.
+func (db database) UpdateURL(userID, id, url string) error {
+	var (
+		oldURL, owner string
+		deleted       bool
+	)
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	ctx, cancelfunc := context.WithTimeout(db.ctx, 5*time.Second)
+	defer cancelfunc()
+
+	query := "SELECT url, user_id, deleted FROM urls WHERE id = $1 FOR UPDATE"
+	err = tx.QueryRowContext(ctx, query, id).Scan(&oldURL, &owner, &deleted)
+	if errors.Is(err, sql.ErrNoRows) {
+		return storageerrors.ErrNotFound
+	} else if err != nil {
+		return fmt.Errorf("error when loading URL using id %v: %w", id, err)
+	}
+
+	switch {
+	case owner != userID:
+		return storageerrors.ErrNotOwner
+	case deleted:
+		return storageerrors.ErrURLGone
+	case oldURL == url:
+		return nil
+	}
+
+	var exists bool
+	query = "SELECT EXISTS (SELECT 1 FROM urls WHERE url = $1)"
+	if err = tx.QueryRowContext(ctx, query, url).Scan(&exists); err != nil {
+		return fmt.Errorf("error when looking up URL %v: %w", url, err)
+	}
+
+	if exists {
+		return storageerrors.ErrConflict
+	}
+
+	query = "INSERT INTO url_history(id, url, replaced_at) VALUES ($1, $2, $3)"
+	if _, err = tx.ExecContext(ctx, query, id, oldURL, time.Now()); err != nil {
+		return fmt.Errorf("error when inserting row into url_history table %w", err)
+	}
+
+	query = "UPDATE urls SET url = $1 WHERE id = $2"
+	if _, err = tx.ExecContext(ctx, query, url, id); err != nil {
+		return fmt.Errorf("error when updating row in urls table %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// UpdateSettings replaces settings of the URL stored by id.
+func (db database) UpdateSettings(userID, id string, settings model.LinkSettings) error {
+	var (
+		owner   sql.NullString
+		deleted bool
+	)
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	ctx, cancelfunc := context.WithTimeout(db.ctx, 5*time.Second)
+	defer cancelfunc()
+
+	query := "SELECT user_id, deleted FROM urls WHERE id = $1 FOR UPDATE"
+	err = tx.QueryRowContext(ctx, query, id).Scan(&owner, &deleted)
+	if errors.Is(err, sql.ErrNoRows) {
+		return storageerrors.ErrNotFound
+	} else if err != nil {
+		return fmt.Errorf("error when loading URL using id %v: %w", id, err)
+	}
+
+	switch {
+	case owner.String != userID:
+		return storageerrors.ErrNotOwner
+	case deleted:
+		return storageerrors.ErrURLGone
+	}
+
+	query = "UPDATE urls SET title = $1, interstitial = $2 WHERE id = $3"
+	if _, err = tx.ExecContext(ctx, query, settings.Title, settings.Interstitial, id); err != nil {
+		return fmt.Errorf("error when updating row in urls table %w", err)
+	}
+
 	return tx.Commit()
 }
 
@@ -167,6 +578,7 @@ func (db database) LoadURL(id string) (string, error) {
 	var (
 		url, query string
 		deleted    bool
+		disabled   bool
 		rows       *sql.Rows
 		err        error
 	)
@@ -174,7 +586,7 @@ func (db database) LoadURL(id string) (string, error) {
 	ctx, cancelfunc := context.WithTimeout(db.ctx, 5*time.Second)
 	defer cancelfunc()
 
-	query = "SELECT url, deleted FROM urls WHERE id = $1"
+	query = "SELECT url, deleted, disabled FROM urls WHERE id = $1"
 	rows, err = db.QueryContext(ctx, query, id)
 	if err != nil {
 		log.Printf("Error %s when lodaing URL using id %v", err, id)
@@ -192,7 +604,7 @@ func (db database) LoadURL(id string) (string, error) {
 		return "", nil
 	}
 
-	err = rows.Scan(&url, &deleted)
+	err = rows.Scan(&url, &deleted, &disabled)
 	if err != nil {
 		log.Printf("Error %s when scanning query results; id %v", err, id)
 		return "", err
@@ -202,6 +614,10 @@ func (db database) LoadURL(id string) (string, error) {
 		return "", storageerrors.ErrURLGone
 	}
 
+	if disabled {
+		return "", storageerrors.ErrURLDisabled
+	}
+
 	return url, nil
 }
 
@@ -322,6 +738,365 @@ func (db database) DeleteURLs(userID string, ids []string) error {
 	return nil
 }
 
+// RestoreURLs undeletes URLs if they were uploaded by the user with userID.
+// It restores nothing and returns storageerrors.ErrQuotaExceeded
+// if the user would have more than quota links.
+func (db database) RestoreURLs(userID string, ids []string, quota int) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	if err := db.Flush(); err != nil {
+		return fmt.Errorf("failed to flush pending deletions: %w", err)
+	}
+
+	placeholders := make([]string, len(ids))
+	args := make([]interface{}, 0, len(ids)+1)
+	args = append(args, userID)
+
+	for i, id := range ids {
+		placeholders[i] = fmt.Sprintf("$%v", i+2)
+		args = append(args, id)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	ctx, cancelfunc := context.WithTimeout(db.ctx, 5*time.Second)
+	defer cancelfunc()
+
+	left, err := lockQuota(ctx, tx, []string{userID}, quota)
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf("UPDATE urls SET deleted = FALSE, deleted_at = NULL "+
+		"WHERE user_id = $1 AND deleted AND id IN (%s)",
+		strings.Join(placeholders, ","))
+
+	res, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to restore urls in database: %w", err)
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error when finding rows affected %w", err)
+	}
+
+	if quota > 0 && rows > int64(left[userID]) {
+		return storageerrors.ErrQuotaExceeded
+	}
+
+	return tx.Commit()
+}
+
+// Purge removes URLs deleted before deletedBefore
+// and returns the number of removed URLs.
+func (db database) Purge(deletedBefore time.Time) (int, error) {
+	ctx, cancelfunc := context.WithTimeout(db.ctx, 30*time.Second)
+	defer cancelfunc()
+
+	res, err := db.ExecContext(ctx, "DELETE FROM urls WHERE deleted AND deleted_at < $1", deletedBefore)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge deleted urls: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	return int(n), nil
+}
+
+// LoadLink returns the URL stored by id whether it is deleted or not.
+func (db database) LoadLink(id string) (model.Link, error) {
+	return db.loadLink("id = $1", id)
+}
+
+// FindLinkByURL returns the URL stored with the destination url.
+func (db database) FindLinkByURL(url string) (model.Link, error) {
+	return db.loadLink("url = $1", url)
+}
+
+func (db database) loadLink(cond string, arg string) (model.Link, error) {
+	var (
+		link        model.Link
+		userID      sql.NullString
+		createdAt   sql.NullTime
+		lastClickAt sql.NullTime
+	)
+
+	ctx, cancelfunc := context.WithTimeout(db.ctx, 5*time.Second)
+	defer cancelfunc()
+
+	query := "SELECT id, url, user_id, deleted, disabled, created_at, clicks, last_click_at, title, interstitial FROM urls WHERE " + cond
+	err := db.QueryRowContext(ctx, query, arg).Scan(&link.ID, &link.OriginalURL, &userID, &link.Deleted, &link.Disabled, &createdAt,
+		&link.Clicks, &lastClickAt, &link.Title, &link.Interstitial)
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.Link{}, storageerrors.ErrNotFound
+	} else if err != nil {
+		return model.Link{}, fmt.Errorf("error when loading URL: %w", err)
+	}
+
+	link.UserID = userID.String
+	link.CreatedAt = createdAt.Time
+	link.LastClickAt = lastClickAt.Time
+
+	return link, nil
+}
+
+// LoadLinksByUser returns all URLs stored by the user including deleted ones.
+func (db database) LoadLinksByUser(userID string) ([]model.Link, error) {
+	ctx, cancelfunc := context.WithTimeout(db.ctx, 5*time.Second)
+	defer cancelfunc()
+
+	query := "SELECT id, url, deleted, disabled, created_at, clicks, last_click_at, title, interstitial FROM urls WHERE user_id = $1"
+	rows, err := db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error when loading URLs of user %v: %w", userID, err)
+	}
+
+	defer rows.Close()
+
+	links := make([]model.Link, 0)
+
+	for rows.Next() {
+		var createdAt, lastClickAt sql.NullTime
+
+		link := model.Link{UserID: userID}
+		if err = rows.Scan(&link.ID, &link.OriginalURL, &link.Deleted, &link.Disabled, &createdAt, &link.Clicks, &lastClickAt,
+			&link.Title, &link.Interstitial); err != nil {
+			return nil, fmt.Errorf("error when scanning query results: %w", err)
+		}
+
+		link.CreatedAt = createdAt.Time
+		link.LastClickAt = lastClickAt.Time
+
+		links = append(links, link)
+	}
+
+	return links, rows.Err()
+}
+
+// LoadLinksPage returns up to limit URLs stored by the user including
+// deleted ones, newest first, skipping offset of them.
+func (db database) LoadLinksPage(userID string, offset, limit int) (model.LinksPage, error) {
+	ctx, cancelfunc := context.WithTimeout(db.ctx, 5*time.Second)
+	defer cancelfunc()
+
+	page := model.LinksPage{Links: make([]model.Link, 0, limit)}
+
+	query := "SELECT COUNT(id), COALESCE(SUM(clicks), 0) FROM urls WHERE user_id = $1"
+	if err := db.QueryRowContext(ctx, query, userID).Scan(&page.Total, &page.Clicks); err != nil {
+		return model.LinksPage{}, fmt.Errorf("error when counting URLs of user %v: %w", userID, err)
+	}
+
+	query = "SELECT id, url, deleted, disabled, created_at, clicks, last_click_at, title, interstitial FROM urls " +
+		"WHERE user_id = $1 ORDER BY created_at DESC NULLS LAST, id LIMIT $2 OFFSET $3"
+
+	rows, err := db.QueryContext(ctx, query, userID, limit, offset)
+	if err != nil {
+		return model.LinksPage{}, fmt.Errorf("error when loading URLs of user %v: %w", userID, err)
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var createdAt, lastClickAt sql.NullTime
+
+		link := model.Link{UserID: userID}
+		if err = rows.Scan(&link.ID, &link.OriginalURL, &link.Deleted, &link.Disabled, &createdAt, &link.Clicks, &lastClickAt,
+			&link.Title, &link.Interstitial); err != nil {
+			return model.LinksPage{}, fmt.Errorf("error when scanning query results: %w", err)
+		}
+
+		link.CreatedAt = createdAt.Time
+		link.LastClickAt = lastClickAt.Time
+
+		page.Links = append(page.Links, link)
+	}
+
+	return page, rows.Err()
+}
+
+// RangeLinks calls f for every stored URL until f returns an error.
+func (db database) RangeLinks(f func(link model.Link) error) error {
+	ctx, cancelfunc := context.WithTimeout(db.ctx, 30*time.Minute)
+	defer cancelfunc()
+
+	rows, err := db.QueryContext(ctx, "SELECT id, url, user_id, deleted, disabled, created_at, clicks, last_click_at, title, interstitial FROM urls ORDER BY id")
+	if err != nil {
+		return fmt.Errorf("error when loading URLs: %w", err)
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			link        model.Link
+			userID      sql.NullString
+			createdAt   sql.NullTime
+			lastClickAt sql.NullTime
+		)
+
+		if err = rows.Scan(&link.ID, &link.OriginalURL, &userID, &link.Deleted, &link.Disabled, &createdAt,
+			&link.Clicks, &lastClickAt, &link.Title, &link.Interstitial); err != nil {
+			return fmt.Errorf("error when scanning query results: %w", err)
+		}
+
+		link.UserID = userID.String
+		link.CreatedAt = createdAt.Time
+		link.LastClickAt = lastClickAt.Time
+
+		if err = f(link); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// SetDisabled disables or enables redirects for the URL stored by id.
+func (db database) SetDisabled(id string, disabled bool) error {
+	ctx, cancelfunc := context.WithTimeout(db.ctx, 5*time.Second)
+	defer cancelfunc()
+
+	res, err := db.ExecContext(ctx, "UPDATE urls SET disabled = $1 WHERE id = $2", disabled, id)
+	if err != nil {
+		return fmt.Errorf("error when updating row in urls table %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error when finding rows affected %w", err)
+	}
+
+	if n == 0 {
+		return storageerrors.ErrNotFound
+	}
+
+	return nil
+}
+
+// AddClick counts a redirect to the URL stored by id made at.
+// Clicks are written every clickFlushInterval and by Flush,
+// clicks on ids that are not stored are dropped then.
+func (db database) AddClick(id string, at time.Time) error {
+	db.clicks.mx.Lock()
+	defer db.clicks.mx.Unlock()
+
+	c := db.clicks.counts[id]
+	c.n++
+	if at.After(c.last) {
+		c.last = at
+	}
+
+	db.clicks.counts[id] = c
+
+	return nil
+}
+
+// flushClicks adds the clicks counted since the last flush
+// to urls with one update of up to storeURLsBatch rows per statement.
+// Clicks that fail to be written are lost.
+func (db database) flushClicks() error {
+	db.clicks.mx.Lock()
+	counts := db.clicks.counts
+	db.clicks.counts = make(map[string]clickCount)
+	db.clicks.mx.Unlock()
+
+	ids := make([]string, 0, len(counts))
+	for id := range counts {
+		ids = append(ids, id)
+	}
+
+	// rows are locked in the same order by every flush
+	sort.Strings(ids)
+
+	ctx, cancelfunc := context.WithTimeout(db.ctx, 5*time.Second)
+	defer cancelfunc()
+
+	for len(ids) > 0 {
+		n := len(ids)
+		if n > storeURLsBatch {
+			n = storeURLsBatch
+		}
+
+		valueStrings := make([]string, 0, n)
+		valueArgs := make([]interface{}, 0, n*3)
+
+		for i, id := range ids[:n] {
+			valueStrings = append(valueStrings, fmt.Sprintf("($%v, $%v::BIGINT, $%v::TIMESTAMP)", i*3+1, i*3+2, i*3+3))
+			valueArgs = append(valueArgs, id, counts[id].n, counts[id].last)
+		}
+
+		query := "UPDATE urls SET clicks = urls.clicks + tmp.n, last_click_at = GREATEST(urls.last_click_at, tmp.at) " +
+			"FROM (VALUES " + strings.Join(valueStrings, ",") + ") AS tmp (id, n, at) WHERE urls.id = tmp.id"
+
+		if _, err := db.ExecContext(ctx, query, valueArgs...); err != nil {
+			return fmt.Errorf("error when updating rows in urls table %w", err)
+		}
+
+		ids = ids[n:]
+	}
+
+	return nil
+}
+
+// DeleteUser removes all URLs and the session of the user
+// and returns the number of removed URLs.
+func (db database) DeleteUser(userID string) (int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	ctx, cancelfunc := context.WithTimeout(db.ctx, 5*time.Second)
+	defer cancelfunc()
+
+	res, err := tx.ExecContext(ctx, "DELETE FROM urls WHERE user_id = $1", userID)
+	if err != nil {
+		return 0, fmt.Errorf("error when deleting rows from urls table %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("error when finding rows affected %w", err)
+	}
+
+	if _, err = tx.ExecContext(ctx, "DELETE FROM users WHERE id = $1", userID); err != nil {
+		return 0, fmt.Errorf("error when deleting row from users table %w", err)
+	}
+
+	return int(n), tx.Commit()
+}
+
+// DeleteSessions invalidates the session of the user
+// and returns the number of removed sessions.
+// The user row is kept as it is referenced by the user's URLs.
+func (db database) DeleteSessions(userID string) (int, error) {
+	ctx, cancelfunc := context.WithTimeout(db.ctx, 5*time.Second)
+	defer cancelfunc()
+
+	res, err := db.ExecContext(ctx, "UPDATE users SET token = NULL WHERE id = $1 AND token IS NOT NULL", userID)
+	if err != nil {
+		return 0, fmt.Errorf("error when updating row in users table %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("error when finding rows affected %w", err)
+	}
+
+	return int(n), nil
+}
+
 func (ab *asyncBuf) itsToBytes(its deletedItems) ([]byte, error) {
 	ab.mx.Lock()
 	defer ab.mx.Unlock()
@@ -388,7 +1163,7 @@ loop:
 		c += 2
 	}
 
-	stmt := fmt.Sprintf("UPDATE urls SET deleted = tmp.deleted from (values %s) as tmp (id, user_id, deleted) "+
+	stmt := fmt.Sprintf("UPDATE urls SET deleted = tmp.deleted, deleted_at = NOW() from (values %s) as tmp (id, user_id, deleted) "+
 		"WHERE urls.user_id = tmp.user_id AND urls.id = tmp.id AND urls.deleted = false",
 		strings.Join(valueStrings, ","))
 
@@ -499,12 +1274,34 @@ func (db database) CountURLs() (int, error) {
 	return count, err
 }
 
+func (db database) CountURLsByUser(userID string) (int, error) {
+	var count int
+	query := "SELECT COUNT(id) FROM urls WHERE user_id = $1 AND NOT deleted"
+
+	ctx, cancelfunc := context.WithTimeout(db.ctx, 5*time.Second)
+	defer cancelfunc()
+
+	err := db.QueryRowContext(ctx, query, userID).Scan(&count)
+	if err != nil {
+		log.Printf("Error %s when counting URLs of user %v", err, userID)
+		return 0, err
+	}
+
+	return count, nil
+}
+
 func (db database) Flush() error {
+	clicksErr := db.flushClicks()
+
 	db.buffer.mx.Lock()
 	defer db.buffer.mx.Unlock()
 
 	// reset encoder to rewrite type info with next encoding
 	db.buffer.enc = gob.NewEncoder(db.buffer.ew)
 
-	return db.buffer.Flush()
+	if err := db.buffer.Flush(); err != nil {
+		return err
+	}
+
+	return clicksErr
 }
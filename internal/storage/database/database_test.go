@@ -0,0 +1,87 @@
+package database_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/usa4ev/urlshortner/internal/storage/database"
+	"github.com/usa4ev/urlshortner/internal/storage/model"
+	"github.com/usa4ev/urlshortner/internal/storage/storageerrors"
+)
+
+// testDSN returns the DSN of the PostgreSQL database to test with,
+// tests are skipped unless TEST_DATABASE_DSN is set.
+func testDSN(t *testing.T) string {
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN is not set")
+	}
+
+	return dsn
+}
+
+func Test_database_RestoreQuota(t *testing.T) {
+	db, err := database.New(testDSN(t), context.Background())
+	require.NoError(t, err)
+
+	// ids, urls and the user are unique, so the test runs on a used database
+	suffix := fmt.Sprint(time.Now().UnixNano())
+	userID := "quota" + suffix
+	ids := []string{"q1" + suffix, "q2" + suffix, "q3" + suffix}
+	url := func(i int) string { return fmt.Sprintf("http://go.dev/%v/%v", suffix, i) }
+
+	require.NoError(t, db.StoreSession(userID, "token"+suffix))
+	require.NoError(t, db.StoreURL(ids[0], url(0), userID, 2))
+	require.NoError(t, db.StoreURL(ids[1], url(1), userID, 2))
+	require.NoError(t, db.DeleteURLs(userID, ids[:2]))
+	require.NoError(t, db.Flush())
+	require.NoError(t, db.StoreURL(ids[2], url(2), userID, 2))
+
+	assert.ErrorIs(t, db.RestoreURLs(userID, ids[:2], 2), storageerrors.ErrQuotaExceeded)
+
+	_, err = db.LoadURL(ids[0])
+	assert.ErrorIs(t, err, storageerrors.ErrURLGone, "nothing must be restored over the quota")
+
+	require.NoError(t, db.RestoreURLs(userID, ids[:1], 2))
+
+	got, err := db.LoadURL(ids[0])
+	require.NoError(t, err)
+	assert.Equal(t, url(0), got)
+}
+
+func Test_database_LoadLinksPage(t *testing.T) {
+	db, err := database.New(testDSN(t), context.Background())
+	require.NoError(t, err)
+
+	suffix := fmt.Sprint(time.Now().UnixNano())
+	userID := "page" + suffix
+	created := time.Date(2023, 4, 1, 12, 0, 0, 0, time.UTC)
+
+	links := make([]model.Link, 3)
+	for i := range links {
+		links[i] = model.Link{ID: fmt.Sprintf("p%v%v", i, suffix), OriginalURL: fmt.Sprintf("http://go.dev/%v/p%v", suffix, i),
+			UserID: userID, CreatedAt: created.Add(time.Duration(i) * time.Minute)}
+	}
+
+	require.NoError(t, db.StoreSession(userID, "token"+suffix))
+
+	errs, err := db.StoreURLs(links, true, 0)
+	require.NoError(t, err)
+	require.Equal(t, make([]error, len(links)), errs)
+
+	page, err := db.LoadLinksPage(userID, 1, 1)
+	require.NoError(t, err)
+	require.Len(t, page.Links, 1)
+	assert.Equal(t, links[1].ID, page.Links[0].ID)
+	assert.Equal(t, 3, page.Total)
+
+	page, err = db.LoadLinksPage(userID, 3, 1)
+	require.NoError(t, err)
+	assert.Empty(t, page.Links)
+}
@@ -2,22 +2,32 @@ package filestorage
 
 import (
 	"encoding/csv"
+	"fmt"
 	"os"
 	"strconv"
-	"sync"
+	"strings"
+	"time"
 )
 
 type (
 	FileStorage struct {
 		filePath string
 	}
-	storer struct {
-		url     string
-		userID  string
-		deleted bool
-	}
-	Writer interface {
-		Write([]string) error
+
+	// Record is a row of the storage file.
+	Record struct {
+		ID           string
+		URL          string
+		UserID       string
+		Deleted      bool
+		DeletedAt    time.Time
+		History      []string // previous destinations, oldest first
+		Disabled     bool
+		CreatedAt    time.Time
+		Clicks       int64
+		LastClickAt  time.Time
+		Title        string
+		Interstitial bool
 	}
 )
 
@@ -31,48 +41,128 @@ func New(p string) *FileStorage {
 	}
 }
 
-func (f FileStorage) ReadFile() (*sync.Map, error) {
-	var s sync.Map
-
+// ReadFile returns all records from the storage file
+// creating the file if it does not exist.
+func (f FileStorage) ReadFile() ([]Record, error) {
 	file, err := os.OpenFile(f.filePath, os.O_RDONLY|os.O_CREATE|os.O_APPEND, 0o777)
 	if err != nil {
-		return &s, err
+		return nil, err
 	}
 
 	defer file.Close()
 
 	reader := csv.NewReader(file)
+	// files written by older versions have fewer columns
+	reader.FieldsPerRecord = -1
 
-	strings, err := reader.ReadAll()
+	rows, err := reader.ReadAll()
 	if err != nil {
-		return &s, err
+		return nil, err
 	}
 
-	for _, v := range strings {
+	records := make([]Record, 0, len(rows))
+
+	for i, v := range rows {
+		if len(v) < 4 {
+			return nil, fmt.Errorf("line %v: expected at least 4 fields, got %v", i+1, len(v))
+		}
+
 		deleted, err := strconv.ParseBool(v[3])
 		if err != nil {
-			return &s, err
+			return nil, err
+		}
+
+		rec := Record{ID: v[0], URL: v[1], UserID: v[2], Deleted: deleted}
+		if len(v) > 4 {
+			rec.History = strings.Fields(v[4])
 		}
 
-		s.Store(v[0], storer{v[1], v[2], deleted})
+		if len(v) > 5 && v[5] != "" {
+			rec.DeletedAt, err = time.Parse(time.RFC3339, v[5])
+			if err != nil {
+				return nil, fmt.Errorf("line %v: %w", i+1, err)
+			}
+		}
+
+		if len(v) > 6 && v[6] != "" {
+			rec.Disabled, err = strconv.ParseBool(v[6])
+			if err != nil {
+				return nil, fmt.Errorf("line %v: %w", i+1, err)
+			}
+		}
+
+		if len(v) > 7 && v[7] != "" {
+			rec.CreatedAt, err = time.Parse(time.RFC3339, v[7])
+			if err != nil {
+				return nil, fmt.Errorf("line %v: %w", i+1, err)
+			}
+		}
+
+		if len(v) > 8 && v[8] != "" {
+			rec.Clicks, err = strconv.ParseInt(v[8], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %v: %w", i+1, err)
+			}
+		}
+
+		if len(v) > 9 && v[9] != "" {
+			rec.LastClickAt, err = time.Parse(time.RFC3339, v[9])
+			if err != nil {
+				return nil, fmt.Errorf("line %v: %w", i+1, err)
+			}
+		}
+
+		if len(v) > 10 {
+			rec.Title = v[10]
+		}
+
+		if len(v) > 11 && v[11] != "" {
+			rec.Interstitial, err = strconv.ParseBool(v[11])
+			if err != nil {
+				return nil, fmt.Errorf("line %v: %w", i+1, err)
+			}
+		}
+
+		records = append(records, rec)
 	}
 
-	return &s, nil
+	return records, nil
 }
 
-func NewWriter(file *os.File) Writer {
-	return csv.NewWriter(file)
-}
+// WriteFile replaces the content of the storage file with records.
+func (f FileStorage) WriteFile(records []Record) error {
+	file, err := os.OpenFile(f.filePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o777)
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+
+	for _, rec := range records {
+		// destinations are validated URLs that cannot contain spaces
+		row := []string{rec.ID, rec.URL, rec.UserID, strconv.FormatBool(rec.Deleted), strings.Join(rec.History, " "),
+			formatTime(rec.DeletedAt), strconv.FormatBool(rec.Disabled), formatTime(rec.CreatedAt),
+			strconv.FormatInt(rec.Clicks, 10), formatTime(rec.LastClickAt), rec.Title, strconv.FormatBool(rec.Interstitial)}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
 
-func (f FileStorage) OpnFileW() (*os.File, error) {
-	return openFileW(f.filePath)
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return err
+	}
+
+	return file.Close()
 }
 
-func openFileW(storagePath string) (*os.File, error) {
-	file, err := os.OpenFile(storagePath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o777)
-	if err != nil {
-		return nil, err
+// formatTime returns t in RFC3339 or an empty string if t is zero.
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
 	}
 
-	return file, nil
+	return t.Format(time.RFC3339)
 }
@@ -7,11 +7,15 @@ package inmemory
 import (
 	"context"
 	"fmt"
+	"sort"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"golang.org/x/sync/errgroup"
 
 	"github.com/usa4ev/urlshortner/internal/storage/inmemory/filestorage"
+	"github.com/usa4ev/urlshortner/internal/storage/model"
 	"github.com/usa4ev/urlshortner/internal/storage/storageerrors"
 )
 
@@ -20,6 +24,8 @@ type (
 		data        *sync.Map
 		sessions    *sync.Map
 		fileManager *filestorage.FileStorage
+		mx          *sync.Mutex       // serializes updates of stored URLs
+		ids         map[string]string // ids of stored URLs by URL, guarded by mx
 	}
 
 	item struct {
@@ -28,9 +34,21 @@ type (
 	}
 
 	storer struct {
-		url     string
-		userID  string
-		deleted bool
+		url       string
+		userID    string
+		deleted   bool
+		deletedAt time.Time
+		history   []string // previous destinations, oldest first
+		disabled  bool
+		createdAt time.Time
+		clicks    *clicks // shared by copies of the row
+		settings  model.LinkSettings
+	}
+
+	// clicks counts redirects to a row without locking the storage.
+	clicks struct {
+		n    int64
+		last int64 // UnixNano of the latest click, 0 if there were none
 	}
 
 	config interface {
@@ -46,18 +64,37 @@ func New(c config) (ims, error) {
 		// setting up file storage if required
 		i.fileManager = filestorage.New(storagePath)
 
-		data, err := i.fileManager.ReadFile()
+		records, err := i.fileManager.ReadFile()
 		if err != nil {
 
 			return i, fmt.Errorf("failed to read from storage: %w", err)
 		}
 
-		i.data = data
+		i.data = &sync.Map{}
+		for _, rec := range records {
+			row := storer{url: rec.URL, userID: rec.UserID, deleted: rec.Deleted, deletedAt: rec.DeletedAt, history: rec.History, disabled: rec.Disabled, createdAt: rec.CreatedAt,
+				clicks: newClicks(rec.Clicks, rec.LastClickAt), settings: model.LinkSettings{Title: rec.Title, Interstitial: rec.Interstitial}}
+			// URLs deleted before deletion time was tracked
+			// are kept for the whole retention period from now on
+			if row.deleted && row.deletedAt.IsZero() {
+				row.deletedAt = time.Now()
+			}
+
+			i.data.Store(rec.ID, row)
+		}
 	} else {
 		i.data = &sync.Map{}
 	}
 
+	i.ids = make(map[string]string)
+	i.data.Range(func(key, value any) bool {
+		i.ids[value.(storer).url] = key.(string)
+
+		return true
+	})
+
 	i.sessions = &sync.Map{}
+	i.mx = &sync.Mutex{}
 
 	return i, nil
 }
@@ -69,6 +106,10 @@ func (s ims) LoadURL(id string) (string, error) {
 			return "", storageerrors.ErrURLGone
 		}
 
+		if val.(storer).disabled {
+			return "", storageerrors.ErrURLDisabled
+		}
+
 		return val.(storer).url, nil
 	}
 
@@ -88,12 +129,193 @@ func (s ims) LoadUrlsByUser(add func(id, url string), userID string) error {
 	return nil
 }
 
-// StoreURL adds url to the data and returns an error if the key is not unique.
-func (s ims) StoreURL(id, url, userID string) error {
-	if _, ok := s.data.LoadOrStore(id, storer{url, userID, false}); ok {
+// StoreURL adds url to the data and returns storageerrors.ErrConflict
+// if the id or the url is already stored, otherwise
+// storageerrors.ErrQuotaExceeded if the user has quota links.
+func (s ims) StoreURL(id, url, userID string, quota int) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if _, ok := s.ids[url]; ok {
+		return storageerrors.ErrConflict
+	}
+
+	if _, ok := s.data.Load(id); ok {
+		return storageerrors.ErrConflict
+	}
+
+	if quota > 0 {
+		if stored, _ := s.CountURLsByUser(userID); stored >= quota {
+			return storageerrors.ErrQuotaExceeded
+		}
+	}
+
+	if _, ok := s.data.LoadOrStore(id, storer{url: url, userID: userID, createdAt: time.Now(), clicks: &clicks{}}); ok {
+		return storageerrors.ErrConflict
+	}
+
+	s.ids[url] = id
+
+	return nil
+}
+
+// StoreURLs adds links to the data. Links whose id or url is already
+// stored, or repeats in links, get storageerrors.ErrConflict, links
+// their user has no quota left for get storageerrors.ErrQuotaExceeded.
+// If atomic is set and any link fails, nothing is stored
+// and the other links get storageerrors.ErrBatchAborted.
+func (s ims) StoreURLs(links []model.Link, atomic bool, quota int) ([]error, error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	errs := make([]error, len(links))
+	// URLs and ids of the batch stored before
+	urls := make(map[string]struct{})
+	ids := make(map[string]struct{})
+	failed := false
+
+	for i, link := range links {
+		_, urlStored := s.ids[link.OriginalURL]
+		_, urlFound := urls[link.OriginalURL]
+		_, idFound := ids[link.ID]
+
+		if _, ok := s.data.Load(link.ID); ok || urlStored || urlFound || idFound {
+			errs[i] = storageerrors.ErrConflict
+			failed = true
+
+			continue
+		}
+
+		urls[link.OriginalURL] = struct{}{}
+		ids[link.ID] = struct{}{}
+	}
+
+	if quota > 0 {
+		// links each user may still store
+		left := make(map[string]int)
+
+		for i, link := range links {
+			if errs[i] != nil {
+				continue
+			}
+
+			if _, ok := left[link.UserID]; !ok {
+				stored, _ := s.CountURLsByUser(link.UserID)
+				left[link.UserID] = quota - stored
+			}
+
+			if left[link.UserID] <= 0 {
+				errs[i] = storageerrors.ErrQuotaExceeded
+				failed = true
+
+				continue
+			}
+
+			left[link.UserID]--
+		}
+	}
+
+	if atomic && failed {
+		abort(errs)
+
+		return errs, nil
+	}
+
+	now := time.Now()
+
+	for i, link := range links {
+		if errs[i] != nil {
+			continue
+		}
+
+		// StoreURL does not lock mx
+		row := storer{url: link.OriginalURL, userID: link.UserID, createdAt: link.CreatedAt, clicks: &clicks{}}
+		if row.createdAt.IsZero() {
+			row.createdAt = now
+		}
+
+		if _, ok := s.data.LoadOrStore(link.ID, row); ok {
+			errs[i] = storageerrors.ErrConflict
+
+			continue
+		}
+
+		s.ids[link.OriginalURL] = link.ID
+	}
+
+	return errs, nil
+}
+
+// abort sets nil errors of a batch to storageerrors.ErrBatchAborted.
+func abort(errs []error) {
+	for i := range errs {
+		if errs[i] == nil {
+			errs[i] = storageerrors.ErrBatchAborted
+		}
+	}
+}
+
+// UpdateURL replaces the destination of the URL stored by id
+// keeping the previous one in the history.
+func (s ims) UpdateURL(userID, id, url string) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	val, ok := s.data.Load(id)
+	if !ok {
+		return storageerrors.ErrNotFound
+	}
+
+	row := val.(storer)
+
+	switch {
+	case row.userID != userID:
+		return storageerrors.ErrNotOwner
+	case row.deleted:
+		return storageerrors.ErrURLGone
+	case row.url == url:
+		return nil
+	}
+
+	if _, ok := s.ids[url]; ok {
 		return storageerrors.ErrConflict
 	}
 
+	history := make([]string, len(row.history), len(row.history)+1)
+	copy(history, row.history)
+
+	delete(s.ids, row.url)
+	s.ids[url] = id
+
+	row.history = append(history, row.url)
+	row.url = url
+	s.data.Store(id, row)
+
+	return nil
+}
+
+// UpdateSettings replaces settings of the URL stored by id.
+func (s ims) UpdateSettings(userID, id string, settings model.LinkSettings) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	val, ok := s.data.Load(id)
+	if !ok {
+		return storageerrors.ErrNotFound
+	}
+
+	row := val.(storer)
+
+	switch {
+	case row.userID != userID:
+		return storageerrors.ErrNotOwner
+	case row.deleted:
+		return storageerrors.ErrURLGone
+	}
+
+	row.settings = settings
+	s.data.Store(id, row)
+
 	return nil
 }
 
@@ -141,27 +363,49 @@ func (s ims) CountURLs() (int, error) {
 	return length, nil
 }
 
-// Flush writes data from the storage to a file if file manager is set.
-func (s ims) Flush() error {
-	if s.fileManager != nil {
-		file, err := s.fileManager.OpnFileW()
-		if err != nil {
-			return err
+// CountURLsByUser returns the number of links stored by the user
+// that are not deleted.
+func (s ims) CountURLsByUser(userID string) (int, error) {
+	length := 0
+
+	s.data.Range(func(_, v interface{}) bool {
+		row := v.(storer)
+		if row.userID == userID && !row.deleted {
+			length++
 		}
 
-		writer := filestorage.NewWriter(file)
+		return true
+	})
 
-		f := func(key, value any) bool {
-			err = writer.Write([]string{key.(string), value.(storer).url, value.(storer).userID, fmt.Sprintf("%t", value.(storer).deleted)})
+	return length, nil
+}
 
-			return err == nil
-		}
+// Flush writes data from the storage to a file if file manager is set.
+func (s ims) Flush() error {
+	if s.fileManager != nil {
+		records := make([]filestorage.Record, 0)
+
+		s.data.Range(func(key, value any) bool {
+			row := value.(storer)
+			records = append(records, filestorage.Record{
+				ID:           key.(string),
+				URL:          row.url,
+				UserID:       row.userID,
+				Deleted:      row.deleted,
+				DeletedAt:    row.deletedAt,
+				History:      row.history,
+				Disabled:     row.disabled,
+				CreatedAt:    row.createdAt,
+				Clicks:       row.clicks.count(),
+				LastClickAt:  row.clicks.lastAt(),
+				Title:        row.settings.Title,
+				Interstitial: row.settings.Interstitial,
+			})
 
-		s.data.Range(f)
+			return true
+		})
 
-		if err != nil {
-			return err
-		}
+		return s.fileManager.WriteFile(records)
 	}
 
 	return nil
@@ -169,6 +413,9 @@ func (s ims) Flush() error {
 
 // DeleteURLs deletes URLs if they were uploaded by the user with userID.
 func (s ims) DeleteURLs(userID string, ids []string) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
 	ch := make(chan item)
 
 	g, ctx := errgroup.WithContext(context.Background())
@@ -183,7 +430,10 @@ func (s ims) DeleteURLs(userID string, ids []string) error {
 				// if items id matches one from the ids slice
 				// we can safely delete it and remove the id from the slice
 				if val.id == v {
-					s.data.Store(val.id, storer{val.data.url, val.data.userID, true})
+					row := val.data
+					row.deleted = true
+					row.deletedAt = time.Now()
+					s.data.Store(val.id, row)
 					ids = append(ids[:i], ids[i+1:]...)
 
 					break
@@ -223,3 +473,270 @@ func (s ims) findURLsByUser(ctx context.Context, ch chan item, userID string) {
 		s.data.Range(f)
 	}()
 }
+
+// RestoreURLs undeletes URLs if they were uploaded by the user with userID.
+// It restores nothing and returns storageerrors.ErrQuotaExceeded
+// if the user would have more than quota links.
+func (s ims) RestoreURLs(userID string, ids []string, quota int) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	rows := make(map[string]storer)
+
+	for _, id := range ids {
+		val, ok := s.data.Load(id)
+		if !ok {
+			continue
+		}
+
+		row := val.(storer)
+		if row.userID != userID || !row.deleted {
+			continue
+		}
+
+		row.deleted = false
+		row.deletedAt = time.Time{}
+		rows[id] = row
+	}
+
+	if quota > 0 && len(rows) > 0 {
+		// CountURLsByUser does not lock mx
+		if stored, _ := s.CountURLsByUser(userID); stored+len(rows) > quota {
+			return storageerrors.ErrQuotaExceeded
+		}
+	}
+
+	for id, row := range rows {
+		s.data.Store(id, row)
+	}
+
+	return nil
+}
+
+// Purge removes URLs deleted before deletedBefore
+// and returns the number of removed URLs.
+func (s ims) Purge(deletedBefore time.Time) (int, error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	purged := 0
+
+	s.data.Range(func(key, value any) bool {
+		row := value.(storer)
+		if row.deleted && row.deletedAt.Before(deletedBefore) {
+			s.data.Delete(key)
+			delete(s.ids, row.url)
+			purged++
+		}
+
+		return true
+	})
+
+	return purged, nil
+}
+
+// LoadLink returns the URL stored by id whether it is deleted or not.
+func (s ims) LoadLink(id string) (model.Link, error) {
+	val, ok := s.data.Load(id)
+	if !ok {
+		return model.Link{}, storageerrors.ErrNotFound
+	}
+
+	return val.(storer).link(id), nil
+}
+
+// FindLinkByURL returns the URL stored with the destination url.
+func (s ims) FindLinkByURL(url string) (model.Link, error) {
+	s.mx.Lock()
+	id, ok := s.ids[url]
+	s.mx.Unlock()
+
+	if !ok {
+		return model.Link{}, storageerrors.ErrNotFound
+	}
+
+	return s.LoadLink(id)
+}
+
+// LoadLinksByUser returns all URLs stored by the user including deleted ones.
+func (s ims) LoadLinksByUser(userID string) ([]model.Link, error) {
+	links := make([]model.Link, 0)
+
+	s.data.Range(func(key, value any) bool {
+		if row := value.(storer); row.userID == userID {
+			links = append(links, row.link(key.(string)))
+		}
+
+		return true
+	})
+
+	return links, nil
+}
+
+// LoadLinksPage returns up to limit URLs stored by the user including
+// deleted ones, newest first, skipping offset of them.
+func (s ims) LoadLinksPage(userID string, offset, limit int) (model.LinksPage, error) {
+	links, err := s.LoadLinksByUser(userID)
+	if err != nil {
+		return model.LinksPage{}, err
+	}
+
+	page := model.LinksPage{Total: len(links)}
+
+	for _, link := range links {
+		page.Clicks += link.Clicks
+	}
+
+	sort.Slice(links, func(i, j int) bool {
+		if !links[i].CreatedAt.Equal(links[j].CreatedAt) {
+			return links[i].CreatedAt.After(links[j].CreatedAt)
+		}
+
+		return links[i].ID < links[j].ID
+	})
+
+	if offset > len(links) {
+		offset = len(links)
+	}
+
+	if limit > len(links)-offset {
+		limit = len(links) - offset
+	}
+
+	page.Links = links[offset : offset+limit]
+
+	return page, nil
+}
+
+// RangeLinks calls f for every stored URL until f returns an error.
+func (s ims) RangeLinks(f func(link model.Link) error) error {
+	var err error
+
+	s.data.Range(func(key, value any) bool {
+		err = f(value.(storer).link(key.(string)))
+
+		return err == nil
+	})
+
+	return err
+}
+
+// SetDisabled disables or enables redirects for the URL stored by id.
+func (s ims) SetDisabled(id string, disabled bool) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	val, ok := s.data.Load(id)
+	if !ok {
+		return storageerrors.ErrNotFound
+	}
+
+	row := val.(storer)
+	row.disabled = disabled
+	s.data.Store(id, row)
+
+	return nil
+}
+
+// AddClick counts a redirect to the URL stored by id made at.
+// Clicks are counted by the row, so redirects do not wait for mx.
+func (s ims) AddClick(id string, at time.Time) error {
+	val, ok := s.data.Load(id)
+	if !ok {
+		return storageerrors.ErrNotFound
+	}
+
+	val.(storer).clicks.add(at)
+
+	return nil
+}
+
+func newClicks(n int64, last time.Time) *clicks {
+	c := &clicks{n: n}
+	if !last.IsZero() {
+		c.last = last.UnixNano()
+	}
+
+	return c
+}
+
+func (c *clicks) add(at time.Time) {
+	atomic.AddInt64(&c.n, 1)
+
+	for {
+		last := atomic.LoadInt64(&c.last)
+		if at.UnixNano() <= last || atomic.CompareAndSwapInt64(&c.last, last, at.UnixNano()) {
+			return
+		}
+	}
+}
+
+func (c *clicks) count() int64 {
+	return atomic.LoadInt64(&c.n)
+}
+
+func (c *clicks) lastAt() time.Time {
+	last := atomic.LoadInt64(&c.last)
+	if last == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(0, last)
+}
+
+// DeleteUser removes all URLs and sessions of the user
+// and returns the number of removed URLs.
+func (s ims) DeleteUser(userID string) (int, error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	deleted := 0
+
+	s.data.Range(func(key, value any) bool {
+		if row := value.(storer); row.userID == userID {
+			s.data.Delete(key)
+			delete(s.ids, row.url)
+			deleted++
+		}
+
+		return true
+	})
+
+	if _, err := s.DeleteSessions(userID); err != nil {
+		return deleted, err
+	}
+
+	return deleted, nil
+}
+
+// DeleteSessions invalidates all sessions of the user
+// and returns the number of removed sessions.
+func (s ims) DeleteSessions(userID string) (int, error) {
+	deleted := 0
+
+	s.sessions.Range(func(key, value any) bool {
+		if value.(string) == userID {
+			s.sessions.Delete(key)
+			deleted++
+		}
+
+		return true
+	})
+
+	return deleted, nil
+}
+
+func (row storer) link(id string) model.Link {
+	return model.Link{
+		ID:           id,
+		OriginalURL:  row.url,
+		UserID:       row.userID,
+		Deleted:      row.deleted,
+		Disabled:     row.disabled,
+		CreatedAt:    row.createdAt,
+		Clicks:       row.clicks.count(),
+		LastClickAt:  row.clicks.lastAt(),
+		Title:        row.settings.Title,
+		Interstitial: row.settings.Interstitial,
+	}
+}
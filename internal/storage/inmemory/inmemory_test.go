@@ -4,13 +4,18 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/usa4ev/urlshortner/internal/config"
 	"github.com/usa4ev/urlshortner/internal/storage/inmemory"
+	"github.com/usa4ev/urlshortner/internal/storage/model"
+	"github.com/usa4ev/urlshortner/internal/storage/storageerrors"
 )
 
 func resetStorage(path string) error {
@@ -34,7 +39,8 @@ func resetStorage(path string) error {
 }
 
 func Test_ims_StoreLoadURL(t *testing.T) {
-	config := config.New(config.IgnoreOsArgs())
+	config, err := config.New(config.IgnoreOsArgs())
+	require.NoError(t, err)
 	defer resetStorage(config.StoragePath())
 
 	testUserID := "testuser"
@@ -63,7 +69,7 @@ func Test_ims_StoreLoadURL(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run("Strore URL's", func(t *testing.T) {
-			if err := storage.StoreURL(tt.id, tt.url, tt.userid); err != nil {
+			if err := storage.StoreURL(tt.id, tt.url, tt.userid, 0); err != nil {
 				require.NoError(t, err, "Error occurred when tried to store URL")
 			}
 		})
@@ -126,7 +132,7 @@ func Test_ims_StoreLoadURL(t *testing.T) {
 }
 
 func Example() {
-	config := config.New(config.IgnoreOsArgs())
+	config, _ := config.New(config.IgnoreOsArgs())
 
 	// new storage
 	storage, _ := inmemory.New(config)
@@ -137,7 +143,7 @@ func Example() {
 	url := "foo.com"
 
 	// store data
-	storage.StoreURL(id, url, userID)
+	storage.StoreURL(id, url, userID, 0)
 
 	// load data
 	got, _ := storage.LoadURL(id)
@@ -158,7 +164,8 @@ func Example() {
 }
 
 func Test_ims_StoreLoadUserInfo(t *testing.T) {
-	config := config.New(config.IgnoreOsArgs())
+	config, err := config.New(config.IgnoreOsArgs())
+	require.NoError(t, err)
 	defer resetStorage(config.StoragePath())
 
 	type args struct {
@@ -196,7 +203,8 @@ func Test_ims_StoreLoadUserInfo(t *testing.T) {
 }
 
 func Test_ims_DeleteURLs(t *testing.T) {
-	config := config.New(config.IgnoreOsArgs())
+	config, err := config.New(config.IgnoreOsArgs())
+	require.NoError(t, err)
 	defer resetStorage(config.StoragePath())
 
 	testUserID := "testuser"
@@ -230,7 +238,7 @@ func Test_ims_DeleteURLs(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run("Strore URL's", func(t *testing.T) {
-			if err := storage.StoreURL(tt.id, tt.url, tt.userid); err != nil {
+			if err := storage.StoreURL(tt.id, tt.url, tt.userid, 0); err != nil {
 				require.NoError(t, err, "Error occurred when tried to store URL")
 			}
 		})
@@ -258,3 +266,496 @@ func Test_ims_DeleteURLs(t *testing.T) {
 		assert.Equal(t, 0, len(p), "got wrong number of url's by user %v", testUserID)
 	})
 }
+
+func Test_ims_UpdateURL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage.csv")
+	config, err := config.New(config.IgnoreOsArgs(), config.WithEnvVars(map[string]string{"FILE_STORAGE_PATH": path}))
+	require.NoError(t, err)
+
+	testUserID := "testuser"
+
+	storage, err := inmemory.New(config)
+	require.NoError(t, err)
+
+	require.NoError(t, storage.StoreURL("1", "http://ya.ru/", testUserID, 0))
+	require.NoError(t, storage.StoreURL("2", "http://go.dev/", testUserID, 0))
+	require.NoError(t, storage.StoreURL("3", "http://go.org/", "different user", 0))
+
+	tests := []struct {
+		name   string
+		userID string
+		id     string
+		url    string
+		want   error
+	}{
+		{"update", testUserID, "1", "http://ya.ru/new", nil},
+		{"same url", testUserID, "1", "http://ya.ru/new", nil},
+		{"not found", testUserID, "4", "http://ya.ru/", storageerrors.ErrNotFound},
+		{"not owner", testUserID, "3", "http://ya.ru/", storageerrors.ErrNotOwner},
+		{"conflict", testUserID, "1", "http://go.dev/", storageerrors.ErrConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := storage.UpdateURL(tt.userID, tt.id, tt.url)
+			assert.ErrorIs(t, err, tt.want)
+		})
+	}
+
+	t.Run("deleted", func(t *testing.T) {
+		require.NoError(t, storage.DeleteURLs(testUserID, []string{"2"}))
+		assert.ErrorIs(t, storage.UpdateURL(testUserID, "2", "http://go.dev/new"), storageerrors.ErrURLGone)
+	})
+
+	t.Run("persisted", func(t *testing.T) {
+		require.NoError(t, storage.Flush())
+
+		reloaded, err := inmemory.New(config)
+		require.NoError(t, err)
+
+		got, err := reloaded.LoadURL("1")
+		require.NoError(t, err)
+		assert.Equal(t, "http://ya.ru/new", got)
+
+		_, err = reloaded.LoadURL("2")
+		assert.ErrorIs(t, err, storageerrors.ErrURLGone)
+	})
+}
+
+func Test_ims_RestorePurge(t *testing.T) {
+	config, err := config.New(config.IgnoreOsArgs(), config.WithEnvVars(map[string]string{}))
+	require.NoError(t, err)
+
+	testUserID := "testuser"
+
+	storage, err := inmemory.New(config)
+	require.NoError(t, err)
+
+	require.NoError(t, storage.StoreURL("1", "http://ya.ru/", testUserID, 0))
+	require.NoError(t, storage.StoreURL("2", "http://go.dev/", testUserID, 0))
+	require.NoError(t, storage.StoreURL("3", "http://go.org/", "different user", 0))
+	require.NoError(t, storage.DeleteURLs(testUserID, []string{"1", "2"}))
+	require.NoError(t, storage.DeleteURLs("different user", []string{"3"}))
+
+	t.Run("restore", func(t *testing.T) {
+		require.NoError(t, storage.RestoreURLs(testUserID, []string{"1", "3"}, 0))
+
+		got, err := storage.LoadURL("1")
+		require.NoError(t, err)
+		assert.Equal(t, "http://ya.ru/", got)
+
+		_, err = storage.LoadURL("3")
+		assert.ErrorIs(t, err, storageerrors.ErrURLGone, "URL of another user must not be restored")
+	})
+
+	t.Run("purge", func(t *testing.T) {
+		n, err := storage.Purge(time.Now().Add(-time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, 0, n, "URLs within retention period must not be purged")
+
+		n, err = storage.Purge(time.Now().Add(time.Second))
+		require.NoError(t, err)
+		assert.Equal(t, 2, n)
+
+		_, err = storage.LoadURL("2")
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, storageerrors.ErrURLGone)
+
+		got, err := storage.LoadURL("1")
+		require.NoError(t, err)
+		assert.Equal(t, "http://ya.ru/", got, "restored URL must not be purged")
+	})
+}
+
+func Test_ims_Admin(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage.csv")
+	config, err := config.New(config.IgnoreOsArgs(), config.WithEnvVars(map[string]string{"FILE_STORAGE_PATH": path}))
+	require.NoError(t, err)
+
+	testUserID := "testuser"
+
+	storage, err := inmemory.New(config)
+	require.NoError(t, err)
+
+	require.NoError(t, storage.StoreURL("1", "http://ya.ru/", testUserID, 0))
+	require.NoError(t, storage.StoreURL("2", "http://go.dev/", testUserID, 0))
+	require.NoError(t, storage.StoreURL("3", "http://go.org/", "different user", 0))
+	require.NoError(t, storage.StoreSession(testUserID, "token"))
+
+	t.Run("find", func(t *testing.T) {
+		link, err := storage.FindLinkByURL("http://go.dev/")
+		require.NoError(t, err)
+		assert.Equal(t, "2", link.ID)
+		assert.Equal(t, testUserID, link.UserID)
+
+		_, err = storage.FindLinkByURL("http://unknown.org/")
+		assert.ErrorIs(t, err, storageerrors.ErrNotFound)
+	})
+
+	t.Run("disable", func(t *testing.T) {
+		require.NoError(t, storage.SetDisabled("1", true))
+
+		_, err := storage.LoadURL("1")
+		assert.ErrorIs(t, err, storageerrors.ErrURLDisabled)
+
+		link, err := storage.LoadLink("1")
+		require.NoError(t, err)
+		assert.True(t, link.Disabled)
+
+		assert.ErrorIs(t, storage.SetDisabled("4", true), storageerrors.ErrNotFound)
+	})
+
+	t.Run("persisted", func(t *testing.T) {
+		require.NoError(t, storage.Flush())
+
+		reloaded, err := inmemory.New(config)
+		require.NoError(t, err)
+
+		_, err = reloaded.LoadURL("1")
+		assert.ErrorIs(t, err, storageerrors.ErrURLDisabled)
+
+		link, err := storage.LoadLink("2")
+		require.NoError(t, err)
+		require.False(t, link.CreatedAt.IsZero(), "creation time is not set")
+
+		reloadedLink, err := reloaded.LoadLink("2")
+		require.NoError(t, err)
+		assert.WithinDuration(t, link.CreatedAt, reloadedLink.CreatedAt, time.Second)
+	})
+
+	t.Run("delete sessions", func(t *testing.T) {
+		n, err := storage.DeleteSessions(testUserID)
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+
+		userID, err := storage.LoadUser("token")
+		require.NoError(t, err)
+		assert.Empty(t, userID)
+	})
+
+	t.Run("delete user", func(t *testing.T) {
+		n, err := storage.DeleteUser(testUserID)
+		require.NoError(t, err)
+		assert.Equal(t, 2, n)
+
+		links, err := storage.LoadLinksByUser(testUserID)
+		require.NoError(t, err)
+		assert.Empty(t, links)
+
+		_, err = storage.LoadLink("3")
+		assert.NoError(t, err, "URLs of other users must be kept")
+	})
+}
+
+func Test_ims_StoreURLs(t *testing.T) {
+	config, err := config.New(config.IgnoreOsArgs(), config.WithEnvVars(map[string]string{"FILE_STORAGE_PATH": ""}))
+	require.NoError(t, err)
+
+	storage, err := inmemory.New(config)
+	require.NoError(t, err)
+
+	require.NoError(t, storage.StoreURL("1", "http://ya.ru/", "testuser", 0))
+
+	links := []model.Link{
+		{ID: "old-1", OriginalURL: "http://go.dev/", UserID: "importer"},
+		{ID: "1", OriginalURL: "http://go.org/", UserID: "importer"},
+		{ID: "old-2", OriginalURL: "http://ya.ru/", UserID: "importer"},
+		{ID: "old-3", OriginalURL: "http://go.dev/", UserID: "importer"},
+		{ID: "old-4", OriginalURL: "http://vk.com/", UserID: "importer"},
+	}
+
+	t.Run("atomic", func(t *testing.T) {
+		errs, err := storage.StoreURLs(links, true, 0)
+		require.NoError(t, err)
+
+		assert.ErrorIs(t, errs[0], storageerrors.ErrBatchAborted)
+		assert.ErrorIs(t, errs[1], storageerrors.ErrConflict)
+
+		_, err = storage.LoadLink("old-1")
+		assert.ErrorIs(t, err, storageerrors.ErrNotFound, "nothing is stored if a link conflicts")
+	})
+
+	errs, err := storage.StoreURLs(links, false, 0)
+	require.NoError(t, err)
+	require.Len(t, errs, len(links))
+
+	assert.NoError(t, errs[0])
+	assert.ErrorIs(t, errs[1], storageerrors.ErrConflict, "id is stored")
+	assert.ErrorIs(t, errs[2], storageerrors.ErrConflict, "url is stored")
+	assert.ErrorIs(t, errs[3], storageerrors.ErrConflict, "url repeats in the batch")
+	assert.NoError(t, errs[4])
+
+	stored := make(map[string]string)
+	require.NoError(t, storage.RangeLinks(func(link model.Link) error {
+		stored[link.ID] = link.OriginalURL
+
+		return nil
+	}))
+
+	assert.Equal(t, map[string]string{"1": "http://ya.ru/", "old-1": "http://go.dev/", "old-4": "http://vk.com/"}, stored)
+
+	stop := errors.New("stop")
+	assert.ErrorIs(t, storage.RangeLinks(func(link model.Link) error { return stop }), stop)
+}
+
+func Test_ims_AddClick(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage.csv")
+	config, err := config.New(config.IgnoreOsArgs(), config.WithEnvVars(map[string]string{"FILE_STORAGE_PATH": path}))
+	require.NoError(t, err)
+
+	storage, err := inmemory.New(config)
+	require.NoError(t, err)
+
+	require.NoError(t, storage.StoreURL("1", "http://ya.ru/", "testuser", 0))
+
+	last := time.Date(2023, 4, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, storage.AddClick("1", last))
+	// clicks counted out of order keep the latest time
+	require.NoError(t, storage.AddClick("1", last.Add(-time.Minute)))
+	assert.ErrorIs(t, storage.AddClick("2", last), storageerrors.ErrNotFound)
+
+	link, err := storage.LoadLink("1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), link.Clicks)
+	assert.True(t, last.Equal(link.LastClickAt), "got %v", link.LastClickAt)
+
+	t.Run("persisted", func(t *testing.T) {
+		require.NoError(t, storage.Flush())
+
+		reloaded, err := inmemory.New(config)
+		require.NoError(t, err)
+
+		link, err := reloaded.LoadLink("1")
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), link.Clicks)
+		assert.True(t, last.Equal(link.LastClickAt), "got %v", link.LastClickAt)
+	})
+
+	t.Run("concurrent", func(t *testing.T) {
+		var wg sync.WaitGroup
+
+		for i := 0; i < 100; i++ {
+			wg.Add(1)
+
+			go func(i int) {
+				defer wg.Done()
+				assert.NoError(t, storage.AddClick("1", last.Add(time.Duration(i)*time.Second)))
+			}(i)
+		}
+
+		// clicks survive the row being replaced meanwhile
+		require.NoError(t, storage.UpdateURL("testuser", "1", "http://ya.ru/moved"))
+		wg.Wait()
+
+		link, err := storage.LoadLink("1")
+		require.NoError(t, err)
+		assert.Equal(t, int64(102), link.Clicks)
+		assert.True(t, last.Add(99*time.Second).Equal(link.LastClickAt), "got %v", link.LastClickAt)
+	})
+}
+
+func Test_ims_LoadLinksPage(t *testing.T) {
+	config, err := config.New(config.IgnoreOsArgs(), config.WithEnvVars(map[string]string{"FILE_STORAGE_PATH": ""}))
+	require.NoError(t, err)
+
+	storage, err := inmemory.New(config)
+	require.NoError(t, err)
+
+	created := time.Date(2023, 4, 1, 12, 0, 0, 0, time.UTC)
+	links := make([]model.Link, 5)
+
+	for i := range links {
+		links[i] = model.Link{ID: fmt.Sprint(i), OriginalURL: fmt.Sprintf("http://ya.ru/%v", i), UserID: "pageuser",
+			CreatedAt: created.Add(time.Duration(i) * time.Minute)}
+	}
+
+	links = append(links, model.Link{ID: "other", OriginalURL: "http://go.dev/", UserID: "otheruser"})
+
+	errs, err := storage.StoreURLs(links, true, 0)
+	require.NoError(t, err)
+	require.Equal(t, make([]error, len(links)), errs)
+
+	require.NoError(t, storage.AddClick("4", created))
+	require.NoError(t, storage.AddClick("0", created))
+	require.NoError(t, storage.DeleteURLs("pageuser", []string{"3"}))
+
+	ids := func(links []model.Link) []string {
+		ids := make([]string, 0, len(links))
+		for _, link := range links {
+			ids = append(ids, link.ID)
+		}
+
+		return ids
+	}
+
+	page, err := storage.LoadLinksPage("pageuser", 0, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"4", "3"}, ids(page.Links), "newest first, deleted ones included")
+	assert.Equal(t, 5, page.Total)
+	assert.Equal(t, int64(2), page.Clicks)
+
+	page, err = storage.LoadLinksPage("pageuser", 4, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"0"}, ids(page.Links))
+
+	page, err = storage.LoadLinksPage("pageuser", 6, 2)
+	require.NoError(t, err)
+	assert.Empty(t, page.Links)
+	assert.Equal(t, 5, page.Total)
+}
+
+func Test_ims_UpdateSettings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage.csv")
+	config, err := config.New(config.IgnoreOsArgs(), config.WithEnvVars(map[string]string{"FILE_STORAGE_PATH": path}))
+	require.NoError(t, err)
+
+	storage, err := inmemory.New(config)
+	require.NoError(t, err)
+
+	require.NoError(t, storage.StoreURL("1", "http://ya.ru/", "testuser", 0))
+	require.NoError(t, storage.StoreURL("2", "http://ya.com/", "testuser", 0))
+	require.NoError(t, storage.DeleteURLs("testuser", []string{"2"}))
+
+	settings := model.LinkSettings{Title: "Yandex, \"search\"", Interstitial: true}
+	require.NoError(t, storage.UpdateSettings("testuser", "1", settings))
+	assert.ErrorIs(t, storage.UpdateSettings("otheruser", "1", model.LinkSettings{}), storageerrors.ErrNotOwner)
+	assert.ErrorIs(t, storage.UpdateSettings("testuser", "2", settings), storageerrors.ErrURLGone)
+	assert.ErrorIs(t, storage.UpdateSettings("testuser", "3", settings), storageerrors.ErrNotFound)
+
+	link, err := storage.LoadLink("1")
+	require.NoError(t, err)
+	assert.Equal(t, settings.Title, link.Title)
+	assert.True(t, link.Interstitial)
+
+	t.Run("persisted", func(t *testing.T) {
+		require.NoError(t, storage.Flush())
+
+		reloaded, err := inmemory.New(config)
+		require.NoError(t, err)
+
+		link, err := reloaded.LoadLink("1")
+		require.NoError(t, err)
+		assert.Equal(t, settings.Title, link.Title)
+		assert.True(t, link.Interstitial)
+	})
+}
+
+func Test_ims_StoreURL(t *testing.T) {
+	config, err := config.New(config.IgnoreOsArgs(), config.WithEnvVars(map[string]string{"FILE_STORAGE_PATH": ""}))
+	require.NoError(t, err)
+
+	storage, err := inmemory.New(config)
+	require.NoError(t, err)
+
+	require.NoError(t, storage.StoreURL("1", "http://ya.ru/", "testuser", 0))
+	assert.ErrorIs(t, storage.StoreURL("1", "http://go.dev/", "testuser", 0), storageerrors.ErrConflict)
+	assert.ErrorIs(t, storage.StoreURL("2", "http://ya.ru/", "testuser", 0), storageerrors.ErrConflict)
+
+	require.NoError(t, storage.UpdateURL("testuser", "1", "http://ya.ru/new"))
+	assert.ErrorIs(t, storage.StoreURL("2", "http://ya.ru/new", "testuser", 0), storageerrors.ErrConflict)
+	// the previous destination may be stored again
+	require.NoError(t, storage.StoreURL("2", "http://ya.ru/", "testuser", 0))
+
+	link, err := storage.FindLinkByURL("http://ya.ru/new")
+	require.NoError(t, err)
+	assert.Equal(t, "1", link.ID)
+
+	link, err = storage.FindLinkByURL("http://ya.ru/")
+	require.NoError(t, err)
+	assert.Equal(t, "2", link.ID)
+
+	t.Run("purged", func(t *testing.T) {
+		require.NoError(t, storage.DeleteURLs("testuser", []string{"2"}))
+		_, err := storage.Purge(time.Now().Add(time.Minute))
+		require.NoError(t, err)
+
+		_, err = storage.FindLinkByURL("http://ya.ru/")
+		assert.ErrorIs(t, err, storageerrors.ErrNotFound)
+		require.NoError(t, storage.StoreURL("3", "http://ya.ru/", "testuser", 0))
+	})
+}
+
+func Test_ims_Quota(t *testing.T) {
+	config, err := config.New(config.IgnoreOsArgs(), config.WithEnvVars(map[string]string{"FILE_STORAGE_PATH": ""}))
+	require.NoError(t, err)
+
+	storage, err := inmemory.New(config)
+	require.NoError(t, err)
+
+	const quota = 5
+
+	t.Run("concurrent", func(t *testing.T) {
+		var wg sync.WaitGroup
+
+		errs := make([]error, 50)
+		for i := range errs {
+			wg.Add(1)
+
+			go func(i int) {
+				defer wg.Done()
+				errs[i] = storage.StoreURL(fmt.Sprint(i), fmt.Sprintf("http://ya.ru/%v", i), "testuser", quota)
+			}(i)
+		}
+
+		wg.Wait()
+
+		stored := 0
+		for _, err := range errs {
+			if err == nil {
+				stored++
+			} else {
+				assert.ErrorIs(t, err, storageerrors.ErrQuotaExceeded)
+			}
+		}
+
+		assert.Equal(t, quota, stored)
+	})
+
+	t.Run("conflict before quota", func(t *testing.T) {
+		assert.ErrorIs(t, storage.StoreURL("new", "http://go.dev/", "testuser", quota), storageerrors.ErrQuotaExceeded)
+
+		for i := 0; i < 50; i++ {
+			id := fmt.Sprint(i)
+			if url, err := storage.LoadURL(id); err == nil {
+				assert.ErrorIs(t, storage.StoreURL("new", url, "testuser", quota), storageerrors.ErrConflict)
+				assert.ErrorIs(t, storage.StoreURL(id, "http://go.dev/", "testuser", quota), storageerrors.ErrConflict)
+
+				return
+			}
+		}
+
+		t.Fatal("no url is stored")
+	})
+
+	t.Run("batch", func(t *testing.T) {
+		links := []model.Link{
+			{ID: "b1", OriginalURL: "http://go.dev/1", UserID: "batchuser"},
+			{ID: "b2", OriginalURL: "http://go.dev/2", UserID: "batchuser"},
+			{ID: "b3", OriginalURL: "http://go.dev/3", UserID: "batchuser"},
+		}
+
+		errs, err := storage.StoreURLs(links, true, 2)
+		require.NoError(t, err)
+		assert.Equal(t, []error{storageerrors.ErrBatchAborted, storageerrors.ErrBatchAborted, storageerrors.ErrQuotaExceeded}, errs)
+
+		errs, err = storage.StoreURLs(links, false, 2)
+		require.NoError(t, err)
+		assert.Equal(t, []error{nil, nil, storageerrors.ErrQuotaExceeded}, errs)
+	})
+
+	t.Run("restore", func(t *testing.T) {
+		require.NoError(t, storage.DeleteURLs("batchuser", []string{"b1", "b2"}))
+		require.NoError(t, storage.StoreURL("b3", "http://go.dev/3", "batchuser", 2))
+
+		assert.ErrorIs(t, storage.RestoreURLs("batchuser", []string{"b1", "b2"}, 2), storageerrors.ErrQuotaExceeded)
+
+		_, err := storage.LoadURL("b1")
+		assert.ErrorIs(t, err, storageerrors.ErrURLGone, "nothing must be restored over the quota")
+
+		require.NoError(t, storage.RestoreURLs("batchuser", []string{"b1"}, 2))
+
+		got, err := storage.LoadURL("b1")
+		require.NoError(t, err)
+		assert.Equal(t, "http://go.dev/1", got)
+	})
+}
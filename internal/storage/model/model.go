@@ -0,0 +1,40 @@
+// Package model defines data structures shared by storage implementations.
+package model
+
+import "time"
+
+// Link is a stored URL with its metadata.
+type Link struct {
+	ID          string `json:"id"`
+	ShortURL    string `json:"short_url,omitempty"` // filled by the shortener, not by storage
+	OriginalURL string `json:"original_url"`
+	UserID      string `json:"user_id"`
+	Deleted     bool   `json:"deleted"`
+	Disabled    bool   `json:"disabled"`
+	// CreatedAt is zero for links stored before it was tracked.
+	CreatedAt time.Time `json:"created_at"`
+	// Clicks is the number of redirects to OriginalURL,
+	// LastClickAt is zero if there are none.
+	Clicks      int64     `json:"clicks"`
+	LastClickAt time.Time `json:"last_click_at"`
+	// Title and Interstitial are LinkSettings of the link.
+	Title        string `json:"title,omitempty"`
+	Interstitial bool   `json:"interstitial"`
+}
+
+// LinkSettings are options of a link its owner may change.
+type LinkSettings struct {
+	// Title is shown on the preview page of the link.
+	Title string `json:"title"`
+	// Interstitial makes redirects show the preview page
+	// instead of going to the destination right away.
+	Interstitial bool `json:"interstitial"`
+}
+
+// LinksPage is a page of links of a user.
+type LinksPage struct {
+	Links []Link
+	// Total and Clicks count all links of the user.
+	Total  int
+	Clicks int64
+}
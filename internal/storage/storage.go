@@ -5,11 +5,17 @@ package storage
 import (
 	"context"
 	"fmt"
+	"time"
 
+	"github.com/usa4ev/urlshortner/internal/logging"
 	"github.com/usa4ev/urlshortner/internal/storage/database"
 	"github.com/usa4ev/urlshortner/internal/storage/inmemory"
+	"github.com/usa4ev/urlshortner/internal/storage/model"
 )
 
+// maxPurgeInterval limits how long deleted URLs may outlive the retention period.
+const maxPurgeInterval = time.Hour
+
 type (
 	Storage struct {
 		storerLoader
@@ -30,13 +36,46 @@ type (
 	storerLoader interface {
 		LoadURL(id string) (string, error)
 		LoadUrlsByUser(makeFunc func(id, url string), userID string) error
-		StoreURL(id, url, userid string) error
+		// StoreURL stores url by id and returns ErrConflict if the id
+		// or the url is already stored, otherwise ErrQuotaExceeded if
+		// the user has quota links that are not deleted. Zero quota
+		// means no limit. Quota is checked and the url is stored atomically.
+		StoreURL(id, url, userid string, quota int) error
+		// StoreURLs stores links and returns ErrConflict for links
+		// whose id or url is already stored, ErrQuotaExceeded for links
+		// their user has no quota left for, nil for stored ones.
+		// Links without CreatedAt are created now.
+		// If atomic is set, nothing is stored unless every link is
+		// and the other links get ErrBatchAborted.
+		StoreURLs(links []model.Link, atomic bool, quota int) ([]error, error)
+		UpdateURL(userID, id, url string) error
+		// UpdateSettings replaces settings of the URL stored by id.
+		UpdateSettings(userID, id string, settings model.LinkSettings) error
 		LoadUser(session string) (string, error)
 		StoreSession(id, session string) error
 		CountUsers() (int, error)
 		CountURLs() (int, error)
+		CountURLsByUser(userID string) (int, error)
 		Flush() error
 		DeleteURLs(userID string, ids []string) error
+		// RestoreURLs undeletes links of the user by ids and returns
+		// ErrQuotaExceeded without restoring any if the user would have
+		// more than quota links that are not deleted.
+		RestoreURLs(userID string, ids []string, quota int) error
+		Purge(deletedBefore time.Time) (int, error)
+		LoadLink(id string) (model.Link, error)
+		FindLinkByURL(url string) (model.Link, error)
+		LoadLinksByUser(userID string) ([]model.Link, error)
+		// LoadLinksPage returns up to limit links of the user including
+		// deleted ones, newest first, skipping offset of them.
+		LoadLinksPage(userID string, offset, limit int) (model.LinksPage, error)
+		// RangeLinks calls f for every stored link until f returns an error.
+		RangeLinks(f func(link model.Link) error) error
+		SetDisabled(id string, disabled bool) error
+		// AddClick counts a redirect to the URL stored by id made at.
+		AddClick(id string, at time.Time) error
+		DeleteUser(userID string) (int, error)
+		DeleteSessions(userID string) (int, error)
 	}
 )
 
@@ -63,7 +102,42 @@ func New(c config) (*Storage, error) {
 	return &Storage{db}, nil
 }
 
+// RunPurge removes URLs deleted more than retention ago
+// until ctx is done. It does nothing if retention is not positive.
+func (s Storage) RunPurge(ctx context.Context, retention time.Duration) {
+	if retention <= 0 {
+		return
+	}
+
+	interval := retention
+	if interval > maxPurgeInterval {
+		interval = maxPurgeInterval
+	}
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			n, err := s.Purge(time.Now().Add(-retention))
+			if err != nil {
+				logging.Errorf("failed to purge deleted URLs: %v", err)
+
+				continue
+			}
+
+			if n > 0 {
+				logging.Infof("purged %v deleted URLs", n)
+			}
+		}
+	}
+}
+
 // LoadByUser wraps LoadUrlsByUser storage method
+//
 //	to pass down the common appending function.
 func (s Storage) LoadByUser(makeURL func(id string) string, userID string) (Pairs, error) {
 	p := Pairs{}
@@ -3,6 +3,15 @@ package storageerrors
 import "errors"
 
 var (
-	ErrConflict = errors.New("URL has already been shortened")
-	ErrURLGone  = errors.New("URL with this id is deleted")
+	ErrConflict    = errors.New("URL has already been shortened")
+	ErrURLGone     = errors.New("URL with this id is deleted")
+	ErrURLDisabled = errors.New("URL with this id is disabled by operator")
+	ErrNotFound    = errors.New("URL with this id is not found")
+	ErrNotOwner    = errors.New("URL with this id belongs to another user")
+	// ErrBatchAborted is returned for links of a batch stored all-or-nothing
+	// that are not stored because other links of the batch failed.
+	ErrBatchAborted = errors.New("URL is not stored as other URLs of the batch failed")
+	// ErrQuotaExceeded is returned when a user tries to store
+	// more links than their quota allows.
+	ErrQuotaExceeded = errors.New("user quota on stored links is exceeded")
 )
@@ -34,17 +34,21 @@ func BenchmarkProf(b *testing.B) {
 	}
 
 	vars := map[string]string{"FILE_STORAGE_PATH": ""}
-	cfg := config.New(config.IgnoreOsArgs(), config.WithEnvVars(vars))
+	cfg, err := config.New(config.IgnoreOsArgs(), config.WithEnvVars(vars))
+	if err != nil {
+		b.Fatal(err)
+	}
+
 	storage, _ := inmemory.New(cfg)
 
 	//store
 	for _, v := range data {
-		storage.StoreURL(v.id, v.url, v.userID)
+		storage.StoreURL(v.id, v.url, v.userID, 0)
 	}
 
 	// repeat to cover conflict cases
 	for _, v := range data {
-		storage.StoreURL(v.id, v.url, v.userID)
+		storage.StoreURL(v.id, v.url, v.userID, 0)
 	}
 
 	// load data